@@ -162,7 +162,6 @@ func runAICrawl(cmd *cobra.Command, args []string) error {
 
 		title := strings.TrimSpace(doc.Find("title").First().Text())
 		desc, _ := doc.Find("meta[name='description']").Attr("content")
-		author, _ := doc.Find("meta[name='author']").Attr("content")
 
 		var headings []string
 		doc.Find("h1, h2, h3").Each(func(_ int, s *goquery.Selection) {
@@ -171,9 +170,8 @@ func runAICrawl(cmd *cobra.Command, args []string) error {
 			}
 		})
 
-		bodyClone := doc.Find("body").Clone()
-		bodyClone.Find("script, style, nav, footer, header, aside").Remove()
-		bodyText := strings.TrimSpace(bodyClone.Text())
+		article := parser.ExtractArticleFromDocument(doc, resp.FinalURL)
+		bodyText := article.Text
 		words := strings.Fields(bodyText)
 		bodyText = strings.Join(words, " ")
 		if len(bodyText) > 3000 {
@@ -185,10 +183,14 @@ func runAICrawl(cmd *cobra.Command, args []string) error {
 			item.Set("url", url)
 			item.Set("title", title)
 			item.Set("description", desc)
-			item.Set("author", author)
+			item.Set("author", article.Byline)
 			item.Set("headings", headings)
 			item.Set("body_text", bodyText)
 			item.Set("word_count", len(words))
+			if !article.Published.IsZero() {
+				item.Set("published", article.Published.Format(time.RFC3339))
+			}
+			item.Set("lead_image", article.LeadImage)
 			item.Set("crawled_at", time.Now().Format(time.RFC3339))
 			return []*types.Item{item}, nil, nil
 		}
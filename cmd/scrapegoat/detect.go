@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/fetcher"
+	"github.com/IshaanNene/ScrapeGoat/internal/parser"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+var (
+	detectLimit   int
+	detectSamples int
+	detectSave    string
+)
+
+// detectCmd creates the "detect" subcommand.
+func detectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "detect [url]",
+		Short: "Detect repeated records on a page and generate parse rules",
+		Long: `Fetch a page, find repeated record structures (product cards, search
+results, table rows) and infer a selector for each of their fields.
+
+Prints the detected record sets with sample items and the generated parse
+rules as YAML. Use --save to write the rules of the best set to a config
+file that can be edited and passed to "scrapegoat crawl -c".`,
+		Args: cobra.ExactArgs(1),
+		RunE: runDetect,
+	}
+
+	cmd.Flags().IntVar(&detectLimit, "limit", 3, "maximum number of record sets to show")
+	cmd.Flags().IntVar(&detectSamples, "samples", 3, "sample items to print per record set")
+	cmd.Flags().StringVar(&detectSave, "save", "", "write the best record set's rules to this YAML file")
+
+	return cmd
+}
+
+func runDetect(cmd *cobra.Command, args []string) error {
+	logger := setupLogger()
+
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if err := config.ValidateURL(args[0]); err != nil {
+		return fmt.Errorf("invalid URL %q: %w", args[0], err)
+	}
+
+	httpFetcher, err := fetcher.NewHTTPFetcher(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("create fetcher: %w", err)
+	}
+	defer httpFetcher.Close()
+
+	req, err := types.NewRequest(args[0])
+	if err != nil {
+		return err
+	}
+	resp, err := httpFetcher.Fetch(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("fetch: %w", err)
+	}
+
+	sets, err := parser.NewRecordDetector(logger).DetectAll(resp, detectLimit)
+	if err != nil {
+		return fmt.Errorf("detect records: %w", err)
+	}
+	if len(sets) == 0 {
+		fmt.Println("No repeated records found.")
+		return nil
+	}
+
+	for i, rs := range sets {
+		fmt.Printf("\n#%d  %s  (%d records, score %.1f)\n", i+1, rs.Selector, rs.Count, rs.Score)
+		for _, f := range rs.Fields {
+			attr := f.Attribute
+			if attr == "" {
+				attr = "text"
+			}
+			fmt.Printf("   %-20s %-40s %-6s %3.0f%%\n", f.Name, f.Selector, attr, f.Coverage*100)
+		}
+		for j, item := range rs.Items {
+			if j >= detectSamples {
+				break
+			}
+			data, _ := json.Marshal(item.Fields)
+			fmt.Printf("   sample: %s\n", data)
+		}
+
+		out, err := rulesYAML(rs.Rules())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n%s", out)
+	}
+
+	if detectSave != "" {
+		out, err := rulesYAML(sets[0].Rules())
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(detectSave, out, 0644); err != nil {
+			return fmt.Errorf("save rules: %w", err)
+		}
+		fmt.Printf("\n✅ Rules saved to %s\n", detectSave)
+	}
+	return nil
+}
+
+// rulesYAML renders rules as a "parser.rules" config fragment.
+func rulesYAML(rules []config.ParseRule) ([]byte, error) {
+	type rule struct {
+		Name      string `yaml:"name"`
+		Selector  string `yaml:"selector"`
+		Type      string `yaml:"type"`
+		Attribute string `yaml:"attribute,omitempty"`
+	}
+	var doc struct {
+		Parser struct {
+			Rules []rule `yaml:"rules"`
+		} `yaml:"parser"`
+	}
+	for _, r := range rules {
+		doc.Parser.Rules = append(doc.Parser.Rules, rule{r.Name, r.Selector, r.Type, r.Attribute})
+	}
+	return yaml.Marshal(&doc)
+}
@@ -5,18 +5,23 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/IshaanNene/ScrapeGoat/internal/api"
 	"github.com/IshaanNene/ScrapeGoat/internal/config"
 	"github.com/IshaanNene/ScrapeGoat/internal/engine"
 	"github.com/IshaanNene/ScrapeGoat/internal/fetcher"
 	"github.com/IshaanNene/ScrapeGoat/internal/observability"
 	"github.com/IshaanNene/ScrapeGoat/internal/parser"
 	"github.com/IshaanNene/ScrapeGoat/internal/pipeline"
+	"github.com/IshaanNene/ScrapeGoat/internal/plugin"
+	"github.com/IshaanNene/ScrapeGoat/internal/schema"
 	"github.com/IshaanNene/ScrapeGoat/internal/storage"
 )
 
@@ -32,6 +37,7 @@ var (
 	maxRequests    int
 	maxRetries     int
 	allowedDomains string
+	sitemapMode    bool
 )
 
 func main() {
@@ -58,6 +64,8 @@ Features:
 	rootCmd.AddCommand(crawlCmd())
 	rootCmd.AddCommand(searchCmd())
 	rootCmd.AddCommand(aiCrawlCmd())
+	rootCmd.AddCommand(detectCmd())
+	rootCmd.AddCommand(testRulesCmd())
 	rootCmd.AddCommand(versionCmd())
 	rootCmd.AddCommand(configCmd())
 
@@ -77,7 +85,7 @@ func crawlCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&outputPath, "output", "o", "./output", "output directory or file path")
-	cmd.Flags().StringVarP(&outputType, "format", "f", "json", "output format: json, jsonl, csv")
+	cmd.Flags().StringVarP(&outputType, "format", "f", "json", "output format: json, jsonl, csv, sqlite, postgres, parquet, arrow")
 	cmd.Flags().IntVarP(&depth, "depth", "d", 3, "maximum crawl depth")
 	cmd.Flags().IntVarP(&concurrent, "concurrency", "n", 10, "number of concurrent workers")
 	cmd.Flags().StringVar(&delay, "delay", "1s", "politeness delay between requests per domain")
@@ -85,6 +93,7 @@ func crawlCmd() *cobra.Command {
 	cmd.Flags().IntVarP(&maxRequests, "max-requests", "m", 0, "maximum total requests (0 = unlimited)")
 	cmd.Flags().IntVar(&maxRetries, "max-retries", -1, "max retries per failed request (-1 = use config default of 3)")
 	cmd.Flags().StringVar(&allowedDomains, "allowed-domains", "", "comma-separated domains to stay within (e.g. en.wikipedia.org)")
+	cmd.Flags().BoolVar(&sitemapMode, "sitemap", false, "seed each site from its sitemaps (a seed may also be a sitemap URL)")
 
 	return cmd
 }
@@ -135,31 +144,120 @@ func runCrawl(cmd *cobra.Command, args []string) error {
 
 	// Setup parser
 	compositeParser := parser.NewCompositeParser(logger)
+	compositeParser.SetAutoDetect(cfg.Parser.AutoDetect)
+	compositeParser.SetSchemaOrg(cfg.Parser.SchemaOrg)
+	if cfg.Parser.Feed.OnlyNew {
+		feedState, err := parser.LoadFeedState(cfg.Parser.Feed.StatePath)
+		if err != nil {
+			return fmt.Errorf("load feed state: %w", err)
+		}
+		compositeParser.SetFeedState(feedState)
+	}
+	var tracker *parser.SmartTracker
+	if selfHealing(cfg.Parser) {
+		tracker = parser.NewSmartTracker(logger)
+		tracker.SetStore(cfg.Parser.SelfHeal.SnapshotDir)
+		tracker.SetMinSimilarity(cfg.Parser.SelfHeal.MinSimilarity)
+		compositeParser.SetSelfHealing(tracker)
+	}
 	eng.SetParser(compositeParser)
+	if len(cfg.Parser.Pagination) > 0 {
+		paginator, err := parser.NewPaginator(cfg.Parser.Pagination, logger)
+		if err != nil {
+			return fmt.Errorf("invalid pagination config: %w", err)
+		}
+		eng.SetPaginator(paginator)
+	}
+	if len(cfg.Parser.Forms) > 0 {
+		forms, err := parser.NewFormSubmitter(cfg.Parser.Forms, logger)
+		if err != nil {
+			return fmt.Errorf("invalid forms config: %w", err)
+		}
+		eng.SetFormSubmitter(forms)
+	}
 
-	// Setup pipeline
-	pipe := pipeline.New(logger)
-	pipe.Use(&pipeline.TrimMiddleware{})
+	// Setup pipeline from pipeline.middlewares, which may name middleware
+	// plugins; trimming is the default
+	pipe, err := plugin.DefaultRegistry.BuildPipeline(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("invalid pipeline config: %w", err)
+	}
+	defer plugin.DefaultRegistry.CloseAll()
+	defer pipe.Close()
+	if pipe.Len() == 0 {
+		pipe.Use(&pipeline.TrimMiddleware{})
+	}
+	var itemSchema *schema.Schema
+	if sc := cfg.Pipeline.Schema; sc.File != "" {
+		itemSchema, err = schema.Load(sc.File)
+		if err != nil {
+			return fmt.Errorf("load item schema: %w", err)
+		}
+		validate, err := pipeline.NewSchemaValidateMiddleware(itemSchema, sc.OnInvalid, sc.DeadLetterPath, sc.Coerce)
+		if err != nil {
+			return fmt.Errorf("create schema validation: %w", err)
+		}
+		pipe.Use(validate)
+	}
 	eng.SetPipeline(pipe)
 
 	// Setup storage
-	store, err := storage.NewFileStorage(cfg.Storage.Type, cfg.Storage.OutputPath, logger)
+	var storeOpts []storage.Option
+	if cfg.Storage.Upsert {
+		storeOpts = append(storeOpts, storage.WithUpsert())
+	}
+	storeOpts = append(storeOpts,
+		storage.WithCompression(cfg.Storage.Compression),
+		storage.WithRowGroupSize(cfg.Storage.RowGroupSize),
+		storage.WithRotation(cfg.Storage.RotateItems, int64(cfg.Storage.RotateSizeMB)<<20),
+		storage.WithRotateInterval(cfg.Storage.RotateInterval),
+		storage.WithPathTemplate(cfg.Storage.PathTemplate))
+	if itemSchema != nil {
+		storeOpts = append(storeOpts, storage.WithSchema(itemSchema))
+	}
+	store, err := storage.New(cfg.Storage.Type, cfg.Storage.OutputPath, cfg.Storage.DSN, cfg.Storage.Table, logger, storeOpts...)
 	if err != nil {
 		return fmt.Errorf("create storage: %w", err)
 	}
+	if csvStore, ok := store.(interface{ SetColumns([]string) }); ok && itemSchema != nil {
+		csvStore.SetColumns(itemSchema.PropertyNames())
+	}
 	eng.SetStorage(store)
 
 	// Setup metrics (if enabled)
 	if cfg.Metrics.Enabled {
 		metrics := observability.NewMetrics(logger)
+		metrics.AddCollector(pipelineSamples(pipe))
 		if err := metrics.StartServer(cfg.Metrics.Port, cfg.Metrics.Path); err != nil {
 			logger.Warn("failed to start metrics server", "error", err)
 		}
 	}
 
+	// Setup REST API (if enabled)
+	if cfg.API.Enabled {
+		srv := api.NewServer(cfg.API.Port, logger)
+		srv.SetEngine(apiEngine{eng})
+		if tracker != nil {
+			srv.SetRepairReporter(tracker)
+		}
+		if err := srv.Start(); err != nil {
+			logger.Warn("failed to start API server", "error", err)
+		}
+	}
+
 	// Add seed URLs — robots-block on a seed is a warning, not fatal
 	var seedsAdded int
 	for _, rawURL := range args {
+		if cfg.Engine.Sitemap.Enabled {
+			n, err := eng.AddSitemapSeeds(rawURL)
+			if err != nil {
+				logger.Warn("sitemap seeding failed", "url", rawURL, "reason", err)
+			}
+			seedsAdded += n
+			if engine.IsSitemapURL(rawURL) {
+				continue
+			}
+		}
 		if err := eng.AddSeed(rawURL); err != nil {
 			logger.Warn("seed skipped", "url", rawURL, "reason", err)
 		} else {
@@ -205,6 +303,26 @@ func runCrawl(cmd *cobra.Command, args []string) error {
 	fmt.Printf("   Data:      %v bytes downloaded\n", stats["bytes_downloaded"])
 	fmt.Printf("   Output:    %s\n", cfg.Storage.OutputPath)
 
+	for _, st := range pipe.Stats() {
+		logger.Debug("pipeline stage",
+			"stage", st.Name, "workers", st.Workers,
+			"in", st.In, "out", st.Out, "dropped", st.Dropped, "errors", st.Errors,
+			"batches", st.Batches, "blocked", st.Blocked,
+		)
+	}
+
+	if tracker != nil {
+		if repairs := tracker.Repairs(); len(repairs) > 0 {
+			fmt.Printf("\n🔧 %d selector repair(s), recorded in %s:\n", len(repairs), filepath.Join(cfg.Parser.SelfHeal.SnapshotDir, "repairs.jsonl"))
+			for _, r := range repairs {
+				if r.Flagged {
+					fmt.Print("   (flagged, below min_similarity — not applied)\n")
+				}
+				fmt.Print(r.Diff())
+			}
+		}
+	}
+
 	if stats["items_scraped"] == int64(0) {
 		fmt.Println("\n💡 No items were scraped. The crawl command discovers and follows links by default.")
 		fmt.Println("   For automatic content extraction, try:")
@@ -216,6 +334,28 @@ func runCrawl(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// selfHealing reports whether any parse rule, in rules or a rule set, has
+// self_heal set.
+func selfHealing(cfg config.ParserConfig) bool {
+	heals := func(r config.ParseRule) bool { return r.SelfHeal }
+	if slices.ContainsFunc(cfg.Rules, heals) {
+		return true
+	}
+	for _, rules := range cfg.RuleSets {
+		if slices.ContainsFunc(rules, heals) {
+			return true
+		}
+	}
+	return false
+}
+
+// apiEngine adapts the engine to the API server's controller interface.
+type apiEngine struct{ *engine.Engine }
+
+func (e apiEngine) GetState() string { return e.Engine.GetState().String() }
+
+func (e apiEngine) GetStats() map[string]any { return e.Stats().Snapshot() }
+
 // versionCmd creates the "version" subcommand.
 func versionCmd() *cobra.Command {
 	return &cobra.Command{
@@ -280,6 +420,25 @@ func setupLogger() *slog.Logger {
 	return slog.New(handler)
 }
 
+// pipelineSamples reports each pipeline stage's backpressure as gauges.
+func pipelineSamples(pipe *pipeline.Pipeline) func() []observability.Sample {
+	return func() []observability.Sample {
+		var samples []observability.Sample
+		for _, st := range pipe.Stats() {
+			labels := map[string]string{"stage": st.Name}
+			samples = append(samples,
+				observability.Sample{Name: "scrapegoat_pipeline_stage_queued", Help: "Items waiting for a pipeline stage", Labels: labels, Value: float64(st.Queued)},
+				observability.Sample{Name: "scrapegoat_pipeline_stage_busy", Help: "Pipeline stage workers processing now", Labels: labels, Value: float64(st.Busy)},
+				observability.Sample{Name: "scrapegoat_pipeline_stage_items", Help: "Items that entered a pipeline stage", Labels: labels, Value: float64(st.In)},
+				observability.Sample{Name: "scrapegoat_pipeline_stage_dropped", Help: "Items a pipeline stage dropped", Labels: labels, Value: float64(st.Dropped)},
+				observability.Sample{Name: "scrapegoat_pipeline_stage_errors", Help: "Items a pipeline stage failed", Labels: labels, Value: float64(st.Errors)},
+				observability.Sample{Name: "scrapegoat_pipeline_stage_blocked_seconds", Help: "Time a pipeline stage waited on the next one", Labels: labels, Value: st.Blocked.Seconds()},
+			)
+		}
+		return samples
+	}
+}
+
 // applyCLIOverrides applies command-line flag values to the config.
 func applyCLIOverrides(cfg *config.Config) {
 	// Always apply depth and concurrency since they have sensible defaults
@@ -317,4 +476,7 @@ func applyCLIOverrides(cfg *config.Config) {
 		}
 		cfg.Engine.AllowedDomains = domains
 	}
+	if sitemapMode {
+		cfg.Engine.Sitemap.Enabled = true
+	}
 }
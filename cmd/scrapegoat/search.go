@@ -28,6 +28,8 @@ var (
 	searchOutput         string
 	searchDelay          string
 	searchAllowedDomains string
+	searchFormat         string
+	searchChunkTokens    int
 )
 
 // searchCmd creates the "search" subcommand.
@@ -39,7 +41,11 @@ func searchCmd() *cobra.Command {
 
 Extracts for each page: title, meta description, keywords, canonical URL,
 h1/h2/h3 headings, cleaned body text, word count, outbound link count,
-image count, and content hash. Output is JSONL (one document per line).`,
+image count, and content hash. Output is JSONL (one document per line).
+
+With --format markdown the main content is stored as GitHub-flavoured
+Markdown in body_markdown instead of body_text, and --chunk-tokens splits it
+by heading into chunks sized for embedding.`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: runSearch,
 	}
@@ -50,11 +56,17 @@ image count, and content hash. Output is JSONL (one document per line).`,
 	cmd.Flags().StringVarP(&searchOutput, "output", "o", "./output/search_index", "output directory")
 	cmd.Flags().StringVar(&searchDelay, "delay", "200ms", "delay between requests")
 	cmd.Flags().StringVar(&searchAllowedDomains, "allowed-domains", "", "comma-separated domains to stay within")
+	cmd.Flags().StringVar(&searchFormat, "format", "text", "body format: text, markdown")
+	cmd.Flags().IntVar(&searchChunkTokens, "chunk-tokens", 0, "split markdown into chunks of at most N tokens (0 = no chunking)")
 
 	return cmd
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
+	if searchFormat != "text" && searchFormat != "markdown" {
+		return fmt.Errorf("invalid --format %q (must be text or markdown)", searchFormat)
+	}
+
 	logger := setupLogger()
 	cfg := config.DefaultConfig()
 	cfg.Engine.Concurrency = searchConcurrent
@@ -129,9 +141,8 @@ func runSearch(cmd *cobra.Command, args []string) error {
 			}
 		})
 
-		bodyClone := doc.Find("body").Clone()
-		bodyClone.Find("script, style, nav, footer, header, aside, .sidebar, .menu, .nav, .cookie").Remove()
-		bodyText := strings.TrimSpace(bodyClone.Text())
+		article := parser.ExtractArticleFromDocument(doc, resp.FinalURL)
+		bodyText := article.Text
 		words := strings.Fields(bodyText)
 		bodyText = strings.Join(words, " ")
 		if len(bodyText) > 5000 {
@@ -152,8 +163,24 @@ func runSearch(cmd *cobra.Command, args []string) error {
 			item.Set("h1", h1s)
 			item.Set("h2", h2s)
 			item.Set("h3", h3s)
-			item.Set("body_text", bodyText)
+			if searchFormat == "markdown" {
+				md, err := parser.HTMLStringToMarkdown(article.HTML, url)
+				if err != nil {
+					return nil, nil, err
+				}
+				item.Set("body_markdown", md)
+				if searchChunkTokens > 0 {
+					item.Set("chunks", parser.ChunkMarkdown(md, searchChunkTokens))
+				}
+			} else {
+				item.Set("body_text", bodyText)
+			}
 			item.Set("word_count", len(words))
+			item.Set("byline", article.Byline)
+			if !article.Published.IsZero() {
+				item.Set("published", article.Published.Format(time.RFC3339))
+			}
+			item.Set("lead_image", article.LeadImage)
 			item.Set("outbound_links", linkCount)
 			item.Set("images", imgCount)
 			item.Set("content_hash", hash)
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/parser"
+)
+
+var (
+	testRulesUpdate bool
+	testRulesJSON   bool
+)
+
+// testRulesCmd creates the "test-rules" subcommand.
+func testRulesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test-rules [fixtures-dir]",
+		Short: "Check parse rules against saved fixtures and golden output",
+		Long: `Run the config's parse rules against saved pages and compare the items
+produced with golden expected-output files.
+
+A fixture is a saved page (product.html, feed.xml, ...) or a cached response
+(product.response.json with url, status_code, content_type and body). Its
+expected items are read from product.golden.json; an optional product.url
+holds the page's original URL for resolving relative links. A fixture is
+parsed with the rule set named by the response's rule_set field or by an
+optional product.rule_set file, and with the default rules otherwise.
+
+The parser is set up as for a crawl, except that state is kept in memory:
+with feed only_new, fixtures of the same feed URL see only entries earlier
+fixtures did not have, and self-healing rules are repaired from snapshots
+of earlier fixtures rather than from snapshot_dir. Fixtures run in name
+order, so results do not depend on previous runs.
+
+Reports per-field differences and, for each rule, the share of fixtures it
+produced a value for. Exits non-zero when any fixture fails, so it can run
+in CI. Use --update to write golden files from the current output.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runTestRules,
+	}
+
+	cmd.Flags().BoolVar(&testRulesUpdate, "update", false, "write golden files from the current output")
+	cmd.Flags().BoolVar(&testRulesJSON, "json", false, "print the report as JSON")
+
+	return cmd
+}
+
+func runTestRules(cmd *cobra.Command, args []string) error {
+	logger := setupLogger()
+
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	dir := "fixtures"
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	fixtures, err := parser.LoadFixtures(dir)
+	if err != nil {
+		return err
+	}
+	if len(fixtures) == 0 {
+		return fmt.Errorf("no fixtures found in %s", dir)
+	}
+
+	compositeParser := parser.NewCompositeParser(logger)
+	compositeParser.SetAutoDetect(cfg.Parser.AutoDetect)
+	compositeParser.SetSchemaOrg(cfg.Parser.SchemaOrg)
+	if cfg.Parser.Feed.OnlyNew {
+		feedState, _ := parser.LoadFeedState("") // in memory; cannot fail
+		compositeParser.SetFeedState(feedState)
+	}
+	if selfHealing(cfg.Parser) {
+		tracker := parser.NewSmartTracker(logger)
+		tracker.SetMinSimilarity(cfg.Parser.SelfHeal.MinSimilarity)
+		compositeParser.SetSelfHealing(tracker)
+	}
+
+	report := parser.RunConformance(compositeParser, cfg.Parser.Rules, cfg.Parser.RuleSets, fixtures, testRulesUpdate)
+
+	if testRulesJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printConformanceReport(report)
+	}
+
+	if failed := report.Failed(); failed > 0 {
+		cmd.SilenceUsage = true // a failing fixture is not a usage error
+		return fmt.Errorf("%d of %d fixtures failed", failed, len(report.Results))
+	}
+	return nil
+}
+
+func printConformanceReport(report *parser.ConformanceReport) {
+	for _, res := range report.Results {
+		status := "PASS"
+		switch {
+		case res.Error != "":
+			status = "ERROR"
+		case res.Updated:
+			status = "UPDATED"
+		case res.NoGolden:
+			status = "NO GOLDEN"
+		case !res.Passed():
+			status = "FAIL"
+		}
+		fmt.Printf("%-9s %s (%d items)\n", status, res.Name, res.Items)
+		if res.Error != "" {
+			fmt.Printf("          %s\n", res.Error)
+		}
+		if res.NoGolden {
+			fmt.Println("          no golden file; run with --update to create it")
+		}
+		for _, d := range res.Diffs {
+			fmt.Printf("          %s\n", d)
+		}
+	}
+
+	if len(report.Coverage) > 0 {
+		fmt.Println("\nRule coverage:")
+		for _, c := range report.Coverage {
+			pct := 0.0
+			if c.Total > 0 {
+				pct = float64(c.Matched) / float64(c.Total) * 100
+			}
+			warn := ""
+			if c.Matched == 0 {
+				warn = "  ⚠ never matched"
+			}
+			name := c.Rule
+			if c.RuleSet != "" {
+				name = c.RuleSet + "/" + c.Rule
+			}
+			fmt.Printf("   %-24s %3d/%-3d %4.0f%%%s\n", name, c.Matched, c.Total, pct, warn)
+		}
+	}
+
+	passed := len(report.Results) - report.Failed()
+	fmt.Printf("\n%d/%d fixtures passed\n", passed, len(report.Results))
+}
@@ -268,33 +268,88 @@ func (s *SentimentAnalyzer) Name() string { return "ai_sentiment" }
 
 func (s *SentimentAnalyzer) Process(item *types.Item) (*types.Item, error) {
 	for _, field := range s.fields {
-		text := item.GetString(field)
-		if text == "" {
-			continue
+		s.analyze(item, field)
+	}
+	return item, nil
+}
+
+func (s *SentimentAnalyzer) analyze(item *types.Item, field string) {
+	text := item.GetString(field)
+	if text == "" {
+		return
+	}
+	if len(text) > 2000 {
+		text = text[:2000]
+	}
+
+	prompt := fmt.Sprintf(`Analyze the sentiment of the following text. Return JSON with:
+- "sentiment": "positive", "negative", "neutral", or "mixed"
+- "score": float from -1.0 (very negative) to 1.0 (very positive)
+- "keywords": array of key sentiment-bearing words
+
+Text: %s`, text)
+
+	response, err := s.client.Generate(context.Background(), prompt)
+	if err != nil {
+		s.logger.Warn("sentiment analysis failed", "field", field, "error", err)
+		return
+	}
+
+	var sentiment map[string]any
+	if err := json.Unmarshal([]byte(extractJSON(response)), &sentiment); err == nil {
+		item.Set(field+"_sentiment", sentiment)
+	}
+}
+
+// ProcessBatch analyzes a field of every item in one LLM call. When the reply
+// does not hold one result per text, the items are analyzed one at a time.
+func (s *SentimentAnalyzer) ProcessBatch(items []*types.Item) ([]*types.Item, error) {
+	for _, field := range s.fields {
+		var (
+			indexes []int
+			texts   strings.Builder
+		)
+		for i, item := range items {
+			text := item.GetString(field)
+			if text == "" {
+				continue
+			}
+			if len(text) > 1000 {
+				text = text[:1000]
+			}
+			indexes = append(indexes, i)
+			fmt.Fprintf(&texts, "[%d] %s\n\n", len(indexes), text)
 		}
-		if len(text) > 2000 {
-			text = text[:2000]
+		if len(indexes) == 0 {
+			continue
 		}
 
-		prompt := fmt.Sprintf(`Analyze the sentiment of the following text. Return JSON with:
+		prompt := fmt.Sprintf(`Analyze the sentiment of each numbered text below. Return JSON {"results": [...]} with one object per text, in order, each with:
 - "sentiment": "positive", "negative", "neutral", or "mixed"
 - "score": float from -1.0 (very negative) to 1.0 (very positive)
 - "keywords": array of key sentiment-bearing words
 
-Text: %s`, text)
+%s`, texts.String())
 
+		var reply struct {
+			Results []map[string]any `json:"results"`
+		}
 		response, err := s.client.Generate(context.Background(), prompt)
-		if err != nil {
-			s.logger.Warn("sentiment analysis failed", "field", field, "error", err)
+		if err == nil {
+			err = json.Unmarshal([]byte(extractJSON(response)), &reply)
+		}
+		if err != nil || len(reply.Results) != len(indexes) {
+			s.logger.Warn("batch sentiment analysis failed, analyzing items singly", "field", field, "items", len(indexes), "error", err)
+			for _, i := range indexes {
+				s.analyze(items[i], field)
+			}
 			continue
 		}
-
-		var sentiment map[string]any
-		if err := json.Unmarshal([]byte(extractJSON(response)), &sentiment); err == nil {
-			item.Set(field+"_sentiment", sentiment)
+		for j, i := range indexes {
+			items[i].Set(field+"_sentiment", reply.Results[j])
 		}
 	}
-	return item, nil
+	return items, nil
 }
 
 // ContentFilter pre-filters content before sending to LLM.
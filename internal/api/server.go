@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/parser"
 )
 
 // Server provides a REST API for external control of the crawler.
@@ -18,6 +20,9 @@ type Server struct {
 	// Engine interface (set at runtime)
 	engineCtrl EngineController
 
+	// Self-healing selector repairs (optional)
+	repairs RepairReporter
+
 	// Job tracking
 	jobs   map[string]*Job
 	jobsMu sync.RWMutex
@@ -34,6 +39,11 @@ type EngineController interface {
 	GetStats() map[string]any
 }
 
+// RepairReporter exposes the selector repairs made by self-healing rules.
+type RepairReporter interface {
+	Repairs() []parser.SelectorRepair
+}
+
 // Job tracks a crawl job.
 type Job struct {
 	ID        string         `json:"id"`
@@ -62,6 +72,11 @@ func (s *Server) SetEngine(engine EngineController) {
 	s.engineCtrl = engine
 }
 
+// SetRepairReporter sets the source of selector repairs for review.
+func (s *Server) SetRepairReporter(r RepairReporter) {
+	s.repairs = r
+}
+
 // Start starts the API server.
 func (s *Server) Start() error {
 	addr := fmt.Sprintf(":%d", s.port)
@@ -97,6 +112,9 @@ func (s *Server) registerRoutes() {
 
 	// Stats
 	s.mux.HandleFunc("GET /api/stats", s.handleStats)
+
+	// Self-healing selectors
+	s.mux.HandleFunc("GET /api/selectors/repairs", s.handleRepairs)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -226,6 +244,18 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	s.jsonResponse(w, http.StatusOK, s.engineCtrl.GetStats())
 }
 
+func (s *Server) handleRepairs(w http.ResponseWriter, r *http.Request) {
+	if s.repairs == nil {
+		s.jsonResponse(w, http.StatusOK, []parser.SelectorRepair{})
+		return
+	}
+	repairs := s.repairs.Repairs()
+	if repairs == nil {
+		repairs = []parser.SelectorRepair{}
+	}
+	s.jsonResponse(w, http.StatusOK, repairs)
+}
+
 func (s *Server) jsonResponse(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
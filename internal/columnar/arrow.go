@@ -0,0 +1,310 @@
+package columnar
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// ArrowCodecs lists the compression names NewArrowWriter accepts.
+var ArrowCodecs = []string{"none", "zstd"}
+
+// Arrow flatbuffer enum values used here.
+const (
+	arrowV5 = 4
+
+	arrowHeaderSchema      = 1
+	arrowHeaderRecordBatch = 3
+
+	arrowTypeInt           = 2
+	arrowTypeFloatingPoint = 3
+	arrowTypeUtf8          = 5
+	arrowTypeBool          = 6
+	arrowTypeTimestamp     = 10
+
+	arrowPrecisionDouble = 2
+	arrowMillisecond     = 1
+	arrowCodecZstd       = 1
+)
+
+// ArrowWriter writes an Arrow IPC file (the random-access format, readable
+// as a stream too) with one record batch per WriteRows call.
+type ArrowWriter struct {
+	w       *countingWriter
+	schema  *Schema
+	codec   string
+	batches []arrowBlock
+}
+
+type arrowBlock struct {
+	offset     int64
+	metaLength int32
+	bodyLength int64
+}
+
+// NewArrowWriter starts an Arrow IPC file on w. codec is one of
+// ArrowCodecs.
+func NewArrowWriter(w io.Writer, s *Schema, codec string) (*ArrowWriter, error) {
+	if codec != "none" && codec != "zstd" {
+		return nil, fmt.Errorf("unsupported arrow compression %q (valid: none, zstd)", codec)
+	}
+	a := &ArrowWriter{w: &countingWriter{w: w}, schema: s, codec: codec}
+	if _, err := a.w.Write([]byte("ARROW1\x00\x00")); err != nil {
+		return nil, err
+	}
+	b := flatbuffers.NewBuilder(1024)
+	schema := a.buildSchema(b)
+	if _, err := a.writeMessage(b, arrowHeaderSchema, schema, nil); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *ArrowWriter) Size() int64 { return a.w.n }
+
+// buildSchema adds the Schema table to b.
+func (a *ArrowWriter) buildSchema(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+	fields := make([]flatbuffers.UOffsetT, len(a.schema.Columns))
+	for i, col := range a.schema.Columns {
+		name := b.CreateString(col.Name)
+		var typeType byte
+		var typ flatbuffers.UOffsetT
+		switch col.Type {
+		case Bool:
+			typeType = arrowTypeBool
+			b.StartObject(0)
+			typ = b.EndObject()
+		case Int64:
+			typeType = arrowTypeInt
+			b.StartObject(2)
+			b.PrependInt32Slot(0, 64, 0)
+			b.PrependBoolSlot(1, true, false)
+			typ = b.EndObject()
+		case Double:
+			typeType = arrowTypeFloatingPoint
+			b.StartObject(1)
+			b.PrependInt16Slot(0, arrowPrecisionDouble, 0)
+			typ = b.EndObject()
+		case Timestamp:
+			typeType = arrowTypeTimestamp
+			tz := b.CreateString("UTC")
+			b.StartObject(2)
+			b.PrependInt16Slot(0, arrowMillisecond, 0)
+			b.PrependUOffsetTSlot(1, tz, 0)
+			typ = b.EndObject()
+		default: // strings and JSON text
+			typeType = arrowTypeUtf8
+			b.StartObject(0)
+			typ = b.EndObject()
+		}
+		b.StartVector(4, 0, 4)
+		children := b.EndVector(0)
+
+		b.StartObject(7)
+		b.PrependUOffsetTSlot(0, name, 0)
+		b.PrependBoolSlot(1, true, false)
+		b.PrependByteSlot(2, typeType, 0)
+		b.PrependUOffsetTSlot(3, typ, 0)
+		b.PrependUOffsetTSlot(5, children, 0)
+		fields[i] = b.EndObject()
+	}
+	b.StartVector(4, len(fields), 4)
+	for i := len(fields) - 1; i >= 0; i-- {
+		b.PrependUOffsetT(fields[i])
+	}
+	vec := b.EndVector(len(fields))
+
+	b.StartObject(4)
+	b.PrependInt16Slot(0, 0, 0) // little endian
+	b.PrependUOffsetTSlot(1, vec, 0)
+	return b.EndObject()
+}
+
+// writeMessage writes an encapsulated message: continuation marker,
+// metadata length, the Message flatbuffer padded to 8 bytes, and body.
+func (a *ArrowWriter) writeMessage(b *flatbuffers.Builder, headerType byte, header flatbuffers.UOffsetT, body []byte) (arrowBlock, error) {
+	b.StartObject(5)
+	b.PrependInt16Slot(0, arrowV5, 0)
+	b.PrependByteSlot(1, headerType, 0)
+	b.PrependUOffsetTSlot(2, header, 0)
+	b.PrependInt64Slot(3, int64(len(body)), 0)
+	b.Finish(b.EndObject())
+	meta := b.FinishedBytes()
+
+	block := arrowBlock{offset: a.w.n, bodyLength: int64(len(body))}
+	padded := pad8(len(meta))
+	buf := make([]byte, 8, 8+padded+len(body))
+	binary.LittleEndian.PutUint32(buf, 0xffffffff)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(padded))
+	buf = append(buf, meta...)
+	buf = append(buf, make([]byte, padded-len(meta))...)
+	block.metaLength = int32(len(buf))
+	buf = append(buf, body...)
+	_, err := a.w.Write(buf)
+	return block, err
+}
+
+func pad8(n int) int { return (n + 7) &^ 7 }
+
+func (a *ArrowWriter) WriteRows(rows []map[string]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	type node struct{ length, nulls int64 }
+	var nodes []node
+	var buffers [][2]int64 // offset, length in body
+	var body []byte
+	addBuffer := func(data []byte) error {
+		if a.codec == "zstd" {
+			compressed, err := compress("zstd", data)
+			if err != nil {
+				return err
+			}
+			prefixed := binary.LittleEndian.AppendUint64(nil, uint64(len(data)))
+			data = append(prefixed, compressed...)
+		}
+		buffers = append(buffers, [2]int64{int64(len(body)), int64(len(data))})
+		body = append(body, data...)
+		body = append(body, make([]byte, pad8(len(body))-len(body))...)
+		return nil
+	}
+
+	for _, col := range a.schema.Columns {
+		valid := make([]bool, len(rows))
+		var nulls int64
+		var values, offsets []byte
+		var bits []bool
+		if col.Type != Bool && col.Type != Int64 && col.Type != Double && col.Type != Timestamp {
+			offsets = binary.LittleEndian.AppendUint32(nil, 0)
+		}
+		for i, row := range rows {
+			v, ok := convert(row[col.Name], col.Type)
+			valid[i] = ok
+			if !ok {
+				nulls++
+			}
+			switch col.Type {
+			case Bool:
+				b, _ := v.(bool)
+				bits = append(bits, b)
+			case Int64:
+				n, _ := v.(int64)
+				values = binary.LittleEndian.AppendUint64(values, uint64(n))
+			case Double:
+				f, _ := v.(float64)
+				values = binary.LittleEndian.AppendUint64(values, math.Float64bits(f))
+			case Timestamp:
+				var ms int64
+				if ts, ok := v.(time.Time); ok {
+					ms = ts.UnixMilli()
+				}
+				values = binary.LittleEndian.AppendUint64(values, uint64(ms))
+			default:
+				s, _ := v.(string)
+				values = append(values, s...)
+				offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(values)))
+			}
+		}
+		if col.Type == Bool {
+			values = packBits(bits)
+		}
+
+		nodes = append(nodes, node{int64(len(rows)), nulls})
+		var validity []byte
+		if nulls > 0 {
+			validity = packBits(valid)
+		}
+		parts := [][]byte{validity, values}
+		if offsets != nil {
+			parts = [][]byte{validity, offsets, values}
+		}
+		for _, part := range parts {
+			if len(part) == 0 {
+				buffers = append(buffers, [2]int64{int64(len(body)), 0})
+				continue
+			}
+			if err := addBuffer(part); err != nil {
+				return fmt.Errorf("arrow column %s: %w", col.Name, err)
+			}
+		}
+	}
+
+	b := flatbuffers.NewBuilder(1024)
+	var compression flatbuffers.UOffsetT
+	if a.codec == "zstd" {
+		b.StartObject(2)
+		b.PrependByteSlot(0, arrowCodecZstd, 0)
+		compression = b.EndObject()
+	}
+	b.StartVector(16, len(nodes), 8)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		b.Prep(8, 16)
+		b.PrependInt64(nodes[i].nulls)
+		b.PrependInt64(nodes[i].length)
+	}
+	nodeVec := b.EndVector(len(nodes))
+	b.StartVector(16, len(buffers), 8)
+	for i := len(buffers) - 1; i >= 0; i-- {
+		b.Prep(8, 16)
+		b.PrependInt64(buffers[i][1])
+		b.PrependInt64(buffers[i][0])
+	}
+	bufVec := b.EndVector(len(buffers))
+
+	b.StartObject(4)
+	b.PrependInt64Slot(0, int64(len(rows)), 0)
+	b.PrependUOffsetTSlot(1, nodeVec, 0)
+	b.PrependUOffsetTSlot(2, bufVec, 0)
+	if compression != 0 {
+		b.PrependUOffsetTSlot(3, compression, 0)
+	}
+	batch := b.EndObject()
+
+	block, err := a.writeMessage(b, arrowHeaderRecordBatch, batch, body)
+	if err != nil {
+		return err
+	}
+	a.batches = append(a.batches, block)
+	return nil
+}
+
+// Close writes the end-of-stream marker and the file footer.
+func (a *ArrowWriter) Close() error {
+	eos := binary.LittleEndian.AppendUint32(nil, 0xffffffff)
+	eos = binary.LittleEndian.AppendUint32(eos, 0)
+	if _, err := a.w.Write(eos); err != nil {
+		return err
+	}
+
+	b := flatbuffers.NewBuilder(1024)
+	schema := a.buildSchema(b)
+	b.StartVector(24, 0, 8)
+	dicts := b.EndVector(0)
+	b.StartVector(24, len(a.batches), 8)
+	for i := len(a.batches) - 1; i >= 0; i-- {
+		blk := a.batches[i]
+		b.Prep(8, 24)
+		b.PrependInt64(blk.bodyLength)
+		b.Pad(4)
+		b.PrependInt32(blk.metaLength)
+		b.PrependInt64(blk.offset)
+	}
+	batches := b.EndVector(len(a.batches))
+	b.StartObject(5)
+	b.PrependInt16Slot(0, arrowV5, 0)
+	b.PrependUOffsetTSlot(1, schema, 0)
+	b.PrependUOffsetTSlot(2, dicts, 0)
+	b.PrependUOffsetTSlot(3, batches, 0)
+	b.Finish(b.EndObject())
+	footer := b.FinishedBytes()
+
+	trailer := append([]byte(nil), footer...)
+	trailer = binary.LittleEndian.AppendUint32(trailer, uint32(len(footer)))
+	trailer = append(trailer, "ARROW1"...)
+	_, err := a.w.Write(trailer)
+	return err
+}
@@ -0,0 +1,216 @@
+package columnar
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2026, 3, 4, 5, 6, 7, 891234567, time.UTC)
+
+// testRows covers every column type, nulls, missing fields and non-ASCII
+// text.
+func testRows() []map[string]any {
+	return []map[string]any{
+		{"_url": "https://example.com/1", "title": "Café ☕", "price": 12, "rating": 4.5, "stock": true, "seen": testTime, "tags": []string{"a", "b"}},
+		{"_url": "https://example.com/2", "title": "", "price": -3, "rating": nil, "stock": false},
+		{"_url": "https://example.com/3", "price": int64(1) << 40, "rating": 0.0, "seen": testTime.Add(-time.Hour), "tags": map[string]any{"k": 1}},
+	}
+}
+
+// expected returns rows as a reader sees them under schema: values in
+// their stored Go types, timestamps at millisecond precision, nulls and
+// fields outside the schema left out.
+func expected(schema *Schema, rows []map[string]any) []map[string]any {
+	out := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		out[i] = map[string]any{}
+		for _, col := range schema.Columns {
+			v, ok := convert(row[col.Name], col.Type)
+			if !ok {
+				continue
+			}
+			if ts, isTime := v.(time.Time); isTime {
+				v = time.UnixMilli(ts.UnixMilli()).UTC()
+			}
+			out[i][col.Name] = v
+		}
+	}
+	return out
+}
+
+// arrowTypes returns schema as stored in Arrow, where JSON is Utf8 text.
+func arrowTypes(schema *Schema) *Schema {
+	out := &Schema{Columns: append([]Column(nil), schema.Columns...)}
+	for i := range out.Columns {
+		if out.Columns[i].Type == JSON {
+			out.Columns[i].Type = String
+		}
+	}
+	return out
+}
+
+// writeFile writes each batch of rows as one row group or record batch
+// and reads the file back.
+func writeFile(t *testing.T, format, codec string, schema *Schema, batches ...[]map[string]any) (*readFile, int) {
+	t.Helper()
+	var buf bytes.Buffer
+	var w Writer
+	var err error
+	if format == "parquet" {
+		w, err = NewParquetWriter(&buf, schema, codec)
+	} else {
+		w, err = NewArrowWriter(&buf, schema, codec)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rows := range batches {
+		if err := w.WriteRows(rows); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Size() != int64(buf.Len()) {
+		t.Errorf("Size() = %d, wrote %d bytes", w.Size(), buf.Len())
+	}
+
+	var f *readFile
+	if format == "parquet" {
+		f, err = readParquet(buf.Bytes())
+	} else {
+		f, err = readArrow(buf.Bytes())
+	}
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	return f, buf.Len()
+}
+
+func TestRoundTrip(t *testing.T) {
+	rows := testRows()
+	schema := Infer(nil, rows, "_url")
+	want := expected(schema, rows)
+	want = append(want, want...)
+
+	tests := []struct {
+		format string
+		codecs []string
+	}{
+		{"parquet", ParquetCodecs},
+		{"arrow", ArrowCodecs},
+	}
+	for _, tt := range tests {
+		for _, codec := range tt.codecs {
+			t.Run(tt.format+"/"+codec, func(t *testing.T) {
+				f, _ := writeFile(t, tt.format, codec, schema, rows, rows)
+				wantSchema := schema
+				if tt.format == "arrow" {
+					wantSchema = arrowTypes(schema)
+				}
+				if !f.schema.Equal(wantSchema) {
+					t.Errorf("schema = %v, want %v", f.schema.Columns, wantSchema.Columns)
+				}
+				if f.codec != codec {
+					t.Errorf("codec = %s, want %s", f.codec, codec)
+				}
+				if f.groups != 2 {
+					t.Errorf("%d row groups, want 2", f.groups)
+				}
+				if !reflect.DeepEqual(f.rows, want) {
+					t.Errorf("rows =\n%v\nwant\n%v", f.rows, want)
+				}
+			})
+		}
+	}
+}
+
+func TestCompressionShrinksFile(t *testing.T) {
+	rows := make([]map[string]any, 500)
+	for i := range rows {
+		rows[i] = map[string]any{"title": strings.Repeat("scrapegoat ", 20), "n": i % 7}
+	}
+	schema := Infer(nil, rows)
+
+	for _, format := range []string{"parquet", "arrow"} {
+		codecs := ParquetCodecs
+		if format == "arrow" {
+			codecs = ArrowCodecs
+		}
+		_, plain := writeFile(t, format, "none", schema, rows)
+		for _, codec := range codecs[1:] {
+			f, size := writeFile(t, format, codec, schema, rows)
+			if size*4 > plain {
+				t.Errorf("%s/%s: %d bytes, uncompressed %d", format, codec, size, plain)
+			}
+			if !reflect.DeepEqual(f.rows, expected(schema, rows)) {
+				t.Errorf("%s/%s: rows differ after round trip", format, codec)
+			}
+		}
+	}
+}
+
+func TestSchemaEvolution(t *testing.T) {
+	first := []map[string]any{
+		{"_url": "https://example.com/1", "title": "a", "price": 1, "sku": 100},
+		{"_url": "https://example.com/2", "title": "b", "price": 2, "sku": 101},
+	}
+	second := []map[string]any{
+		{"_url": "https://example.com/3", "title": "c", "price": 2.5, "sku": "X-1", "rating": 4.0},
+		{"_url": "https://example.com/4", "price": 3, "tags": []any{"new"}},
+	}
+
+	before := Infer(nil, first, "_url")
+	after := Infer(before, second, "_url")
+	wantAfter := []Column{
+		{"_url", String}, {"price", Double}, {"sku", String}, {"title", String},
+		{"rating", Double}, {"tags", JSON},
+	}
+	if !reflect.DeepEqual(after.Columns, wantAfter) {
+		t.Fatalf("evolved schema = %v, want %v", after.Columns, wantAfter)
+	}
+	if after.Equal(before) || !Infer(after, second, "_url").Equal(after) {
+		t.Error("Infer should change the schema once and then be stable")
+	}
+
+	for _, format := range []string{"parquet", "arrow"} {
+		t.Run(format, func(t *testing.T) {
+			old, _ := writeFile(t, format, "none", before, first)
+			evolved, _ := writeFile(t, format, "none", after, second)
+
+			// Later files extend earlier ones: columns keep their names
+			// and positions, so a reader can combine them by name.
+			for i, col := range old.schema.Columns {
+				if evolved.schema.Columns[i].Name != col.Name {
+					t.Errorf("column %d is %s in the first file, %s in the second", i, col.Name, evolved.schema.Columns[i].Name)
+				}
+			}
+			if got := old.schema.Columns[1].Type; got != Int64 {
+				t.Errorf("first file price type = %v, want int64", got)
+			}
+			if !reflect.DeepEqual(old.rows, expected(before, first)) {
+				t.Errorf("first file rows = %v", old.rows)
+			}
+			if !reflect.DeepEqual(evolved.rows, expected(after, second)) {
+				t.Errorf("second file rows = %v", evolved.rows)
+			}
+			if sku, tags := evolved.rows[0]["sku"], evolved.rows[1]["tags"]; sku != "X-1" || tags != `["new"]` {
+				t.Errorf("widened values = %v, %v", sku, tags)
+			}
+		})
+	}
+}
+
+func TestUnsupportedCodec(t *testing.T) {
+	schema := &Schema{Columns: []Column{{"title", String}}}
+	if _, err := NewParquetWriter(&bytes.Buffer{}, schema, "lzo"); err == nil {
+		t.Error("parquet: expected error for lzo")
+	}
+	if _, err := NewArrowWriter(&bytes.Buffer{}, schema, "snappy"); err == nil {
+		t.Error("arrow: expected error for snappy")
+	}
+}
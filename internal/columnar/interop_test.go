@@ -0,0 +1,131 @@
+package columnar
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+)
+
+// TestParquetGolden reads a file written by another implementation,
+// xitongsys/parquet-go (testdata/parquet-go-flat.parquet, from its
+// examples), so the reader the round-trip tests rely on is checked against
+// more than this package's writer. The file has required INT32, INT64,
+// FLOAT, BOOLEAN, DATE and dictionary-encoded UTF8 columns.
+func TestParquetGolden(t *testing.T) {
+	data, err := os.ReadFile("testdata/parquet-go-flat.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := readParquet(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSchema := &Schema{Columns: []Column{
+		{"name", String}, {"age", Int64}, {"id", Int64}, {"weight", Double}, {"sex", Bool}, {"day", Int64},
+	}}
+	if !f.schema.Equal(wantSchema) {
+		t.Errorf("schema = %v, want %v", f.schema.Columns, wantSchema.Columns)
+	}
+	if f.codec != "snappy" || f.groups != 1 {
+		t.Errorf("codec %s, %d row groups; want snappy, 1", f.codec, f.groups)
+	}
+	// The rows parquet-go's example writes.
+	var want []map[string]any
+	for i := 0; i < 10; i++ {
+		want = append(want, map[string]any{
+			"name":   "StudentName",
+			"age":    int64(20 + i%5),
+			"id":     int64(i),
+			"weight": float64(float32(50.0 + float32(i)*0.1)),
+			"sex":    i%2 == 0,
+			"day":    int64(18040), // 2019-05-24
+		})
+	}
+	if !reflect.DeepEqual(f.rows, want) {
+		t.Errorf("rows =\n%v\nwant\n%v", f.rows, want)
+	}
+}
+
+// TestArrowApacheReader reads the Arrow writer's output with the Apache
+// Arrow Go implementation. Its version predates IPC body compression, so
+// only uncompressed files are checked.
+func TestArrowApacheReader(t *testing.T) {
+	rows := testRows()
+	schema := Infer(nil, rows, "_url")
+	var buf bytes.Buffer
+	w, err := NewArrowWriter(&buf, schema, "none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, batch := range [][]map[string]any{rows[:2], rows[2:]} {
+		if err := w.WriteRows(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ipc.NewFileReader(bytes.NewReader(buf.Bytes()), ipc.WithAllocator(memory.NewGoAllocator()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	wantTypes := map[Type]arrow.DataType{
+		Bool:      arrow.FixedWidthTypes.Boolean,
+		Int64:     arrow.PrimitiveTypes.Int64,
+		Double:    arrow.PrimitiveTypes.Float64,
+		String:    arrow.BinaryTypes.String,
+		Timestamp: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"},
+	}
+	for i, field := range r.Schema().Fields() {
+		col := arrowTypes(schema).Columns[i]
+		if field.Name != col.Name || !arrow.TypeEqual(field.Type, wantTypes[col.Type]) || !field.Nullable {
+			t.Errorf("field %d = %v, want nullable %s %s", i, field, col.Name, wantTypes[col.Type])
+		}
+	}
+
+	var got []map[string]any
+	for i := 0; i < r.NumRecords(); i++ {
+		rec, err := r.Record(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for row := 0; row < int(rec.NumRows()); row++ {
+			values := map[string]any{}
+			for c, arr := range rec.Columns() {
+				if arr.IsNull(row) {
+					continue
+				}
+				var v any
+				switch arr := arr.(type) {
+				case *array.Boolean:
+					v = arr.Value(row)
+				case *array.Int64:
+					v = arr.Value(row)
+				case *array.Float64:
+					v = arr.Value(row)
+				case *array.String:
+					v = arr.Value(row)
+				case *array.Timestamp:
+					v = time.UnixMilli(int64(arr.Value(row))).UTC()
+				default:
+					t.Fatalf("unexpected array %T", arr)
+				}
+				values[rec.ColumnName(c)] = v
+			}
+			got = append(got, values)
+		}
+	}
+	if want := expected(arrowTypes(schema), rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,321 @@
+package columnar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Writer writes rows to a columnar file.
+type Writer interface {
+	// WriteRows writes rows as one row group (Parquet) or record batch
+	// (Arrow). Fields not in the schema are ignored.
+	WriteRows(rows []map[string]any) error
+
+	// Size returns the bytes written so far.
+	Size() int64
+
+	// Close writes the footer. It does not close the underlying writer.
+	Close() error
+}
+
+// parquetCodecs are the Parquet compression codec ids.
+var parquetCodecs = map[string]int32{"none": 0, "snappy": 1, "gzip": 2, "brotli": 4, "zstd": 6}
+
+// ParquetCodecs lists the compression names NewParquetWriter accepts.
+var ParquetCodecs = []string{"none", "snappy", "gzip", "zstd", "brotli"}
+
+var (
+	zstdOnce sync.Once
+	zstdEnc  *zstd.Encoder
+)
+
+func compress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case "none":
+		return data, nil
+	case "snappy":
+		return snappy.Encode(nil, data), nil
+	case "zstd":
+		zstdOnce.Do(func() { zstdEnc, _ = zstd.NewWriter(nil) })
+		return zstdEnc.EncodeAll(data, nil), nil
+	}
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch codec {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "brotli":
+		w = brotli.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", codec)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Parquet physical types, converted types and encodings used here.
+const (
+	pqBoolean   = 0
+	pqInt64     = 2
+	pqDouble    = 5
+	pqByteArray = 6
+
+	pqUTF8            = 0
+	pqTimestampMillis = 9
+	pqJSON            = 19
+
+	pqPlain = 0
+	pqRLE   = 3
+)
+
+// ParquetWriter writes a Parquet file with one optional column per schema
+// column and one plain-encoded data page per column chunk.
+type ParquetWriter struct {
+	w         *countingWriter
+	schema    *Schema
+	codec     string
+	rowGroups []parquetRowGroup
+	numRows   int64
+}
+
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int64
+	bytes  int64 // uncompressed
+}
+
+type parquetChunk struct {
+	offset       int64
+	uncompressed int64
+	compressed   int64
+}
+
+// NewParquetWriter starts a Parquet file on w. codec is one of
+// ParquetCodecs.
+func NewParquetWriter(w io.Writer, s *Schema, codec string) (*ParquetWriter, error) {
+	if _, ok := parquetCodecs[codec]; !ok {
+		return nil, fmt.Errorf("unsupported parquet compression %q (valid: none, snappy, gzip, zstd, brotli)", codec)
+	}
+	p := &ParquetWriter{w: &countingWriter{w: w}, schema: s, codec: codec}
+	if _, err := p.w.Write([]byte("PAR1")); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *ParquetWriter) Size() int64 { return p.w.n }
+
+func (p *ParquetWriter) WriteRows(rows []map[string]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	rg := parquetRowGroup{rows: int64(len(rows))}
+	for _, col := range p.schema.Columns {
+		page := encodeParquetPage(col, rows)
+		data, err := compress(p.codec, page)
+		if err != nil {
+			return fmt.Errorf("parquet column %s: %w", col.Name, err)
+		}
+
+		var h thriftWriter
+		h.begin()
+		h.i32(1, 0) // DATA_PAGE
+		h.i32(2, int32(len(page)))
+		h.i32(3, int32(len(data)))
+		h.structField(5)
+		h.i32(1, int32(len(rows)))
+		h.i32(2, pqPlain)
+		h.i32(3, pqRLE)
+		h.i32(4, pqRLE)
+		h.end()
+		h.end()
+
+		chunk := parquetChunk{
+			offset:       p.w.n,
+			uncompressed: int64(len(h.buf) + len(page)),
+			compressed:   int64(len(h.buf) + len(data)),
+		}
+		if _, err := p.w.Write(h.buf); err != nil {
+			return err
+		}
+		if _, err := p.w.Write(data); err != nil {
+			return err
+		}
+		rg.chunks = append(rg.chunks, chunk)
+		rg.bytes += chunk.uncompressed
+	}
+	p.rowGroups = append(p.rowGroups, rg)
+	p.numRows += rg.rows
+	return nil
+}
+
+// encodeParquetPage returns a data page body: definition levels followed
+// by the plain-encoded non-null values.
+func encodeParquetPage(col Column, rows []map[string]any) []byte {
+	defined := make([]bool, len(rows))
+	var values []byte
+	var bits []bool
+	for i, row := range rows {
+		v, ok := convert(row[col.Name], col.Type)
+		if !ok {
+			continue
+		}
+		defined[i] = true
+		switch v := v.(type) {
+		case bool:
+			bits = append(bits, v)
+		case int64:
+			values = binary.LittleEndian.AppendUint64(values, uint64(v))
+		case float64:
+			values = binary.LittleEndian.AppendUint64(values, math.Float64bits(v))
+		case time.Time:
+			values = binary.LittleEndian.AppendUint64(values, uint64(v.UnixMilli()))
+		case string:
+			values = binary.LittleEndian.AppendUint32(values, uint32(len(v)))
+			values = append(values, v...)
+		}
+	}
+	if col.Type == Bool {
+		values = packBits(bits)
+	}
+
+	levels := rleBits(defined)
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	page = append(page, levels...)
+	return append(page, values...)
+}
+
+// rleBits encodes 1-bit values with the RLE/bit-packing hybrid, as runs.
+func rleBits(v []bool) []byte {
+	var out []byte
+	for i := 0; i < len(v); {
+		j := i
+		for j < len(v) && v[j] == v[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		if v[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i = j
+	}
+	return out
+}
+
+// packBits packs booleans LSB first, as Parquet and Arrow both do.
+func packBits(v []bool) []byte {
+	out := make([]byte, (len(v)+7)/8)
+	for i, b := range v {
+		if b {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	return out
+}
+
+func parquetType(t Type) (physical, converted int32) {
+	switch t {
+	case Bool:
+		return pqBoolean, -1
+	case Int64:
+		return pqInt64, -1
+	case Double:
+		return pqDouble, -1
+	case Timestamp:
+		return pqInt64, pqTimestampMillis
+	case JSON:
+		return pqByteArray, pqJSON
+	}
+	return pqByteArray, pqUTF8
+}
+
+// Close writes the file metadata and trailer.
+func (p *ParquetWriter) Close() error {
+	var m thriftWriter
+	m.begin()
+	m.i32(1, 1) // version
+
+	m.list(2, tStruct, len(p.schema.Columns)+1)
+	m.begin()
+	m.str(4, "schema")
+	m.i32(5, int32(len(p.schema.Columns)))
+	m.end()
+	for _, col := range p.schema.Columns {
+		physical, converted := parquetType(col.Type)
+		m.begin()
+		m.i32(1, physical)
+		m.i32(3, 1) // OPTIONAL
+		m.str(4, col.Name)
+		if converted >= 0 {
+			m.i32(6, converted)
+		}
+		m.end()
+	}
+
+	m.i64(3, p.numRows)
+
+	m.list(4, tStruct, len(p.rowGroups))
+	for _, rg := range p.rowGroups {
+		m.begin()
+		m.list(1, tStruct, len(rg.chunks))
+		for i, chunk := range rg.chunks {
+			col := p.schema.Columns[i]
+			physical, _ := parquetType(col.Type)
+			m.begin()
+			m.i64(2, chunk.offset)
+			m.structField(3)
+			m.i32(1, physical)
+			m.list(2, tI32, 2)
+			m.listI32(pqPlain)
+			m.listI32(pqRLE)
+			m.list(3, tBinary, 1)
+			m.listStr(col.Name)
+			m.i32(4, parquetCodecs[p.codec])
+			m.i64(5, rg.rows)
+			m.i64(6, chunk.uncompressed)
+			m.i64(7, chunk.compressed)
+			m.i64(9, chunk.offset)
+			m.end()
+			m.end()
+		}
+		m.i64(2, rg.bytes)
+		m.i64(3, rg.rows)
+		m.end()
+	}
+	m.str(6, "scrapegoat")
+	m.end()
+
+	trailer := binary.LittleEndian.AppendUint32(m.buf, uint32(len(m.buf)))
+	trailer = append(trailer, "PAR1"...)
+	_, err := p.w.Write(trailer)
+	return err
+}
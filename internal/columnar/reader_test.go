@@ -0,0 +1,715 @@
+package columnar
+
+// Readers for the files the writers produce, used to round-trip them in
+// tests. They follow the Parquet and Arrow specifications rather than the
+// writers' code: Thrift compact structs are decoded generically by field
+// id, Arrow metadata through the flatbuffers runtime, and every codec a
+// reader may meet is decompressed with its reference Go decoder.
+// interop_test.go checks them against other implementations: Arrow output
+// is also read with Apache Arrow's Go reader, and the Parquet reader
+// decodes a file written by parquet-go.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/golang/snappy"
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/klauspost/compress/zstd"
+)
+
+// readFile is a decoded file: its schema, the codec named in its
+// metadata, and its rows with nulls left out.
+type readFile struct {
+	schema *Schema
+	codec  string
+	groups int // row groups or record batches
+	rows   []map[string]any
+}
+
+// thriftStruct is a compact-protocol struct keyed by field id. Integers
+// decode as int64, binary as string, lists as []any.
+type thriftStruct map[int16]any
+
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.buf[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		panic("thrift: bad varint")
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	u := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *thriftReader) readStruct() thriftStruct {
+	s := thriftStruct{}
+	var id int16
+	for {
+		h := r.byte()
+		if h == 0 {
+			return s
+		}
+		typ := h & 0x0f
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.varint())
+		}
+		switch typ {
+		case 1, 2: // boolean true, false
+			s[id] = typ == 1
+		default:
+			s[id] = r.value(typ)
+		}
+	}
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case 1, 2: // boolean list element
+		return r.byte() == 1
+	case 3:
+		return int64(int8(r.byte()))
+	case 4, 5, 6:
+		return r.varint()
+	case 7:
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.buf[r.pos:]))
+		r.pos += 8
+		return v
+	case 8:
+		n := int(r.uvarint())
+		s := string(r.buf[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case 9, 10:
+		h := r.byte()
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(h & 0x0f)
+		}
+		return list
+	case 12:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("thrift: unsupported type %d", typ))
+}
+
+func (s thriftStruct) int(id int16) int64        { v, _ := s[id].(int64); return v }
+func (s thriftStruct) str(id int16) string       { v, _ := s[id].(string); return v }
+func (s thriftStruct) list(id int16) []any       { v, _ := s[id].([]any); return v }
+func (s thriftStruct) sub(id int16) thriftStruct { v, _ := s[id].(thriftStruct); return v }
+
+// Parquet enum values, from parquet.thrift.
+var parquetCodecNames = map[int64]string{0: "none", 1: "snappy", 2: "gzip", 4: "brotli", 6: "zstd"}
+
+func decompress(codec string, data []byte, size int) ([]byte, error) {
+	var out []byte
+	var err error
+	switch codec {
+	case "none":
+		out = data
+	case "snappy":
+		out, err = snappy.Decode(nil, data)
+	case "zstd":
+		var d *zstd.Decoder
+		if d, err = zstd.NewReader(nil); err == nil {
+			out, err = d.DecodeAll(data, nil)
+			d.Close()
+		}
+	case "gzip":
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			out, err = io.ReadAll(zr)
+		}
+	case "brotli":
+		out, err = io.ReadAll(brotli.NewReader(bytes.NewReader(data)))
+	default:
+		err = fmt.Errorf("unknown codec %q", codec)
+	}
+	if err == nil && size >= 0 && len(out) != size {
+		err = fmt.Errorf("%s: decompressed %d bytes, header says %d", codec, len(out), size)
+	}
+	return out, err
+}
+
+// readParquet decodes a Parquet file of flat required or optional columns
+// with plain or dictionary-encoded v1 data pages. INT32 and FLOAT values
+// are widened to int64 and float64.
+func readParquet(data []byte) (f *readFile, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("parquet: %v", r)
+		}
+	}()
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		return nil, fmt.Errorf("parquet: missing magic")
+	}
+	metaLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := (&thriftReader{buf: data[len(data)-8-metaLen : len(data)-8]}).readStruct()
+
+	f = &readFile{schema: &Schema{}}
+	elems := meta.list(2)
+	if root := elems[0].(thriftStruct); int(root.int(5)) != len(elems)-1 {
+		return nil, fmt.Errorf("parquet: root has %d children, schema has %d leaves", root.int(5), len(elems)-1)
+	}
+	var leaves []parquetLeaf
+	for _, e := range elems[1:] {
+		el := e.(thriftStruct)
+		if el.int(3) > 1 {
+			return nil, fmt.Errorf("parquet: column %s is repeated", el.str(4))
+		}
+		converted := int64(-1)
+		if _, ok := el[6]; ok {
+			converted = el.int(6)
+		}
+		var t Type
+		switch [2]int64{el.int(1), converted} {
+		case [2]int64{0, -1}:
+			t = Bool
+		case [2]int64{1, -1}, [2]int64{1, 6}, [2]int64{2, -1}: // INT32, DATE, INT64
+			t = Int64
+		case [2]int64{4, -1}, [2]int64{5, -1}: // FLOAT, DOUBLE
+			t = Double
+		case [2]int64{2, 9}:
+			t = Timestamp
+		case [2]int64{6, 0}:
+			t = String
+		case [2]int64{6, 19}:
+			t = JSON
+		default:
+			return nil, fmt.Errorf("parquet: column %s has type %d/%d", el.str(4), el.int(1), converted)
+		}
+		col := Column{Name: el.str(4), Type: t}
+		f.schema.Columns = append(f.schema.Columns, col)
+		leaves = append(leaves, parquetLeaf{col: col, physical: el.int(1), optional: el.int(3) == 1})
+	}
+
+	for _, g := range meta.list(4) {
+		rg := g.(thriftStruct)
+		n := int(rg.int(3))
+		rows := make([]map[string]any, n)
+		for i := range rows {
+			rows[i] = map[string]any{}
+		}
+		chunks := rg.list(1)
+		if len(chunks) != len(leaves) {
+			return nil, fmt.Errorf("parquet: row group has %d chunks for %d columns", len(chunks), len(leaves))
+		}
+		for c, ch := range chunks {
+			cm := ch.(thriftStruct).sub(3)
+			leaf := leaves[c]
+			if path := cm.list(3); len(path) != 1 || path[0] != leaf.col.Name {
+				return nil, fmt.Errorf("parquet: chunk path %v for column %s", path, leaf.col.Name)
+			}
+			if cm.int(1) != leaf.physical || cm.int(5) != int64(n) {
+				return nil, fmt.Errorf("parquet: chunk metadata of %s disagrees with schema", leaf.col.Name)
+			}
+			codec, ok := parquetCodecNames[cm.int(4)]
+			if !ok {
+				return nil, fmt.Errorf("parquet: codec id %d", cm.int(4))
+			}
+			if f.codec != "" && f.codec != codec {
+				return nil, fmt.Errorf("parquet: mixed codecs %s and %s", f.codec, codec)
+			}
+			f.codec = codec
+			if err := readParquetChunk(data, cm, codec, leaf, rows); err != nil {
+				return nil, fmt.Errorf("parquet: column %s: %w", leaf.col.Name, err)
+			}
+		}
+		f.rows = append(f.rows, rows...)
+		f.groups++
+	}
+	if int64(len(f.rows)) != meta.int(3) {
+		return nil, fmt.Errorf("parquet: %d rows read, metadata says %d", len(f.rows), meta.int(3))
+	}
+	return f, nil
+}
+
+// parquetLeaf is a column as declared in the file's schema.
+type parquetLeaf struct {
+	col      Column
+	physical int64
+	optional bool
+}
+
+// readParquetChunk reads a column chunk's pages, an optional dictionary
+// page followed by data pages, into rows.
+func readParquetChunk(data []byte, cm thriftStruct, codec string, leaf parquetLeaf, rows []map[string]any) error {
+	start := cm.int(9)
+	if _, ok := cm[11]; ok && cm.int(11) < start {
+		start = cm.int(11)
+	}
+	r := &thriftReader{buf: data, pos: int(start)}
+	var dict []any
+	var uncompressed int64
+	for row := 0; row < len(rows); {
+		headerStart := r.pos
+		page := r.readStruct()
+		uncompressed += int64(r.pos-headerStart) + page.int(2)
+		body, err := decompress(codec, data[r.pos:r.pos+int(page.int(3))], int(page.int(2)))
+		if err != nil {
+			return err
+		}
+		r.pos += int(page.int(3))
+		switch page.int(1) {
+		case 2: // dictionary page
+			if dict, _, err = plainValues(body, leaf.physical, int(page.sub(7).int(1))); err != nil {
+				return err
+			}
+		case 0: // data page v1
+			dp := page.sub(5)
+			count := int(dp.int(1))
+			if row+count > len(rows) {
+				return fmt.Errorf("%d values past the row group", row+count-len(rows))
+			}
+			if err := decodeParquetPage(body, leaf, dp.int(2), dict, rows[row:row+count]); err != nil {
+				return err
+			}
+			row += count
+		default:
+			return fmt.Errorf("page type %d", page.int(1))
+		}
+	}
+	if int64(r.pos)-start != cm.int(7) || uncompressed != cm.int(6) {
+		return fmt.Errorf("pages take %d bytes (%d uncompressed), metadata says %d (%d)",
+			int64(r.pos)-start, uncompressed, cm.int(7), cm.int(6))
+	}
+	return nil
+}
+
+// decodeParquetPage reads a data page's definition levels, for optional
+// columns, and its plain or dictionary-indexed values into rows.
+func decodeParquetPage(page []byte, leaf parquetLeaf, encoding int64, dict []any, rows []map[string]any) error {
+	defined := len(rows)
+	levels := make([]int, len(rows))
+	if leaf.optional {
+		levelLen := int(binary.LittleEndian.Uint32(page))
+		levels = hybridDecode(page[4:4+levelLen], 1, len(rows))
+		page = page[4+levelLen:]
+		defined = 0
+		for _, l := range levels {
+			defined += l
+		}
+	}
+
+	var values []any
+	switch encoding {
+	case 0: // PLAIN
+		var n int
+		var err error
+		if values, n, err = plainValues(page, leaf.physical, defined); err != nil {
+			return err
+		}
+		if n != len(page) {
+			return fmt.Errorf("%d trailing value bytes", len(page)-n)
+		}
+	case 2, 8: // PLAIN_DICTIONARY, RLE_DICTIONARY
+		for _, i := range hybridDecode(page[1:], int(page[0]), defined) {
+			values = append(values, dict[i])
+		}
+	default:
+		return fmt.Errorf("encoding %d", encoding)
+	}
+
+	for i, row := range rows {
+		if leaf.optional && levels[i] == 0 {
+			continue
+		}
+		v := values[0]
+		values = values[1:]
+		switch v := v.(type) {
+		case int32:
+			row[leaf.col.Name] = int64(v)
+		case float32:
+			row[leaf.col.Name] = float64(v)
+		case int64:
+			if leaf.col.Type == Timestamp {
+				row[leaf.col.Name] = time.UnixMilli(v).UTC()
+			} else {
+				row[leaf.col.Name] = v
+			}
+		default:
+			row[leaf.col.Name] = v
+		}
+	}
+	return nil
+}
+
+// plainValues decodes n plain-encoded values of a physical type and
+// returns them with the bytes they took.
+func plainValues(buf []byte, physical int64, n int) ([]any, int, error) {
+	values := make([]any, n)
+	pos := 0
+	for i := range values {
+		switch physical {
+		case 0: // BOOLEAN, bit-packed
+			values[i] = buf[i/8]&(1<<(i%8)) != 0
+			pos = (i + 8) / 8
+		case 1: // INT32
+			values[i] = int32(binary.LittleEndian.Uint32(buf[pos:]))
+			pos += 4
+		case 2: // INT64
+			values[i] = int64(binary.LittleEndian.Uint64(buf[pos:]))
+			pos += 8
+		case 4: // FLOAT
+			values[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[pos:]))
+			pos += 4
+		case 5: // DOUBLE
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[pos:]))
+			pos += 8
+		case 6: // BYTE_ARRAY
+			size := int(binary.LittleEndian.Uint32(buf[pos:]))
+			values[i] = string(buf[pos+4 : pos+4+size])
+			pos += 4 + size
+		default:
+			return nil, 0, fmt.Errorf("physical type %d", physical)
+		}
+	}
+	return values, pos, nil
+}
+
+// hybridDecode reads n values of the given bit width from the RLE/bit-packed
+// hybrid encoding.
+func hybridDecode(buf []byte, width, n int) []int {
+	r := &thriftReader{buf: buf}
+	var out []int
+	for len(out) < n {
+		h := r.uvarint()
+		if h&1 == 0 { // run of one value, in whole bytes
+			v := 0
+			for i := 0; i < (width+7)/8; i++ {
+				v |= int(r.byte()) << (8 * i)
+			}
+			for i := uint64(0); i < h>>1; i++ {
+				out = append(out, v)
+			}
+			continue
+		}
+		groups := int(h >> 1) // groups of 8 values, least significant bit first
+		bits := r.buf[r.pos : r.pos+groups*width]
+		r.pos += groups * width
+		for i := 0; i < groups*8; i++ {
+			v := 0
+			for b := 0; b < width; b++ {
+				bit := i*width + b
+				if bits[bit/8]&(1<<(bit%8)) != 0 {
+					v |= 1 << b
+				}
+			}
+			out = append(out, v)
+		}
+	}
+	return out[:n]
+}
+
+// Arrow flatbuffer tables are read by vtable slot, as numbered in
+// Schema.fbs, Message.fbs and File.fbs.
+type fbTable struct{ flatbuffers.Table }
+
+func rootTable(buf []byte) fbTable {
+	return fbTable{flatbuffers.Table{Bytes: buf, Pos: flatbuffers.GetUOffsetT(buf)}}
+}
+
+func slot(field int) flatbuffers.VOffsetT { return flatbuffers.VOffsetT(4 + 2*field) }
+
+// table returns the table in field, or ok false when it is absent.
+func (t fbTable) table(field int) (fbTable, bool) {
+	o := t.Offset(slot(field))
+	if o == 0 {
+		return fbTable{}, false
+	}
+	return fbTable{flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(t.Pos + flatbuffers.UOffsetT(o))}}, true
+}
+
+func (t fbTable) string(field int) string {
+	o := t.Offset(slot(field))
+	if o == 0 {
+		return ""
+	}
+	return t.String(t.Pos + flatbuffers.UOffsetT(o))
+}
+
+// vector returns the start and length of the vector in field.
+func (t fbTable) vector(field int) (flatbuffers.UOffsetT, int) {
+	o := t.Offset(slot(field))
+	if o == 0 {
+		return 0, 0
+	}
+	return t.Vector(flatbuffers.UOffsetT(o)), t.VectorLen(flatbuffers.UOffsetT(o))
+}
+
+func (t fbTable) tables(field int) []fbTable {
+	start, n := t.vector(field)
+	out := make([]fbTable, n)
+	for i := range out {
+		at := start + flatbuffers.UOffsetT(4*i)
+		out[i] = fbTable{flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(at)}}
+	}
+	return out
+}
+
+// structs returns the little-endian int64 words of each struct in the
+// vector in field; size is the struct size in bytes.
+func (t fbTable) structs(field, size int) [][]int64 {
+	start, n := t.vector(field)
+	out := make([][]int64, n)
+	for i := range out {
+		at := int(start) + i*size
+		for w := 0; w < size; w += 8 {
+			out[i] = append(out[i], int64(binary.LittleEndian.Uint64(t.Bytes[at+w:])))
+		}
+	}
+	return out
+}
+
+// arrowSchema maps an Arrow Schema table to the columnar types.
+func arrowSchema(s fbTable) (*Schema, error) {
+	if s.GetInt16Slot(slot(0), 0) != 0 {
+		return nil, fmt.Errorf("arrow: big-endian schema")
+	}
+	out := &Schema{}
+	for _, field := range s.tables(1) {
+		name := field.string(0)
+		if !field.GetBoolSlot(slot(1), false) {
+			return nil, fmt.Errorf("arrow: field %s is not nullable", name)
+		}
+		typ, ok := field.table(3)
+		if !ok {
+			return nil, fmt.Errorf("arrow: field %s has no type", name)
+		}
+		var t Type
+		switch field.GetByteSlot(slot(2), 0) {
+		case 2: // Int
+			if typ.GetInt32Slot(slot(0), 0) != 64 || !typ.GetBoolSlot(slot(1), false) {
+				return nil, fmt.Errorf("arrow: field %s is not int64", name)
+			}
+			t = Int64
+		case 3: // FloatingPoint
+			if typ.GetInt16Slot(slot(0), 0) != 2 {
+				return nil, fmt.Errorf("arrow: field %s is not double", name)
+			}
+			t = Double
+		case 5: // Utf8
+			t = String
+		case 6: // Bool
+			t = Bool
+		case 10: // Timestamp
+			if typ.GetInt16Slot(slot(0), 0) != 1 || typ.string(1) != "UTC" {
+				return nil, fmt.Errorf("arrow: field %s is not a UTC millisecond timestamp", name)
+			}
+			t = Timestamp
+		default:
+			return nil, fmt.Errorf("arrow: field %s has type %d", name, field.GetByteSlot(slot(2), 0))
+		}
+		out.Columns = append(out.Columns, Column{Name: name, Type: t})
+	}
+	return out, nil
+}
+
+// arrowMessage reads the encapsulated message at pos and returns its
+// Message table, its body and the offset of the next message. ok is false
+// at the end-of-stream marker.
+func arrowMessage(data []byte, pos int) (msg fbTable, body []byte, next int, ok bool, err error) {
+	if binary.LittleEndian.Uint32(data[pos:]) != 0xffffffff {
+		return msg, nil, 0, false, fmt.Errorf("arrow: no continuation marker at %d", pos)
+	}
+	metaLen := int(binary.LittleEndian.Uint32(data[pos+4:]))
+	if metaLen == 0 {
+		return msg, nil, pos + 8, false, nil
+	}
+	if metaLen%8 != 0 {
+		return msg, nil, 0, false, fmt.Errorf("arrow: metadata length %d is not 8-byte aligned", metaLen)
+	}
+	msg = rootTable(data[pos+8 : pos+8+metaLen])
+	if v := msg.GetInt16Slot(slot(0), 0); v != 4 {
+		return msg, nil, 0, false, fmt.Errorf("arrow: metadata version %d", v)
+	}
+	start := pos + 8 + metaLen
+	end := start + int(msg.GetInt64Slot(slot(3), 0))
+	return msg, data[start:end], end, true, nil
+}
+
+// readArrow decodes an Arrow IPC file. It walks the messages as a stream
+// reader would and checks that the footer points at the same schema and
+// record batches.
+func readArrow(data []byte) (f *readFile, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("arrow: %v", r)
+		}
+	}()
+	if len(data) < 18 || string(data[:8]) != "ARROW1\x00\x00" || string(data[len(data)-6:]) != "ARROW1" {
+		return nil, fmt.Errorf("arrow: missing magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-10:]))
+	footer := rootTable(data[len(data)-10-footerLen : len(data)-10])
+	fs, _ := footer.table(1)
+	schema, err := arrowSchema(fs)
+	if err != nil {
+		return nil, err
+	}
+	f = &readFile{schema: schema, codec: "none"}
+
+	// Blocks: offset int64, metaDataLength int32 (padded), bodyLength int64.
+	blocks := footer.structs(3, 24)
+	msg, _, pos, ok, err := arrowMessage(data, 8)
+	if err != nil || !ok || msg.GetByteSlot(slot(1), 0) != 1 {
+		return nil, fmt.Errorf("arrow: stream does not start with a schema: %v", err)
+	}
+	header, _ := msg.table(2)
+	if streamSchema, err := arrowSchema(header); err != nil || !streamSchema.Equal(schema) {
+		return nil, fmt.Errorf("arrow: stream schema differs from footer schema: %v", err)
+	}
+
+	for {
+		at := pos
+		msg, body, next, ok, err := arrowMessage(data, pos)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		pos = next
+		if msg.GetByteSlot(slot(1), 0) != 3 {
+			return nil, fmt.Errorf("arrow: unexpected message type %d", msg.GetByteSlot(slot(1), 0))
+		}
+		if f.groups >= len(blocks) {
+			return nil, fmt.Errorf("arrow: more record batches than footer blocks")
+		}
+		blk := blocks[f.groups]
+		if blk[0] != int64(at) || int(int32(blk[1]))+at != next-len(body) || blk[2] != int64(len(body)) {
+			return nil, fmt.Errorf("arrow: footer block %d is %v, batch is at %d", f.groups, blk, at)
+		}
+		f.groups++
+
+		batch, _ := msg.table(2)
+		rows, codec, err := decodeArrowBatch(batch, body, schema)
+		if err != nil {
+			return nil, err
+		}
+		f.codec = codec
+		f.rows = append(f.rows, rows...)
+	}
+	if f.groups != len(blocks) {
+		return nil, fmt.Errorf("arrow: %d record batches, footer lists %d", f.groups, len(blocks))
+	}
+	return f, nil
+}
+
+func decodeArrowBatch(batch fbTable, body []byte, schema *Schema) ([]map[string]any, string, error) {
+	n := int(batch.GetInt64Slot(slot(0), 0))
+	nodes := batch.structs(1, 16)   // length, null_count
+	buffers := batch.structs(2, 16) // offset, length
+	codec := "none"
+	if c, ok := batch.table(3); ok {
+		switch c.GetByteSlot(slot(0), 0) {
+		case 0:
+			codec = "lz4"
+		case 1:
+			codec = "zstd"
+		}
+	}
+	buffer := func(i int) ([]byte, error) {
+		off, size := buffers[i][0], buffers[i][1]
+		if off%8 != 0 {
+			return nil, fmt.Errorf("arrow: buffer %d at unaligned offset %d", i, off)
+		}
+		b := body[off : off+size]
+		if codec == "none" || len(b) == 0 {
+			return b, nil
+		}
+		// Compressed buffers start with the uncompressed length, -1 when
+		// the data was left uncompressed.
+		size = int64(binary.LittleEndian.Uint64(b))
+		if size == -1 {
+			return b[8:], nil
+		}
+		return decompress(codec, b[8:], int(size))
+	}
+
+	rows := make([]map[string]any, n)
+	for i := range rows {
+		rows[i] = map[string]any{}
+	}
+	if len(nodes) != len(schema.Columns) {
+		return nil, "", fmt.Errorf("arrow: %d field nodes for %d columns", len(nodes), len(schema.Columns))
+	}
+	next := 0
+	for c, col := range schema.Columns {
+		if nodes[c][0] != int64(n) {
+			return nil, "", fmt.Errorf("arrow: column %s has %d values for %d rows", col.Name, nodes[c][0], n)
+		}
+		validity, err := buffer(next)
+		if err != nil {
+			return nil, "", err
+		}
+		next++
+		var offsets []byte
+		if col.Type == String || col.Type == JSON {
+			if offsets, err = buffer(next); err != nil {
+				return nil, "", err
+			}
+			next++
+		}
+		values, err := buffer(next)
+		if err != nil {
+			return nil, "", err
+		}
+		next++
+
+		nulls := 0
+		for i, row := range rows {
+			if nodes[c][1] > 0 && validity[i/8]&(1<<(i%8)) == 0 {
+				nulls++
+				continue
+			}
+			switch col.Type {
+			case Bool:
+				row[col.Name] = values[i/8]&(1<<(i%8)) != 0
+			case Int64:
+				row[col.Name] = int64(binary.LittleEndian.Uint64(values[8*i:]))
+			case Timestamp:
+				row[col.Name] = time.UnixMilli(int64(binary.LittleEndian.Uint64(values[8*i:]))).UTC()
+			case Double:
+				row[col.Name] = math.Float64frombits(binary.LittleEndian.Uint64(values[8*i:]))
+			default:
+				from := binary.LittleEndian.Uint32(offsets[4*i:])
+				to := binary.LittleEndian.Uint32(offsets[4*i+4:])
+				row[col.Name] = string(values[from:to])
+			}
+		}
+		if int64(nulls) != nodes[c][1] {
+			return nil, "", fmt.Errorf("arrow: column %s has %d nulls, node says %d", col.Name, nulls, nodes[c][1])
+		}
+	}
+	if next != len(buffers) {
+		return nil, "", fmt.Errorf("arrow: %d buffers, %d used", len(buffers), next)
+	}
+	return rows, codec, nil
+}
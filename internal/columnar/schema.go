@@ -0,0 +1,200 @@
+// Package columnar writes items as Parquet and Arrow IPC files.
+//
+// Both formats need a fixed schema per file. Schemas are inferred from rows
+// (flat maps of field values) with Infer, which also evolves a file's
+// schema when later rows bring new fields or values of a wider type; the
+// caller then starts a new file with the evolved schema.
+package columnar
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Type is a column's logical type.
+type Type int
+
+const (
+	Bool Type = iota
+	Int64
+	Double
+	String
+	Timestamp // millisecond precision, UTC
+	JSON      // objects and arrays, as JSON text
+)
+
+var typeNames = [...]string{"bool", "int64", "double", "string", "timestamp", "json"}
+
+func (t Type) String() string { return typeNames[t] }
+
+// Column is a named, nullable column.
+type Column struct {
+	Name string
+	Type Type
+}
+
+// Schema is the ordered list of a file's columns.
+type Schema struct {
+	Columns []Column
+}
+
+// Equal reports whether both schemas have the same columns in the same
+// order.
+func (s *Schema) Equal(o *Schema) bool {
+	return s != nil && o != nil && slices.Equal(s.Columns, o.Columns)
+}
+
+func (s *Schema) index(name string) int {
+	return slices.IndexFunc(s.Columns, func(c Column) bool { return c.Name == name })
+}
+
+// Infer returns the schema covering base and rows. Columns of base keep
+// their position, widened when a row holds a value they cannot store (an
+// integer column receiving fractions becomes double, other conflicts
+// become string, or json when either side is json); fields new to base are
+// appended, in the order given by first, then alphabetically. base may be
+// nil.
+func Infer(base *Schema, rows []map[string]any, first ...string) *Schema {
+	s := &Schema{}
+	if base != nil {
+		s.Columns = slices.Clone(base.Columns)
+	}
+	seen := make(map[string]Type)
+	for _, row := range rows {
+		for k, v := range row {
+			if v == nil {
+				if _, ok := seen[k]; !ok && s.index(k) < 0 {
+					seen[k] = -1 // null so far
+				}
+				continue
+			}
+			t := TypeOf(v)
+			if prev, ok := seen[k]; ok && prev >= 0 {
+				t = widen(prev, t)
+			}
+			seen[k] = t
+		}
+	}
+
+	var added []string
+	for k, t := range seen {
+		if i := s.index(k); i >= 0 {
+			if t >= 0 {
+				s.Columns[i].Type = widen(s.Columns[i].Type, t)
+			}
+			continue
+		}
+		added = append(added, k)
+	}
+	sort.Slice(added, func(i, j int) bool {
+		pi, pj := slices.Index(first, added[i]), slices.Index(first, added[j])
+		switch {
+		case pi >= 0 && pj >= 0:
+			return pi < pj
+		case pi >= 0 || pj >= 0:
+			return pi >= 0
+		}
+		return added[i] < added[j]
+	})
+	for _, k := range added {
+		t := seen[k]
+		if t < 0 {
+			t = String
+		}
+		s.Columns = append(s.Columns, Column{Name: k, Type: t})
+	}
+	return s
+}
+
+// TypeOf returns the column type for a value.
+func TypeOf(v any) Type {
+	switch v.(type) {
+	case bool:
+		return Bool
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return Int64
+	case float32, float64:
+		return Double
+	case string:
+		return String
+	case time.Time:
+		return Timestamp
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return JSON
+	}
+	return String
+}
+
+// widen returns the narrowest type that can hold values of both a and b.
+func widen(a, b Type) Type {
+	switch {
+	case a == b:
+		return a
+	case a == Int64 && b == Double, a == Double && b == Int64:
+		return Double
+	case a == JSON || b == JSON:
+		return JSON
+	}
+	return String
+}
+
+// convert returns v as the Go type stored for t: bool, int64, float64,
+// string or time.Time. ok is false for nil.
+func convert(v any, t Type) (out any, ok bool) {
+	if v == nil {
+		return nil, false
+	}
+	switch t {
+	case Bool:
+		b, ok := v.(bool)
+		return b, ok
+	case Int64:
+		rv := reflect.ValueOf(v)
+		switch {
+		case rv.CanInt():
+			return rv.Int(), true
+		case rv.CanUint():
+			return int64(rv.Uint()), true
+		}
+	case Double:
+		rv := reflect.ValueOf(v)
+		switch {
+		case rv.CanFloat():
+			return rv.Float(), true
+		case rv.CanInt():
+			return float64(rv.Int()), true
+		case rv.CanUint():
+			return float64(rv.Uint()), true
+		}
+	case Timestamp:
+		ts, ok := v.(time.Time)
+		return ts, ok
+	case String:
+		switch v := v.(type) {
+		case string:
+			return v, true
+		case time.Time:
+			return v.UTC().Format(time.RFC3339Nano), true
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		}
+		if TypeOf(v) != JSON {
+			return fmt.Sprint(v), true
+		}
+		fallthrough
+	case JSON:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v), true
+		}
+		return string(data), true
+	}
+	return nil, false
+}
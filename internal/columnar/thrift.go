@@ -0,0 +1,88 @@
+package columnar
+
+import (
+	"encoding/binary"
+)
+
+// Thrift compact protocol field types.
+const (
+	tI32    = 5
+	tI64    = 6
+	tBinary = 8
+	tList   = 9
+	tStruct = 12
+)
+
+// thriftWriter encodes the Thrift compact protocol, as far as Parquet
+// metadata needs it: i32, i64, binary, lists and structs.
+type thriftWriter struct {
+	buf    []byte
+	lastID []int16 // last field id of each open struct
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	last := &w.lastID[len(w.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|typ)
+	} else {
+		w.buf = append(w.buf, typ)
+		w.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) begin() { w.lastID = append(w.lastID, 0) }
+
+func (w *thriftWriter) end() {
+	w.buf = append(w.buf, 0) // stop
+	w.lastID = w.lastID[:len(w.lastID)-1]
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, tI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, tI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) str(id int16, s string) {
+	w.field(id, tBinary)
+	w.varint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// structField starts a struct-valued field; close it with end.
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, tStruct)
+	w.begin()
+}
+
+// list starts a list field of n elements of typ. Struct elements are each
+// written between begin and end.
+func (w *thriftWriter) list(id int16, typ byte, n int) {
+	w.field(id, tList)
+	if n < 15 {
+		w.buf = append(w.buf, byte(n)<<4|typ)
+	} else {
+		w.buf = append(w.buf, 0xf0|typ)
+		w.varint(uint64(n))
+	}
+}
+
+func (w *thriftWriter) listI32(v int32) { w.zigzag(int64(v)) }
+
+func (w *thriftWriter) listStr(s string) {
+	w.varint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
@@ -18,6 +18,7 @@ type Config struct {
 	AI       AIConfig       `mapstructure:"ai"       yaml:"ai"`
 	Logging  LoggingConfig  `mapstructure:"logging"  yaml:"logging"`
 	Metrics  MetricsConfig  `mapstructure:"metrics"  yaml:"metrics"`
+	API      APIConfig      `mapstructure:"api"      yaml:"api"`
 }
 
 // EngineConfig controls the core crawler engine.
@@ -36,6 +37,29 @@ type EngineConfig struct {
 	AllowedURLPatterns []string      `mapstructure:"allowed_url_patterns" yaml:"allowed_url_patterns"`
 	MaxRequests        int           `mapstructure:"max_requests"         yaml:"max_requests"`
 	MaxItems           int           `mapstructure:"max_items"            yaml:"max_items"`
+	Sitemap            SitemapConfig `mapstructure:"sitemap"              yaml:"sitemap"`
+}
+
+// SitemapConfig controls sitemap crawl mode, in which each seed site is
+// expanded into the URLs its sitemaps list. Entries become depth-0 requests
+// whose frontier priority follows their priority and changefreq.
+type SitemapConfig struct {
+	Enabled      bool           `mapstructure:"enabled"        yaml:"enabled"`
+	URLs         []string       `mapstructure:"urls"           yaml:"urls"`           // sitemaps to read; empty discovers them via robots.txt and well-known paths
+	Include      []string       `mapstructure:"include"        yaml:"include"`        // regexes; entries must match one
+	Exclude      []string       `mapstructure:"exclude"        yaml:"exclude"`        // regexes; entries matching any are skipped
+	SinceLastRun bool           `mapstructure:"since_last_run" yaml:"since_last_run"` // skip entries whose lastmod predates the previous completed run
+	StatePath    string         `mapstructure:"state_path"     yaml:"state_path"`     // where run times are persisted per site
+	MaxURLs      int            `mapstructure:"max_urls"       yaml:"max_urls"`       // per seed site; 0 = no limit
+	Routes       []SitemapRoute `mapstructure:"routes"         yaml:"routes"`
+}
+
+// SitemapRoute sends sitemap entries matching a URL regex to a rule set.
+// The first matching route wins.
+type SitemapRoute struct {
+	Match   string `mapstructure:"match"    yaml:"match"`
+	RuleSet string `mapstructure:"rule_set" yaml:"rule_set"`
+	Tag     string `mapstructure:"tag"      yaml:"tag"`
 }
 
 // FetcherConfig controls the request fetcher.
@@ -60,22 +84,103 @@ type ProxyConfig struct {
 
 // ParserConfig controls the parser.
 type ParserConfig struct {
-	AutoDetect bool        `mapstructure:"auto_detect" yaml:"auto_detect"`
-	Rules      []ParseRule `mapstructure:"rules"       yaml:"rules"`
+	AutoDetect bool           `mapstructure:"auto_detect" yaml:"auto_detect"` // detect repeated records when no rules are set
+	SchemaOrg  bool           `mapstructure:"schema_org"  yaml:"schema_org"`  // emit one typed item per schema.org entity
+	Rules      []ParseRule    `mapstructure:"rules"       yaml:"rules"`
+	Feed       FeedConfig     `mapstructure:"feed"        yaml:"feed"`
+	SelfHeal   SelfHealConfig `mapstructure:"self_heal"   yaml:"self_heal"`
+
+	// RuleSets are named alternatives to Rules, chosen per request by its
+	// "rule_set" meta value. Names are case-insensitive.
+	RuleSets   map[string][]ParseRule `mapstructure:"rule_sets"  yaml:"rule_sets"`
+	Pagination []PaginationConfig     `mapstructure:"pagination" yaml:"pagination"`
+	Forms      []FormConfig           `mapstructure:"forms"      yaml:"forms"`
+}
+
+// FormConfig submits a form found on matching pages, as a browser would:
+// hidden inputs and tokens are kept, Fields override chosen values, and the
+// request is sent with the form's method and encoding.
+type FormConfig struct {
+	Match    string      `mapstructure:"match"    yaml:"match"`    // regex on the page URL; empty means seed pages only
+	Selector string      `mapstructure:"selector" yaml:"selector"` // the form, or an element inside it (default "form")
+	Fields   []FormField `mapstructure:"fields"   yaml:"fields"`
+	Submit   string      `mapstructure:"submit"   yaml:"submit"`   // name, value or id of the button to click
+	RuleSet  string      `mapstructure:"rule_set" yaml:"rule_set"` // rule set for the result page
+	Tag      string      `mapstructure:"tag"      yaml:"tag"`
+}
+
+// FormField sets one form field. Names are kept as a list rather than map
+// keys so case-sensitive names such as __EVENTTARGET survive config loading.
+type FormField struct {
+	Name  string `mapstructure:"name"  yaml:"name"`
+	Value string `mapstructure:"value" yaml:"value"`
+}
+
+// PaginationConfig follows a paginated listing without callbacks. Exactly
+// one of NextSelector, URLTemplate or CursorPath sets how the next page is
+// found. Next pages keep the parent request's depth, tag and rule set.
+type PaginationConfig struct {
+	Match   string `mapstructure:"match"    yaml:"match"`    // regex on the page URL; with no rule_set either, only seeds start chains
+	RuleSet string `mapstructure:"rule_set" yaml:"rule_set"` // only pages parsed with this rule set
+
+	NextSelector string `mapstructure:"next_selector" yaml:"next_selector"` // link to the next page (href, or ::attr(x))
+	URLTemplate  string `mapstructure:"url_template"  yaml:"url_template"`  // e.g. "/list?page={page}" or "?offset={offset}"
+	StartPage    int    `mapstructure:"start_page"    yaml:"start_page"`    // number of the first page (default 1)
+	PageSize     int    `mapstructure:"page_size"     yaml:"page_size"`     // {offset} step per page
+	CursorPath   string `mapstructure:"cursor_path"   yaml:"cursor_path"`   // JSONPath to the next cursor in a JSON response
+	CursorParam  string `mapstructure:"cursor_param"  yaml:"cursor_param"`  // query parameter carrying the cursor (default "cursor")
+
+	MaxPages int      `mapstructure:"max_pages" yaml:"max_pages"` // 0 = no limit
+	StopWhen []string `mapstructure:"stop_when" yaml:"stop_when"` // no_new_items, repeated_content (default both)
+}
+
+// FeedConfig controls RSS/Atom/JSON feed handling.
+type FeedConfig struct {
+	OnlyNew   bool   `mapstructure:"only_new"   yaml:"only_new"`   // skip entries seen in previous runs
+	StatePath string `mapstructure:"state_path" yaml:"state_path"` // where seen entries are persisted
+}
+
+// SelfHealConfig controls selector repair for rules with self_heal enabled.
+type SelfHealConfig struct {
+	SnapshotDir   string  `mapstructure:"snapshot_dir"   yaml:"snapshot_dir"`   // element snapshots per site and rule
+	MinSimilarity float64 `mapstructure:"min_similarity" yaml:"min_similarity"` // below this, items are flagged instead of repaired
 }
 
 // ParseRule defines a single extraction rule.
 type ParseRule struct {
-	Name      string `mapstructure:"name"      yaml:"name"`
-	Selector  string `mapstructure:"selector"  yaml:"selector"`
-	Type      string `mapstructure:"type"      yaml:"type"` // css, xpath, regex
-	Attribute string `mapstructure:"attribute" yaml:"attribute"`
-	Pattern   string `mapstructure:"pattern"   yaml:"pattern"`
+	Name      string    `mapstructure:"name"      yaml:"name"`
+	Selector  string    `mapstructure:"selector"  yaml:"selector"` // css rules accept ::text, ::attr(x), :has-text() etc.; see parser.CompileSelector
+	Type      string    `mapstructure:"type"      yaml:"type"`     // css, xpath, regex, readability, markdown, jsdata, table
+	Attribute string    `mapstructure:"attribute" yaml:"attribute"`
+	Pattern   string    `mapstructure:"pattern"   yaml:"pattern"`
+	SelfHeal  bool      `mapstructure:"self_heal" yaml:"self_heal"` // relocate the element when the selector stops matching
+	Table     TableRule `mapstructure:"table"     yaml:"table"`     // options for type "table"
+}
+
+// TableRule picks and converts HTML tables for "table" rules. Each body row
+// becomes an item keyed by the column headers.
+type TableRule struct {
+	Caption    string   `mapstructure:"caption"     yaml:"caption"`     // only tables whose caption contains this
+	Headers    []string `mapstructure:"headers"     yaml:"headers"`     // only tables with columns containing all of these
+	HeaderRows int      `mapstructure:"header_rows" yaml:"header_rows"` // 0 infers from <thead> or leading <th> rows
+	Numeric    bool     `mapstructure:"numeric"     yaml:"numeric"`     // convert cells like "1,234", "$9.99", "12%" to numbers
 }
 
 // PipelineConfig controls the processing pipeline.
 type PipelineConfig struct {
 	Middlewares []MiddlewareConfig `mapstructure:"middlewares" yaml:"middlewares"`
+	Schema      SchemaConfig       `mapstructure:"schema"      yaml:"schema"`
+	Ordered     bool               `mapstructure:"ordered"     yaml:"ordered"` // emit items in the order they were scraped
+	Buffer      int                `mapstructure:"buffer"      yaml:"buffer"`  // queue length in front of each stage
+}
+
+// SchemaConfig declares the JSON Schema items are validated against. The
+// schema also fixes the CSV column order.
+type SchemaConfig struct {
+	File           string `mapstructure:"file"             yaml:"file"`       // .json or .yaml; empty disables validation
+	OnInvalid      string `mapstructure:"on_invalid"       yaml:"on_invalid"` // annotate, drop, dead_letter
+	DeadLetterPath string `mapstructure:"dead_letter_path" yaml:"dead_letter_path"`
+	Coerce         bool   `mapstructure:"coerce"           yaml:"coerce"` // convert numeric/boolean strings first
 }
 
 // MiddlewareConfig defines a single pipeline middleware.
@@ -83,6 +188,11 @@ type MiddlewareConfig struct {
 	Name    string         `mapstructure:"name"    yaml:"name"`
 	Type    string         `mapstructure:"type"    yaml:"type"`
 	Options map[string]any `mapstructure:"options" yaml:"options"`
+
+	Concurrency int           `mapstructure:"concurrency" yaml:"concurrency"` // workers for this stage (default 1)
+	BatchSize   int           `mapstructure:"batch_size"  yaml:"batch_size"`  // items per batch, for middlewares that batch
+	BatchWait   time.Duration `mapstructure:"batch_wait"  yaml:"batch_wait"`  // longest wait to fill a batch (default 100ms)
+	Timeout     time.Duration `mapstructure:"timeout"     yaml:"timeout"`     // per-item deadline for context-aware middlewares (0 = none)
 }
 
 // StorageConfig controls output/storage.
@@ -90,6 +200,16 @@ type StorageConfig struct {
 	Type       string `mapstructure:"type"        yaml:"type"`
 	OutputPath string `mapstructure:"output_path" yaml:"output_path"`
 	BatchSize  int    `mapstructure:"batch_size"  yaml:"batch_size"`
+	Upsert     bool   `mapstructure:"upsert"      yaml:"upsert"` // replace stored items with the same key (json, jsonl, sqlite, postgres) instead of overwriting
+	DSN        string `mapstructure:"dsn"         yaml:"dsn"`    // sqlite file (default output_path/results.db) or postgres connection string
+	Table      string `mapstructure:"table"       yaml:"table"`  // sqlite and postgres table
+
+	Compression    string        `mapstructure:"compression"     yaml:"compression"`     // parquet: none, snappy, gzip, zstd, brotli; arrow: none, zstd; jsonl/csv: none, gzip, zstd
+	RowGroupSize   int           `mapstructure:"row_group_size"  yaml:"row_group_size"`  // items per parquet row group / arrow record batch
+	RotateItems    int           `mapstructure:"rotate_items"    yaml:"rotate_items"`    // start a new file after this many items; 0 disables
+	RotateSizeMB   int           `mapstructure:"rotate_size_mb"  yaml:"rotate_size_mb"`  // ... or after this many megabytes; 0 disables
+	RotateInterval time.Duration `mapstructure:"rotate_interval" yaml:"rotate_interval"` // jsonl/csv: ... or after this long; 0 disables
+	PathTemplate   string        `mapstructure:"path_template"   yaml:"path_template"`   // jsonl/csv file names under output_path, e.g. {spider}/{date}/{hour}-{seq}.jsonl.gz
 }
 
 // AIConfig controls LLM integration.
@@ -114,6 +234,12 @@ type MetricsConfig struct {
 	Path    string `mapstructure:"path"    yaml:"path"`
 }
 
+// APIConfig controls the REST API served during a crawl.
+type APIConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled"`
+	Port    int  `mapstructure:"port"    yaml:"port"`
+}
+
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
@@ -126,6 +252,9 @@ func DefaultConfig() *Config {
 			MaxRetries:         3,
 			RetryDelay:         2 * time.Second,
 			CheckpointInterval: 60 * time.Second,
+			Sitemap: SitemapConfig{
+				StatePath: ".scrapegoat_sitemaps/state.json",
+			},
 			UserAgents: []string{
 				"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
 				"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
@@ -146,12 +275,28 @@ func DefaultConfig() *Config {
 			RotateOnFail: true,
 		},
 		Parser: ParserConfig{
-			AutoDetect: true,
+			Feed: FeedConfig{
+				StatePath: ".scrapegoat_feeds/state.json",
+			},
+			SelfHeal: SelfHealConfig{
+				SnapshotDir:   ".scrapegoat_selectors",
+				MinSimilarity: 0.7,
+			},
+		},
+		Pipeline: PipelineConfig{
+			Ordered: true,
+			Buffer:  100,
+			Schema: SchemaConfig{
+				OnInvalid:      "annotate",
+				DeadLetterPath: "./output/dead_letter.jsonl",
+			},
 		},
 		Storage: StorageConfig{
-			Type:       "json",
-			OutputPath: "./output",
-			BatchSize:  100,
+			Type:         "json",
+			OutputPath:   "./output",
+			BatchSize:    100,
+			Table:        "items",
+			RowGroupSize: 10000,
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -163,5 +308,9 @@ func DefaultConfig() *Config {
 			Port:    9090,
 			Path:    "/metrics",
 		},
+		API: APIConfig{
+			Enabled: false,
+			Port:    8080,
+		},
 	}
 }
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAutoDetectDefault(t *testing.T) {
+	if DefaultConfig().Parser.AutoDetect {
+		t.Error("DefaultConfig: auto_detect should be off")
+	}
+
+	tests := []struct {
+		name string
+		yaml string
+		want bool
+	}{
+		{"unset", "engine:\n  concurrency: 2\n", false},
+		{"enabled", "parser:\n  auto_detect: true\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scrapegoat.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Parser.AutoDetect != tt.want {
+				t.Errorf("auto_detect = %v, want %v", cfg.Parser.AutoDetect, tt.want)
+			}
+		})
+	}
+}
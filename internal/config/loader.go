@@ -69,6 +69,9 @@ func setDefaults(v *viper.Viper, cfg *Config) {
 	v.SetDefault("engine.retry_delay", cfg.Engine.RetryDelay)
 	v.SetDefault("engine.checkpoint_interval", cfg.Engine.CheckpointInterval)
 	v.SetDefault("engine.user_agents", cfg.Engine.UserAgents)
+	v.SetDefault("engine.sitemap.enabled", cfg.Engine.Sitemap.Enabled)
+	v.SetDefault("engine.sitemap.since_last_run", cfg.Engine.Sitemap.SinceLastRun)
+	v.SetDefault("engine.sitemap.state_path", cfg.Engine.Sitemap.StatePath)
 
 	v.SetDefault("fetcher.type", cfg.Fetcher.Type)
 	v.SetDefault("fetcher.follow_redirects", cfg.Fetcher.FollowRedirects)
@@ -82,9 +85,31 @@ func setDefaults(v *viper.Viper, cfg *Config) {
 	v.SetDefault("proxy.health_check", cfg.Proxy.HealthCheck)
 	v.SetDefault("proxy.rotate_on_fail", cfg.Proxy.RotateOnFail)
 
+	v.SetDefault("parser.auto_detect", cfg.Parser.AutoDetect)
+	v.SetDefault("parser.schema_org", cfg.Parser.SchemaOrg)
+	v.SetDefault("parser.feed.only_new", cfg.Parser.Feed.OnlyNew)
+	v.SetDefault("parser.feed.state_path", cfg.Parser.Feed.StatePath)
+	v.SetDefault("parser.self_heal.snapshot_dir", cfg.Parser.SelfHeal.SnapshotDir)
+	v.SetDefault("parser.self_heal.min_similarity", cfg.Parser.SelfHeal.MinSimilarity)
+
+	v.SetDefault("pipeline.ordered", cfg.Pipeline.Ordered)
+	v.SetDefault("pipeline.buffer", cfg.Pipeline.Buffer)
+	v.SetDefault("pipeline.schema.on_invalid", cfg.Pipeline.Schema.OnInvalid)
+	v.SetDefault("pipeline.schema.dead_letter_path", cfg.Pipeline.Schema.DeadLetterPath)
+	v.SetDefault("pipeline.schema.coerce", cfg.Pipeline.Schema.Coerce)
+
 	v.SetDefault("storage.type", cfg.Storage.Type)
 	v.SetDefault("storage.output_path", cfg.Storage.OutputPath)
 	v.SetDefault("storage.batch_size", cfg.Storage.BatchSize)
+	v.SetDefault("storage.upsert", cfg.Storage.Upsert)
+	v.SetDefault("storage.dsn", cfg.Storage.DSN)
+	v.SetDefault("storage.table", cfg.Storage.Table)
+	v.SetDefault("storage.compression", cfg.Storage.Compression)
+	v.SetDefault("storage.row_group_size", cfg.Storage.RowGroupSize)
+	v.SetDefault("storage.rotate_items", cfg.Storage.RotateItems)
+	v.SetDefault("storage.rotate_size_mb", cfg.Storage.RotateSizeMB)
+	v.SetDefault("storage.rotate_interval", cfg.Storage.RotateInterval)
+	v.SetDefault("storage.path_template", cfg.Storage.PathTemplate)
 
 	v.SetDefault("logging.level", cfg.Logging.Level)
 	v.SetDefault("logging.format", cfg.Logging.Format)
@@ -93,4 +118,7 @@ func setDefaults(v *viper.Viper, cfg *Config) {
 	v.SetDefault("metrics.enabled", cfg.Metrics.Enabled)
 	v.SetDefault("metrics.port", cfg.Metrics.Port)
 	v.SetDefault("metrics.path", cfg.Metrics.Path)
+
+	v.SetDefault("api.enabled", cfg.API.Enabled)
+	v.SetDefault("api.port", cfg.API.Port)
 }
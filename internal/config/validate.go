@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"regexp"
 )
 
 // Validate checks the configuration for invalid values.
@@ -26,6 +27,20 @@ func Validate(cfg *Config) error {
 		return fmt.Errorf("engine.max_retries must be >= 0, got %d", cfg.Engine.MaxRetries)
 	}
 
+	if cfg.Engine.Sitemap.Enabled {
+		sm := cfg.Engine.Sitemap
+		for _, p := range append(append([]string{}, sm.Include...), sm.Exclude...) {
+			if _, err := regexp.Compile(p); err != nil {
+				return fmt.Errorf("engine.sitemap: invalid pattern %q: %w", p, err)
+			}
+		}
+		for i, r := range sm.Routes {
+			if _, err := regexp.Compile(r.Match); err != nil {
+				return fmt.Errorf("engine.sitemap.routes[%d]: invalid match: %w", i, err)
+			}
+		}
+	}
+
 	if cfg.Fetcher.MaxBodySize <= 0 {
 		return fmt.Errorf("fetcher.max_body_size must be > 0")
 	}
@@ -48,10 +63,31 @@ func Validate(cfg *Config) error {
 	}
 
 	validStorageTypes := map[string]bool{
-		"json": true, "jsonl": true, "csv": true,
+		"json": true, "jsonl": true, "csv": true, "sqlite": true, "postgres": true, "parquet": true, "arrow": true,
 	}
 	if !validStorageTypes[cfg.Storage.Type] {
-		return fmt.Errorf("storage.type %q is not supported (valid: json, jsonl, csv)", cfg.Storage.Type)
+		return fmt.Errorf("storage.type %q is not supported (valid: json, jsonl, csv, sqlite, postgres, parquet, arrow)", cfg.Storage.Type)
+	}
+	if cfg.Storage.Type == "sqlite" || cfg.Storage.Type == "postgres" {
+		if cfg.Storage.Table == "" {
+			return fmt.Errorf("storage.table is required for %s", cfg.Storage.Type)
+		}
+		if cfg.Storage.Type == "postgres" && cfg.Storage.DSN == "" {
+			return fmt.Errorf("storage.dsn is required for postgres")
+		}
+	}
+	if cfg.Storage.Upsert && (cfg.Storage.Type == "csv" || cfg.Storage.Type == "parquet" || cfg.Storage.Type == "arrow") {
+		return fmt.Errorf("storage.upsert is not supported for %s output", cfg.Storage.Type)
+	}
+	if cfg.Storage.RowGroupSize < 0 || cfg.Storage.RotateItems < 0 || cfg.Storage.RotateSizeMB < 0 || cfg.Storage.RotateInterval < 0 {
+		return fmt.Errorf("storage.row_group_size, rotate_items, rotate_size_mb and rotate_interval must not be negative")
+	}
+	if (cfg.Storage.PathTemplate != "" || cfg.Storage.RotateInterval > 0) && cfg.Storage.Type != "jsonl" && cfg.Storage.Type != "csv" {
+		return fmt.Errorf("storage.path_template and rotate_interval need jsonl or csv output")
+	}
+	if cfg.Storage.Upsert && cfg.Storage.Type == "jsonl" && (cfg.Storage.PathTemplate != "" || cfg.Storage.RotateInterval > 0 ||
+		cfg.Storage.RotateItems > 0 || cfg.Storage.RotateSizeMB > 0 || cfg.Storage.Compression != "" && cfg.Storage.Compression != "none") {
+		return fmt.Errorf("storage.upsert cannot be combined with rotation, compression or path_template")
 	}
 
 	validLogLevels := map[string]bool{
@@ -69,6 +105,11 @@ func Validate(cfg *Config) error {
 			return fmt.Errorf("metrics.port must be 1-65535, got %d", cfg.Metrics.Port)
 		}
 	}
+	if cfg.API.Enabled {
+		if cfg.API.Port < 1 || cfg.API.Port > 65535 {
+			return fmt.Errorf("api.port must be 1-65535, got %d", cfg.API.Port)
+		}
+	}
 
 	return nil
 }
@@ -3,10 +3,10 @@ package engine
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"net/url"
-	"sort"
 	"strings"
 	"sync"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
 )
 
 // Deduplicator tracks visited URLs to avoid re-crawling.
@@ -24,7 +24,7 @@ func NewDeduplicator(estimatedCapacity int) *Deduplicator {
 
 // IsSeen returns true if the URL (after canonicalization) has been seen before.
 func (d *Deduplicator) IsSeen(rawURL string) bool {
-	canonical := CanonicalizeURL(rawURL)
+	canonical := types.CanonicalizeURL(rawURL)
 	hash := hashURL(canonical)
 
 	d.mu.RLock()
@@ -35,7 +35,7 @@ func (d *Deduplicator) IsSeen(rawURL string) bool {
 
 // MarkSeen marks a URL as seen.
 func (d *Deduplicator) MarkSeen(rawURL string) {
-	canonical := CanonicalizeURL(rawURL)
+	canonical := types.CanonicalizeURL(rawURL)
 	hash := hashURL(canonical)
 
 	d.mu.Lock()
@@ -77,62 +77,16 @@ func (d *Deduplicator) Import(hashes []string) {
 	}
 }
 
-// CanonicalizeURL normalizes a URL for deduplication:
-// - lowercases scheme and host
-// - removes fragment
-// - sorts query parameters
-// - removes trailing slash (except root)
-// - removes default ports (80 for http, 443 for https)
-func CanonicalizeURL(rawURL string) string {
-	u, err := url.Parse(rawURL)
-	if err != nil {
-		return rawURL
-	}
-
-	// Lowercase scheme and host
-	u.Scheme = strings.ToLower(u.Scheme)
-	u.Host = strings.ToLower(u.Host)
-
-	// Remove fragment
-	u.Fragment = ""
-
-	// Remove default ports
-	host := u.Hostname()
-	port := u.Port()
-	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
-		u.Host = host
-	}
-
-	// Sort query parameters
-	if u.RawQuery != "" {
-		params := u.Query()
-		keys := make([]string, 0, len(params))
-		for k := range params {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-
-		var sorted []string
-		for _, k := range keys {
-			vals := params[k]
-			sort.Strings(vals)
-			for _, v := range vals {
-				sorted = append(sorted, url.QueryEscape(k)+"="+url.QueryEscape(v))
-			}
-		}
-		u.RawQuery = strings.Join(sorted, "&")
-	}
-
-	// Remove trailing slash (except root "/")
-	if u.Path != "/" && strings.HasSuffix(u.Path, "/") {
-		u.Path = strings.TrimRight(u.Path, "/")
-	}
-
-	// Ensure path is at least "/"
-	if u.Path == "" {
-		u.Path = "/"
-	}
-
+// RequestKey identifies a request with a body for deduplication: its method,
+// canonical URL and a body digest, in the form of a URL so it can be passed to
+// IsSeen and MarkSeen.
+func RequestKey(req *types.Request) string {
+	sum := sha256.Sum256(req.Body)
+	u := *req.URL
+	q := u.Query()
+	q.Set("__method", strings.ToUpper(req.Method))
+	q.Set("__body", hex.EncodeToString(sum[:12]))
+	u.RawQuery = q.Encode()
 	return u.String()
 }
 
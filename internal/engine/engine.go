@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -95,17 +97,59 @@ type Parser interface {
 	Parse(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error)
 }
 
+// Paginator finds the next page of a paginated listing from a response and
+// the items parsed from it. EndChain is called for a next page that will not
+// be fetched, so the paginator can drop the state of its listing.
+type Paginator interface {
+	Next(resp *types.Response, items []*types.Item) []*types.Request
+	EndChain(req *types.Request)
+}
+
+// FormSubmitter derives form submissions from a response.
+type FormSubmitter interface {
+	Submit(resp *types.Response) []*types.Request
+}
+
 // Pipeline is the interface for the item processing pipeline.
 type Pipeline interface {
 	Process(item *types.Item) (*types.Item, error)
 }
 
+// StreamingPipeline is a Pipeline that processes items concurrently.
+// StreamContext reads items until in is closed, calls emit serially for each
+// item that passes or fails (with a non-nil err), and returns when all are
+// done. ctx is cancelled when the engine is stopped.
+type StreamingPipeline interface {
+	Pipeline
+	StreamContext(ctx context.Context, in <-chan *types.Item, emit func(item *types.Item, err error))
+}
+
+// RequestPrioritizer is a Pipeline that adjusts the priority of discovered
+// requests from what it has learned about the items already scraped.
+type RequestPrioritizer interface {
+	Prioritize(rawURL string, priority int) int
+}
+
+// StoreCommitter is a Pipeline with middlewares that record items only once
+// storage has made them durable, such as the identity store. Items held for
+// a BufferedStorage carry only their URL, Key and Checksum.
+type StoreCommitter interface {
+	Committed(items []*types.Item) error
+}
+
 // Storage is the interface for all storage backends.
 type Storage interface {
 	Store(items []*types.Item) error
 	Close() error
 }
 
+// BufferedStorage is a Storage whose Store may keep items in memory or in
+// unfinished files, so they are durable only once Close succeeds.
+type BufferedStorage interface {
+	Storage
+	Buffered() bool
+}
+
 // ResponseCallback is a function called when a response is received.
 type ResponseCallback func(resp *types.Response) ([]*types.Item, []*types.Request, error)
 
@@ -120,6 +164,9 @@ type Engine struct {
 	scheduler  *Scheduler
 	fetchers   map[string]Fetcher
 	parser     Parser
+	paginator  Paginator
+	forms      FormSubmitter
+	sitemaps   *sitemapSeeder
 	pipeline   Pipeline
 	storage    Storage
 
@@ -134,11 +181,17 @@ type Engine struct {
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 	mu     sync.RWMutex
+
+	// itemCtx outlives ctx so that items scraped before a normal finish
+	// drain through the pipeline; only Stop cancels it.
+	itemCtx    context.Context
+	itemCancel context.CancelFunc
 }
 
 // New creates a new Engine with the given configuration.
 func New(cfg *config.Config, logger *slog.Logger) *Engine {
 	ctx, cancel := context.WithCancel(context.Background())
+	itemCtx, itemCancel := context.WithCancel(context.Background())
 
 	e := &Engine{
 		cfg:        cfg,
@@ -155,8 +208,10 @@ func New(cfg *config.Config, logger *slog.Logger) *Engine {
 		stats: &Stats{
 			domainStats: make(map[string]*DomainStats),
 		},
-		ctx:    ctx,
-		cancel: cancel,
+		ctx:        ctx,
+		cancel:     cancel,
+		itemCtx:    itemCtx,
+		itemCancel: itemCancel,
 	}
 
 	e.scheduler = NewScheduler(e)
@@ -177,6 +232,32 @@ func (e *Engine) SetParser(p Parser) {
 	e.parser = p
 }
 
+// SetPaginator enables declarative pagination.
+func (e *Engine) SetPaginator(p Paginator) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.paginator = p
+}
+
+// SetFormSubmitter enables config-driven form submission.
+func (e *Engine) SetFormSubmitter(f FormSubmitter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.forms = f
+}
+
+// rulesFor returns the parse rules for a request: its rule set when it names
+// one, otherwise the default rules.
+func (e *Engine) rulesFor(req *types.Request) []config.ParseRule {
+	if name, ok := req.Meta[types.MetaRuleSet].(string); ok && name != "" {
+		if rules, ok := e.cfg.Parser.RuleSets[strings.ToLower(name)]; ok {
+			return rules
+		}
+		e.logger.Warn("unknown rule set", "rule_set", name, "url", req.URLString())
+	}
+	return e.cfg.Parser.Rules
+}
+
 // SetPipeline sets the pipeline implementation.
 func (e *Engine) SetPipeline(p Pipeline) {
 	e.mu.Lock()
@@ -219,8 +300,12 @@ func (e *Engine) AddRequest(req *types.Request) error {
 		return types.ErrMaxDepth
 	}
 
-	// Check dedup
-	if e.dedup.IsSeen(urlStr) {
+	// Check dedup; POST requests to one URL differ by body
+	dedupKey := urlStr
+	if (req.Method != "" && req.Method != http.MethodGet) || len(req.Body) > 0 {
+		dedupKey = RequestKey(req)
+	}
+	if e.dedup.IsSeen(dedupKey) {
 		e.stats.URLsFiltered.Add(1)
 		return types.ErrDuplicate
 	}
@@ -237,7 +322,12 @@ func (e *Engine) AddRequest(req *types.Request) error {
 		return fmt.Errorf("domain %q is not allowed", req.Domain())
 	}
 
-	e.dedup.MarkSeen(urlStr)
+	// Seeds keep their priority; discovered pages may be demoted
+	if rp, ok := e.pipeline.(RequestPrioritizer); ok && req.Depth > 0 {
+		req.Priority = rp.Prioritize(urlStr, req.Priority)
+	}
+
+	e.dedup.MarkSeen(dedupKey)
 	e.frontier.Push(req)
 	e.stats.URLsEnqueued.Add(1)
 	return nil
@@ -280,6 +370,7 @@ func (e *Engine) Start() error {
 // Wait blocks until all work is done.
 func (e *Engine) Wait() {
 	e.scheduler.Wait()
+	completed := State(e.state.Load()) == StateRunning
 
 	// Cancel context to stop checkpoint goroutine and other background tasks
 	e.cancel()
@@ -289,8 +380,16 @@ func (e *Engine) Wait() {
 	close(e.errChan)
 
 	e.wg.Wait()
+	e.itemCancel()
 	e.state.Store(int32(StateStopped))
 
+	// Record sitemap runs only for crawls that were not interrupted
+	if completed && e.sitemaps != nil && e.sitemaps.state != nil {
+		if err := e.sitemaps.state.save(); err != nil {
+			e.logger.Warn("failed to save sitemap state", "error", err)
+		}
+	}
+
 	// Close fetchers
 	e.mu.RLock()
 	for _, f := range e.fetchers {
@@ -312,6 +411,7 @@ func (e *Engine) Stop() {
 	// Close frontier first so all workers polling TryPop() see IsClosed() and exit
 	e.frontier.Close()
 	e.cancel()
+	e.itemCancel()
 }
 
 // Pause pauses the engine.
@@ -369,20 +469,36 @@ func (e *Engine) isDomainAllowed(domain string) bool {
 // processItems runs the pipeline on scraped items.
 func (e *Engine) processItems() {
 	defer e.wg.Done()
+	defer close(e.resultChan)
+
+	emit := func(item *types.Item, err error) {
+		if err != nil {
+			e.stats.ItemsDropped.Add(1)
+			e.logger.Warn("pipeline dropped item", "url", item.URL, "error", err)
+			return
+		}
+		e.stats.ItemsScraped.Add(1)
+		e.resultChan <- item
+	}
+
+	if sp, ok := e.pipeline.(StreamingPipeline); ok {
+		sp.StreamContext(e.itemCtx, e.itemChan, emit)
+		return
+	}
 	for item := range e.itemChan {
 		if e.pipeline != nil {
 			processed, err := e.pipeline.Process(item)
 			if err != nil {
-				e.stats.ItemsDropped.Add(1)
-				e.logger.Warn("pipeline dropped item", "url", item.URL, "error", err)
+				emit(item, err)
 				continue
 			}
+			if processed == nil {
+				continue // dropped by a middleware
+			}
 			item = processed
 		}
-		e.stats.ItemsScraped.Add(1)
-		e.resultChan <- item
+		emit(item, nil)
 	}
-	close(e.resultChan)
 }
 
 // storeResults persists items from the result channel.
@@ -390,6 +506,19 @@ func (e *Engine) storeResults() {
 	defer e.wg.Done()
 	batch := make([]*types.Item, 0, e.cfg.Storage.BatchSize)
 
+	// Items are committed to the pipeline once durable: after Store, or
+	// for buffered storage after Close. Until then only what Committed
+	// needs to identify them is kept.
+	sc, commits := e.pipeline.(StoreCommitter)
+	bs, buffered := e.storage.(BufferedStorage)
+	buffered = buffered && bs.Buffered()
+	var pending []*types.Item
+	commit := func(items []*types.Item) {
+		if err := sc.Committed(items); err != nil {
+			e.logger.Error("commit stored items", "error", err, "batch_size", len(items))
+		}
+	}
+
 	flush := func() {
 		if len(batch) == 0 {
 			return
@@ -397,7 +526,18 @@ func (e *Engine) storeResults() {
 		if e.storage != nil {
 			if err := e.storage.Store(batch); err != nil {
 				e.logger.Error("storage error", "error", err, "batch_size", len(batch))
+				batch = batch[:0]
+				return
+			}
+		}
+		switch {
+		case !commits:
+		case buffered:
+			for _, item := range batch {
+				pending = append(pending, &types.Item{URL: item.URL, Key: item.Key, Checksum: item.Checksum})
 			}
+		default:
+			commit(batch)
 		}
 		batch = batch[:0]
 	}
@@ -412,9 +552,13 @@ func (e *Engine) storeResults() {
 
 	if e.storage != nil {
 		if err := e.storage.Close(); err != nil {
-			e.logger.Error("storage close error", "error", err)
+			e.logger.Error("storage close error", "error", err, "uncommitted", len(pending))
+			return
 		}
 	}
+	if len(pending) > 0 {
+		commit(pending)
+	}
 }
 
 // autoCheckpoint periodically saves engine state.
@@ -1,9 +1,20 @@
 package engine
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
 	"github.com/IshaanNene/ScrapeGoat/internal/types"
 )
 
@@ -116,6 +127,187 @@ func TestDeduplicatorURLVariants(t *testing.T) {
 	}
 }
 
+func TestRequestKey(t *testing.T) {
+	post := func(body string) *types.Request {
+		r, _ := types.NewRequest("https://example.com/search?b=2&a=1")
+		r.Method = "POST"
+		r.Body = []byte(body)
+		return r
+	}
+
+	d := NewDeduplicator(10)
+	d.MarkSeen(RequestKey(post("q=go")))
+	if !d.IsSeen(RequestKey(post("q=go"))) {
+		t.Error("identical POST should be seen")
+	}
+	if d.IsSeen(RequestKey(post("q=rust"))) {
+		t.Error("POST with another body should not be seen")
+	}
+	if d.IsSeen("https://example.com/search?a=1&b=2") {
+		t.Error("GET of the same URL should not be seen")
+	}
+}
+
+// --- Sitemap Tests ---
+
+func TestAddSitemapSeeds(t *testing.T) {
+	gz := func(s string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(s))
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nSitemap: %s/index.xml.gz\n", srv.URL)
+		case "/index.xml.gz":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(gz(fmt.Sprintf(`<sitemapindex>
+				<sitemap><loc>%[1]s/pages.xml</loc><lastmod>2026-03-01</lastmod></sitemap>
+				<sitemap><loc>%[1]s/archive.xml</loc><lastmod>2020-01-01</lastmod></sitemap>
+				<sitemap><loc>%[1]s/index.xml.gz</loc></sitemap>
+			</sitemapindex>`, srv.URL)))
+		case "/pages.xml":
+			fmt.Fprintf(w, `<urlset>
+				<url><loc>%[1]s/products/1</loc><lastmod>2026-02-20T10:00:00Z</lastmod><priority>0.9</priority><changefreq>daily</changefreq></url>
+				<url><loc>%[1]s/blog/new</loc><lastmod>2026-02-15</lastmod><changefreq>yearly</changefreq></url>
+				<url><loc>%[1]s/blog/old</loc><lastmod>2025-06-01</lastmod></url>
+				<url><loc>%[1]s/tag/go</loc></url>
+			</urlset>`, srv.URL)
+		case "/archive.xml":
+			t.Error("unchanged child sitemap should not be fetched")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	statePath := t.TempDir() + "/sitemaps.json"
+	os.WriteFile(statePath, []byte(fmt.Sprintf(`{%q: "2026-01-01T00:00:00Z"}`, srv.URL)), 0o644)
+
+	cfg := config.DefaultConfig()
+	cfg.Engine.RespectRobotsTxt = false
+	cfg.Engine.Sitemap = config.SitemapConfig{
+		Enabled:      true,
+		Exclude:      []string{"/tag/"},
+		SinceLastRun: true,
+		StatePath:    statePath,
+		Routes:       []config.SitemapRoute{{Match: "/products/", RuleSet: "product", Tag: "shop"}},
+	}
+	e := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	n, err := e.AddSitemapSeeds(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("AddSitemapSeeds: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 seeds, got %d", n)
+	}
+
+	got := make(map[string]*types.Request)
+	for _, req := range e.frontier.Snapshot() {
+		got[strings.TrimPrefix(req.URLString(), srv.URL)] = req
+	}
+	product, blog := got["/products/1"], got["/blog/new"]
+	if product == nil || blog == nil || len(got) != 2 {
+		t.Fatalf("unexpected frontier: %v", got)
+	}
+	if product.Priority != types.PriorityHigh || blog.Priority != types.PriorityLow {
+		t.Errorf("priorities = %d, %d; want %d, %d", product.Priority, blog.Priority, types.PriorityHigh, types.PriorityLow)
+	}
+	if product.Meta[types.MetaRuleSet] != "product" || product.Tag != "shop" || product.Depth != 0 {
+		t.Errorf("product not routed: meta=%v tag=%q depth=%d", product.Meta, product.Tag, product.Depth)
+	}
+	if _, ok := blog.Meta[types.MetaRuleSet]; ok {
+		t.Error("unrouted entry should use the default rules")
+	}
+
+	// The run is only recorded once committed.
+	if err := e.sitemaps.state.save(); err != nil {
+		t.Fatal(err)
+	}
+	state, err := loadSitemapState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.lastRun(srv.URL).After(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("last run not advanced: %v", state.lastRun(srv.URL))
+	}
+
+	if !IsSitemapURL("https://example.com/sitemap-news.xml.gz") || IsSitemapURL("https://example.com/products") {
+		t.Error("IsSitemapURL misclassified a URL")
+	}
+}
+
+// --- Storage Tests ---
+
+// commitPipeline records the items committed after storage.
+type commitPipeline struct{ committed []string }
+
+func (p *commitPipeline) Process(item *types.Item) (*types.Item, error) { return item, nil }
+
+func (p *commitPipeline) Committed(items []*types.Item) error {
+	for _, item := range items {
+		p.committed = append(p.committed, item.URL)
+	}
+	return nil
+}
+
+// testStorage rejects items whose URL ends in "fail", and optionally
+// buffers items until Close.
+type testStorage struct {
+	buffered bool
+	closeErr error
+}
+
+func (testStorage) Store(items []*types.Item) error {
+	for _, item := range items {
+		if strings.HasSuffix(item.URL, "fail") {
+			return fmt.Errorf("rejected %s", item.URL)
+		}
+	}
+	return nil
+}
+
+func (s testStorage) Close() error   { return s.closeErr }
+func (s testStorage) Buffered() bool { return s.buffered }
+
+func TestStoreResultsCommitsStoredItems(t *testing.T) {
+	stored := []string{"https://example.com/a", "https://example.com/b"}
+	tests := []struct {
+		name    string
+		storage testStorage
+		want    []string
+	}{
+		{"after each batch", testStorage{}, stored},
+		{"after close when buffered", testStorage{buffered: true}, stored},
+		{"not when close fails", testStorage{buffered: true, closeErr: errors.New("disk full")}, nil},
+	}
+	for _, tt := range tests {
+		cfg := config.DefaultConfig()
+		cfg.Storage.BatchSize = 1
+		e := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		pipe := &commitPipeline{}
+		e.SetPipeline(pipe)
+		e.SetStorage(tt.storage)
+
+		for _, u := range []string{"https://example.com/a", "https://example.com/fail", "https://example.com/b"} {
+			e.resultChan <- types.NewItem(u)
+		}
+		close(e.resultChan)
+		e.wg.Add(1)
+		e.storeResults()
+
+		if fmt.Sprint(pipe.committed) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: committed = %v, want %v", tt.name, pipe.committed, tt.want)
+		}
+	}
+}
+
 // --- Stats Tests ---
 
 func TestStatsSnapshot(t *testing.T) {
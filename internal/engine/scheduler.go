@@ -215,6 +215,15 @@ func (s *Scheduler) processRequest(ctx context.Context, logger *slog.Logger, req
 	s.engine.stats.BytesDownloaded.Add(resp.ContentLength)
 	logger.Debug("fetched", "status", resp.StatusCode, "size", resp.ContentLength, "duration", resp.FetchDuration)
 
+	// Items share one origin, built once the first item needs it
+	var origin *types.Origin
+	originOf := func() *types.Origin {
+		if origin == nil {
+			origin = &types.Origin{Request: req, Response: resp.Info(), Stats: s.engine.stats.Snapshot}
+		}
+		return origin
+	}
+
 	// Invoke ALL registered callbacks on every response
 	s.engine.mu.RLock()
 	callbacksCopy := make(map[string]ResponseCallback, len(s.engine.callbacks))
@@ -232,6 +241,7 @@ func (s *Scheduler) processRequest(ctx context.Context, logger *slog.Logger, req
 		for _, item := range items {
 			item.SpiderName = cbName
 			item.Depth = req.Depth
+			item.Origin = originOf()
 			s.engine.itemChan <- item
 		}
 		for _, r := range newReqs {
@@ -243,14 +253,54 @@ func (s *Scheduler) processRequest(ctx context.Context, logger *slog.Logger, req
 
 	// Always run the parser for link discovery and structured data
 	if s.engine.parser != nil {
-		items, links, err := s.engine.parser.Parse(resp, s.engine.cfg.Parser.Rules)
+		items, links, err := s.engine.parser.Parse(resp, s.engine.rulesFor(req))
 		if err != nil {
 			logger.Warn("parse error", "error", err)
 		}
+
+		// Next pages first, so link discovery cannot claim their URLs
+		// without the pagination state.
+		if s.engine.paginator != nil {
+			for _, next := range s.engine.paginator.Next(resp, items) {
+				next.Depth = req.Depth
+				next.ParentURL = req.URLString()
+				next.Tag = req.Tag
+				next.FetcherType = req.FetcherType
+				next.Headers = req.Headers.Clone()
+				if ruleSet, ok := req.Meta[types.MetaRuleSet]; ok {
+					next.Meta[types.MetaRuleSet] = ruleSet
+				}
+				if err := s.engine.AddRequest(next); err != nil {
+					logger.Debug("next page skipped", "next", next.URLString(), "reason", err)
+					s.engine.paginator.EndChain(next)
+				}
+			}
+		}
+
+		if s.engine.forms != nil {
+			for _, formReq := range s.engine.forms.Submit(resp) {
+				formReq.Depth = req.Depth + 1
+				formReq.ParentURL = req.URLString()
+				formReq.FetcherType = req.FetcherType
+				if formReq.Tag == "" {
+					formReq.Tag = req.Tag
+				}
+				if ruleSet, ok := req.Meta[types.MetaRuleSet]; ok {
+					if _, set := formReq.Meta[types.MetaRuleSet]; !set {
+						formReq.Meta[types.MetaRuleSet] = ruleSet
+					}
+				}
+				if err := s.engine.AddRequest(formReq); err != nil {
+					logger.Debug("form submission skipped", "action", formReq.URLString(), "reason", err)
+				}
+			}
+		}
+
 		// Only emit parser items if no callbacks produced items (avoid duplicates)
 		if len(callbacksCopy) == 0 {
 			for _, item := range items {
 				item.Depth = req.Depth
+				item.Origin = originOf()
 				s.engine.itemChan <- item
 			}
 		}
@@ -294,6 +344,9 @@ func (s *Scheduler) handleFetchError(logger *slog.Logger, req *types.Request, er
 
 	s.engine.stats.ResponsesError.Add(1)
 	logger.Error("fetch failed permanently", "error", err, "retries", req.RetryCount)
+	if s.engine.paginator != nil {
+		s.engine.paginator.EndChain(req)
+	}
 }
 
 // applyThrottle enforces per-domain politeness delays.
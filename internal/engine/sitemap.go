@@ -0,0 +1,329 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/seo"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// sitemapSeeder expands seed sites into the URLs their sitemaps list.
+type sitemapSeeder struct {
+	cfg     config.SitemapConfig
+	crawler *seo.SitemapCrawler
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	routes  []sitemapRoute
+	state   *sitemapState // nil unless since_last_run is set
+}
+
+type sitemapRoute struct {
+	config.SitemapRoute
+	match *regexp.Regexp
+}
+
+func newSitemapSeeder(e *Engine) (*sitemapSeeder, error) {
+	cfg := e.cfg.Engine.Sitemap
+	s := &sitemapSeeder{cfg: cfg, crawler: seo.NewSitemapCrawler(e.logger)}
+
+	var err error
+	if s.include, err = compilePatterns(cfg.Include); err != nil {
+		return nil, fmt.Errorf("sitemap include: %w", err)
+	}
+	if s.exclude, err = compilePatterns(cfg.Exclude); err != nil {
+		return nil, fmt.Errorf("sitemap exclude: %w", err)
+	}
+	for i, r := range cfg.Routes {
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("sitemap routes[%d]: %w", i, err)
+		}
+		s.routes = append(s.routes, sitemapRoute{SitemapRoute: r, match: re})
+	}
+	if cfg.SinceLastRun {
+		if s.state, err = loadSitemapState(cfg.StatePath); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// AddSitemapSeeds seeds the crawl from the sitemaps of rawURL's site and
+// returns the number of requests enqueued. If rawURL is itself a sitemap it
+// is read directly; otherwise the configured sitemap URLs are used, or the
+// sitemaps listed in robots.txt, or the well-known sitemap paths.
+//
+// Entries are filtered by the include/exclude patterns and, with
+// since_last_run, by lastmod against the start of the previous completed
+// run. Each entry is routed to the rule set of the first matching route.
+func (e *Engine) AddSitemapSeeds(rawURL string) (int, error) {
+	e.mu.Lock()
+	if e.sitemaps == nil {
+		s, err := newSitemapSeeder(e)
+		if err != nil {
+			e.mu.Unlock()
+			return 0, err
+		}
+		e.sitemaps = s
+	}
+	s := e.sitemaps
+	e.mu.Unlock()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, err
+	}
+	site := u.Scheme + "://" + u.Host
+	started := time.Now()
+
+	sitemaps := e.sitemapsFor(s, u, site)
+	if len(sitemaps) == 0 {
+		return 0, fmt.Errorf("no sitemaps found for %s", site)
+	}
+
+	var since time.Time
+	if s.state != nil {
+		since = s.state.lastRun(site)
+		s.state.begin(site, started)
+	}
+
+	added, skipped := 0, 0
+	for _, sitemapURL := range sitemaps {
+		if s.cfg.MaxURLs > 0 && added >= s.cfg.MaxURLs {
+			break
+		}
+		entries, err := s.crawler.CrawlSince(sitemapURL, since)
+		if err != nil {
+			e.logger.Warn("sitemap failed", "url", sitemapURL, "error", err)
+			continue
+		}
+		for _, entry := range entries {
+			if s.cfg.MaxURLs > 0 && added >= s.cfg.MaxURLs {
+				break
+			}
+			if !s.wanted(entry.Loc) {
+				skipped++
+				continue
+			}
+			if mod := entry.Modified(); !since.IsZero() && !mod.IsZero() && !mod.After(since) {
+				skipped++
+				continue
+			}
+			req, err := s.request(entry)
+			if err != nil {
+				skipped++
+				continue
+			}
+			if err := e.AddRequest(req); err != nil {
+				skipped++
+				continue
+			}
+			added++
+		}
+	}
+
+	e.logger.Info("sitemap seeds added", "site", site, "sitemaps", len(sitemaps), "added", added, "skipped", skipped)
+	return added, nil
+}
+
+// IsSitemapURL reports whether rawURL names a sitemap rather than a page.
+func IsSitemapURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	base := strings.ToLower(path.Base(u.Path))
+	return strings.HasSuffix(base, ".xml") || strings.HasSuffix(base, ".xml.gz") ||
+		strings.Contains(base, "sitemap") && strings.HasSuffix(base, ".txt")
+}
+
+// sitemapsFor returns the sitemaps to read for a seed.
+func (e *Engine) sitemapsFor(s *sitemapSeeder, seed *url.URL, site string) []string {
+	if IsSitemapURL(seed.String()) {
+		return []string{seed.String()}
+	}
+
+	// Relative config URLs apply to every seed site, absolute ones to their own.
+	if len(s.cfg.URLs) > 0 {
+		var out []string
+		for _, raw := range s.cfg.URLs {
+			ref, err := url.Parse(raw)
+			if err != nil {
+				continue
+			}
+			if resolved := seed.ResolveReference(ref); resolved.Host == seed.Host {
+				out = append(out, resolved.String())
+			}
+		}
+		return out
+	}
+
+	if data := e.robots.getRobotsData(site); data != nil && len(data.sitemaps) > 0 {
+		return data.sitemaps
+	}
+	return s.crawler.DiscoverSitemaps(site)
+}
+
+// wanted applies the include and exclude patterns.
+func (s *sitemapSeeder) wanted(loc string) bool {
+	if loc == "" {
+		return false
+	}
+	for _, re := range s.exclude {
+		if re.MatchString(loc) {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, re := range s.include {
+		if re.MatchString(loc) {
+			return true
+		}
+	}
+	return false
+}
+
+// request builds the depth-0 request for a sitemap entry.
+func (s *sitemapSeeder) request(entry seo.SitemapURL) (*types.Request, error) {
+	req, err := types.NewRequest(entry.Loc)
+	if err != nil {
+		return nil, err
+	}
+	req.Priority = sitemapPriority(entry)
+	req.Meta["sitemap_lastmod"] = entry.LastMod
+	for _, r := range s.routes {
+		if r.match.MatchString(entry.Loc) {
+			if r.RuleSet != "" {
+				req.Meta[types.MetaRuleSet] = r.RuleSet
+			}
+			req.Tag = r.Tag
+			break
+		}
+	}
+	return req, nil
+}
+
+// sitemapPriority maps an entry's priority (0.0–1.0, default 0.5) and
+// changefreq to a frontier priority. Seeds keep PriorityHighest, so sitemap
+// entries range from PriorityHigh down to PriorityLowest.
+func sitemapPriority(entry seo.SitemapURL) int {
+	score := entry.Priority
+	if score <= 0 || score > 1 {
+		score = 0.5
+	}
+	switch strings.ToLower(strings.TrimSpace(entry.ChangeFreq)) {
+	case "always", "hourly":
+		score += 0.2
+	case "daily":
+		score += 0.1
+	case "monthly":
+		score -= 0.1
+	case "yearly":
+		score -= 0.2
+	case "never":
+		score -= 0.3
+	}
+
+	switch {
+	case score >= 0.8:
+		return types.PriorityHigh
+	case score >= 0.5:
+		return types.PriorityNormal
+	case score >= 0.3:
+		return types.PriorityLow
+	default:
+		return types.PriorityLowest
+	}
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// --- Sitemap State ---
+
+// sitemapState records when each site's sitemaps were last expanded by a
+// run that completed. Runs in progress are only committed by save, so an
+// interrupted crawl does not hide entries from the next one.
+type sitemapState struct {
+	path    string
+	mu      sync.Mutex
+	runs    map[string]time.Time
+	pending map[string]time.Time
+}
+
+// loadSitemapState reads state from statePath. A missing file yields an empty state.
+func loadSitemapState(statePath string) (*sitemapState, error) {
+	s := &sitemapState{
+		path:    statePath,
+		runs:    make(map[string]time.Time),
+		pending: make(map[string]time.Time),
+	}
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("read sitemap state: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.runs); err != nil {
+		return nil, fmt.Errorf("decode sitemap state: %w", err)
+	}
+	return s, nil
+}
+
+func (s *sitemapState) lastRun(site string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs[site]
+}
+
+func (s *sitemapState) begin(site string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[site] = t
+}
+
+// save commits the pending runs and writes the state via a temp file and
+// rename.
+func (s *sitemapState) save() error {
+	s.mu.Lock()
+	for site, t := range s.pending {
+		s.runs[site] = t
+	}
+	clear(s.pending)
+	data, err := json.MarshalIndent(s.runs, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encode sitemap state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("create sitemap state dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write sitemap state: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,217 @@
+package enrich
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// Address is a postal address split into its parts. Region is a state or
+// province code where the country's table knows it, and Country an ISO
+// 3166 code.
+type Address struct {
+	Street     string `json:"street,omitempty"`
+	City       string `json:"city,omitempty"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// Map returns the non-empty parts keyed as in JSON.
+func (a Address) Map() map[string]any {
+	m := make(map[string]any, 5)
+	for k, v := range map[string]string{
+		"street": a.Street, "city": a.City, "region": a.Region,
+		"postal_code": a.PostalCode, "country": a.Country,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// countryNames maps lowercased country names and codes to ISO codes.
+var countryNames = map[string]string{
+	"us": "US", "usa": "US", "u.s.a.": "US", "united states": "US", "united states of america": "US",
+	"ca": "CA", "canada": "CA",
+	"gb": "GB", "uk": "GB", "u.k.": "GB", "united kingdom": "GB", "great britain": "GB", "england": "GB", "scotland": "GB", "wales": "GB",
+	"ie": "IE", "ireland": "IE",
+	"de": "DE", "germany": "DE", "deutschland": "DE",
+	"at": "AT", "austria": "AT", "österreich": "AT",
+	"ch": "CH", "switzerland": "CH", "schweiz": "CH", "suisse": "CH",
+	"fr": "FR", "france": "FR",
+	"be": "BE", "belgium": "BE", "belgique": "BE", "belgië": "BE",
+	"nl": "NL", "netherlands": "NL", "the netherlands": "NL", "nederland": "NL",
+	"es": "ES", "spain": "ES", "españa": "ES",
+	"pt": "PT", "portugal": "PT",
+	"it": "IT", "italy": "IT", "italia": "IT",
+	"pl": "PL", "poland": "PL", "polska": "PL",
+	"se": "SE", "sweden": "SE", "sverige": "SE",
+	"in": "IN", "india": "IN",
+	"au": "AU", "australia": "AU",
+	"nz": "NZ", "new zealand": "NZ",
+	"jp": "JP", "japan": "JP",
+	"cn": "CN", "china": "CN",
+	"br": "BR", "brazil": "BR", "brasil": "BR",
+	"mx": "MX", "mexico": "MX", "méxico": "MX",
+	"za": "ZA", "south africa": "ZA",
+	"sg": "SG", "singapore": "SG",
+}
+
+// postalCodes are each country's postal code formats.
+var postalCodes = map[string]*regexp.Regexp{
+	"US": regexp.MustCompile(`\b\d{5}(?:-\d{4})?\b`),
+	"CA": regexp.MustCompile(`(?i)\b[ABCEGHJ-NPRSTVXY]\d[A-Z] ?\d[A-Z]\d\b`),
+	"GB": regexp.MustCompile(`(?i)\b[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}\b`),
+	"IE": regexp.MustCompile(`(?i)\b[AC-FHKNPRTV-Y]\d{2}(?: ?[0-9AC-FHKNPRTV-Y]{4})?\b`),
+	"DE": regexp.MustCompile(`\b\d{5}\b`),
+	"AT": regexp.MustCompile(`\b\d{4}\b`),
+	"CH": regexp.MustCompile(`\b\d{4}\b`),
+	"FR": regexp.MustCompile(`\b\d{5}\b`),
+	"BE": regexp.MustCompile(`\b\d{4}\b`),
+	"NL": regexp.MustCompile(`(?i)\b\d{4} ?[A-Z]{2}\b`),
+	"ES": regexp.MustCompile(`\b\d{5}\b`),
+	"PT": regexp.MustCompile(`\b\d{4}-\d{3}\b`),
+	"IT": regexp.MustCompile(`\b\d{5}\b`),
+	"PL": regexp.MustCompile(`\b\d{2}-\d{3}\b`),
+	"SE": regexp.MustCompile(`\b\d{3} ?\d{2}\b`),
+	"IN": regexp.MustCompile(`\b\d{3} ?\d{3}\b`),
+	"AU": regexp.MustCompile(`\b\d{4}\b`),
+	"NZ": regexp.MustCompile(`\b\d{4}\b`),
+	"JP": regexp.MustCompile(`\b\d{3}-\d{4}\b`),
+	"CN": regexp.MustCompile(`\b\d{6}\b`),
+	"BR": regexp.MustCompile(`\b\d{5}-?\d{3}\b`),
+	"MX": regexp.MustCompile(`\b\d{5}\b`),
+	"ZA": regexp.MustCompile(`\b\d{4}\b`),
+	"SG": regexp.MustCompile(`\b\d{6}\b`),
+}
+
+// regionNames maps each country's lowercased state or province names and
+// codes to codes.
+var regionNames = map[string]map[string]string{
+	"US": namesWithCodes(map[string]string{
+		"AL": "alabama", "AK": "alaska", "AZ": "arizona", "AR": "arkansas", "CA": "california",
+		"CO": "colorado", "CT": "connecticut", "DE": "delaware", "DC": "district of columbia",
+		"FL": "florida", "GA": "georgia", "HI": "hawaii", "ID": "idaho", "IL": "illinois",
+		"IN": "indiana", "IA": "iowa", "KS": "kansas", "KY": "kentucky", "LA": "louisiana",
+		"ME": "maine", "MD": "maryland", "MA": "massachusetts", "MI": "michigan", "MN": "minnesota",
+		"MS": "mississippi", "MO": "missouri", "MT": "montana", "NE": "nebraska", "NV": "nevada",
+		"NH": "new hampshire", "NJ": "new jersey", "NM": "new mexico", "NY": "new york",
+		"NC": "north carolina", "ND": "north dakota", "OH": "ohio", "OK": "oklahoma", "OR": "oregon",
+		"PA": "pennsylvania", "RI": "rhode island", "SC": "south carolina", "SD": "south dakota",
+		"TN": "tennessee", "TX": "texas", "UT": "utah", "VT": "vermont", "VA": "virginia",
+		"WA": "washington", "WV": "west virginia", "WI": "wisconsin", "WY": "wyoming", "PR": "puerto rico",
+	}),
+	"CA": namesWithCodes(map[string]string{
+		"AB": "alberta", "BC": "british columbia", "MB": "manitoba", "NB": "new brunswick",
+		"NL": "newfoundland and labrador", "NS": "nova scotia", "NT": "northwest territories",
+		"NU": "nunavut", "ON": "ontario", "PE": "prince edward island", "QC": "quebec",
+		"SK": "saskatchewan", "YT": "yukon",
+	}),
+	"AU": namesWithCodes(map[string]string{
+		"ACT": "australian capital territory", "NSW": "new south wales", "NT": "northern territory",
+		"QLD": "queensland", "SA": "south australia", "TAS": "tasmania", "VIC": "victoria",
+		"WA": "western australia",
+	}),
+}
+
+func namesWithCodes(codes map[string]string) map[string]string {
+	out := make(map[string]string, 2*len(codes))
+	for code, name := range codes {
+		out[strings.ToLower(code)] = code
+		out[name] = code
+	}
+	return out
+}
+
+// ParseAddress splits a one-line or multi-line address. The country is
+// taken from the last part when it names one, otherwise it is region; its
+// tables then pick out the postal code and state or province.
+func ParseAddress(raw, region string) Address {
+	var parts []string
+	for _, p := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' || r == ';' }) {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	var a Address
+	if len(parts) == 0 {
+		return a
+	}
+
+	if code, ok := countryNames[strings.ToLower(strings.TrimSuffix(parts[len(parts)-1], "."))]; ok && len(parts) > 1 {
+		a.Country = code
+		parts = parts[:len(parts)-1]
+	} else {
+		a.Country = strings.ToUpper(region)
+	}
+
+	// The postal code is in one of the last two parts, usually beside the
+	// city or region.
+	if re := postalCodes[a.Country]; re != nil {
+		for i := len(parts) - 1; i >= max(len(parts)-2, 1); i-- {
+			if loc := re.FindStringIndex(parts[i]); loc != nil {
+				a.PostalCode = strings.ToUpper(parts[i][loc[0]:loc[1]])
+				parts[i] = strings.TrimSpace(parts[i][:loc[0]] + " " + parts[i][loc[1]:])
+				break
+			}
+		}
+	}
+
+	// A region is the last part, or the end of it ("Springfield IL").
+	if names := regionNames[a.Country]; names != nil && len(parts) > 1 {
+		last := parts[len(parts)-1]
+		if code, ok := names[strings.ToLower(last)]; ok {
+			a.Region = code
+			parts[len(parts)-1] = ""
+		} else if i := strings.LastIndexByte(last, ' '); i > 0 {
+			if code, ok := names[strings.ToLower(last[i+1:])]; ok {
+				a.Region = code
+				parts[len(parts)-1] = strings.TrimSpace(last[:i])
+			}
+		}
+	}
+
+	var rest []string
+	for _, p := range parts {
+		if p != "" {
+			rest = append(rest, p)
+		}
+	}
+	switch len(rest) {
+	case 0:
+	case 1:
+		a.Street = rest[0]
+	default:
+		a.City = rest[len(rest)-1]
+		a.Street = strings.Join(rest[:len(rest)-1], ", ")
+	}
+	return a
+}
+
+// AddressParser writes the parts of address fields to field+"_parsed".
+type AddressParser struct {
+	fields []string
+	region string
+}
+
+// NewAddressParser creates an address parser assuming region (an ISO 3166
+// code) for addresses that do not name their country.
+func NewAddressParser(fields []string, region string) *AddressParser {
+	return &AddressParser{fields: fields, region: region}
+}
+
+func (p *AddressParser) Name() string { return "address_parse" }
+
+func (p *AddressParser) Process(item *types.Item) (*types.Item, error) {
+	for _, field := range p.fields {
+		raw := item.GetString(field)
+		if raw == "" {
+			continue
+		}
+		item.Set(field+"_parsed", ParseAddress(raw, p.region).Map())
+	}
+	return item, nil
+}
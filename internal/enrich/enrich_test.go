@@ -0,0 +1,195 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"The weather was nice so we went for a walk along the river with the children.", "en"},
+		{"Das Wetter war schön, also sind wir mit den Kindern am Fluss spazieren gegangen.", "de"},
+		{"Il faisait beau, alors nous sommes allés nous promener le long de la rivière avec les enfants.", "fr"},
+		{"Hacía buen tiempo, así que fuimos a pasear por el río con los niños.", "es"},
+		{"Погода была хорошая, поэтому мы пошли гулять вдоль реки с детьми.", "ru"},
+		{"天気が良かったので、子供たちと川沿いを散歩しました。", "ja"},
+		{"ok", ""},
+		{"1234 5678", ""},
+	}
+	for _, tt := range tests {
+		if got, _ := DetectLanguage(tt.text); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	tests := []struct {
+		body string
+		want any
+	}{
+		{"one two three four five", 1},
+		{"one two three four five six", 2},
+		{"", nil},
+	}
+	r := NewReadingTime([]string{"body"}, "reading_time", 5)
+	for _, tt := range tests {
+		item := types.NewItem("https://example.com/")
+		item.Set("body", tt.body)
+		out, _ := r.Process(item)
+		if got, _ := out.Get("reading_time"); got != tt.want {
+			t.Errorf("reading time of %q = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestURLNormalize(t *testing.T) {
+	n := NewURLNormalizer(nil, false, false, DefaultShorteners, time.Second, testLogger)
+	base, _ := url.Parse("https://shop.example.com/products/widget")
+	tests := []struct {
+		raw, want string
+	}{
+		{"../reviews/?utm_source=mail&page=2#top", "https://shop.example.com/reviews?page=2"},
+		{"HTTPS://Shop.Example.com:443/products/widget/?fbclid=abc", "https://shop.example.com/products/widget"},
+		{"mailto:sales@example.com", "mailto:sales@example.com"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := n.Normalize(base, tt.raw); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+	if got := NewURLNormalizer(nil, true, false, nil, time.Second, testLogger).Normalize(nil, "https://example.com/?utm_source=x"); got != "https://example.com/?utm_source=x" {
+		t.Errorf("keep_tracking: %q", got)
+	}
+}
+
+func TestURLNormalizeUnshorten(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-hang:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		http.Redirect(w, r, "https://example.com/article", http.StatusMovedPermanently)
+	}))
+	defer srv.Close()
+	host, _ := url.Parse(srv.URL)
+	n := NewURLNormalizer([]string{"link"}, false, true, []string{host.Hostname()}, time.Minute, testLogger)
+
+	item := types.NewItem("https://example.com/")
+	item.Set("link", srv.URL+"/abc")
+	if out, err := n.ProcessContext(context.Background(), item); err != nil || out.GetString("link") != "https://example.com/article" {
+		t.Errorf("unshortened = %v, %v", out, err)
+	}
+
+	// A stalled lookup ends with the context, failing the item, and is not
+	// cached.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	item.Set("link", srv.URL+"/slow")
+	if _, err := n.ProcessContext(ctx, item); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want deadline exceeded", err)
+	}
+	if _, ok := n.resolved[srv.URL+"/slow"]; ok {
+		t.Error("cancelled lookup was cached")
+	}
+}
+
+func TestParsePhone(t *testing.T) {
+	tests := []struct {
+		raw, region      string
+		e164, wantRegion string
+		ok               bool
+	}{
+		{"020 7946 0958", "GB", "+442079460958", "GB", true},
+		{"+49 (30) 1234567", "GB", "+49301234567", "DE", true},
+		{"(415) 555-2671 ext. 12", "US", "+14155552671", "US", true},
+		{"06 12 34 56 78", "FR", "+33612345678", "FR", true},
+		{"12", "US", "", "", false},
+	}
+	for _, tt := range tests {
+		e164, region, ok := ParsePhone(tt.raw, tt.region)
+		if e164 != tt.e164 || region != tt.wantRegion || ok != tt.ok {
+			t.Errorf("ParsePhone(%q, %s) = %q, %q, %v; want %q, %q, %v", tt.raw, tt.region, e164, region, ok, tt.e164, tt.wantRegion, tt.ok)
+		}
+	}
+	if PhoneRegionKnown("XX") || !PhoneRegionKnown("GB") {
+		t.Error("PhoneRegionKnown")
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		raw, region string
+		want        Address
+	}{
+		{"1600 Amphitheatre Pkwy, Mountain View, CA 94043, USA", "", Address{Street: "1600 Amphitheatre Pkwy", City: "Mountain View", Region: "CA", PostalCode: "94043", Country: "US"}},
+		{"Unter den Linden 77\n10117 Berlin\nGermany", "US", Address{Street: "Unter den Linden 77", City: "Berlin", PostalCode: "10117", Country: "DE"}},
+	}
+	for _, tt := range tests {
+		if got := ParseAddress(tt.raw, tt.region); got != tt.want {
+			t.Errorf("ParseAddress(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		text  string
+		value float64
+		unit  string
+		ok    bool
+	}{
+		{"2.5 lb", 2.5, "lb", true},
+		{"Store below 77°F", 77, "°f", true},
+		{"Inhalt: 1,5 l", 1.5, "l", true},
+		{"1,500 g", 1500, "g", true},
+		{"5 in stock", 0, "", false},
+	}
+	for _, tt := range tests {
+		v, u, ok := ParseQuantity(tt.text)
+		if v != tt.value || u != tt.unit || ok != tt.ok {
+			t.Errorf("ParseQuantity(%q) = %v, %q, %v; want %v, %q, %v", tt.text, v, u, ok, tt.value, tt.unit, tt.ok)
+		}
+	}
+}
+
+func TestConvertUnit(t *testing.T) {
+	tests := []struct {
+		value    float64
+		from, to string
+		want     float64
+		err      bool
+	}{
+		{1, "km", "mi", 0.621371, false},
+		{2.5, "lb", "g", 1133.981, false},
+		{77, "°f", "°c", 25, false},
+		{0, "°c", "kelvin", 273.15, false},
+		{1, "kg", "m", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ConvertUnit(tt.value, tt.from, tt.to)
+		if (err != nil) != tt.err || math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("ConvertUnit(%v %s → %s) = %v, %v; want %v", tt.value, tt.from, tt.to, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Package enrich holds offline item enrichment middlewares: language
+// identification, reading time, URL normalisation, phone and address
+// parsing, and unit conversion.
+package enrich
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// profileSize is the number of top trigrams compared, as in Cavnar and
+// Trenkle's out-of-place measure.
+const profileSize = 300
+
+// languageSamples are the texts the Latin-script trigram profiles are built
+// from. Function words dominate short texts, so the samples use them freely.
+var languageSamples = map[string]string{
+	"en": `The quick answer is that all of the people who have been in the city for a long time
+		would like to see what the council is going to do with the old station. It was one of the
+		first buildings in the area and there are many stories about it. We think that this is
+		something which should be kept for the children and their families, because they will be
+		the ones who live here when we are gone. There is no reason why it could not be used again.`,
+	"es": `La respuesta es que todas las personas que han vivido en la ciudad durante mucho tiempo
+		quieren saber lo que el ayuntamiento va a hacer con la antigua estación. Fue uno de los
+		primeros edificios de la zona y hay muchas historias sobre ella. Creemos que es algo que
+		se debería conservar para los niños y sus familias, porque ellos serán los que vivan aquí
+		cuando nosotros ya no estemos. No hay ninguna razón por la que no se pueda usar de nuevo.`,
+	"fr": `La réponse est que toutes les personnes qui habitent dans la ville depuis longtemps
+		voudraient savoir ce que le conseil va faire de l'ancienne gare. C'était un des premiers
+		bâtiments du quartier et il y a beaucoup d'histoires à son sujet. Nous pensons que c'est
+		quelque chose qui doit être gardé pour les enfants et leurs familles, parce que ce sont eux
+		qui vivront ici quand nous ne serons plus là. Il n'y a aucune raison de ne pas l'utiliser.`,
+	"de": `Die Antwort ist, dass alle Menschen, die schon lange in der Stadt wohnen, wissen möchten,
+		was der Rat mit dem alten Bahnhof machen wird. Es war eines der ersten Gebäude in der Gegend
+		und es gibt viele Geschichten darüber. Wir denken, dass es etwas ist, das für die Kinder und
+		ihre Familien erhalten werden sollte, weil sie hier leben werden, wenn wir nicht mehr da
+		sind. Es gibt keinen Grund, warum es nicht wieder genutzt werden könnte.`,
+	"it": `La risposta è che tutte le persone che vivono in città da molto tempo vorrebbero sapere
+		che cosa il consiglio farà con la vecchia stazione. Era uno dei primi edifici della zona e
+		ci sono molte storie su di essa. Pensiamo che sia qualcosa che dovrebbe essere conservato per
+		i bambini e le loro famiglie, perché saranno loro a vivere qui quando noi non ci saremo più.
+		Non c'è nessun motivo per cui non si possa usare di nuovo.`,
+	"pt": `A resposta é que todas as pessoas que vivem na cidade há muito tempo gostariam de saber
+		o que a câmara vai fazer com a antiga estação. Foi um dos primeiros edifícios da região e
+		há muitas histórias sobre ela. Achamos que é algo que deveria ser preservado para as crianças
+		e as suas famílias, porque são elas que vão viver aqui quando nós já não estivermos. Não há
+		nenhuma razão para que não possa ser usada outra vez.`,
+	"nl": `Het antwoord is dat alle mensen die al lang in de stad wonen graag willen weten wat de
+		gemeenteraad met het oude station gaat doen. Het was een van de eerste gebouwen in de buurt
+		en er zijn veel verhalen over. Wij denken dat het iets is dat voor de kinderen en hun
+		families bewaard moet worden, omdat zij hier zullen wonen als wij er niet meer zijn. Er is
+		geen enkele reden waarom het niet opnieuw gebruikt zou kunnen worden.`,
+	"pl": `Odpowiedź jest taka, że wszyscy ludzie, którzy od dawna mieszkają w mieście, chcieliby
+		wiedzieć, co rada zrobi ze starym dworcem. Był to jeden z pierwszych budynków w okolicy i
+		krąży o nim wiele historii. Uważamy, że jest to coś, co powinno zostać zachowane dla dzieci
+		i ich rodzin, ponieważ to one będą tu mieszkać, kiedy nas już nie będzie. Nie ma żadnego
+		powodu, dla którego nie można by go znowu używać.`,
+}
+
+// scriptLanguages maps scripts used by a single common language to it.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+var languageProfiles = func() map[string]map[string]int {
+	profiles := make(map[string]map[string]int, len(languageSamples))
+	for lang, sample := range languageSamples {
+		profiles[lang] = trigramProfile(sample)
+	}
+	return profiles
+}()
+
+// trigramProfile ranks the most frequent character trigrams of text, with
+// words padded by spaces so that prefixes and suffixes count.
+func trigramProfile(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+	grams := make([]string, 0, len(counts))
+	for g := range counts {
+		grams = append(grams, g)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+	ranks := make(map[string]int, len(grams))
+	for i, g := range grams {
+		ranks[g] = i
+	}
+	return ranks
+}
+
+// DetectLanguage returns the ISO 639-1 code of text's language and a
+// confidence between 0 and 1, or "" for text too short to tell. Scripts
+// used by one language decide directly; Latin text is matched against
+// trigram profiles of en, es, fr, de, it, pt, nl and pl.
+func DetectLanguage(text string) (string, float64) {
+	scripts := make(map[string]int)
+	letters, latin := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.table, r) {
+				scripts[s.lang]++
+				break
+			}
+		}
+	}
+	if letters < 10 {
+		return "", 0
+	}
+	// Kana marks Japanese even among more numerous Han characters.
+	if scripts["ja"] > 0 && scripts["ja"]+scripts["zh"] > latin {
+		return "ja", float64(scripts["ja"]+scripts["zh"]) / float64(letters)
+	}
+	best, bestCount := "", 0
+	for lang, n := range scripts {
+		if n > bestCount || n == bestCount && lang < best {
+			best, bestCount = lang, n
+		}
+	}
+	if bestCount > latin {
+		return best, float64(bestCount) / float64(letters)
+	}
+
+	profile := trigramProfile(text)
+	type score struct {
+		lang     string
+		distance int
+	}
+	scores := make([]score, 0, len(languageProfiles))
+	for lang, ref := range languageProfiles {
+		d := 0
+		for g, rank := range profile {
+			if refRank, ok := ref[g]; ok {
+				d += abs(rank - refRank)
+			} else {
+				d += profileSize
+			}
+		}
+		scores = append(scores, score{lang, d})
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].distance < scores[j].distance })
+	// Confidence is how far ahead of the runner-up the best match is.
+	confidence := 1.0
+	if second := scores[1].distance; second > 0 {
+		confidence = float64(second-scores[0].distance) / float64(second)
+	}
+	return scores[0].lang, confidence
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// LanguageDetector sets the language of an item's text fields.
+type LanguageDetector struct {
+	fields []string
+	output string
+}
+
+// NewLanguageDetector creates a language detector writing the code of the
+// joined fields' language to output and its confidence to
+// output+"_confidence".
+func NewLanguageDetector(fields []string, output string) *LanguageDetector {
+	return &LanguageDetector{fields: fields, output: output}
+}
+
+func (d *LanguageDetector) Name() string { return "language_detect" }
+
+func (d *LanguageDetector) Process(item *types.Item) (*types.Item, error) {
+	lang, confidence := DetectLanguage(joinFields(item, d.fields))
+	if lang == "" {
+		return item, nil
+	}
+	item.Set(d.output, lang)
+	item.Set(d.output+"_confidence", float64(int(confidence*100))/100)
+	return item, nil
+}
+
+// ReadingTime sets the estimated reading time, in whole minutes, of an
+// item's text fields.
+type ReadingTime struct {
+	fields         []string
+	output         string
+	wordsPerMinute int
+}
+
+// NewReadingTime creates a reading time estimator at wordsPerMinute.
+func NewReadingTime(fields []string, output string, wordsPerMinute int) *ReadingTime {
+	return &ReadingTime{fields: fields, output: output, wordsPerMinute: wordsPerMinute}
+}
+
+func (r *ReadingTime) Name() string { return "reading_time" }
+
+func (r *ReadingTime) Process(item *types.Item) (*types.Item, error) {
+	words := len(strings.Fields(joinFields(item, r.fields)))
+	if words == 0 {
+		return item, nil
+	}
+	item.Set(r.output, (words+r.wordsPerMinute-1)/r.wordsPerMinute)
+	return item, nil
+}
+
+// joinFields joins the string values of fields.
+func joinFields(item *types.Item, fields []string) string {
+	var sb strings.Builder
+	for _, f := range fields {
+		if s := item.GetString(f); s != "" {
+			sb.WriteString(s)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
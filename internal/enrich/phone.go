@@ -0,0 +1,141 @@
+package enrich
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// phoneRegion is a country's telephone numbering plan, as far as needed to
+// write national numbers in E.164 form.
+type phoneRegion struct {
+	code       string // country calling code
+	trunk      string // national prefix dropped in international form
+	minLen     int    // national significant number length
+	maxLen     int
+	keepsTrunk bool // the leading 0 is part of the number (Italy)
+}
+
+var phoneRegions = map[string]phoneRegion{
+	"US": {code: "1", trunk: "1", minLen: 10, maxLen: 10},
+	"CA": {code: "1", trunk: "1", minLen: 10, maxLen: 10},
+	"GB": {code: "44", trunk: "0", minLen: 9, maxLen: 10},
+	"IE": {code: "353", trunk: "0", minLen: 7, maxLen: 9},
+	"DE": {code: "49", trunk: "0", minLen: 6, maxLen: 11},
+	"AT": {code: "43", trunk: "0", minLen: 4, maxLen: 13},
+	"CH": {code: "41", trunk: "0", minLen: 9, maxLen: 9},
+	"FR": {code: "33", trunk: "0", minLen: 9, maxLen: 9},
+	"BE": {code: "32", trunk: "0", minLen: 8, maxLen: 9},
+	"NL": {code: "31", trunk: "0", minLen: 9, maxLen: 9},
+	"ES": {code: "34", minLen: 9, maxLen: 9},
+	"PT": {code: "351", minLen: 9, maxLen: 9},
+	"IT": {code: "39", minLen: 6, maxLen: 11, keepsTrunk: true},
+	"PL": {code: "48", minLen: 9, maxLen: 9},
+	"SE": {code: "46", trunk: "0", minLen: 7, maxLen: 9},
+	"IN": {code: "91", trunk: "0", minLen: 10, maxLen: 10},
+	"AU": {code: "61", trunk: "0", minLen: 9, maxLen: 9},
+	"NZ": {code: "64", trunk: "0", minLen: 8, maxLen: 10},
+	"JP": {code: "81", trunk: "0", minLen: 9, maxLen: 10},
+	"CN": {code: "86", trunk: "0", minLen: 10, maxLen: 11},
+	"BR": {code: "55", trunk: "0", minLen: 10, maxLen: 11},
+	"MX": {code: "52", minLen: 10, maxLen: 10},
+	"ZA": {code: "27", trunk: "0", minLen: 9, maxLen: 9},
+	"SG": {code: "65", minLen: 8, maxLen: 8},
+}
+
+var phoneExtension = regexp.MustCompile(`(?i)\s*(?:ext\.?|extension|x|#)\s*\d+\s*$`)
+
+// ParsePhone returns the E.164 form of a phone number and the region it
+// belongs to. Numbers without an international prefix are read as national
+// numbers of region. ok is false for text that is not a valid number.
+func ParsePhone(raw, region string) (e164, numberRegion string, ok bool) {
+	region = strings.ToUpper(region)
+	raw = phoneExtension.ReplaceAllString(strings.TrimSpace(raw), "")
+	international := strings.HasPrefix(raw, "+")
+	var digits strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" -.()/ +", r):
+		default:
+			return "", "", false
+		}
+	}
+	number := digits.String()
+	if !international && strings.HasPrefix(number, "00") {
+		international, number = true, number[2:]
+	}
+
+	if international {
+		// Calling codes are prefix-free: at most one matches.
+		for r, plan := range phoneRegions {
+			if !strings.HasPrefix(number, plan.code) {
+				continue
+			}
+			national := number[len(plan.code):]
+			if len(national) >= plan.minLen && len(national) <= plan.maxLen {
+				switch {
+				case plan.code == phoneRegions[region].code:
+					r = region // +1 is both US and CA
+				case plan.code == "1":
+					r = "US"
+				}
+				return "+" + number, r, true
+			}
+		}
+		if len(number) >= 8 && len(number) <= 15 {
+			return "+" + number, "", true
+		}
+		return "", "", false
+	}
+
+	plan, known := phoneRegions[region]
+	if !known {
+		return "", "", false
+	}
+	if plan.trunk != "" && !plan.keepsTrunk && strings.HasPrefix(number, plan.trunk) &&
+		len(number)-len(plan.trunk) >= plan.minLen {
+		number = number[len(plan.trunk):]
+	}
+	if len(number) < plan.minLen || len(number) > plan.maxLen {
+		return "", "", false
+	}
+	return "+" + plan.code + number, region, true
+}
+
+// PhoneParser writes the E.164 form of phone number fields to
+// field+"_e164" and their region to field+"_region".
+type PhoneParser struct {
+	fields []string
+	region string
+}
+
+// NewPhoneParser creates a phone parser reading national numbers as
+// region's (an ISO 3166 code such as "US").
+func NewPhoneParser(fields []string, region string) *PhoneParser {
+	return &PhoneParser{fields: fields, region: strings.ToUpper(region)}
+}
+
+func (p *PhoneParser) Name() string { return "phone_parse" }
+
+func (p *PhoneParser) Process(item *types.Item) (*types.Item, error) {
+	for _, field := range p.fields {
+		e164, region, ok := ParsePhone(item.GetString(field), p.region)
+		if !ok {
+			continue
+		}
+		item.Set(field+"_e164", e164)
+		if region != "" {
+			item.Set(field+"_region", region)
+		}
+	}
+	return item, nil
+}
+
+// PhoneRegionKnown reports whether region has a numbering plan.
+func PhoneRegionKnown(region string) bool {
+	_, ok := phoneRegions[strings.ToUpper(region)]
+	return ok
+}
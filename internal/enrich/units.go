@@ -0,0 +1,139 @@
+package enrich
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// unit is a unit of measurement as a linear map to its dimension's base
+// unit: base = value*factor + offset.
+type unit struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+// units maps lowercased unit spellings to units. Base units are metres,
+// kilograms, litres and degrees Celsius. "in" and "k" are left out: "5 in
+// stock" and "100k" are far more common than inches and kelvins.
+var units = map[string]unit{
+	"mm": {"length", 0.001, 0}, "cm": {"length", 0.01, 0}, "m": {"length", 1, 0}, "km": {"length", 1000, 0},
+	"inch": {"length", 0.0254, 0}, "inches": {"length", 0.0254, 0}, `"`: {"length", 0.0254, 0},
+	"ft": {"length", 0.3048, 0}, "foot": {"length", 0.3048, 0}, "feet": {"length", 0.3048, 0},
+	"yd": {"length", 0.9144, 0}, "mi": {"length", 1609.344, 0}, "mile": {"length", 1609.344, 0}, "miles": {"length", 1609.344, 0},
+
+	"mg": {"mass", 1e-6, 0}, "g": {"mass", 0.001, 0}, "kg": {"mass", 1, 0}, "t": {"mass", 1000, 0},
+	"oz": {"mass", 0.028349523125, 0}, "lb": {"mass", 0.45359237, 0}, "lbs": {"mass", 0.45359237, 0},
+
+	"ml": {"volume", 0.001, 0}, "cl": {"volume", 0.01, 0}, "l": {"volume", 1, 0},
+	"fl oz": {"volume", 0.0295735295625, 0}, "cup": {"volume", 0.2365882365, 0}, "cups": {"volume", 0.2365882365, 0},
+	"pt": {"volume", 0.473176473, 0}, "qt": {"volume", 0.946352946, 0}, "gal": {"volume", 3.785411784, 0},
+
+	"°c": {"temperature", 1, 0}, "c": {"temperature", 1, 0}, "°f": {"temperature", 5.0 / 9, -160.0 / 9},
+	"f": {"temperature", 5.0 / 9, -160.0 / 9}, "kelvin": {"temperature", 1, -273.15},
+}
+
+// unitSystems are the default target units per dimension.
+var unitSystems = map[string]map[string]string{
+	"metric":   {"length": "m", "mass": "kg", "volume": "l", "temperature": "°c"},
+	"imperial": {"length": "inch", "mass": "lb", "volume": "gal", "temperature": "°f"},
+}
+
+var quantityRe = regexp.MustCompile(`(?i)(-?\d+(?:[.,]\d+)*)\s*(fl\.? oz|°\s?[cf]|[a-z]+|")`)
+
+// ParseQuantity reads the first "number unit" in text, such as "1.5 kg" or
+// "70°F". Both decimal points and decimal commas are read; a comma before
+// exactly three digits is a thousands separator.
+func ParseQuantity(text string) (value float64, unitName string, ok bool) {
+	for _, m := range quantityRe.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(m[2], " ", ""), ".", ""))
+		name = strings.Replace(name, "floz", "fl oz", 1)
+		if _, known := units[name]; !known {
+			continue
+		}
+		v, err := parseNumber(m[1])
+		if err != nil {
+			continue
+		}
+		return v, name, true
+	}
+	return 0, "", false
+}
+
+func parseNumber(s string) (float64, error) {
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 && s[i] == ',' && len(s)-i-1 != 3 {
+		// Decimal comma: any dots are thousands separators.
+		s = strings.ReplaceAll(s[:i], ".", "") + "." + s[i+1:]
+	}
+	return strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+}
+
+// ConvertUnit converts value between units of the same dimension.
+func ConvertUnit(value float64, from, to string) (float64, error) {
+	f, ok := units[strings.ToLower(from)]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	t, ok := units[strings.ToLower(to)]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if f.dimension != t.dimension {
+		return 0, fmt.Errorf("cannot convert %s (%s) to %s (%s)", from, f.dimension, to, t.dimension)
+	}
+	return (value*f.factor + f.offset - t.offset) / t.factor, nil
+}
+
+// UnitConverter reads quantities such as "12 oz" from fields and writes
+// them in the target unit to field+"_value" and field+"_unit".
+type UnitConverter struct {
+	fields  []string
+	targets map[string]string // dimension -> unit
+}
+
+// NewUnitConverter creates a converter to system's units ("metric" or
+// "imperial"), with targets overriding the unit of a dimension ("length",
+// "mass", "volume" or "temperature").
+func NewUnitConverter(fields []string, system string, targets map[string]string) (*UnitConverter, error) {
+	base, ok := unitSystems[system]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit system %q (valid: metric, imperial)", system)
+	}
+	merged := make(map[string]string, len(base))
+	for dim, u := range base {
+		merged[dim] = u
+	}
+	for dim, u := range targets {
+		dim, u = strings.ToLower(dim), strings.ToLower(u)
+		target, known := units[u]
+		if !known || target.dimension != dim {
+			return nil, fmt.Errorf("%q is not a %s unit", u, dim)
+		}
+		merged[dim] = u
+	}
+	return &UnitConverter{fields: fields, targets: merged}, nil
+}
+
+func (c *UnitConverter) Name() string { return "unit_convert" }
+
+func (c *UnitConverter) Process(item *types.Item) (*types.Item, error) {
+	for _, field := range c.fields {
+		value, from, ok := ParseQuantity(item.GetString(field))
+		if !ok {
+			continue
+		}
+		to := c.targets[units[from].dimension]
+		converted, err := ConvertUnit(value, from, to)
+		if err != nil {
+			continue
+		}
+		item.Set(field+"_value", math.Round(converted*1000)/1000)
+		item.Set(field+"_unit", to)
+	}
+	return item, nil
+}
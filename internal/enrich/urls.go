@@ -0,0 +1,185 @@
+package enrich
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// trackingParams are query parameters that identify a campaign or click
+// rather than a resource.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true,
+	"mc_cid": true, "mc_eid": true, "igshid": true, "ref_src": true, "_hsenc": true, "_hsmi": true,
+}
+
+// DefaultShorteners are the URL shortener hosts unshortened by default.
+var DefaultShorteners = []string{
+	"bit.ly", "t.co", "tinyurl.com", "goo.gl", "ow.ly", "buff.ly", "is.gd",
+	"lnkd.in", "rebrand.ly", "cutt.ly", "shorturl.at", "t.ly", "tiny.cc", "trib.al",
+}
+
+// URLNormalizer normalises link fields: relative links are resolved against
+// the item URL, tracking parameters are removed and the result is passed
+// through types.CanonicalizeURL. Fields may hold a string or a list.
+//
+// Unshortening is the one step that is not offline: links on shortener
+// hosts are resolved with HEAD requests, following redirects only while
+// they lead to other shorteners. ProcessContext bounds those requests by
+// its context.
+type URLNormalizer struct {
+	fields       []string
+	keepTracking bool
+	unshorten    bool
+	shorteners   map[string]bool
+	client       *http.Client
+	logger       *slog.Logger
+	mu           sync.Mutex
+	resolved     map[string]string
+}
+
+// NewURLNormalizer creates a URL normaliser. With unshorten, links on the
+// shorteners hosts are resolved within timeout each.
+func NewURLNormalizer(fields []string, keepTracking, unshorten bool, shorteners []string, timeout time.Duration, logger *slog.Logger) *URLNormalizer {
+	n := &URLNormalizer{
+		fields:       fields,
+		keepTracking: keepTracking,
+		unshorten:    unshorten,
+		shorteners:   make(map[string]bool, len(shorteners)),
+		logger:       logger.With("component", "url_normalize"),
+		resolved:     make(map[string]string),
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	for _, h := range shorteners {
+		n.shorteners[strings.ToLower(h)] = true
+	}
+	return n
+}
+
+func (n *URLNormalizer) Name() string { return "url_normalize" }
+
+func (n *URLNormalizer) Process(item *types.Item) (*types.Item, error) {
+	return n.ProcessContext(context.Background(), item)
+}
+
+// ProcessContext is Process with unshortening requests bound to ctx. The
+// item fails with ctx's error if it is done before the fields are.
+func (n *URLNormalizer) ProcessContext(ctx context.Context, item *types.Item) (*types.Item, error) {
+	base, _ := url.Parse(item.URL)
+	for _, field := range n.fields {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		switch v := item.Fields[field].(type) {
+		case string:
+			item.Set(field, n.normalize(ctx, base, v))
+		case []string:
+			out := make([]string, len(v))
+			for i, s := range v {
+				out[i] = n.normalize(ctx, base, s)
+			}
+			item.Set(field, out)
+		case []any:
+			out := make([]any, len(v))
+			for i, s := range v {
+				if str, ok := s.(string); ok {
+					out[i] = n.normalize(ctx, base, str)
+				} else {
+					out[i] = s
+				}
+			}
+			item.Set(field, out)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Normalize returns the normalised form of raw, resolved against base
+// (which may be nil). Values that are not http(s) links are returned as is.
+func (n *URLNormalizer) Normalize(base *url.URL, raw string) string {
+	return n.normalize(context.Background(), base, raw)
+}
+
+func (n *URLNormalizer) normalize(ctx context.Context, base *url.URL, raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return raw
+	}
+	if n.unshorten && n.shorteners[strings.ToLower(u.Hostname())] {
+		if target, err := url.Parse(n.expand(ctx, u.String())); err == nil {
+			u = target
+		}
+	}
+	if !n.keepTracking && u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if trackingParams[strings.ToLower(k)] || strings.HasPrefix(strings.ToLower(k), "utm_") {
+				q.Del(k)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return types.CanonicalizeURL(u.String())
+}
+
+// expand follows a short link's redirects, at most five, caching results.
+// A lookup cut short by ctx is not cached.
+func (n *URLNormalizer) expand(ctx context.Context, short string) string {
+	n.mu.Lock()
+	if target, ok := n.resolved[short]; ok {
+		n.mu.Unlock()
+		return target
+	}
+	n.mu.Unlock()
+
+	target := short
+	for range 5 {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+		if err != nil {
+			break
+		}
+		resp, err := n.client.Do(req)
+		if err != nil {
+			n.logger.Debug("unshorten failed", "url", short, "error", err)
+			break
+		}
+		resp.Body.Close()
+		loc, err := resp.Location()
+		if err != nil {
+			break // no redirect: target is final
+		}
+		target = loc.String()
+		if !n.shorteners[strings.ToLower(loc.Hostname())] {
+			break
+		}
+	}
+
+	if ctx.Err() != nil {
+		return target
+	}
+	n.mu.Lock()
+	n.resolved[short] = target
+	n.mu.Unlock()
+	return target
+}
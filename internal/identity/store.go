@@ -0,0 +1,159 @@
+// Package identity remembers items across crawls, so that recrawls can tell
+// new items from changed and unchanged ones.
+package identity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Status is an item's state relative to the previous crawls.
+type Status string
+
+const (
+	StatusNew       Status = "new"       // key never seen before
+	StatusChanged   Status = "changed"   // key seen, content differs
+	StatusUnchanged Status = "unchanged" // key seen with the same content
+)
+
+var itemsBucket = []byte("items")
+
+// Record is what the store keeps for each key.
+type Record struct {
+	Hash        string    `json:"hash"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	LastChanged time.Time `json:"last_changed"`
+}
+
+// Store is a persistent key → content hash map in a bbolt file.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the store at path, creating it if needed. Only one process can
+// hold a store open at a time.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create identity store dir: %w", err)
+	}
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open identity store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(itemsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init identity store: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Check returns how hash compares to the content committed for key. It does
+// not change the store: items are recorded with Commit once they have been
+// stored, so an item lost later in the run is not taken as seen.
+func (s *Store) Check(key, hash string) (Status, error) {
+	rec, found, err := s.Get(key)
+	switch {
+	case err != nil:
+		return "", fmt.Errorf("decode record %q: %w", key, err)
+	case !found:
+		return StatusNew, nil
+	case rec.Hash == hash:
+		return StatusUnchanged, nil
+	}
+	return StatusChanged, nil
+}
+
+// Entry is a key and its content hash, as passed to Commit.
+type Entry struct {
+	Key  string
+	Hash string
+}
+
+// Commit records entries as seen now, in a single transaction.
+func (s *Store) Commit(entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(itemsBucket)
+		for _, e := range entries {
+			rec := Record{Hash: e.Hash, FirstSeen: now, LastSeen: now, LastChanged: now}
+			if data := b.Get([]byte(e.Key)); data != nil {
+				var prev Record
+				if err := json.Unmarshal(data, &prev); err != nil {
+					return fmt.Errorf("decode record %q: %w", e.Key, err)
+				}
+				rec.FirstSeen = prev.FirstSeen
+				if prev.Hash == e.Hash {
+					rec.LastChanged = prev.LastChanged
+				}
+			}
+			data, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(e.Key), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the record for key.
+func (s *Store) Get(key string) (Record, bool, error) {
+	var rec Record
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(itemsBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &rec)
+	})
+	return rec, found, err
+}
+
+// Len returns the number of keys in the store.
+func (s *Store) Len() int {
+	n := 0
+	_ = s.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(itemsBucket).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// Close closes the store file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Hash returns a content hash of fields. Fields whose names start with an
+// underscore are item metadata, such as the status field, and are ignored.
+func Hash(fields map[string]any) string {
+	content := make(map[string]any, len(fields))
+	for k, v := range fields {
+		if !strings.HasPrefix(k, "_") {
+			content[k] = v
+		}
+	}
+	data, _ := json.Marshal(content) // map keys are sorted
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
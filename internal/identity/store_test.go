@@ -0,0 +1,97 @@
+package identity
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreCheckAndCommit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "identity.db")
+
+	// Each run reopens the store, checks its items, then commits those
+	// listed in commit, as the pipeline does after storage accepts them.
+	runs := []struct {
+		name   string
+		check  []Entry
+		want   []Status
+		commit []Entry
+	}{
+		{
+			name:   "empty store",
+			check:  []Entry{{"a", "h1"}, {"b", "h2"}},
+			want:   []Status{StatusNew, StatusNew},
+			commit: []Entry{{"a", "h1"}, {"b", "h2"}},
+		},
+		{
+			name:   "recrawl",
+			check:  []Entry{{"a", "h1"}, {"b", "h3"}, {"c", "h4"}},
+			want:   []Status{StatusUnchanged, StatusChanged, StatusNew},
+			commit: nil, // storage failed: nothing is recorded
+		},
+		{
+			name:   "after a failed run",
+			check:  []Entry{{"b", "h3"}, {"c", "h4"}},
+			want:   []Status{StatusChanged, StatusNew},
+			commit: []Entry{{"b", "h3"}, {"c", "h4"}},
+		},
+		{
+			name:  "committed",
+			check: []Entry{{"a", "h1"}, {"b", "h3"}, {"c", "h4"}},
+			want:  []Status{StatusUnchanged, StatusUnchanged, StatusUnchanged},
+		},
+	}
+	for _, run := range runs {
+		s, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, e := range run.check {
+			got, err := s.Check(e.Key, e.Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != run.want[i] {
+				t.Errorf("%s: Check(%s) = %s, want %s", run.name, e.Key, got, run.want[i])
+			}
+		}
+		if err := s.Commit(run.commit); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", s.Len())
+	}
+	a, _, _ := s.Get("a")
+	b, _, _ := s.Get("b")
+	if !a.LastChanged.Equal(a.FirstSeen) || !b.LastChanged.After(b.FirstSeen) {
+		t.Errorf("a = %+v, b = %+v: only b should have changed", a, b)
+	}
+}
+
+func TestHash(t *testing.T) {
+	base := map[string]any{"title": "Widget", "price": 10}
+	tests := []struct {
+		name   string
+		fields map[string]any
+		same   bool
+	}{
+		{"same content", map[string]any{"price": 10, "title": "Widget"}, true},
+		{"metadata ignored", map[string]any{"title": "Widget", "price": 10, "_item_status": "new", "_url": "x"}, true},
+		{"value changed", map[string]any{"title": "Widget", "price": 11}, false},
+		{"field added", map[string]any{"title": "Widget", "price": 10, "stock": true}, false},
+	}
+	for _, tt := range tests {
+		if got := Hash(tt.fields) == Hash(base); got != tt.same {
+			t.Errorf("%s: equal hash = %v, want %v", tt.name, got, tt.same)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package neardup finds near-duplicate texts with 64-bit SimHash
+// fingerprints, such as one article served under several URLs.
+package neardup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// shingleSize is the number of words hashed together. Word trigrams make
+// the fingerprint sensitive to word order, not just vocabulary.
+const shingleSize = 3
+
+// Words splits text into lowercased words.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// SimHash returns the SimHash of words. Texts differing in a few words get
+// fingerprints a few bits apart.
+func SimHash(words []string) uint64 {
+	var weights [64]int
+	add := func(shingle string) {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		sum := h.Sum64()
+		for i := range weights {
+			if sum&(1<<i) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	if len(words) < shingleSize {
+		add(strings.Join(words, " "))
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		add(strings.Join(words[i:i+shingleSize], " "))
+	}
+
+	var fp uint64
+	for i, w := range weights {
+		if w > 0 {
+			fp |= 1 << i
+		}
+	}
+	return fp
+}
+
+// Distance is the number of bits in which two fingerprints differ.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// --- Index ---
+
+// Index finds fingerprints within a Hamming distance of those added before.
+// Fingerprints are split into maxDistance+1 bands: two fingerprints at most
+// maxDistance bits apart agree on at least one whole band, so only those
+// sharing a band need comparing.
+type Index struct {
+	maxDistance int
+	widths      []int
+	mu          sync.Mutex
+	bands       []map[uint64][]entry
+	size        int
+}
+
+type entry struct {
+	fp uint64
+	id string
+}
+
+// NewIndex creates an index matching fingerprints at most maxDistance bits
+// apart (clamped to 0–15).
+func NewIndex(maxDistance int) *Index {
+	maxDistance = min(max(maxDistance, 0), 15)
+	n := maxDistance + 1
+	ix := &Index{maxDistance: maxDistance, widths: make([]int, n), bands: make([]map[uint64][]entry, n)}
+	for i := range n {
+		ix.widths[i] = 64 / n
+		if i < 64%n {
+			ix.widths[i]++
+		}
+		ix.bands[i] = make(map[uint64][]entry)
+	}
+	return ix
+}
+
+// band returns the value of band i of fp.
+func (ix *Index) band(fp uint64, i int) uint64 {
+	shift := 0
+	for _, w := range ix.widths[:i] {
+		shift += w
+	}
+	return (fp >> shift) & (1<<ix.widths[i] - 1)
+}
+
+// Check returns the id of the closest earlier fingerprint within the
+// index's distance of fp, and its distance. If there is none, fp is added
+// under id and ok is false.
+func (ix *Index) Check(fp uint64, id string) (match string, distance int, ok bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	distance = ix.maxDistance + 1
+	for i := range ix.bands {
+		for _, e := range ix.bands[i][ix.band(fp, i)] {
+			if d := Distance(fp, e.fp); d < distance {
+				match, distance, ok = e.id, d, true
+			}
+		}
+	}
+	if ok {
+		return match, distance, true
+	}
+	for i := range ix.bands {
+		b := ix.band(fp, i)
+		ix.bands[i][b] = append(ix.bands[i][b], entry{fp: fp, id: id})
+	}
+	ix.size++
+	return "", 0, false
+}
+
+// Len returns the number of fingerprints in the index.
+func (ix *Index) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.size
+}
+
+// --- Frontier Hints ---
+
+// Hints tracks the share of near-duplicates each site section (host and
+// first path segment, e.g. example.com/print) produces, so the crawler can
+// deprioritise sections that mostly repeat content found elsewhere.
+type Hints struct {
+	minSamples int
+	ratio      float64
+	mu         sync.Mutex
+	sections   map[string]*sectionCounts
+}
+
+type sectionCounts struct {
+	items, duplicates int
+}
+
+// NewHints creates hints that flag a section once it has produced at least
+// minSamples items, of which at least ratio were duplicates.
+func NewHints(minSamples int, ratio float64) *Hints {
+	return &Hints{minSamples: minSamples, ratio: ratio, sections: make(map[string]*sectionCounts)}
+}
+
+// Record counts an item from rawURL.
+func (h *Hints) Record(rawURL string, duplicate bool) {
+	key := section(rawURL)
+	if key == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := h.sections[key]
+	if c == nil {
+		c = &sectionCounts{}
+		h.sections[key] = c
+	}
+	c.items++
+	if duplicate {
+		c.duplicates++
+	}
+}
+
+// Ratio returns the duplicate share of rawURL's section and the number of
+// items it is based on.
+func (h *Hints) Ratio(rawURL string) (float64, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := h.sections[section(rawURL)]
+	if c == nil || c.items == 0 {
+		return 0, 0
+	}
+	return float64(c.duplicates) / float64(c.items), c.items
+}
+
+// Prioritize moves requests into flagged sections to the lowest priority.
+func (h *Hints) Prioritize(rawURL string, priority int) int {
+	if ratio, n := h.Ratio(rawURL); n >= h.minSamples && ratio >= h.ratio {
+		return types.PriorityLowest
+	}
+	return priority
+}
+
+func section(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	// Single-segment paths are pages of the site root, not sections.
+	first, _, nested := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if !nested {
+		first = ""
+	}
+	return strings.ToLower(u.Host) + "/" + first
+}
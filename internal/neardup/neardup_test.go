@@ -0,0 +1,103 @@
+package neardup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+const (
+	article = "The city council voted on Tuesday to expand the riverside park, adding two new playgrounds, " +
+		"a community garden and a cycling path that will connect the old harbour to the train station. " +
+		"Construction is expected to begin next spring and finish within eighteen months."
+	other = "Researchers at the university have published a study on the migration of songbirds, showing " +
+		"that warmer autumns delay departure by up to two weeks and change the routes the birds follow south."
+)
+
+func TestSimHashDistance(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		min, max int
+	}{
+		{"identical", article, article, 0, 0},
+		{"case and punctuation", article, strings.ToUpper(strings.ReplaceAll(article, ",", "")), 0, 0},
+		{"footer added", article, article + " Share this story.", 0, 3},
+		{"one word changed", article, strings.Replace(article, "Tuesday", "Monday", 1), 1, 12},
+		{"unrelated", article, other, 16, 64},
+		{"word order", "one two three four five", "five four three two one", 8, 64},
+	}
+	for _, tt := range tests {
+		d := Distance(SimHash(Words(tt.a)), SimHash(Words(tt.b)))
+		if d < tt.min || d > tt.max {
+			t.Errorf("%s: distance = %d, want %d–%d", tt.name, d, tt.min, tt.max)
+		}
+	}
+}
+
+func TestIndexCheck(t *testing.T) {
+	ix := NewIndex(3)
+	base := SimHash(Words(article))
+	steps := []struct {
+		id        string
+		fp        uint64
+		match     string
+		distance  int
+		duplicate bool
+	}{
+		{"a", base, "", 0, false},
+		{"b", base ^ 0b101, "a", 2, true},
+		{"c", base ^ 0b1111, "", 0, false}, // 4 bits from a: added
+		{"d", base ^ 0b1110, "c", 1, true}, // closer to c than to a
+		{"e", ^base, "", 0, false},
+	}
+	for _, s := range steps {
+		match, distance, ok := ix.Check(s.fp, s.id)
+		if match != s.match || distance != s.distance || ok != s.duplicate {
+			t.Errorf("Check(%s) = %q, %d, %v; want %q, %d, %v", s.id, match, distance, ok, s.match, s.distance, s.duplicate)
+		}
+	}
+	if ix.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", ix.Len())
+	}
+}
+
+func TestHintsPrioritize(t *testing.T) {
+	h := NewHints(3, 0.5)
+	for _, r := range []struct {
+		url       string
+		duplicate bool
+	}{
+		{"https://news.example.com/print/a", true},
+		{"https://news.example.com/print/b", true},
+		{"https://news.example.com/print/c", false},
+		{"https://news.example.com/2024/a", false},
+		{"https://news.example.com/2024/b", true},
+		{"https://news.example.com/2024/c", false},
+		{"https://news.example.com/amp/a", true},
+		{"https://news.example.com/about", true},
+		{"https://news.example.com/contact", true},
+		{"https://news.example.com/faq", true},
+	} {
+		h.Record(r.url, r.duplicate)
+	}
+
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"https://news.example.com/print/new", types.PriorityLowest},
+		{"https://NEWS.example.com/print/x?y=1", types.PriorityLowest},
+		{"https://news.example.com/2024/new", types.PriorityNormal}, // 1 in 3
+		{"https://news.example.com/amp/new", types.PriorityNormal},  // too few samples
+		{"https://news.example.com/jobs", types.PriorityLowest},     // root pages share a section
+		{"https://other.example.com/print/new", types.PriorityNormal},
+		{"not a url", types.PriorityNormal},
+	}
+	for _, tt := range tests {
+		if got := h.Prioritize(tt.url, types.PriorityNormal); got != tt.want {
+			t.Errorf("Prioritize(%s) = %d, want %d", tt.url, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 )
 
@@ -35,9 +38,29 @@ type Metrics struct {
 	ProxyRotations atomic.Int64
 	ProxyErrors    atomic.Int64
 
+	collectorsMu sync.RWMutex
+	collectors   []func() []Sample
+
 	logger *slog.Logger
 }
 
+// Sample is one gauge value reported by a collector, such as the queue
+// length of a pipeline stage.
+type Sample struct {
+	Name   string
+	Help   string
+	Labels map[string]string
+	Value  float64
+}
+
+// AddCollector registers a function whose samples are served as gauges
+// alongside the built-in metrics.
+func (m *Metrics) AddCollector(fn func() []Sample) {
+	m.collectorsMu.Lock()
+	defer m.collectorsMu.Unlock()
+	m.collectors = append(m.collectors, fn)
+}
+
 // NewMetrics creates a new Metrics instance.
 func NewMetrics(logger *slog.Logger) *Metrics {
 	return &Metrics{
@@ -77,6 +100,37 @@ func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "# TYPE %s counter\n", metric.name)
 		fmt.Fprintf(w, "%s %d\n", metric.name, metric.value)
 	}
+
+	m.collectorsMu.RLock()
+	collectors := m.collectors
+	m.collectorsMu.RUnlock()
+	described := make(map[string]bool)
+	for _, collect := range collectors {
+		for _, sample := range collect() {
+			if !described[sample.Name] {
+				fmt.Fprintf(w, "# HELP %s %s\n", sample.Name, sample.Help)
+				fmt.Fprintf(w, "# TYPE %s gauge\n", sample.Name)
+				described[sample.Name] = true
+			}
+			fmt.Fprintf(w, "%s%s %g\n", sample.Name, formatLabels(sample.Labels), sample.Value)
+		}
+	}
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%q", k, labels[k])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
 }
 
 // StartServer starts the metrics HTTP server.
@@ -14,6 +14,15 @@ type CompositeParser struct {
 	regex      *RegexParser
 	xpath      *XPathParser
 	structured *StructuredDataExtractor
+	article    *ReadabilityParser
+	markdown   *MarkdownParser
+	scriptData *ScriptDataParser
+	tables     *TableParser
+	feed       *FeedParser
+	document   *DocumentParser
+	records    *RecordDetector
+	autoDetect bool
+	schemaOrg  bool
 	logger     *slog.Logger
 }
 
@@ -24,12 +33,50 @@ func NewCompositeParser(logger *slog.Logger) *CompositeParser {
 		regex:      NewRegexParser(logger),
 		xpath:      NewXPathParser(logger),
 		structured: NewStructuredDataExtractor(logger),
+		article:    NewReadabilityParser(logger),
+		markdown:   NewMarkdownParser(logger),
+		scriptData: NewScriptDataParser(logger),
+		tables:     NewTableParser(logger),
+		feed:       NewFeedParser(logger),
+		document:   NewDocumentParser(logger),
+		records:    NewRecordDetector(logger),
 		logger:     logger.With("component", "composite_parser"),
 	}
 }
 
+// SetFeedState enables new-entry tracking for feed responses.
+func (p *CompositeParser) SetFeedState(state *FeedState) {
+	p.feed.SetState(state)
+}
+
+// SetAutoDetect enables record detection on pages parsed without rules.
+// Each detected record becomes its own item.
+func (p *CompositeParser) SetAutoDetect(enabled bool) {
+	p.autoDetect = enabled
+}
+
+// SetSchemaOrg enables typed schema.org entity items. Each entity found in
+// the page's structured data becomes its own item next to the page item.
+func (p *CompositeParser) SetSchemaOrg(enabled bool) {
+	p.schemaOrg = enabled
+}
+
+// SetSelfHealing lets CSS and XPath rules marked self_heal recover when a
+// site's markup changes, using tracker for snapshots and repairs.
+func (p *CompositeParser) SetSelfHealing(tracker *SmartTracker) {
+	p.css.SetTracker(tracker)
+	p.xpath.SetTracker(tracker)
+}
+
 // Parse implements Parser by delegating to sub-parsers.
 func (p *CompositeParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	if IsDocument(resp) {
+		return p.parseDocument(resp, rules)
+	}
+	if IsFeed(resp) {
+		return p.parseFeed(resp, rules)
+	}
+
 	var allItems []*types.Item
 	var allLinks []string
 
@@ -37,6 +84,10 @@ func (p *CompositeParser) Parse(resp *types.Response, rules []config.ParseRule)
 	var cssRules []config.ParseRule
 	var regexRules []config.ParseRule
 	var xpathRules []config.ParseRule
+	var articleRules []config.ParseRule
+	var markdownRules []config.ParseRule
+	var scriptRules []config.ParseRule
+	var tableRules []config.ParseRule
 
 	for _, rule := range rules {
 		switch rule.Type {
@@ -44,6 +95,14 @@ func (p *CompositeParser) Parse(resp *types.Response, rules []config.ParseRule)
 			regexRules = append(regexRules, rule)
 		case "xpath":
 			xpathRules = append(xpathRules, rule)
+		case "readability":
+			articleRules = append(articleRules, rule)
+		case "markdown":
+			markdownRules = append(markdownRules, rule)
+		case "jsdata":
+			scriptRules = append(scriptRules, rule)
+		case "table":
+			tableRules = append(tableRules, rule)
 		default: // "css" or empty defaults to CSS
 			cssRules = append(cssRules, rule)
 		}
@@ -75,6 +134,33 @@ func (p *CompositeParser) Parse(resp *types.Response, rules []config.ParseRule)
 		allItems = append(allItems, xpathItems...)
 	}
 
+	// Main-content extraction
+	if len(articleRules) > 0 {
+		articleItems, _, err := p.article.Parse(resp, articleRules)
+		if err != nil {
+			p.logger.Warn("readability parser error", "error", err)
+		}
+		allItems = append(allItems, articleItems...)
+	}
+
+	// Markdown conversion
+	if len(markdownRules) > 0 {
+		mdItems, _, err := p.markdown.Parse(resp, markdownRules)
+		if err != nil {
+			p.logger.Warn("markdown parser error", "error", err)
+		}
+		allItems = append(allItems, mdItems...)
+	}
+
+	// Embedded script data (__NEXT_DATA__, __NUXT__, ...)
+	if len(scriptRules) > 0 {
+		scriptItems, _, err := p.scriptData.Parse(resp, scriptRules)
+		if err != nil {
+			p.logger.Warn("script data parser error", "error", err)
+		}
+		allItems = append(allItems, scriptItems...)
+	}
+
 	// Auto-extract structured data (JSON-LD, OpenGraph, etc.)
 	sdResults, err := p.structured.Extract(resp)
 	if err != nil {
@@ -95,5 +181,92 @@ func (p *CompositeParser) Parse(resp *types.Response, rules []config.ParseRule)
 		allItems = []*types.Item{merged}
 	}
 
+	// One item per table row
+	if len(tableRules) > 0 {
+		tableItems, _, err := p.tables.Parse(resp, tableRules)
+		if err != nil {
+			p.logger.Warn("table parser error", "error", err)
+		}
+		allItems = append(allItems, tableItems...)
+	}
+
+	// One item per schema.org entity
+	if p.schemaOrg {
+		entities, err := p.structured.ExtractEntities(resp)
+		if err != nil {
+			p.logger.Warn("schema.org extraction error", "error", err)
+		}
+		allItems = append(allItems, EntitiesToItems(entities, resp.Request.URLString())...)
+	}
+
+	// Detect repeated records when no rules are configured
+	if p.autoDetect && len(rules) == 0 {
+		rs, err := p.records.Detect(resp)
+		if err != nil {
+			p.logger.Warn("record detection error", "error", err)
+		}
+		if rs != nil {
+			allItems = append(allItems, rs.Items...)
+		}
+	}
+
 	return allItems, allLinks, nil
 }
+
+// parseFeed handles RSS/Atom/JSON feed responses. Each entry is its own item,
+// so feed items are not merged; XPath rules run over the feed XML as a whole.
+func (p *CompositeParser) parseFeed(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	items, links, err := p.feed.Parse(resp, rules)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var xpathRules []config.ParseRule
+	for _, rule := range rules {
+		if rule.Type == "xpath" {
+			xpathRules = append(xpathRules, rule)
+		}
+	}
+	if len(xpathRules) > 0 && IsXML(resp) {
+		xpathItems, _, err := p.xpath.Parse(resp, xpathRules)
+		if err != nil {
+			p.logger.Warn("XPath parser error", "error", err)
+		}
+		items = append(items, xpathItems...)
+	}
+
+	return items, links, nil
+}
+
+// parseDocument handles PDF, DOCX, XLSX and CSV responses. Regex rules run
+// over the extracted text and their fields are merged into the document item.
+func (p *CompositeParser) parseDocument(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	items, _, err := p.document.Parse(resp, rules)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var regexRules []config.ParseRule
+	for _, rule := range rules {
+		if rule.Type == "regex" {
+			regexRules = append(regexRules, rule)
+		}
+	}
+	if len(regexRules) == 0 {
+		return items, nil, nil
+	}
+
+	textResp := *resp
+	textResp.Body = []byte(items[0].GetString("text"))
+	textResp.Doc = nil
+	regexItems, _, err := p.regex.Parse(&textResp, regexRules)
+	if err != nil {
+		p.logger.Warn("regex parser error", "error", err)
+	}
+	for _, item := range regexItems {
+		for k, v := range item.Fields {
+			items[0].Set(k, v)
+		}
+	}
+	return items, nil, nil
+}
@@ -0,0 +1,371 @@
+package parser
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// Fixture file naming. A fixture is a saved page (name.html, name.xml, ...)
+// or a cached response (name.response.json). Its expected items live in
+// name.golden.json next to it, and an optional name.url holds the page URL
+// the fixture was saved from, used to resolve relative links. The rule set
+// a fixture is parsed with is the response's rule_set, or for a saved page
+// the contents of an optional name.rule_set.
+const (
+	goldenSuffix   = ".golden.json"
+	responseSuffix = ".response.json"
+	urlSuffix      = ".url"
+	ruleSetSuffix  = ".rule_set"
+)
+
+// Fixture is a saved response used to check parse rules offline.
+type Fixture struct {
+	Name     string
+	Path     string
+	Golden   string // path of the expected-output file
+	RuleSet  string // rule set to parse with; empty for the default rules
+	Response *types.Response
+}
+
+// cachedResponse is the on-disk form of a saved response.
+type cachedResponse struct {
+	URL         string      `json:"url"`
+	StatusCode  int         `json:"status_code"`
+	ContentType string      `json:"content_type"`
+	Headers     http.Header `json:"headers,omitempty"`
+	Body        string      `json:"body"`
+	BodyBase64  string      `json:"body_base64,omitempty"` // for binary documents
+	RuleSet     string      `json:"rule_set,omitempty"`
+}
+
+// LoadFixtures loads every fixture under dir, sorted by name.
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	var fixtures []*Fixture
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") ||
+			strings.HasSuffix(path, goldenSuffix) || strings.HasSuffix(path, urlSuffix) ||
+			strings.HasSuffix(path, ruleSetSuffix) {
+			return nil
+		}
+		f, err := loadFixture(dir, path)
+		if err != nil {
+			return fmt.Errorf("load fixture %s: %w", path, err)
+		}
+		fixtures = append(fixtures, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Name < fixtures[j].Name })
+	return fixtures, nil
+}
+
+func loadFixture(dir, path string) (*Fixture, error) {
+	base := strings.TrimSuffix(path, responseSuffix)
+	if base == path {
+		base = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	name, _ := filepath.Rel(dir, base)
+	f := &Fixture{Name: filepath.ToSlash(name), Path: path, Golden: base + goldenSuffix}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := cachedResponse{
+		URL:         "https://fixtures.invalid/" + f.Name,
+		StatusCode:  http.StatusOK,
+		ContentType: mime.TypeByExtension(filepath.Ext(path)),
+		Body:        string(data),
+	}
+	if strings.HasSuffix(path, responseSuffix) {
+		if err := json.Unmarshal(data, &cached); err != nil {
+			return nil, fmt.Errorf("parse cached response: %w", err)
+		}
+		if cached.BodyBase64 != "" {
+			body, err := base64.StdEncoding.DecodeString(cached.BodyBase64)
+			if err != nil {
+				return nil, fmt.Errorf("decode body: %w", err)
+			}
+			cached.Body = string(body)
+		}
+	} else {
+		if u, err := os.ReadFile(base + urlSuffix); err == nil {
+			cached.URL = strings.TrimSpace(string(u))
+		}
+		if rs, err := os.ReadFile(base + ruleSetSuffix); err == nil {
+			cached.RuleSet = strings.TrimSpace(string(rs))
+		}
+	}
+	if cached.ContentType == "" {
+		cached.ContentType = "text/html"
+	}
+
+	req, err := types.NewRequest(cached.URL)
+	if err != nil {
+		return nil, err
+	}
+	if f.RuleSet = cached.RuleSet; f.RuleSet != "" {
+		req.Meta[types.MetaRuleSet] = f.RuleSet
+	}
+	f.Response = &types.Response{
+		StatusCode:    cached.StatusCode,
+		Headers:       cached.Headers,
+		Body:          []byte(cached.Body),
+		Request:       req,
+		ContentType:   cached.ContentType,
+		ContentLength: int64(len(cached.Body)),
+		FinalURL:      cached.URL,
+		Meta:          make(map[string]any),
+	}
+	return f, nil
+}
+
+// FieldDiff is one difference between the expected and produced items.
+// Field is empty when a whole item is missing or unexpected.
+type FieldDiff struct {
+	Item  int    `json:"item"`
+	Field string `json:"field,omitempty"`
+	Want  any    `json:"want,omitempty"`
+	Got   any    `json:"got,omitempty"`
+}
+
+func (d FieldDiff) String() string {
+	switch {
+	case d.Field == "" && d.Got == nil:
+		return fmt.Sprintf("item[%d]: missing item", d.Item)
+	case d.Field == "":
+		return fmt.Sprintf("item[%d]: unexpected item", d.Item)
+	case d.Got == nil:
+		return fmt.Sprintf("item[%d].%s: missing, want %s", d.Item, d.Field, compactJSON(d.Want))
+	case d.Want == nil:
+		return fmt.Sprintf("item[%d].%s: unexpected %s", d.Item, d.Field, compactJSON(d.Got))
+	}
+	return fmt.Sprintf("item[%d].%s: want %s, got %s", d.Item, d.Field, compactJSON(d.Want), compactJSON(d.Got))
+}
+
+// FixtureResult is the outcome of running the rules on one fixture.
+type FixtureResult struct {
+	Name     string      `json:"name"`
+	Items    int         `json:"items"`
+	Diffs    []FieldDiff `json:"diffs,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	NoGolden bool        `json:"no_golden,omitempty"` // no expected output to compare with
+	Updated  bool        `json:"updated,omitempty"`   // golden file (re)written
+}
+
+// Passed reports whether the fixture produced exactly the expected items.
+func (r *FixtureResult) Passed() bool {
+	return r.Error == "" && !r.NoGolden && len(r.Diffs) == 0
+}
+
+// RuleCoverage counts the fixtures in which a rule produced a value, out of
+// those parsed with its rule set.
+type RuleCoverage struct {
+	RuleSet string `json:"rule_set,omitempty"`
+	Rule    string `json:"rule"`
+	Matched int    `json:"matched"`
+	Total   int    `json:"total"`
+}
+
+// ConformanceReport is the outcome of a fixture run.
+type ConformanceReport struct {
+	Results  []*FixtureResult `json:"results"`
+	Coverage []RuleCoverage   `json:"coverage"`
+}
+
+// Failed returns the number of fixtures that did not pass.
+func (r *ConformanceReport) Failed() int {
+	n := 0
+	for _, res := range r.Results {
+		if !res.Updated && !res.Passed() {
+			n++
+		}
+	}
+	return n
+}
+
+// RunConformance parses each fixture with the rules of its rule set, looked
+// up case-insensitively in ruleSets, or with rules when it names none, and
+// compares the items with the fixture's golden file. A fixture naming an
+// unknown rule set is an error. With update set, golden files are written
+// from the produced items instead.
+func RunConformance(p Parser, rules []config.ParseRule, ruleSets map[string][]config.ParseRule, fixtures []*Fixture, update bool) *ConformanceReport {
+	report := &ConformanceReport{}
+	matched := make(map[string]map[string]int) // rule set -> rule -> fixtures
+	total := make(map[string]int)              // rule set -> fixtures
+
+	for _, f := range fixtures {
+		res := &FixtureResult{Name: f.Name}
+		report.Results = append(report.Results, res)
+
+		set := strings.ToLower(f.RuleSet)
+		rules := rules
+		if set != "" {
+			var ok bool
+			if rules, ok = ruleSets[set]; !ok {
+				res.Error = fmt.Sprintf("unknown rule set %q", f.RuleSet)
+				continue
+			}
+		}
+		total[set]++
+		if matched[set] == nil {
+			matched[set] = make(map[string]int)
+		}
+
+		items, _, err := p.Parse(f.Response, rules)
+		if err != nil {
+			res.Error = err.Error()
+			continue
+		}
+		got, err := normaliseItems(items)
+		if err != nil {
+			res.Error = err.Error()
+			continue
+		}
+		res.Items = len(got)
+
+		for _, rule := range rules {
+			for _, item := range got {
+				if v, ok := item[rule.Name]; ok && !isEmptyValue(v) {
+					matched[set][rule.Name]++
+					break
+				}
+			}
+		}
+
+		if update {
+			if err := writeGolden(f.Golden, got); err != nil {
+				res.Error = err.Error()
+			} else {
+				res.Updated = true
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(f.Golden)
+		if os.IsNotExist(err) {
+			res.NoGolden = true
+			continue
+		}
+		var want []map[string]any
+		if err == nil {
+			err = json.Unmarshal(data, &want)
+		}
+		if err != nil {
+			res.Error = fmt.Sprintf("read golden: %v", err)
+			continue
+		}
+		res.Diffs = diffItems(want, got)
+	}
+
+	// Default rules first, then each rule set some fixture used, by name.
+	sets := slices.Sorted(maps.Keys(total))
+	for _, set := range sets {
+		rules := rules
+		if set != "" {
+			rules = ruleSets[set]
+		}
+		for _, rule := range rules {
+			report.Coverage = append(report.Coverage, RuleCoverage{
+				RuleSet: set,
+				Rule:    rule.Name,
+				Matched: matched[set][rule.Name],
+				Total:   total[set],
+			})
+		}
+	}
+	return report
+}
+
+// normaliseItems converts item fields to their JSON form so produced values
+// compare equal to values read back from golden files.
+func normaliseItems(items []*types.Item) ([]map[string]any, error) {
+	out := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		data, err := json.Marshal(item.Fields)
+		if err != nil {
+			return nil, fmt.Errorf("encode item: %w", err)
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("decode item: %w", err)
+		}
+		out = append(out, fields)
+	}
+	return out, nil
+}
+
+func diffItems(want, got []map[string]any) []FieldDiff {
+	var diffs []FieldDiff
+	for i := 0; i < len(want) || i < len(got); i++ {
+		switch {
+		case i >= len(got):
+			diffs = append(diffs, FieldDiff{Item: i, Want: want[i]})
+			continue
+		case i >= len(want):
+			diffs = append(diffs, FieldDiff{Item: i, Got: got[i]})
+			continue
+		}
+
+		keys := make(map[string]bool)
+		for k := range want[i] {
+			keys[k] = true
+		}
+		for k := range got[i] {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			w, g := want[i][k], got[i][k]
+			if !reflect.DeepEqual(w, g) {
+				diffs = append(diffs, FieldDiff{Item: i, Field: k, Want: w, Got: g})
+			}
+		}
+	}
+	return diffs
+}
+
+func writeGolden(path string, items []map[string]any) error {
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write golden: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func compactJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return truncate(string(data), 120)
+}
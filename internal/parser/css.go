@@ -13,7 +13,8 @@ import (
 
 // CSSParser extracts data using CSS selectors via goquery.
 type CSSParser struct {
-	logger *slog.Logger
+	logger  *slog.Logger
+	tracker *SmartTracker
 }
 
 // NewCSSParser creates a new CSS selector parser.
@@ -23,6 +24,12 @@ func NewCSSParser(logger *slog.Logger) *CSSParser {
 	}
 }
 
+// SetTracker enables self-healing for rules with self_heal set: matched
+// elements are snapshotted, and rules that stop matching are relocated.
+func (p *CSSParser) SetTracker(tracker *SmartTracker) {
+	p.tracker = tracker
+}
+
 // Parse implements Parser.
 func (p *CSSParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
 	doc, err := resp.Document()
@@ -52,7 +59,26 @@ func (p *CSSParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*ty
 			continue // Skip non-CSS rules
 		}
 
-		values := p.extractCSS(doc, rule)
+		query, err := CompileSelector(rule.Selector)
+		if err != nil {
+			p.logger.Warn("invalid selector", "rule", rule.Name, "error", err)
+			continue
+		}
+
+		sel := query.Select(doc.Selection)
+		if p.tracker != nil && rule.SelfHeal {
+			if sel.Length() > 0 {
+				p.tracker.Observe(resp, rule, sel)
+			} else if healed, repair := p.tracker.Heal(resp, rule); repair != nil {
+				if repair.Flagged {
+					flagItem(item, repair)
+					continue
+				}
+				sel = healed
+			}
+		}
+
+		values := query.Values(sel, rule.Attribute)
 		if len(values) == 1 {
 			item.Set(rule.Name, values[0])
 		} else if len(values) > 1 {
@@ -67,14 +93,15 @@ func (p *CSSParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*ty
 	return items, links, nil
 }
 
-// extractCSS applies a single CSS rule and returns matched values.
-func (p *CSSParser) extractCSS(doc *goquery.Document, rule config.ParseRule) []string {
+// selectionValues returns the non-empty text, HTML or attribute values of the
+// matched elements.
+func selectionValues(matches *goquery.Selection, attribute string) []string {
 	var values []string
 
-	doc.Find(rule.Selector).Each(func(i int, sel *goquery.Selection) {
+	matches.Each(func(i int, sel *goquery.Selection) {
 		var val string
 
-		switch rule.Attribute {
+		switch attribute {
 		case "", "text":
 			val = strings.TrimSpace(sel.Text())
 		case "html", "innerHTML":
@@ -82,7 +109,7 @@ func (p *CSSParser) extractCSS(doc *goquery.Document, rule config.ParseRule) []s
 		case "outerHTML":
 			val, _ = goquery.OuterHtml(sel)
 		default:
-			val, _ = sel.Attr(rule.Attribute)
+			val, _ = sel.Attr(attribute)
 		}
 
 		if val != "" {
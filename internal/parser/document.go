@@ -0,0 +1,579 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// DocumentType identifies a non-HTML document format.
+type DocumentType string
+
+const (
+	DocumentPDF  DocumentType = "pdf"
+	DocumentDOCX DocumentType = "docx"
+	DocumentXLSX DocumentType = "xlsx"
+	DocumentCSV  DocumentType = "csv"
+)
+
+// Document holds the text, metadata and tables extracted from a document.
+type Document struct {
+	Type      DocumentType `json:"type"`
+	Title     string       `json:"title,omitempty"`
+	Author    string       `json:"author,omitempty"`
+	PageCount int          `json:"page_count,omitempty"`
+	Text      string       `json:"text"`
+	Tables    [][][]string `json:"tables,omitempty"` // table -> row -> cell
+	Sheets    []string     `json:"sheets,omitempty"` // XLSX sheet names, parallel to Tables
+}
+
+// DocumentParser extracts plain text, metadata and tables from PDF, DOCX,
+// XLSX and CSV responses. All formats are decoded in pure Go.
+type DocumentParser struct {
+	logger *slog.Logger
+}
+
+// NewDocumentParser creates a new document parser.
+func NewDocumentParser(logger *slog.Logger) *DocumentParser {
+	return &DocumentParser{
+		logger: logger.With("component", "document_parser"),
+	}
+}
+
+// Parse implements Parser. It produces a single item describing the document.
+// Documents have no followable links.
+func (p *DocumentParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	docType := DetectDocumentType(resp)
+	if docType == "" {
+		return nil, nil, &types.ParseError{
+			URL: resp.Request.URLString(),
+			Err: fmt.Errorf("unsupported document content type %q", resp.ContentType),
+		}
+	}
+
+	doc, err := ParseDocument(resp.Body, docType)
+	if err != nil {
+		return nil, nil, &types.ParseError{
+			URL: resp.Request.URLString(),
+			Err: err,
+		}
+	}
+
+	item := types.NewItem(resp.Request.URLString())
+	item.Set("document_type", string(doc.Type))
+	item.Set("text", doc.Text)
+	if doc.Title != "" {
+		item.Set("title", doc.Title)
+	}
+	if doc.Author != "" {
+		item.Set("author", doc.Author)
+	}
+	if doc.PageCount > 0 {
+		item.Set("page_count", doc.PageCount)
+	}
+	if len(doc.Tables) > 0 {
+		item.Set("tables", doc.Tables)
+	}
+	if len(doc.Sheets) > 0 {
+		item.Set("sheets", doc.Sheets)
+	}
+
+	p.logger.Debug("document parsed",
+		"url", resp.Request.URLString(), "type", doc.Type,
+		"chars", len(doc.Text), "tables", len(doc.Tables))
+	return []*types.Item{item}, nil, nil
+}
+
+// IsDocument reports whether a response is a supported document format.
+func IsDocument(resp *types.Response) bool {
+	return DetectDocumentType(resp) != ""
+}
+
+// DetectDocumentType identifies the document format of a response from its
+// Content-Type, falling back to the URL extension and magic bytes for
+// generic types such as application/octet-stream.
+func DetectDocumentType(resp *types.Response) DocumentType {
+	ct := strings.ToLower(resp.ContentType)
+	switch {
+	case strings.Contains(ct, "html"), strings.Contains(ct, "xml") && !strings.Contains(ct, "openxmlformats"):
+		return ""
+	case strings.Contains(ct, "application/pdf"):
+		return DocumentPDF
+	case strings.Contains(ct, "wordprocessingml.document"):
+		return DocumentDOCX
+	case strings.Contains(ct, "spreadsheetml.sheet"):
+		return DocumentXLSX
+	case strings.Contains(ct, "text/csv"), strings.Contains(ct, "application/csv"):
+		return DocumentCSV
+	}
+
+	if resp.Request != nil && resp.Request.URL != nil {
+		switch strings.ToLower(path.Ext(resp.Request.URL.Path)) {
+		case ".pdf":
+			return DocumentPDF
+		case ".docx":
+			return DocumentDOCX
+		case ".xlsx":
+			return DocumentXLSX
+		case ".csv":
+			return DocumentCSV
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(resp.Body, []byte("%PDF-")):
+		return DocumentPDF
+	case bytes.HasPrefix(resp.Body, []byte("PK\x03\x04")):
+		zr, err := zip.NewReader(bytes.NewReader(resp.Body), int64(len(resp.Body)))
+		if err != nil {
+			return ""
+		}
+		for _, f := range zr.File {
+			switch f.Name {
+			case "word/document.xml":
+				return DocumentDOCX
+			case "xl/workbook.xml":
+				return DocumentXLSX
+			}
+		}
+	}
+	return ""
+}
+
+// ParseDocument decodes body as the given document type.
+func ParseDocument(body []byte, docType DocumentType) (*Document, error) {
+	switch docType {
+	case DocumentPDF:
+		return parsePDF(body)
+	case DocumentDOCX:
+		return parseDOCX(body)
+	case DocumentXLSX:
+		return parseXLSX(body)
+	case DocumentCSV:
+		return parseCSV(body)
+	default:
+		return nil, fmt.Errorf("unsupported document type %q", docType)
+	}
+}
+
+// --- PDF ---
+
+// parsePDF extracts text row by row. Tables are approximated from runs of
+// consecutive rows that contain two or more separately positioned text chunks.
+func parsePDF(body []byte) (doc *Document, err error) {
+	// The PDF reader panics on some malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("parse pdf: %v", r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("parse pdf: %w", err)
+	}
+
+	info := r.Trailer().Key("Info")
+	doc = &Document{
+		Type:      DocumentPDF,
+		Title:     strings.TrimSpace(info.Key("Title").Text()),
+		Author:    strings.TrimSpace(info.Key("Author").Text()),
+		PageCount: r.NumPage(),
+	}
+
+	var text strings.Builder
+	for i := 1; i <= doc.PageCount; i++ {
+		rows, err := r.Page(i).GetTextByRow()
+		if err != nil {
+			return nil, fmt.Errorf("parse pdf page %d: %w", i, err)
+		}
+
+		var table [][]string
+		for _, row := range rows {
+			var cells []string
+			for _, chunk := range row.Content {
+				if s := strings.TrimSpace(chunk.S); s != "" {
+					cells = append(cells, s)
+				}
+			}
+			if len(cells) == 0 {
+				continue
+			}
+			text.WriteString(strings.Join(cells, " "))
+			text.WriteByte('\n')
+
+			if len(cells) > 1 {
+				table = append(table, cells)
+				continue
+			}
+			if len(table) > 1 {
+				doc.Tables = append(doc.Tables, table)
+			}
+			table = nil
+		}
+		if len(table) > 1 {
+			doc.Tables = append(doc.Tables, table)
+		}
+		text.WriteByte('\n')
+	}
+
+	doc.Text = strings.TrimSpace(text.String())
+	return doc, nil
+}
+
+// --- DOCX ---
+
+func parseDOCX(body []byte) (*Document, error) {
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("parse docx: %w", err)
+	}
+
+	doc := &Document{Type: DocumentDOCX}
+	readOfficeProperties(zr, doc)
+
+	data, err := readZipFile(zr, "word/document.xml")
+	if err != nil {
+		return nil, fmt.Errorf("parse docx: %w", err)
+	}
+
+	var (
+		paragraphs []string
+		para       strings.Builder
+		inText     bool
+		// Tables may nest; each level tracks its rows and the current row/cell.
+		tables []*docxTable
+	)
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse docx: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteByte('\t')
+			case "br", "cr":
+				para.WriteByte('\n')
+			case "tbl":
+				tables = append(tables, &docxTable{})
+			case "tr":
+				if len(tables) > 0 {
+					tables[len(tables)-1].row = nil
+				}
+			case "tc":
+				if len(tables) > 0 {
+					tables[len(tables)-1].cell.Reset()
+				}
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				s := strings.TrimSpace(para.String())
+				para.Reset()
+				if s == "" {
+					continue
+				}
+				paragraphs = append(paragraphs, s)
+				if len(tables) > 0 {
+					cell := &tables[len(tables)-1].cell
+					if cell.Len() > 0 {
+						cell.WriteByte('\n')
+					}
+					cell.WriteString(s)
+				}
+			case "tc":
+				if len(tables) > 0 {
+					tbl := tables[len(tables)-1]
+					tbl.row = append(tbl.row, tbl.cell.String())
+					tbl.cell.Reset()
+				}
+			case "tr":
+				if len(tables) > 0 {
+					tbl := tables[len(tables)-1]
+					if len(tbl.row) > 0 {
+						tbl.rows = append(tbl.rows, tbl.row)
+					}
+				}
+			case "tbl":
+				if len(tables) > 0 {
+					tbl := tables[len(tables)-1]
+					tables = tables[:len(tables)-1]
+					if len(tbl.rows) > 0 {
+						doc.Tables = append(doc.Tables, tbl.rows)
+					}
+				}
+			}
+		}
+	}
+
+	doc.Text = strings.Join(paragraphs, "\n")
+	return doc, nil
+}
+
+type docxTable struct {
+	rows [][]string
+	row  []string
+	cell strings.Builder
+}
+
+// --- XLSX ---
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt xlsxRichText) String() string {
+	if len(rt.Runs) == 0 {
+		return rt.T
+	}
+	var sb strings.Builder
+	sb.WriteString(rt.T)
+	for _, r := range rt.Runs {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func parseXLSX(body []byte) (*Document, error) {
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("parse xlsx: %w", err)
+	}
+
+	doc := &Document{Type: DocumentXLSX}
+	readOfficeProperties(zr, doc)
+
+	var workbook xlsxWorkbook
+	if err := unmarshalZipFile(zr, "xl/workbook.xml", &workbook); err != nil {
+		return nil, fmt.Errorf("parse xlsx: %w", err)
+	}
+
+	var rels xlsxRelationships
+	_ = unmarshalZipFile(zr, "xl/_rels/workbook.xml.rels", &rels)
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	var shared xlsxSharedStrings
+	_ = unmarshalZipFile(zr, "xl/sharedStrings.xml", &shared)
+
+	var text strings.Builder
+	for i, sheet := range workbook.Sheets {
+		target, ok := targets[sheet.RID]
+		if !ok {
+			target = fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		}
+
+		var ws xlsxWorksheet
+		if err := unmarshalZipFile(zr, target, &ws); err != nil {
+			return nil, fmt.Errorf("parse xlsx sheet %q: %w", sheet.Name, err)
+		}
+
+		var table [][]string
+		for _, row := range ws.Rows {
+			var cells []string
+			for j, c := range row.Cells {
+				col := j
+				if c.Ref != "" {
+					col = columnIndex(c.Ref)
+				}
+				for len(cells) < col {
+					cells = append(cells, "")
+				}
+
+				var val string
+				switch c.Type {
+				case "s":
+					if idx, err := strconv.Atoi(c.Value); err == nil && idx < len(shared.Items) {
+						val = shared.Items[idx].String()
+					}
+				case "inlineStr":
+					val = c.Inline.String()
+				default:
+					val = c.Value
+				}
+				cells = append(cells, val)
+			}
+			table = append(table, cells)
+		}
+
+		doc.Sheets = append(doc.Sheets, sheet.Name)
+		doc.Tables = append(doc.Tables, table)
+
+		text.WriteString(sheet.Name)
+		text.WriteByte('\n')
+		for _, row := range table {
+			text.WriteString(strings.Join(row, "\t"))
+			text.WriteByte('\n')
+		}
+		text.WriteByte('\n')
+	}
+
+	doc.PageCount = len(doc.Sheets)
+	doc.Text = strings.TrimSpace(text.String())
+	return doc, nil
+}
+
+// columnIndex converts a cell reference such as "AB12" to a zero-based column.
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+// --- CSV ---
+
+func parseCSV(body []byte) (*Document, error) {
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+
+	r := csv.NewReader(bytes.NewReader(body))
+	r.Comma = sniffDelimiter(body)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse csv: %w", err)
+	}
+
+	var text strings.Builder
+	for _, rec := range records {
+		text.WriteString(strings.Join(rec, "\t"))
+		text.WriteByte('\n')
+	}
+
+	doc := &Document{
+		Type: DocumentCSV,
+		Text: strings.TrimSpace(text.String()),
+	}
+	if len(records) > 0 {
+		doc.Tables = [][][]string{records}
+	}
+	return doc, nil
+}
+
+// sniffDelimiter picks the most frequent candidate delimiter on the first line.
+func sniffDelimiter(body []byte) rune {
+	line := body
+	if idx := bytes.IndexByte(body, '\n'); idx >= 0 {
+		line = body[:idx]
+	}
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', ';', '\t', '|'} {
+		if n := bytes.Count(line, []byte(string(d))); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+// --- Office helpers ---
+
+type officeCoreProperties struct {
+	Title   string `xml:"title"`
+	Creator string `xml:"creator"`
+}
+
+type officeAppProperties struct {
+	Pages int `xml:"Pages"`
+}
+
+// readOfficeProperties fills title, author and page count from docProps.
+func readOfficeProperties(zr *zip.Reader, doc *Document) {
+	var core officeCoreProperties
+	if err := unmarshalZipFile(zr, "docProps/core.xml", &core); err == nil {
+		doc.Title = strings.TrimSpace(core.Title)
+		doc.Author = strings.TrimSpace(core.Creator)
+	}
+	var app officeAppProperties
+	if err := unmarshalZipFile(zr, "docProps/app.xml", &app); err == nil {
+		doc.PageCount = app.Pages
+	}
+}
+
+func readZipFile(zr *zip.Reader, name string) ([]byte, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func unmarshalZipFile(zr *zip.Reader, name string, v any) error {
+	data, err := readZipFile(zr, name)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
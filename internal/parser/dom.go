@@ -30,6 +30,20 @@ type TraversalResult struct {
 	Children  []TraversalResult
 }
 
+// Query returns the values an extended selector (see CompileSelector)
+// extracts from the page, such as `dt:has-text("Price") + dd::text`.
+func (dt *DOMTraverser) Query(resp *types.Response, selector string) ([]string, error) {
+	doc, err := resp.Document()
+	if err != nil {
+		return nil, err
+	}
+	query, err := CompileSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return query.Values(query.Select(doc.Selection), ""), nil
+}
+
 // FindParent navigates to the parent element of matches.
 func (dt *DOMTraverser) FindParent(resp *types.Response, selector string, levels int) ([]TraversalResult, error) {
 	doc, err := resp.Document()
@@ -124,26 +138,26 @@ func (dt *DOMTraverser) FindClosest(resp *types.Response, startSelector, ancesto
 	return results, nil
 }
 
-// ExtractTable parses an HTML table into a 2D string array.
+// ExtractTable parses an HTML table into a 2D string array. colspan and
+// rowspan cells are repeated over every position they span.
 func (dt *DOMTraverser) ExtractTable(resp *types.Response, tableSelector string) ([][]string, error) {
 	doc, err := resp.Document()
 	if err != nil {
 		return nil, err
 	}
 
-	var table [][]string
-
-	doc.Find(tableSelector).First().Find("tr").Each(func(i int, row *goquery.Selection) {
-		var cells []string
-		row.Find("td, th").Each(func(j int, cell *goquery.Selection) {
-			cells = append(cells, strings.TrimSpace(cell.Text()))
-		})
-		if len(cells) > 0 {
-			table = append(table, cells)
-		}
-	})
-
-	return table, nil
+	table := doc.Find(tableSelector).First()
+	if goquery.NodeName(table) != "table" {
+		table = table.Find("table").First()
+	}
+	if table.Length() == 0 {
+		return nil, nil
+	}
+	t, err := readTable(table, 0)
+	if err != nil {
+		return nil, err
+	}
+	return t.rows(), nil
 }
 
 // ExtractList extracts list items (li) from a list (ul/ol).
@@ -0,0 +1,626 @@
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html/charset"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// FeedFormat identifies the syndication format of a feed.
+type FeedFormat string
+
+const (
+	FeedRSS  FeedFormat = "rss"
+	FeedAtom FeedFormat = "atom"
+	FeedJSON FeedFormat = "json"
+)
+
+// Feed is a parsed RSS, Atom or JSON Feed document.
+type Feed struct {
+	Format      FeedFormat  `json:"format"`
+	Title       string      `json:"title"`
+	Link        string      `json:"link"`
+	Description string      `json:"description,omitempty"`
+	Entries     []FeedEntry `json:"entries"`
+}
+
+// FeedEntry is a single item/entry of a feed.
+type FeedEntry struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Link       string    `json:"link"`
+	Published  time.Time `json:"published"`
+	Updated    time.Time `json:"updated"`
+	Author     string    `json:"author,omitempty"`
+	Content    string    `json:"content,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+	Categories []string  `json:"categories,omitempty"`
+}
+
+// Key returns a stable identifier for the entry, used to detect new entries.
+func (e FeedEntry) Key() string {
+	if e.ID != "" {
+		return e.ID
+	}
+	if e.Link != "" {
+		return e.Link
+	}
+	h := sha256.Sum256([]byte(e.Title + "\x00" + e.Content))
+	return hex.EncodeToString(h[:16])
+}
+
+// FeedParser turns RSS 2.0, Atom and JSON Feed responses into one item per entry.
+type FeedParser struct {
+	logger *slog.Logger
+	state  *FeedState
+}
+
+// NewFeedParser creates a new feed parser.
+func NewFeedParser(logger *slog.Logger) *FeedParser {
+	return &FeedParser{
+		logger: logger.With("component", "feed_parser"),
+	}
+}
+
+// SetState enables new-entry tracking. Entries already recorded in the state
+// are not emitted again and their links are not followed.
+func (p *FeedParser) SetState(state *FeedState) {
+	p.state = state
+}
+
+// Parse implements Parser. Each feed entry becomes an item and entry links are
+// returned as follow-up URLs. Rules are ignored; use XPath rules for custom XML.
+func (p *FeedParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	feed, err := ParseFeed(resp.Body, resp.ContentType)
+	if err != nil {
+		return nil, nil, &types.ParseError{
+			URL: resp.Request.URLString(),
+			Err: err,
+		}
+	}
+
+	feedURL := resp.Request.URLString()
+	base, _ := url.Parse(resp.FinalURL)
+	if base == nil || base.Host == "" {
+		base = resp.Request.URL
+	}
+
+	var items []*types.Item
+	var links []string
+	var fresh []string
+
+	for _, entry := range feed.Entries {
+		key := entry.Key()
+		if p.state != nil && p.state.Seen(feedURL, key) {
+			continue
+		}
+		fresh = append(fresh, key)
+
+		link := resolveFeedLink(base, entry.Link)
+		item := types.NewItem(feedURL)
+		item.Set("feed_format", string(feed.Format))
+		item.Set("feed_title", feed.Title)
+		item.Set("id", entry.ID)
+		item.Set("title", entry.Title)
+		item.Set("link", link)
+		if !entry.Published.IsZero() {
+			item.Set("published", entry.Published.Format(time.RFC3339))
+		}
+		if !entry.Updated.IsZero() {
+			item.Set("updated", entry.Updated.Format(time.RFC3339))
+		}
+		if entry.Author != "" {
+			item.Set("author", entry.Author)
+		}
+		if entry.Content != "" {
+			item.Set("content", entry.Content)
+		}
+		if entry.Summary != "" {
+			item.Set("summary", entry.Summary)
+		}
+		if len(entry.Categories) > 0 {
+			item.Set("categories", entry.Categories)
+		}
+		items = append(items, item)
+
+		if link != "" {
+			links = append(links, link)
+		}
+	}
+
+	if p.state != nil && len(fresh) > 0 {
+		p.state.Mark(feedURL, fresh...)
+		if err := p.state.Save(); err != nil {
+			p.logger.Warn("feed state save failed", "error", err)
+		}
+	}
+
+	p.logger.Debug("feed parsed",
+		"url", feedURL, "format", feed.Format,
+		"entries", len(feed.Entries), "new", len(items))
+	return items, links, nil
+}
+
+// IsFeed reports whether a response looks like an RSS, Atom or JSON feed,
+// using the Content-Type header first and sniffing the body otherwise.
+func IsFeed(resp *types.Response) bool {
+	ct := strings.ToLower(resp.ContentType)
+	switch {
+	case strings.Contains(ct, "rss+xml"),
+		strings.Contains(ct, "atom+xml"),
+		strings.Contains(ct, "feed+json"):
+		return true
+	case strings.Contains(ct, "html"):
+		return false
+	}
+	return sniffFeed(resp.Body) != ""
+}
+
+// IsXML reports whether a response is an XML document rather than HTML.
+func IsXML(resp *types.Response) bool {
+	ct := strings.ToLower(resp.ContentType)
+	if strings.Contains(ct, "html") {
+		return false
+	}
+	if strings.Contains(ct, "xml") {
+		return true
+	}
+	head := bytes.TrimSpace(bytes.TrimPrefix(resp.Body, []byte("\xef\xbb\xbf")))
+	return bytes.HasPrefix(head, []byte("<?xml"))
+}
+
+// ParseFeed detects the feed format of body and parses it.
+func ParseFeed(body []byte, contentType string) (*Feed, error) {
+	format := sniffFeed(body)
+	if format == "" {
+		ct := strings.ToLower(contentType)
+		switch {
+		case strings.Contains(ct, "atom"):
+			format = FeedAtom
+		case strings.Contains(ct, "json"):
+			format = FeedJSON
+		default:
+			format = FeedRSS
+		}
+	}
+
+	switch format {
+	case FeedJSON:
+		return parseJSONFeed(body)
+	case FeedAtom:
+		return parseAtomFeed(body)
+	default:
+		return parseRSSFeed(body)
+	}
+}
+
+// sniffFeed inspects the start of a document for a feed root element.
+func sniffFeed(body []byte) FeedFormat {
+	head := bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	if len(head) > 2048 {
+		head = head[:2048]
+	}
+	if bytes.HasPrefix(head, []byte("{")) {
+		if bytes.Contains(head, []byte("jsonfeed.org/version")) {
+			return FeedJSON
+		}
+		return ""
+	}
+	lower := bytes.ToLower(head)
+	switch {
+	case bytes.Contains(lower, []byte("<rss")), bytes.Contains(lower, []byte("<rdf:rdf")):
+		return FeedRSS
+	case bytes.Contains(lower, []byte("<feed")):
+		return FeedAtom
+	}
+	return ""
+}
+
+// --- RSS 2.0 ---
+
+type rssDocument struct {
+	Channel rssChannel `xml:"channel"`
+	Items   []rssItem  `xml:"item"` // RSS 1.0 (RDF) places items at the root
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"date"` // dc:date
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"creator"` // dc:creator
+	Description string   `xml:"description"`
+	Encoded     string   `xml:"encoded"` // content:encoded
+	Categories  []string `xml:"category"`
+}
+
+func parseRSSFeed(body []byte) (*Feed, error) {
+	var doc rssDocument
+	if err := decodeXML(body, &doc); err != nil {
+		return nil, fmt.Errorf("parse rss: %w", err)
+	}
+
+	feed := &Feed{
+		Format:      FeedRSS,
+		Title:       strings.TrimSpace(doc.Channel.Title),
+		Link:        strings.TrimSpace(doc.Channel.Link),
+		Description: strings.TrimSpace(doc.Channel.Description),
+	}
+
+	items := append(doc.Channel.Items, doc.Items...)
+	for _, it := range items {
+		entry := FeedEntry{
+			ID:         strings.TrimSpace(it.GUID),
+			Title:      strings.TrimSpace(it.Title),
+			Link:       strings.TrimSpace(it.Link),
+			Published:  parseFeedDate(firstNonEmpty(it.PubDate, it.Date)),
+			Author:     strings.TrimSpace(firstNonEmpty(it.Creator, it.Author)),
+			Content:    strings.TrimSpace(firstNonEmpty(it.Encoded, it.Description)),
+			Categories: trimAll(it.Categories),
+		}
+		if it.Encoded != "" {
+			entry.Summary = strings.TrimSpace(it.Description)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed, nil
+}
+
+// --- Atom ---
+
+type atomFeed struct {
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Body  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// String returns the text body, keeping inline markup for xhtml content.
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Body)
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     atomText   `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Content    atomText `xml:"content"`
+	Summary    atomText `xml:"summary"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+}
+
+func parseAtomFeed(body []byte) (*Feed, error) {
+	var doc atomFeed
+	if err := decodeXML(body, &doc); err != nil {
+		return nil, fmt.Errorf("parse atom: %w", err)
+	}
+
+	feed := &Feed{
+		Format:      FeedAtom,
+		Title:       strings.TrimSpace(doc.Title),
+		Link:        atomAlternate(doc.Links),
+		Description: strings.TrimSpace(doc.Subtitle),
+	}
+
+	for _, e := range doc.Entries {
+		var authors []string
+		for _, a := range e.Authors {
+			if name := strings.TrimSpace(a.Name); name != "" {
+				authors = append(authors, name)
+			}
+		}
+		var categories []string
+		for _, c := range e.Categories {
+			if c.Term != "" {
+				categories = append(categories, c.Term)
+			}
+		}
+
+		entry := FeedEntry{
+			ID:         strings.TrimSpace(e.ID),
+			Title:      e.Title.String(),
+			Link:       atomAlternate(e.Links),
+			Published:  parseFeedDate(firstNonEmpty(e.Published, e.Updated)),
+			Updated:    parseFeedDate(e.Updated),
+			Author:     strings.Join(authors, ", "),
+			Content:    firstNonEmpty(e.Content.String(), e.Summary.String()),
+			Summary:    e.Summary.String(),
+			Categories: categories,
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed, nil
+}
+
+// atomAlternate picks the rel="alternate" link (the default when rel is absent).
+func atomAlternate(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	if len(links) > 0 {
+		return strings.TrimSpace(links[0].Href)
+	}
+	return ""
+}
+
+// --- JSON Feed ---
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeed struct {
+	Title       string `json:"title"`
+	HomePageURL string `json:"home_page_url"`
+	Description string `json:"description"`
+	Items       []struct {
+		ID            any              `json:"id"`
+		URL           string           `json:"url"`
+		Title         string           `json:"title"`
+		ContentHTML   string           `json:"content_html"`
+		ContentText   string           `json:"content_text"`
+		Summary       string           `json:"summary"`
+		DatePublished string           `json:"date_published"`
+		DateModified  string           `json:"date_modified"`
+		Author        *jsonFeedAuthor  `json:"author"`
+		Authors       []jsonFeedAuthor `json:"authors"`
+		Tags          []string         `json:"tags"`
+	} `json:"items"`
+}
+
+func parseJSONFeed(body []byte) (*Feed, error) {
+	var doc jsonFeed
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("parse json feed: %w", err)
+	}
+
+	feed := &Feed{
+		Format:      FeedJSON,
+		Title:       doc.Title,
+		Link:        doc.HomePageURL,
+		Description: doc.Description,
+	}
+
+	for _, it := range doc.Items {
+		var authors []string
+		for _, a := range it.Authors {
+			if a.Name != "" {
+				authors = append(authors, a.Name)
+			}
+		}
+		if len(authors) == 0 && it.Author != nil && it.Author.Name != "" {
+			authors = append(authors, it.Author.Name)
+		}
+
+		var id string
+		if it.ID != nil {
+			id = fmt.Sprintf("%v", it.ID)
+		}
+
+		feed.Entries = append(feed.Entries, FeedEntry{
+			ID:         id,
+			Title:      it.Title,
+			Link:       it.URL,
+			Published:  parseFeedDate(it.DatePublished),
+			Updated:    parseFeedDate(it.DateModified),
+			Author:     strings.Join(authors, ", "),
+			Content:    firstNonEmpty(it.ContentHTML, it.ContentText),
+			Summary:    it.Summary,
+			Categories: it.Tags,
+		})
+	}
+	return feed, nil
+}
+
+// --- Feed State ---
+
+// FeedState records which entries of each feed have already been seen, so
+// repeated crawls of the same feed only follow new entries. It is persisted
+// as JSON and written atomically.
+type FeedState struct {
+	path string
+	mu   sync.Mutex
+	seen map[string]map[string]bool // feed URL -> entry key -> seen
+}
+
+// LoadFeedState reads feed state from path. A missing file yields an empty
+// state; an empty path yields one kept in memory only.
+func LoadFeedState(path string) (*FeedState, error) {
+	fs := &FeedState{
+		path: path,
+		seen: make(map[string]map[string]bool),
+	}
+	if path == "" {
+		return fs, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fs, nil
+		}
+		return nil, fmt.Errorf("read feed state: %w", err)
+	}
+
+	var stored map[string][]string
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("decode feed state: %w", err)
+	}
+	for feedURL, keys := range stored {
+		fs.Mark(feedURL, keys...)
+	}
+	return fs, nil
+}
+
+// Seen returns true if the entry key has been recorded for the feed.
+func (fs *FeedState) Seen(feedURL, key string) bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.seen[feedURL][key]
+}
+
+// Mark records entry keys as seen for the feed.
+func (fs *FeedState) Mark(feedURL string, keys ...string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	entries, ok := fs.seen[feedURL]
+	if !ok {
+		entries = make(map[string]bool, len(keys))
+		fs.seen[feedURL] = entries
+	}
+	for _, k := range keys {
+		entries[k] = true
+	}
+}
+
+// Save writes the state to disk via a temp file and rename. A state without
+// a path is not saved.
+func (fs *FeedState) Save() error {
+	if fs.path == "" {
+		return nil
+	}
+	// Hold the lock through the rename so concurrent saves neither share a
+	// half-written temp file nor replace a newer snapshot with an older one.
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	stored := make(map[string][]string, len(fs.seen))
+	for feedURL, entries := range fs.seen {
+		keys := make([]string, 0, len(entries))
+		for k := range entries {
+			keys = append(keys, k)
+		}
+		stored[feedURL] = keys
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fs.path), 0o755); err != nil {
+		return fmt.Errorf("create feed state dir: %w", err)
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("encode feed state: %w", err)
+	}
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write feed state: %w", err)
+	}
+	return os.Rename(tmp, fs.path)
+}
+
+// --- Helpers ---
+
+// decodeXML unmarshals XML, honouring non-UTF-8 encoding declarations.
+func decodeXML(body []byte, v any) error {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+	return dec.Decode(v)
+}
+
+// feedDateFormats are the date layouts seen in the wild in RSS and Atom feeds.
+var feedDateFormats = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseFeedDate parses a feed timestamp, returning the zero time if unknown.
+func parseFeedDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range feedDateFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func resolveFeedLink(base *url.URL, link string) string {
+	if link == "" || base == nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func trimAll(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
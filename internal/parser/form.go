@@ -0,0 +1,345 @@
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// FormOptions controls how a form is filled in and submitted.
+type FormOptions struct {
+	// Selector picks the form (default "form"). It may also match an element
+	// inside the form, such as its submit button.
+	Selector string
+
+	// Fields override or add field values by name. Every other successful
+	// control, including hidden inputs such as CSRF tokens and ASP.NET's
+	// __VIEWSTATE and __EVENTVALIDATION, keeps the value on the page.
+	Fields map[string]string
+
+	// Submit is the name, value or id of the submit button to click. By
+	// default the form's first submit button is used.
+	Submit string
+}
+
+// formField is one name/value pair in document order.
+type formField struct {
+	name, value string
+	file        bool // a file input, sent as an empty file part
+}
+
+// NewFormRequest builds the request a browser would send when submitting a
+// form on the page: GET forms encode their fields into the action URL's
+// query, POST forms send them urlencoded or, for enctype
+// multipart/form-data, as multipart.
+func NewFormRequest(resp *types.Response, opts FormOptions) (*types.Request, error) {
+	doc, err := resp.Document()
+	if err != nil {
+		return nil, err
+	}
+
+	selector := opts.Selector
+	if selector == "" {
+		selector = "form"
+	}
+	query, err := CompileSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	form := query.Select(doc.Selection).First()
+	if form.Length() > 0 && goquery.NodeName(form) != "form" {
+		if inner := form.Find("form").First(); inner.Length() > 0 {
+			form = inner
+		} else {
+			form = form.Closest("form")
+		}
+	}
+	if form.Length() == 0 {
+		return nil, fmt.Errorf("no form matches %q", selector)
+	}
+
+	base := pageBase(resp, doc)
+	action, err := resolveURL(base, form.AttrOr("action", ""))
+	if err != nil {
+		return nil, fmt.Errorf("form action: %w", err)
+	}
+	method := strings.ToUpper(strings.TrimSpace(form.AttrOr("method", "GET")))
+	if method != http.MethodPost {
+		method = http.MethodGet
+	}
+
+	fields := formFields(doc, form, opts.Submit)
+	fields = overrideFields(fields, opts.Fields)
+
+	req, err := types.NewRequest(action)
+	if err != nil {
+		return nil, err
+	}
+	req.Method = method
+	req.Headers.Set("Referer", resp.Request.URLString())
+
+	if method == http.MethodGet {
+		req.URL.RawQuery = encodeFields(fields)
+		return req, nil
+	}
+
+	if origin := base.Scheme + "://" + base.Host; base.Host != "" {
+		req.Headers.Set("Origin", origin)
+	}
+	if strings.EqualFold(strings.TrimSpace(form.AttrOr("enctype", "")), "multipart/form-data") {
+		body, contentType, err := multipartBody(fields)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+		req.Headers.Set("Content-Type", contentType)
+		return req, nil
+	}
+	req.Body = []byte(encodeFields(fields))
+	req.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// formFields collects the form's successful controls in document order,
+// including controls outside it that name it with a form attribute.
+func formFields(doc *goquery.Document, form *goquery.Selection, submit string) []formField {
+	controls := form.Find("input, select, textarea, button")
+	if id := form.AttrOr("id", ""); id != "" {
+		controls = controls.AddSelection(doc.Find(fmt.Sprintf(`[form="%s"]`, strings.ReplaceAll(id, `"`, `\"`))))
+	}
+
+	var fields []formField
+	submitted := false
+	controls.Each(func(_ int, el *goquery.Selection) {
+		name := el.AttrOr("name", "")
+		if _, disabled := el.Attr("disabled"); disabled || el.Closest("fieldset[disabled]").Length() > 0 {
+			return
+		}
+
+		tag := goquery.NodeName(el)
+		typ := strings.ToLower(el.AttrOr("type", ""))
+		if tag == "button" && typ == "" {
+			typ = "submit"
+		}
+
+		switch {
+		case typ == "submit" || typ == "image":
+			// Only the clicked button is sent, and only if it has a name.
+			value := el.AttrOr("value", "")
+			if submitted || submit != "" && submit != name && submit != value && submit != el.AttrOr("id", "") {
+				return
+			}
+			submitted = true
+			if typ == "image" {
+				prefix := ""
+				if name != "" {
+					prefix = name + "."
+				}
+				fields = append(fields, formField{name: prefix + "x", value: "0"}, formField{name: prefix + "y", value: "0"})
+			} else if name != "" {
+				fields = append(fields, formField{name: name, value: value})
+			}
+		case name == "" || typ == "reset" || typ == "button" && tag != "button":
+		case tag == "button":
+		case typ == "checkbox" || typ == "radio":
+			if _, checked := el.Attr("checked"); checked {
+				fields = append(fields, formField{name: name, value: el.AttrOr("value", "on")})
+			}
+		case typ == "file":
+			fields = append(fields, formField{name: name, file: true})
+		case tag == "select":
+			options := el.Find("option")
+			selected := options.FilterFunction(func(_ int, o *goquery.Selection) bool {
+				_, ok := o.Attr("selected")
+				return ok
+			})
+			_, multiple := el.Attr("multiple")
+			if selected.Length() == 0 && !multiple {
+				selected = options.First()
+			}
+			selected.Each(func(_ int, o *goquery.Selection) {
+				value, ok := o.Attr("value")
+				if !ok {
+					value = normaliseText(o.Text())
+				}
+				fields = append(fields, formField{name: name, value: value})
+			})
+		case tag == "textarea":
+			fields = append(fields, formField{name: name, value: el.Text()})
+		default:
+			fields = append(fields, formField{name: name, value: el.AttrOr("value", "")})
+		}
+	})
+	return fields
+}
+
+// overrideFields replaces the values of fields named in overrides, keeping
+// their position, and appends the names not on the page.
+func overrideFields(fields []formField, overrides map[string]string) []formField {
+	if len(overrides) == 0 {
+		return fields
+	}
+	out := make([]formField, 0, len(fields)+len(overrides))
+	done := make(map[string]bool)
+	for _, f := range fields {
+		value, ok := overrides[f.name]
+		if !ok {
+			out = append(out, f)
+			continue
+		}
+		if !done[f.name] {
+			out = append(out, formField{name: f.name, value: value})
+			done[f.name] = true
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(overrides)) {
+		if !done[name] {
+			out = append(out, formField{name: name, value: overrides[name]})
+		}
+	}
+	return out
+}
+
+func encodeFields(fields []formField) string {
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		parts = append(parts, url.QueryEscape(f.name)+"="+url.QueryEscape(f.value))
+	}
+	return strings.Join(parts, "&")
+}
+
+// multipartBody encodes fields as multipart/form-data. The boundary is
+// derived from the fields so identical submissions produce identical bodies
+// and deduplicate.
+func multipartBody(fields []formField) ([]byte, string, error) {
+	sum := sha256.Sum256([]byte(encodeFields(fields)))
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if err := w.SetBoundary("scrapegoat" + hex.EncodeToString(sum[:12])); err != nil {
+		return nil, "", err
+	}
+	for _, f := range fields {
+		var err error
+		if f.file {
+			_, err = w.CreateFormFile(f.name, "")
+		} else {
+			err = w.WriteField(f.name, f.value)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("encode multipart: %w", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// pageBase returns the URL relative links on the page resolve against,
+// honouring <base href>.
+func pageBase(resp *types.Response, doc *goquery.Document) *url.URL {
+	base, err := url.Parse(resp.FinalURL)
+	if err != nil || resp.FinalURL == "" {
+		base = resp.Request.URL
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if ref, err := url.Parse(href); err == nil {
+			base = base.ResolveReference(ref)
+		}
+	}
+	return base
+}
+
+// --- Config-driven form submission ---
+
+// FormSubmitter submits the forms declared in config (parser.forms) on the
+// pages they match. Pages reached by a form submission do not submit the same
+// form again, so postback pages that repeat the form do not loop.
+type FormSubmitter struct {
+	forms  []*formRule
+	logger *slog.Logger
+}
+
+type formRule struct {
+	config.FormConfig
+	index int
+	match *regexp.Regexp
+}
+
+// metaFormIndex marks a request produced by form rule n (1-based).
+const metaFormIndex = "form_submitted"
+
+// NewFormSubmitter validates the form config.
+func NewFormSubmitter(cfgs []config.FormConfig, logger *slog.Logger) (*FormSubmitter, error) {
+	s := &FormSubmitter{logger: logger.With("component", "form_submitter")}
+	for i, cfg := range cfgs {
+		rule := &formRule{FormConfig: cfg, index: i + 1}
+		if cfg.Match != "" {
+			re, err := regexp.Compile(cfg.Match)
+			if err != nil {
+				return nil, fmt.Errorf("forms[%d]: invalid match: %w", i, err)
+			}
+			rule.match = re
+		}
+		if cfg.Selector != "" {
+			if _, err := CompileSelector(cfg.Selector); err != nil {
+				return nil, fmt.Errorf("forms[%d]: %w", i, err)
+			}
+		}
+		for _, f := range cfg.Fields {
+			if f.Name == "" {
+				return nil, fmt.Errorf("forms[%d]: field without a name", i)
+			}
+		}
+		s.forms = append(s.forms, rule)
+	}
+	return s, nil
+}
+
+// Submit returns one request per form rule matching the response's page.
+// A rule without match applies to seed pages only.
+func (s *FormSubmitter) Submit(resp *types.Response) []*types.Request {
+	req := resp.Request
+	from, _ := req.Meta[metaFormIndex].(int)
+
+	var out []*types.Request
+	for _, rule := range s.forms {
+		if rule.index == from {
+			continue
+		}
+		if rule.match != nil && !rule.match.MatchString(req.URLString()) || rule.match == nil && req.Depth > 0 {
+			continue
+		}
+
+		fields := make(map[string]string, len(rule.Fields))
+		for _, f := range rule.Fields {
+			fields[f.Name] = f.Value
+		}
+		formReq, err := NewFormRequest(resp, FormOptions{Selector: rule.Selector, Fields: fields, Submit: rule.Submit})
+		if err != nil {
+			s.logger.Debug("form not submitted", "url", req.URLString(), "selector", rule.Selector, "reason", err)
+			continue
+		}
+		formReq.Meta[metaFormIndex] = rule.index
+		if rule.RuleSet != "" {
+			formReq.Meta[types.MetaRuleSet] = rule.RuleSet
+		}
+		formReq.Tag = rule.Tag
+		out = append(out, formReq)
+	}
+	return out
+}
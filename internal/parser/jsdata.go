@@ -0,0 +1,826 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// ScriptDataParser handles "jsdata" rules, which address data embedded in a
+// page's scripts (__NEXT_DATA__, window.__NUXT__, __APOLLO_STATE__, inline
+// "var x = {...}" assignments) with a JSONPath-style selector such as
+// "$.__NEXT_DATA__.props.pageProps.product.name". The first path segment is
+// the script variable or JSON script element id.
+type ScriptDataParser struct {
+	logger *slog.Logger
+}
+
+// NewScriptDataParser creates a new embedded script data parser.
+func NewScriptDataParser(logger *slog.Logger) *ScriptDataParser {
+	return &ScriptDataParser{
+		logger: logger.With("component", "script_data_parser"),
+	}
+}
+
+// Parse implements Parser.
+func (p *ScriptDataParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	if len(rules) == 0 {
+		return nil, nil, nil
+	}
+
+	data, err := ExtractScriptData(resp)
+	if err != nil {
+		return nil, nil, &types.ParseError{
+			URL: resp.Request.URLString(),
+			Err: err,
+		}
+	}
+
+	item := types.NewItem(resp.Request.URLString())
+	for _, rule := range rules {
+		if rule.Type != "jsdata" {
+			continue
+		}
+		values, err := QueryJSONPath(data, rule.Selector)
+		if err != nil {
+			p.logger.Warn("invalid jsdata path", "selector", rule.Selector, "error", err)
+			continue
+		}
+		if len(values) == 1 {
+			item.Set(rule.Name, values[0])
+		} else if len(values) > 1 {
+			item.Set(rule.Name, values)
+		}
+	}
+
+	if len(item.Fields) == 0 {
+		return nil, nil, nil
+	}
+	return []*types.Item{item}, nil, nil
+}
+
+// ExtractScriptData parses the data embedded in a page's scripts, keyed by
+// variable name or, for JSON script elements, by element id.
+func ExtractScriptData(resp *types.Response) (map[string]any, error) {
+	doc, err := resp.Document()
+	if err != nil {
+		return nil, err
+	}
+	return ScriptDataFromDocument(doc), nil
+}
+
+// scriptAssignment matches the start of a global assignment whose value may
+// hold page data: "var x =", "window.x =", "window['x'] =" and the like.
+var scriptAssignment = regexp.MustCompile(`(?:\b(?:var|let|const)\s+([A-Za-z_$][\w$]*)|\b(?:window|self|globalThis)\s*(?:\.\s*([A-Za-z_$][\w$]*)|\[\s*["']([^"']+)["']\s*\]))\s*=`)
+
+// ScriptDataFromDocument parses the embedded script data of a document.
+// Only object and array values are kept; assignments that cannot be
+// evaluated without running the script are skipped.
+func ScriptDataFromDocument(doc *goquery.Document) map[string]any {
+	data := make(map[string]any)
+
+	doc.Find("script").Each(func(_ int, s *goquery.Selection) {
+		if _, ok := s.Attr("src"); ok {
+			return
+		}
+		typ := strings.ToLower(strings.TrimSpace(attrOr(s, "type", "")))
+		src := s.Text()
+
+		switch {
+		case typ == "application/ld+json":
+			return // handled by StructuredDataExtractor
+		case strings.Contains(typ, "json"):
+			id := attrOr(s, "id", "")
+			if id == "" {
+				return
+			}
+			var v any
+			if err := json.Unmarshal([]byte(src), &v); err == nil {
+				data[id] = v
+			}
+		case typ == "" || strings.Contains(typ, "javascript") || typ == "module":
+			for _, m := range scriptAssignment.FindAllStringSubmatchIndex(src, -1) {
+				end := m[1]
+				if end < len(src) && (src[end] == '=' || src[end] == '>') {
+					continue // comparison or arrow, not an assignment
+				}
+				name := ""
+				for g := 1; g <= 3; g++ {
+					if m[2*g] >= 0 {
+						name = src[m[2*g]:m[2*g+1]]
+					}
+				}
+				v, err := parseJSValueAt(src, end)
+				if err != nil {
+					continue
+				}
+				switch v.(type) {
+				case map[string]any, []any:
+					data[name] = v
+				}
+			}
+		}
+	})
+
+	return data
+}
+
+// --- JavaScript literals ---
+
+// parseJSValueAt evaluates the JavaScript expression starting at pos as data.
+// Besides JSON it accepts what bundlers and hand-written scripts emit:
+// unquoted and numeric keys, single-quoted and template strings, trailing
+// commas, comments, undefined, !0/!1, JSON.parse("...") and immediately
+// invoked functions that return a literal built from their arguments (the
+// form window.__NUXT__ takes).
+func parseJSValueAt(src string, pos int) (any, error) {
+	p := &jsParser{src: src, pos: pos, calls: new(int)}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(*jsFunc); ok {
+		return nil, fmt.Errorf("function is not invoked")
+	}
+	return v, nil
+}
+
+// jsFunc is a function expression whose body returns a literal.
+type jsFunc struct {
+	params []string
+	ret    int // offset of the returned expression
+}
+
+// Limits on evaluating one script value. Page content is untrusted: deep
+// nesting or self-applying functions would otherwise overflow the stack,
+// which is fatal rather than a recoverable panic.
+const (
+	maxJSDepth = 512
+	maxJSCalls = 10_000
+)
+
+type jsParser struct {
+	src   string
+	pos   int
+	vars  map[string]any // bound function parameters
+	depth int            // nesting of values and function calls
+	calls *int           // function calls so far, shared with call bodies
+}
+
+func (p *jsParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *jsParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+// skipSpace skips whitespace and comments.
+func (p *jsParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			p.pos++
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			if i := strings.IndexByte(p.src[p.pos:], '\n'); i >= 0 {
+				p.pos += i + 1
+			} else {
+				p.pos = len(p.src)
+			}
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			if i := strings.Index(p.src[p.pos+2:], "*/"); i >= 0 {
+				p.pos += i + 4
+			} else {
+				p.pos = len(p.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *jsParser) expect(c byte) error {
+	p.skipSpace()
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// value parses a primary expression followed by any function calls.
+func (p *jsParser) value() (any, error) {
+	v, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		fn, ok := v.(*jsFunc)
+		if !ok {
+			return v, nil
+		}
+		p.skipSpace()
+		if p.peek() != '(' {
+			return v, nil
+		}
+		p.pos++
+		args, err := p.list(')')
+		if err != nil {
+			return nil, err
+		}
+		if v, err = p.call(fn, args); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *jsParser) primary() (any, error) {
+	if p.depth++; p.depth > maxJSDepth {
+		return nil, p.errorf("nesting deeper than %d", maxJSDepth)
+	}
+	defer func() { p.depth-- }()
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of script")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		p.pos++
+		return p.list(']')
+	case c == '"' || c == '\'' || c == '`':
+		return p.string()
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	case c == '!':
+		p.pos++
+		v, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		return !jsTruthy(v), nil
+	case c == '(':
+		p.pos++
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return v, p.expect(')')
+	case isJSIdentStart(c):
+		return p.identifier()
+	default:
+		return nil, p.errorf("unexpected %q", c)
+	}
+}
+
+func (p *jsParser) identifier() (any, error) {
+	name := p.ident()
+	switch name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null", "undefined", "NaN", "Infinity":
+		return nil, nil // not representable in JSON
+	case "void":
+		_, err := p.primary()
+		return nil, err
+	case "function":
+		return p.function()
+	case "new":
+		// new Date("...") and similar: keep the first argument.
+		p.skipSpace()
+		p.ident()
+		if err := p.expect('('); err != nil {
+			return nil, err
+		}
+		args, err := p.list(')')
+		if err != nil || len(args) == 0 {
+			return nil, err
+		}
+		return args[0], nil
+	case "JSON":
+		if err := p.expect('.'); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.ident() != "parse" {
+			return nil, p.errorf("unsupported JSON method")
+		}
+		if err := p.expect('('); err != nil {
+			return nil, err
+		}
+		s, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		text, ok := s.(string)
+		if !ok {
+			return nil, p.errorf("JSON.parse of a non-string")
+		}
+		var v any
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			return nil, err
+		}
+		return v, p.expect(')')
+	}
+	if v, ok := p.vars[name]; ok {
+		return v, nil
+	}
+	return nil, p.errorf("unknown identifier %q", name)
+}
+
+func (p *jsParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) && isJSIdentPart(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *jsParser) object() (any, error) {
+	p.pos++ // {
+	obj := make(map[string]any)
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.pos++
+			return obj, nil
+		}
+
+		var key string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'' || c == '`':
+			s, err := p.string()
+			if err != nil {
+				return nil, err
+			}
+			key = s.(string)
+		case c >= '0' && c <= '9':
+			n, err := p.number()
+			if err != nil {
+				return nil, err
+			}
+			key = strconv.FormatFloat(n.(float64), 'f', -1, 64)
+		case isJSIdentStart(c):
+			key = p.ident()
+		default:
+			return nil, p.errorf("unexpected %q in object", c)
+		}
+
+		p.skipSpace()
+		if c := p.peek(); c == ',' || c == '}' {
+			// Shorthand property {a, b}
+			v, ok := p.vars[key]
+			if !ok {
+				return nil, p.errorf("unknown identifier %q", key)
+			}
+			obj[key] = v
+		} else {
+			if err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = v
+		}
+
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			return nil, p.errorf("expected ',' or '}' in object")
+		}
+	}
+}
+
+// list parses comma-separated values up to the closing byte, as in array
+// literals and call arguments. Holes in arrays become nil.
+func (p *jsParser) list(closing byte) ([]any, error) {
+	items := []any{}
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case closing:
+			p.pos++
+			return items, nil
+		case ',':
+			p.pos++
+			items = append(items, nil)
+			continue
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case closing:
+		default:
+			return nil, p.errorf("expected ',' or %q", closing)
+		}
+	}
+}
+
+func (p *jsParser) string() (any, error) {
+	quote := p.src[p.pos]
+	p.pos++
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c == '$' && quote == '`' && strings.HasPrefix(p.src[p.pos:], "${"):
+			return nil, p.errorf("template substitution")
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.pos++
+			if err := p.escape(&sb); err != nil {
+				return nil, err
+			}
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+// escape decodes the escape sequence after a backslash.
+func (p *jsParser) escape(sb *strings.Builder) error {
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case 'n':
+		sb.WriteByte('\n')
+	case 't':
+		sb.WriteByte('\t')
+	case 'r':
+		sb.WriteByte('\r')
+	case 'b':
+		sb.WriteByte('\b')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'v':
+		sb.WriteByte('\v')
+	case '0':
+		sb.WriteByte(0)
+	case '\n':
+		// line continuation
+	case 'x', 'u':
+		var hex string
+		switch {
+		case c == 'x' && p.pos+2 <= len(p.src):
+			hex = p.src[p.pos : p.pos+2]
+		case c == 'u' && p.peek() == '{':
+			end := strings.IndexByte(p.src[p.pos:], '}')
+			if end < 0 {
+				return p.errorf("bad unicode escape")
+			}
+			hex = p.src[p.pos+1 : p.pos+end]
+			p.pos += 2 // braces
+		case c == 'u' && p.pos+4 <= len(p.src):
+			hex = p.src[p.pos : p.pos+4]
+		}
+		r, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return p.errorf("bad %c escape", c)
+		}
+		p.pos += len(hex)
+		if r >= 0xD800 && r < 0xDC00 && strings.HasPrefix(p.src[p.pos:], `\u`) && p.pos+6 <= len(p.src) {
+			// UTF-16 surrogate pair
+			if lo, err := strconv.ParseUint(p.src[p.pos+2:p.pos+6], 16, 32); err == nil && lo >= 0xDC00 && lo < 0xE000 {
+				r = 0x10000 + (r-0xD800)<<10 + (lo - 0xDC00)
+				p.pos += 6
+			}
+		}
+		sb.WriteRune(rune(r))
+	default:
+		// \" \' \\ \/ and any other character stand for themselves.
+		_, size := utf8.DecodeRuneInString(p.src[p.pos-1:])
+		sb.WriteString(p.src[p.pos-1 : p.pos-1+size])
+		p.pos += size - 1
+	}
+	return nil
+}
+
+func (p *jsParser) number() (any, error) {
+	start := p.pos
+	if c := p.peek(); c == '-' || c == '+' {
+		p.pos++
+		p.skipSpace()
+		if isJSIdentStart(p.peek()) {
+			// -Infinity
+			_, err := p.identifier()
+			return nil, err
+		}
+	}
+	sign := strings.TrimSpace(p.src[start:p.pos])
+	digits := p.pos
+	if strings.HasPrefix(p.src[p.pos:], "0x") || strings.HasPrefix(p.src[p.pos:], "0X") {
+		p.pos += 2
+		for p.pos < len(p.src) && strings.IndexByte("0123456789abcdefABCDEF", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		n, err := strconv.ParseInt(p.src[digits+2:p.pos], 16, 64)
+		if err != nil {
+			return nil, p.errorf("bad number")
+		}
+		if sign == "-" {
+			n = -n
+		}
+		return float64(n), nil
+	}
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if (c >= '0' && c <= '9') || c == '.' || c == 'e' || c == 'E' || c == '_' ||
+			((c == '-' || c == '+') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E')) {
+			p.pos++
+			continue
+		}
+		break
+	}
+	n, err := strconv.ParseFloat(sign+strings.ReplaceAll(p.src[digits:p.pos], "_", ""), 64)
+	if err != nil {
+		return nil, p.errorf("bad number %q", p.src[start:p.pos])
+	}
+	return n, nil
+}
+
+// function parses a function expression, noting its parameters and the
+// offset of the value its body returns.
+func (p *jsParser) function() (any, error) {
+	p.skipSpace()
+	p.ident() // optional name
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	fn := &jsFunc{}
+	for {
+		p.skipSpace()
+		if p.peek() == ')' {
+			p.pos++
+			break
+		}
+		name := p.ident()
+		if name == "" {
+			return nil, p.errorf("unsupported parameter list")
+		}
+		fn.params = append(fn.params, name)
+		p.skipSpace()
+		if p.peek() == ',' {
+			p.pos++
+		}
+	}
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+
+	// Find the top-level return and the end of the body.
+	fn.ret = -1
+	for depth := 1; depth > 0; {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated function body")
+		}
+		switch c := p.src[p.pos]; {
+		case c == '"' || c == '\'' || c == '`':
+			if _, err := p.string(); err != nil {
+				return nil, err
+			}
+		case c == '{' || c == '(' || c == '[':
+			depth++
+			p.pos++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+			p.pos++
+		case isJSIdentStart(c):
+			if p.ident() == "return" && depth == 1 && fn.ret < 0 {
+				fn.ret = p.pos
+			}
+		default:
+			p.pos++
+		}
+	}
+	if fn.ret < 0 {
+		return nil, p.errorf("function returns nothing")
+	}
+	return fn, nil
+}
+
+// call evaluates a function's returned value with its parameters bound.
+func (p *jsParser) call(fn *jsFunc, args []any) (any, error) {
+	if *p.calls++; *p.calls > maxJSCalls {
+		return nil, p.errorf("more than %d function calls", maxJSCalls)
+	}
+	vars := make(map[string]any, len(p.vars)+len(fn.params))
+	for k, v := range p.vars {
+		vars[k] = v
+	}
+	for i, name := range fn.params {
+		if i < len(args) {
+			vars[name] = args[i]
+		} else {
+			vars[name] = nil
+		}
+	}
+	body := &jsParser{src: p.src, pos: fn.ret, vars: vars, depth: p.depth + 1, calls: p.calls}
+	return body.value()
+}
+
+func jsTruthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+func isJSIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isJSIdentPart(c byte) bool {
+	return isJSIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// --- JSONPath ---
+
+// pathStep is one segment of a JSONPath expression.
+type pathStep struct {
+	key       string
+	index     int
+	isIndex   bool
+	wildcard  bool
+	recursive bool // ".." descends into every nested value first
+}
+
+// QueryJSONPath returns the values in data matched by a JSONPath-style
+// expression. Supported: $ (optional), .key, ['key'], [n] (negative counts
+// from the end), .* and [*], and ..key for recursive descent.
+func QueryJSONPath(data any, path string) ([]any, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []any{data}
+	for _, step := range steps {
+		var next []any
+		for _, node := range nodes {
+			candidates := []any{node}
+			if step.recursive {
+				candidates = descendants(node, candidates[:0])
+			}
+			for _, c := range candidates {
+				next = applyPathStep(c, step, next)
+			}
+		}
+		nodes = next
+	}
+	return nodes, nil
+}
+
+func parseJSONPath(path string) ([]pathStep, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	if path != "" && path[0] != '.' && path[0] != '[' {
+		path = "." + path
+	}
+
+	var steps []pathStep
+	recursive := false
+	for i := 0; i < len(path); {
+		step := pathStep{recursive: recursive}
+		switch path[i] {
+		case '.':
+			i++
+			if i < len(path) && path[i] == '.' {
+				i++
+				if i < len(path) && path[i] == '[' {
+					recursive = true // "..[0]": applies to the bracket
+					continue
+				}
+				step.recursive = true
+			}
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			step.key = path[start:i]
+			if step.key == "" {
+				return nil, fmt.Errorf("empty key at offset %d in %q", start, path)
+			}
+			step.wildcard = step.key == "*"
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '[' in %q", path)
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+			switch {
+			case inner == "*":
+				step.wildcard = true
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				step.key = inner[1 : len(inner)-1]
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("bad index %q in %q", inner, path)
+				}
+				step.index, step.isIndex = n, true
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d in %q", path[i], i, path)
+		}
+		steps = append(steps, step)
+		recursive = false
+	}
+	return steps, nil
+}
+
+func applyPathStep(node any, step pathStep, out []any) []any {
+	switch n := node.(type) {
+	case map[string]any:
+		switch {
+		case step.wildcard:
+			keys := make([]string, 0, len(n))
+			for k := range n {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				out = append(out, n[k])
+			}
+		case !step.isIndex:
+			if v, ok := n[step.key]; ok {
+				out = append(out, v)
+			}
+		}
+	case []any:
+		switch {
+		case step.wildcard:
+			out = append(out, n...)
+		case step.isIndex:
+			i := step.index
+			if i < 0 {
+				i += len(n)
+			}
+			if i >= 0 && i < len(n) {
+				out = append(out, n[i])
+			}
+		}
+	}
+	return out
+}
+
+// descendants appends node and every value nested in it, depth first.
+func descendants(node any, out []any) []any {
+	out = append(out, node)
+	switch n := node.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			out = descendants(n[k], out)
+		}
+	case []any:
+		for _, v := range n {
+			out = descendants(v, out)
+		}
+	}
+	return out
+}
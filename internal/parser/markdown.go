@@ -0,0 +1,621 @@
+package parser
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// MarkdownParser handles "markdown" rules, converting the elements matched by
+// the rule's selector (the whole body when empty) to GitHub-flavoured Markdown.
+type MarkdownParser struct {
+	logger *slog.Logger
+}
+
+// NewMarkdownParser creates a new Markdown parser.
+func NewMarkdownParser(logger *slog.Logger) *MarkdownParser {
+	return &MarkdownParser{
+		logger: logger.With("component", "markdown_parser"),
+	}
+}
+
+// Parse implements Parser.
+func (p *MarkdownParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	doc, err := resp.Document()
+	if err != nil {
+		return nil, nil, &types.ParseError{
+			URL: resp.Request.URLString(),
+			Err: err,
+		}
+	}
+
+	item := types.NewItem(resp.Request.URLString())
+	for _, rule := range rules {
+		if rule.Type != "markdown" {
+			continue
+		}
+		selector := rule.Selector
+		if selector == "" {
+			selector = "body"
+		}
+
+		var values []string
+		doc.Find(selector).Each(func(_ int, sel *goquery.Selection) {
+			if md := HTMLToMarkdown(sel, responseBaseURL(resp)); md != "" {
+				values = append(values, md)
+			}
+		})
+		if len(values) == 1 {
+			item.Set(rule.Name, values[0])
+		} else if len(values) > 1 {
+			item.Set(rule.Name, values)
+		}
+	}
+
+	if len(item.Fields) == 0 {
+		return nil, nil, nil
+	}
+	return []*types.Item{item}, nil, nil
+}
+
+// ResponseToMarkdown converts a response, or the subtree matched by selector,
+// to Markdown.
+func ResponseToMarkdown(resp *types.Response, selector string) (string, error) {
+	doc, err := resp.Document()
+	if err != nil {
+		return "", err
+	}
+	sel := doc.Selection
+	if selector != "" {
+		sel = doc.Find(selector)
+	} else if body := doc.Find("body"); body.Length() > 0 {
+		sel = body
+	}
+	return HTMLToMarkdown(sel, responseBaseURL(resp)), nil
+}
+
+// HTMLStringToMarkdown converts an HTML fragment to Markdown.
+func HTMLStringToMarkdown(fragment, baseURL string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return "", fmt.Errorf("parse html: %w", err)
+	}
+	sel := doc.Find("body")
+	if sel.Length() == 0 {
+		sel = doc.Selection
+	}
+	return HTMLToMarkdown(sel, baseURL), nil
+}
+
+// HTMLToMarkdown converts a selection to GitHub-flavoured Markdown, keeping
+// headings, lists, tables, links, images, emphasis and code blocks. Relative
+// links are resolved against baseURL.
+func HTMLToMarkdown(sel *goquery.Selection, baseURL string) string {
+	c := &mdConverter{}
+	c.base, _ = url.Parse(baseURL)
+
+	var blocks []string
+	for _, n := range sel.Nodes {
+		blocks = append(blocks, c.blocks(n)...)
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func responseBaseURL(resp *types.Response) string {
+	if resp.FinalURL != "" {
+		return resp.FinalURL
+	}
+	return resp.Request.URLString()
+}
+
+// --- Conversion ---
+
+var mdSkip = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"iframe": true, "svg": true, "canvas": true, "button": true, "input": true,
+	"select": true, "textarea": true, "object": true, "embed": true,
+}
+
+var mdBlock = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true,
+	"dd": true, "details": true, "dialog": true, "div": true, "dl": true, "dt": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "html": true, "li": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "summary": true, "table": true, "ul": true,
+}
+
+type mdConverter struct {
+	base *url.URL
+}
+
+// blocks renders n as a list of Markdown blocks.
+func (c *mdConverter) blocks(n *html.Node) []string {
+	if n.Type != html.ElementNode {
+		return c.containerBlocks(n)
+	}
+	if mdSkip[n.Data] {
+		return nil
+	}
+	if mdBlock[n.Data] {
+		return c.block(n)
+	}
+	if s := mdClean(c.inline(n)); s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+// block renders a single block-level element.
+func (c *mdConverter) block(n *html.Node) []string {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := strings.ReplaceAll(mdClean(c.inlineChildren(n)), "\n", " ")
+		if text == "" {
+			return nil
+		}
+		level := int(n.Data[1] - '0')
+		return []string{strings.Repeat("#", level) + " " + text}
+	case "p", "dt", "summary", "figcaption":
+		if s := mdClean(c.inlineChildren(n)); s != "" {
+			return []string{s}
+		}
+		return nil
+	case "pre":
+		return []string{c.codeBlock(n)}
+	case "ul", "ol":
+		if s := c.list(n); s != "" {
+			return []string{s}
+		}
+		return nil
+	case "blockquote":
+		inner := strings.Join(c.containerBlocks(n), "\n\n")
+		if inner == "" {
+			return nil
+		}
+		lines := strings.Split(inner, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return []string{strings.Join(lines, "\n")}
+	case "hr":
+		return []string{"---"}
+	case "table":
+		if s := c.table(n); s != "" {
+			return []string{s}
+		}
+		return nil
+	default:
+		return c.containerBlocks(n)
+	}
+}
+
+// containerBlocks renders an element's children in block context. Runs of
+// inline content between block elements become paragraphs.
+func (c *mdConverter) containerBlocks(n *html.Node) []string {
+	var out []string
+	var para strings.Builder
+	flush := func() {
+		if s := mdClean(para.String()); s != "" {
+			out = append(out, s)
+		}
+		para.Reset()
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && mdBlock[child.Data] {
+			flush()
+			out = append(out, c.block(child)...)
+			continue
+		}
+		para.WriteString(c.inline(child))
+	}
+	flush()
+	return out
+}
+
+func (c *mdConverter) inlineChildren(n *html.Node) string {
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(c.inline(child))
+	}
+	return sb.String()
+}
+
+// inline renders a node in inline context.
+func (c *mdConverter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return mdCollapse(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if mdSkip[n.Data] {
+		return ""
+	}
+
+	switch n.Data {
+	case "br":
+		return "\n"
+	case "strong", "b":
+		return mdWrap(c.inlineChildren(n), "**")
+	case "em", "i", "cite":
+		return mdWrap(c.inlineChildren(n), "_")
+	case "del", "s", "strike":
+		return mdWrap(c.inlineChildren(n), "~~")
+	case "code", "kbd", "samp", "tt":
+		return mdCodeSpan(textContent(n))
+	case "a":
+		text := strings.TrimSpace(c.inlineChildren(n))
+		href := c.resolve(attr(n, "href"))
+		if href == "" || strings.HasPrefix(href, "javascript:") {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		if title := attr(n, "title"); title != "" {
+			return fmt.Sprintf("[%s](%s %q)", text, href, title)
+		}
+		return fmt.Sprintf("[%s](%s)", text, href)
+	case "img":
+		src := c.resolve(firstNonEmpty(attr(n, "src"), attr(n, "data-src")))
+		if src == "" {
+			return ""
+		}
+		return fmt.Sprintf("![%s](%s)", mdCollapse(attr(n, "alt")), src)
+	}
+
+	if mdBlock[n.Data] {
+		// Block elements nested in inline context (e.g. a <div> in a <span>)
+		// are rendered as separate lines.
+		return "\n" + strings.Join(c.block(n), "\n") + "\n"
+	}
+	return c.inlineChildren(n)
+}
+
+func (c *mdConverter) codeBlock(n *html.Node) string {
+	code := strings.TrimRight(textContent(n), "\n")
+	code = strings.TrimPrefix(code, "\n")
+
+	lang := mdCodeLanguage(attr(n, "class"))
+	for child := n.FirstChild; child != nil && lang == ""; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == "code" {
+			lang = mdCodeLanguage(attr(child, "class"))
+		}
+	}
+
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+func mdCodeLanguage(class string) string {
+	for _, cls := range strings.Fields(class) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(cls, prefix) {
+				return strings.TrimPrefix(cls, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+func (c *mdConverter) list(n *html.Node) string {
+	ordered := n.Data == "ol"
+	num := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		num = start
+	}
+
+	var items []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(num) + ". "
+			num++
+		}
+
+		content := strings.Join(c.containerBlocks(li), "\n")
+		lines := strings.Split(content, "\n")
+		indent := strings.Repeat(" ", len(marker))
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = indent + lines[i]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (c *mdConverter) table(n *html.Node) string {
+	var rows [][]string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "thead", "tbody", "tfoot":
+				walk(child)
+			case "tr":
+				var cells []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != html.ElementNode || (cell.Data != "td" && cell.Data != "th") {
+						continue
+					}
+					text := strings.ReplaceAll(mdClean(c.inlineChildren(cell)), "\n", " ")
+					cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+				}
+				if len(cells) == 0 {
+					continue
+				}
+				rows = append(rows, cells)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+
+	// GFM tables need exactly one header row: the first row is used whether
+	// or not it was marked up with <th> cells.
+	var sb strings.Builder
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for i := 0; i < cols; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteByte('\n')
+	}
+	writeRow(rows[0])
+	sb.WriteString("|" + strings.Repeat(" --- |", cols) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (c *mdConverter) resolve(link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" || strings.HasPrefix(link, "#") {
+		return link
+	}
+	return resolveFeedLink(c.base, link)
+}
+
+// --- Text helpers ---
+
+// mdCollapse collapses whitespace runs to single spaces, keeping a leading or
+// trailing space so adjacent inline nodes stay separated.
+func mdCollapse(s string) string {
+	if s == "" {
+		return ""
+	}
+	collapsed := strings.Join(strings.Fields(s), " ")
+	if collapsed == "" {
+		return " "
+	}
+	if isSpace(s[0]) {
+		collapsed = " " + collapsed
+	}
+	if isSpace(s[len(s)-1]) {
+		collapsed += " "
+	}
+	return collapsed
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// mdClean trims each line of a rendered paragraph and drops blank lines.
+func mdClean(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mdWrap wraps s in a delimiter, moving surrounding spaces outside it.
+func mdWrap(s, delim string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	lead := s[:strings.Index(s, trimmed)]
+	trail := s[len(lead)+len(trimmed):]
+	return lead + delim + trimmed + delim + trail
+}
+
+func mdCodeSpan(code string) string {
+	code = strings.Join(strings.Fields(code), " ")
+	if code == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		return fence + " " + code + " " + fence
+	}
+	return fence + code + fence
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == "br" {
+			sb.WriteByte('\n')
+			continue
+		}
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
+
+// --- Chunking ---
+
+// MarkdownChunk is a section of a Markdown document sized for embedding.
+type MarkdownChunk struct {
+	Headings []string `json:"headings,omitempty"` // enclosing headings, outermost first
+	Text     string   `json:"text"`
+	Tokens   int      `json:"tokens"`
+}
+
+// EstimateTokens approximates the LLM token count of s (about four bytes
+// per token for English text).
+func EstimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// ChunkMarkdown splits Markdown at headings, then packs paragraphs into
+// chunks of at most maxTokens. A maxTokens of zero splits by heading only.
+// Headings inside fenced code blocks are ignored.
+func ChunkMarkdown(md string, maxTokens int) []MarkdownChunk {
+	var chunks []MarkdownChunk
+	var path []string
+	var section []string
+	var fence string
+
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(section, "\n"))
+		section = section[:0]
+		if text == "" {
+			return
+		}
+		headings := append([]string(nil), path...)
+		for _, piece := range splitByBudget(text, maxTokens) {
+			chunks = append(chunks, MarkdownChunk{
+				Headings: headings,
+				Text:     piece,
+				Tokens:   EstimateTokens(piece),
+			})
+		}
+	}
+
+	for _, line := range strings.Split(md, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			section = append(section, line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			section = append(section, line)
+			continue
+		}
+
+		if level, title := markdownHeading(line); level > 0 {
+			flush()
+			for len(path) < level-1 {
+				path = append(path, "")
+			}
+			path = append(path[:level-1], title)
+		}
+		section = append(section, line)
+	}
+	flush()
+
+	for i := range chunks {
+		chunks[i].Headings = trimAll(chunks[i].Headings)
+	}
+	return chunks
+}
+
+// markdownHeading returns the level and text of an ATX heading line.
+func markdownHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || line[level] != ' ' {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(line[level:])
+}
+
+// splitByBudget packs blank-line separated paragraphs into pieces of at most
+// maxTokens, splitting oversized paragraphs on word boundaries.
+func splitByBudget(text string, maxTokens int) []string {
+	if maxTokens <= 0 || EstimateTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	var pieces []string
+	var cur strings.Builder
+	add := func(p string) {
+		if cur.Len() > 0 && EstimateTokens(cur.String()+"\n\n"+p) > maxTokens {
+			pieces = append(pieces, cur.String())
+			cur.Reset()
+		}
+		if cur.Len() > 0 {
+			cur.WriteString("\n\n")
+		}
+		cur.WriteString(p)
+	}
+
+	for _, para := range strings.Split(text, "\n\n") {
+		if para = strings.TrimSpace(para); para == "" {
+			continue
+		}
+		if EstimateTokens(para) <= maxTokens {
+			add(para)
+			continue
+		}
+		var words strings.Builder
+		for _, w := range strings.Fields(para) {
+			if words.Len() > 0 && EstimateTokens(words.String()+" "+w) > maxTokens {
+				add(words.String())
+				words.Reset()
+			}
+			if words.Len() > 0 {
+				words.WriteByte(' ')
+			}
+			words.WriteString(w)
+		}
+		if words.Len() > 0 {
+			add(words.String())
+		}
+	}
+	if cur.Len() > 0 {
+		pieces = append(pieces, cur.String())
+	}
+	return pieces
+}
@@ -0,0 +1,307 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// Request meta keys used to track a pagination chain.
+const (
+	metaPage      = "pagination_page"  // page number of the request
+	metaPageChain = "pagination_chain" // URL of the chain's first page
+)
+
+// Pagination stop conditions.
+const (
+	stopNoNewItems      = "no_new_items"
+	stopRepeatedContent = "repeated_content"
+)
+
+// Paginator follows paginated listings declared in config: a next-page link,
+// a page/offset URL template, or a cursor read from a JSON response. Each
+// listing is a chain of requests that stops at MaxPages, when a page adds no
+// items the chain had not already produced, or when a page repeats content.
+type Paginator struct {
+	rules  []*paginationRule
+	mu     sync.Mutex
+	chains map[string]*pageChain
+	logger *slog.Logger
+}
+
+type paginationRule struct {
+	config.PaginationConfig
+	match *regexp.Regexp
+	next  *Selector
+}
+
+// pageChain is what a chain has seen so far.
+type pageChain struct {
+	bodies map[uint64]bool
+	items  map[uint64]bool
+}
+
+// NewPaginator validates the pagination config.
+func NewPaginator(cfgs []config.PaginationConfig, logger *slog.Logger) (*Paginator, error) {
+	p := &Paginator{
+		chains: make(map[string]*pageChain),
+		logger: logger.With("component", "paginator"),
+	}
+	for i, cfg := range cfgs {
+		rule := &paginationRule{PaginationConfig: cfg}
+		modes := 0
+		for _, set := range []string{cfg.NextSelector, cfg.URLTemplate, cfg.CursorPath} {
+			if set != "" {
+				modes++
+			}
+		}
+		if modes != 1 {
+			return nil, fmt.Errorf("pagination[%d]: set exactly one of next_selector, url_template or cursor_path", i)
+		}
+		if cfg.Match != "" {
+			re, err := regexp.Compile(cfg.Match)
+			if err != nil {
+				return nil, fmt.Errorf("pagination[%d]: invalid match: %w", i, err)
+			}
+			rule.match = re
+		}
+		if cfg.NextSelector != "" {
+			sel, err := CompileSelector(cfg.NextSelector)
+			if err != nil {
+				return nil, fmt.Errorf("pagination[%d]: %w", i, err)
+			}
+			rule.next = sel
+		}
+		if cfg.URLTemplate != "" && !strings.Contains(cfg.URLTemplate, "{page}") && !strings.Contains(cfg.URLTemplate, "{offset}") {
+			return nil, fmt.Errorf("pagination[%d]: url_template needs {page} or {offset}", i)
+		}
+		if rule.StartPage == 0 {
+			rule.StartPage = 1
+		}
+		if rule.CursorParam == "" {
+			rule.CursorParam = "cursor"
+		}
+		if len(rule.StopWhen) == 0 {
+			rule.StopWhen = []string{stopNoNewItems, stopRepeatedContent}
+		}
+		for _, cond := range rule.StopWhen {
+			if cond != stopNoNewItems && cond != stopRepeatedContent {
+				return nil, fmt.Errorf("pagination[%d]: unknown stop condition %q", i, cond)
+			}
+		}
+		p.rules = append(p.rules, rule)
+	}
+	return p, nil
+}
+
+// Next returns the request for the page after resp, or nil when the page is
+// not paginated or its chain has ended. items are the items parsed from resp.
+func (p *Paginator) Next(resp *types.Response, items []*types.Item) []*types.Request {
+	req := resp.Request
+	rule := p.ruleFor(req)
+	if rule == nil {
+		return nil
+	}
+
+	page, _ := req.Meta[metaPage].(int)
+	if page == 0 {
+		page = rule.StartPage
+	}
+	chainID, _ := req.Meta[metaPageChain].(string)
+	if chainID == "" {
+		chainID = req.URLString()
+	}
+
+	if reason := p.observe(chainID, rule, resp, items); reason != "" {
+		p.logger.Debug("pagination stopped", "chain", chainID, "page", page, "reason", reason)
+		return nil
+	}
+	if rule.MaxPages > 0 && page-rule.StartPage+1 >= rule.MaxPages {
+		p.logger.Debug("pagination stopped", "chain", chainID, "page", page, "reason", "max_pages")
+		p.endChain(chainID)
+		return nil
+	}
+
+	nextURL, err := rule.nextURL(resp, page)
+	if err != nil {
+		p.logger.Warn("pagination failed", "url", req.URLString(), "error", err)
+	}
+	if nextURL == "" || nextURL == req.URLString() {
+		p.endChain(chainID)
+		return nil
+	}
+
+	next, err := types.NewRequest(nextURL)
+	if err != nil {
+		p.endChain(chainID)
+		return nil
+	}
+	next.Meta[metaPage] = page + 1
+	next.Meta[metaPageChain] = chainID
+	return []*types.Request{next}
+}
+
+// ruleFor returns the first rule applying to req. A rule with neither match
+// nor rule_set starts chains at seed pages only.
+func (p *Paginator) ruleFor(req *types.Request) *paginationRule {
+	ruleSet, _ := req.Meta[types.MetaRuleSet].(string)
+	_, inChain := req.Meta[metaPageChain]
+	for _, rule := range p.rules {
+		if rule.RuleSet != "" && !strings.EqualFold(rule.RuleSet, ruleSet) {
+			continue
+		}
+		if rule.match != nil && !rule.match.MatchString(req.URLString()) {
+			continue
+		}
+		if rule.match == nil && rule.RuleSet == "" && !inChain && req.Depth > 0 {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// observe records the page in its chain and returns the stop condition it
+// meets, if any.
+func (p *Paginator) observe(chainID string, rule *paginationRule, resp *types.Response, items []*types.Item) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	chain := p.chains[chainID]
+	if chain == nil {
+		chain = &pageChain{bodies: make(map[uint64]bool), items: make(map[uint64]bool)}
+		p.chains[chainID] = chain
+	}
+
+	bodyHash := hash64(resp.Body)
+	repeated := chain.bodies[bodyHash]
+	chain.bodies[bodyHash] = true
+
+	hadItems := len(chain.items) > 0
+	newItems := 0
+	for _, item := range items {
+		data, err := json.Marshal(item.Fields)
+		if err != nil {
+			continue
+		}
+		if h := hash64(data); !chain.items[h] {
+			chain.items[h] = true
+			newItems++
+		}
+	}
+
+	reason := ""
+	switch {
+	case repeated && slices.Contains(rule.StopWhen, stopRepeatedContent):
+		reason = stopRepeatedContent
+	case hadItems && newItems == 0 && slices.Contains(rule.StopWhen, stopNoNewItems):
+		// A chain that never produced items (no rules) is not judged by them.
+		reason = stopNoNewItems
+	}
+	if reason != "" {
+		delete(p.chains, chainID)
+	}
+	return reason
+}
+
+func (p *Paginator) endChain(chainID string) {
+	p.mu.Lock()
+	delete(p.chains, chainID)
+	p.mu.Unlock()
+}
+
+// EndChain forgets the chain of req, a next page returned by Next that will
+// not be fetched: the crawl rejected it or its fetch failed. Requests
+// outside a chain are ignored.
+func (p *Paginator) EndChain(req *types.Request) {
+	if chainID, _ := req.Meta[metaPageChain].(string); chainID != "" {
+		p.logger.Debug("pagination stopped", "chain", chainID, "page", req.Meta[metaPage], "reason", "not_fetched")
+		p.endChain(chainID)
+	}
+}
+
+// nextURL finds the URL of the page after page.
+func (r *paginationRule) nextURL(resp *types.Response, page int) (string, error) {
+	base, err := url.Parse(resp.FinalURL)
+	if err != nil || resp.FinalURL == "" {
+		base = resp.Request.URL
+	}
+
+	switch {
+	case r.next != nil:
+		doc, err := resp.Document()
+		if err != nil {
+			return "", err
+		}
+		values := r.next.Values(r.next.Select(doc.Selection).First(), "href")
+		if len(values) == 0 {
+			return "", nil
+		}
+		return resolveURL(base, values[0])
+
+	case r.URLTemplate != "":
+		next := page + 1
+		offset := (next - r.StartPage) * r.PageSize
+		raw := strings.NewReplacer(
+			"{page}", strconv.Itoa(next),
+			"{offset}", strconv.Itoa(offset),
+		).Replace(r.URLTemplate)
+		return resolveURL(base, raw)
+
+	default:
+		var data any
+		if err := json.Unmarshal(resp.Body, &data); err != nil {
+			return "", fmt.Errorf("cursor: response is not JSON: %w", err)
+		}
+		values, err := QueryJSONPath(data, r.CursorPath)
+		if err != nil {
+			return "", err
+		}
+		if len(values) == 0 {
+			return "", nil
+		}
+		var cursor string
+		switch v := values[0].(type) {
+		case string:
+			cursor = v
+		case float64:
+			cursor = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return "", nil // null, false or a structure: no next page
+		}
+		if cursor == "" {
+			return "", nil
+		}
+		u := *resp.Request.URL
+		q := u.Query()
+		q.Set(r.CursorParam, cursor)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+}
+
+func resolveURL(base *url.URL, raw string) (string, error) {
+	ref, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	resolved := base.ResolveReference(ref)
+	resolved.Fragment = ""
+	return resolved.String(), nil
+}
+
+func hash64(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
@@ -1,7 +1,11 @@
 package parser
 
 import (
+	"archive/zip"
+	"bytes"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 
 	"log/slog"
@@ -107,6 +111,56 @@ func TestCSSParserLinks(t *testing.T) {
 	}
 }
 
+func TestCSSParserExtendedSelectors(t *testing.T) {
+	p := NewCSSParser(testLogger)
+	resp := makeResp("https://example.com", `<html><body>
+<h2 class="name">Widget <small>new</small></h2>
+<dl>
+  <dt>Brand</dt><dd>Acme</dd>
+  <dt> Unit  PRICE </dt><dd>$9.99</dd>
+</dl>
+<p><b>Tel:</b> 555-0100 <br>Fax: none</p>
+<ul><li><a href="/a">A</a></li><li><a href="/b">B</a></li><li><a href="/c">Sale C</a></li></ul>
+</body></html>`)
+
+	rules := []config.ParseRule{
+		{Name: "name", Selector: "h2.name::text"},
+		{Name: "price", Selector: `dt:has-text("unit price") + dd`},
+		{Name: "phone", Selector: `p b:has-text("tel") + ::text`},
+		{Name: "second", Selector: "ul li:nth-match(2) a::attr(href)"},
+		{Name: "last", Selector: "ul a:nth-match(-1)::attr(href)"},
+		{Name: "sale", Selector: `li:contains("Sale") > a`},
+	}
+
+	items, _, err := p.Parse(resp, rules)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+
+	want := map[string]string{
+		"name":   "Widget",
+		"price":  "$9.99",
+		"phone":  "555-0100",
+		"second": "/b",
+		"last":   "/c",
+		"sale":   "Sale C",
+	}
+	for field, w := range want {
+		if got := items[0].GetString(field); got != w {
+			t.Errorf("%s = %q, want %q", field, got, w)
+		}
+	}
+
+	for _, bad := range []string{"a::bogus", "li:nth-match(x)", "dt:has-text(\"x\"", "a::attr()"} {
+		if _, err := CompileSelector(bad); err == nil {
+			t.Errorf("CompileSelector(%q) should fail", bad)
+		}
+	}
+}
+
 // --- XPath Parser Tests ---
 
 func TestXPathParser(t *testing.T) {
@@ -209,6 +263,37 @@ func TestStructuredDataExtraction(t *testing.T) {
 	}
 }
 
+func TestStructuredDataMicrodata(t *testing.T) {
+	html := `<div itemscope itemtype="https://schema.org/Product">
+		<span itemprop="name">Widget</span>
+		<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+			<meta itemprop="price" content="9.99">
+		</div>
+		<span itemprop="color">red</span><span itemprop="color">blue</span>
+	</div>`
+	results, err := NewStructuredDataExtractor(testLogger).Extract(makeResp("https://example.com", html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := StructuredDataToItem(results, "https://example.com")
+
+	// "microdata" keeps the original flat map of every itemprop below the
+	// item; "microdata_items" nests items and lists repeated properties.
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"microdata", "map[@type:https://schema.org/Product color:blue name:Widget price:9.99]"},
+		{"microdata_items", "map[@type:https://schema.org/Product color:[red blue] name:Widget offers:map[@type:https://schema.org/Offer price:9.99]]"},
+	}
+	for _, tt := range tests {
+		got, _ := item.Get(tt.key)
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s = %v, want %s", tt.key, got, tt.want)
+		}
+	}
+}
+
 // --- DOM Traversal Tests ---
 
 func TestDOMTraversal(t *testing.T) {
@@ -325,6 +410,303 @@ func TestCompositeParser(t *testing.T) {
 	}
 }
 
+func TestCompositeParserTableRules(t *testing.T) {
+	p := NewCompositeParser(testLogger)
+	resp := makeResp("https://example.com/stats", `<html><body>
+<table id="nav"><tr><td>Home</td><td>About</td></tr></table>
+<table>
+  <caption>Quarterly Revenue</caption>
+  <thead>
+    <tr><th rowspan="2">Region</th><th colspan="2">Revenue</th></tr>
+    <tr><th>Q1</th><th>Q2</th></tr>
+  </thead>
+  <tbody>
+    <tr><th>North</th><td>$1,200</td><td>(300)</td></tr>
+    <tr><th rowspan="2">South</th><td>950</td><td>12.5%</td></tr>
+    <tr><td>n/a</td><td>7</td></tr>
+  </tbody>
+</table>
+<table class="specs">
+  <tr><th>Weight</th><td>1.2 kg</td></tr>
+  <tr><th>Color</th><td>Red</td></tr>
+</table>
+</body></html>`)
+
+	rules := []config.ParseRule{
+		{Name: "revenue", Type: "table", Table: config.TableRule{Caption: "revenue", Numeric: true}},
+		{Name: "specs", Type: "table", Table: config.TableRule{Headers: []string{"nothing"}}},
+		{Name: "kv", Type: "table", Selector: "table.specs"},
+	}
+
+	items, _, err := p.Parse(resp, rules)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var rows []*types.Item
+	var kv *types.Item
+	for _, item := range items {
+		switch item.GetString("_table") {
+		case "revenue":
+			rows = append(rows, item)
+		case "kv":
+			kv = item
+		case "specs":
+			t.Error("header filter should exclude every table")
+		}
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 revenue rows, got %d", len(rows))
+	}
+
+	checks := []struct {
+		row   int
+		field string
+		want  any
+	}{
+		{0, "Region", "North"},
+		{0, "Revenue / Q1", 1200.0},
+		{0, "Revenue / Q2", -300.0},
+		{1, "Revenue / Q2", 12.5},
+		{2, "Region", "South"}, // rowspan carried down
+		{2, "Revenue / Q1", "n/a"},
+		{2, "Revenue / Q2", 7.0},
+	}
+	for _, c := range checks {
+		if got, _ := rows[c.row].Get(c.field); got != c.want {
+			t.Errorf("row %d %s = %#v, want %#v", c.row, c.field, got, c.want)
+		}
+	}
+
+	if kv == nil {
+		t.Fatal("expected key/value table item")
+	}
+	if kv.GetString("Weight") != "1.2 kg" || kv.GetString("Color") != "Red" {
+		t.Errorf("key/value item = %v", kv.Fields)
+	}
+}
+
+func TestTableShortRowUnderRowspan(t *testing.T) {
+	// The second row stops before column C, which still carries c1.
+	resp := makeResp("https://example.com/t", `<html><body><table>
+  <tr><th>A</th><th>B</th><th>C</th></tr>
+  <tr><td>a1</td><td>b1</td><td rowspan="2">c1</td></tr>
+  <tr><td>a2</td></tr>
+  <tr><td>a3</td><td>b3</td><td>c3</td></tr>
+</table></body></html>`)
+
+	items, _, err := NewCompositeParser(testLogger).Parse(resp, []config.ParseRule{{Name: "t", Type: "table"}})
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	want := []map[string]string{
+		{"A": "a1", "B": "b1", "C": "c1"},
+		{"A": "a2", "B": "", "C": "c1"},
+		{"A": "a3", "B": "b3", "C": "c3"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d rows, got %d", len(want), len(items))
+	}
+	for i, w := range want {
+		for field, v := range w {
+			if got := items[i].GetString(field); got != v {
+				t.Errorf("row %d %s = %q, want %q", i, field, got, v)
+			}
+		}
+	}
+}
+
+func TestTableTooLarge(t *testing.T) {
+	// 1,100 cells spanning 1000 columns each expand past maxTableCells; the
+	// table is skipped and the next one is still read.
+	wide := "<table><tr><th>A</th></tr><tr>" + strings.Repeat(`<td colspan="1000">x</td>`, 1100) + "</tr></table>"
+	resp := makeResp("https://example.com/t", "<html><body>"+wide+
+		"<table><tr><th>A</th></tr><tr><td>a1</td></tr></table></body></html>")
+
+	items, _, err := NewCompositeParser(testLogger).Parse(resp, []config.ParseRule{{Name: "t", Type: "table"}})
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(items) != 1 || items[0].GetString("A") != "a1" {
+		t.Errorf("items = %v, want the small table's row", items)
+	}
+	if _, err := NewDOMTraverser(testLogger).ExtractTable(makeResp("https://example.com/t", wide), "table"); err == nil {
+		t.Error("ExtractTable: expected an error for the oversized table")
+	}
+}
+
+// --- Pagination Tests ---
+
+func TestPaginator(t *testing.T) {
+	page := func(rawURL, body string, from *types.Request) *types.Response {
+		resp := makeResp(rawURL, body)
+		if from != nil {
+			resp.Request = from
+		}
+		return resp
+	}
+	item := func(v string) *types.Item {
+		it := types.NewItem("https://example.com")
+		it.Set("name", v)
+		return it
+	}
+
+	t.Run("NextSelector", func(t *testing.T) {
+		p, err := NewPaginator([]config.PaginationConfig{{NextSelector: "a[rel=next]"}}, testLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := p.Next(page("https://example.com/list", `<a rel="next" href="/list?p=2">Next</a>`, nil), []*types.Item{item("a")})
+		if len(next) != 1 || next[0].URLString() != "https://example.com/list?p=2" {
+			t.Fatalf("next = %v", next)
+		}
+		if next[0].Meta[metaPage] != 2 || next[0].Meta[metaPageChain] != "https://example.com/list" {
+			t.Errorf("chain meta = %v", next[0].Meta)
+		}
+
+		// Same items again: the chain stops.
+		next[0].Depth = 0
+		if more := p.Next(page("", `<p>b</p><a rel="next" href="/list?p=3">Next</a>`, next[0]), []*types.Item{item("a")}); more != nil {
+			t.Errorf("expected stop on no new items, got %v", more)
+		}
+
+		// Discovered (non-seed) pages do not start chains without a match.
+		detail, _ := types.NewRequest("https://example.com/item/1")
+		detail.Depth = 1
+		if more := p.Next(page("", `<a rel="next" href="/item/2">Next</a>`, detail), nil); more != nil {
+			t.Errorf("detail page should not paginate, got %v", more)
+		}
+	})
+
+	t.Run("URLTemplate", func(t *testing.T) {
+		p, err := NewPaginator([]config.PaginationConfig{{
+			Match: "/search", URLTemplate: "/search?q=go&offset={offset}", PageSize: 20, MaxPages: 2,
+		}}, testLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := p.Next(page("https://example.com/search?q=go", "page one", nil), nil)
+		if len(next) != 1 || next[0].URLString() != "https://example.com/search?q=go&offset=20" {
+			t.Fatalf("next = %v", next)
+		}
+		if more := p.Next(page("", "page two", next[0]), nil); more != nil {
+			t.Errorf("expected stop at max_pages, got %v", more)
+		}
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		p, err := NewPaginator([]config.PaginationConfig{{CursorPath: "$.meta.next", CursorParam: "after"}}, testLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := p.Next(page("https://api.example.com/items?limit=10", `{"meta":{"next":"abc"}}`, nil), nil)
+		if len(next) != 1 || next[0].URLString() != "https://api.example.com/items?after=abc&limit=10" {
+			t.Fatalf("next = %v", next)
+		}
+		if more := p.Next(page("", `{"meta":{"next":null}}`, next[0]), nil); more != nil {
+			t.Errorf("expected stop on null cursor, got %v", more)
+		}
+	})
+
+	t.Run("RepeatedContent", func(t *testing.T) {
+		p, _ := NewPaginator([]config.PaginationConfig{{URLTemplate: "?page={page}"}}, testLogger)
+		next := p.Next(page("https://example.com/feed", "same", nil), nil)
+		if len(next) != 1 {
+			t.Fatalf("next = %v", next)
+		}
+		if more := p.Next(page("", "same", next[0]), nil); more != nil {
+			t.Errorf("expected stop on repeated content, got %v", more)
+		}
+	})
+
+	t.Run("EndChain", func(t *testing.T) {
+		p, _ := NewPaginator([]config.PaginationConfig{{URLTemplate: "?page={page}"}}, testLogger)
+		next := p.Next(page("https://example.com/feed", "page one", nil), []*types.Item{item("a")})
+		if len(next) != 1 || len(p.chains) != 1 {
+			t.Fatalf("next = %v, %d chains", next, len(p.chains))
+		}
+		// The crawl rejected the next page: its chain is forgotten.
+		p.EndChain(next[0])
+		if len(p.chains) != 0 {
+			t.Errorf("%d chains left after EndChain", len(p.chains))
+		}
+	})
+
+	if _, err := NewPaginator([]config.PaginationConfig{{NextSelector: "a", URLTemplate: "?p={page}"}}, testLogger); err == nil {
+		t.Error("expected error for two pagination modes")
+	}
+}
+
+// --- Form Tests ---
+
+func TestNewFormRequest(t *testing.T) {
+	resp := makeResp("https://example.com/app/search.aspx", `<html><body>
+<form id="aspnetForm" method="post" action="results.aspx">
+  <input type="hidden" name="__VIEWSTATE" value="dDwtMTA4">
+  <input type="hidden" name="__EVENTTARGET" value="">
+  <input type="text" name="q" value="old">
+  <input type="checkbox" name="inStock" value="1" checked>
+  <input type="checkbox" name="onSale" value="1">
+  <input type="text" name="skipped" disabled value="x">
+  <select name="sort"><option value="relevance">Relevance</option><option value="price" selected>Price</option></select>
+  <textarea name="notes">hi there</textarea>
+  <input type="submit" name="go" value="Search">
+  <input type="submit" name="reset" value="Clear">
+</form>
+<input type="text" name="page" value="2" form="aspnetForm">
+<form class="filters" action="/list" enctype="multipart/form-data" method="POST">
+  <input name="color" value="red"><input type="file" name="upload">
+</form>
+<form class="quick" action="/find?old=1"><input name="term" value="a b"><button>Go</button></form>
+</body></html>`)
+
+	req, err := NewFormRequest(resp, FormOptions{
+		Fields: map[string]string{"q": "golang", "__EVENTTARGET": "ctl00$next"},
+		Submit: "Search",
+	})
+	if err != nil {
+		t.Fatalf("form request: %v", err)
+	}
+	if req.Method != "POST" || req.URLString() != "https://example.com/app/results.aspx" {
+		t.Errorf("request = %s %s", req.Method, req.URLString())
+	}
+	if ct := req.Headers.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+		t.Errorf("content type = %q", ct)
+	}
+	want := "__VIEWSTATE=dDwtMTA4&__EVENTTARGET=ctl00%24next&q=golang&inStock=1&sort=price&notes=hi+there&go=Search&page=2"
+	if string(req.Body) != want {
+		t.Errorf("body =\n%s\nwant\n%s", req.Body, want)
+	}
+
+	get, err := NewFormRequest(resp, FormOptions{Selector: "form.quick button"})
+	if err != nil {
+		t.Fatalf("form request: %v", err)
+	}
+	if get.Method != "GET" || get.URLString() != "https://example.com/find?term=a+b" || len(get.Body) != 0 {
+		t.Errorf("GET request = %s %s %q", get.Method, get.URLString(), get.Body)
+	}
+
+	multi, err := NewFormRequest(resp, FormOptions{Selector: "form.filters"})
+	if err != nil {
+		t.Fatalf("form request: %v", err)
+	}
+	ct := multi.Headers.Get("Content-Type")
+	if !strings.HasPrefix(ct, "multipart/form-data; boundary=") {
+		t.Fatalf("content type = %q", ct)
+	}
+	if body := string(multi.Body); !strings.Contains(body, `name="color"`+"\r\n\r\nred") || !strings.Contains(body, `name="upload"; filename=""`) {
+		t.Errorf("multipart body = %q", body)
+	}
+	again, _ := NewFormRequest(resp, FormOptions{Selector: "form.filters"})
+	if !bytes.Equal(again.Body, multi.Body) {
+		t.Error("multipart body should be deterministic")
+	}
+
+	if _, err := NewFormRequest(resp, FormOptions{Selector: "form.missing"}); err == nil {
+		t.Error("expected error for missing form")
+	}
+}
+
 // --- Benchmarks ---
 
 func BenchmarkCSSParse(b *testing.B) {
@@ -378,3 +760,1107 @@ func BenchmarkCompositeParser(b *testing.B) {
 		cp.Parse(resp, rules)
 	}
 }
+
+// --- Feed Parser Tests ---
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+  <title>Example News</title>
+  <link>https://example.com/</link>
+  <item>
+    <title>First Story</title>
+    <link>/news/1</link>
+    <guid>news-1</guid>
+    <pubDate>Mon, 15 Jan 2024 10:00:00 +0000</pubDate>
+    <dc:creator>Alice</dc:creator>
+    <description>Short summary</description>
+    <content:encoded><![CDATA[<p>Full story</p>]]></content:encoded>
+  </item>
+  <item>
+    <title>Second Story</title>
+    <link>https://example.com/news/2</link>
+    <guid>news-2</guid>
+  </item>
+</channel>
+</rss>`
+
+const testAtom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example Blog</title>
+  <link href="https://blog.example.com/"/>
+  <entry>
+    <id>tag:blog.example.com,2024:1</id>
+    <title>Hello Atom</title>
+    <link rel="alternate" href="https://blog.example.com/hello"/>
+    <published>2024-02-01T08:30:00Z</published>
+    <author><name>Bob</name></author>
+    <summary>Atom summary</summary>
+  </entry>
+</feed>`
+
+const testJSONFeed = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "JSON Example",
+  "items": [
+    {"id": "1", "url": "https://example.org/a", "title": "A", "content_text": "Body A",
+     "date_published": "2024-03-01T12:00:00Z", "authors": [{"name": "Carol"}]}
+  ]
+}`
+
+func TestFeedParserFormats(t *testing.T) {
+	tests := []struct {
+		name, body, format, title, link, author, published string
+	}{
+		{"rss", testRSS, "rss", "First Story", "https://example.com/news/1", "Alice", "2024-01-15T10:00:00Z"},
+		{"atom", testAtom, "atom", "Hello Atom", "https://blog.example.com/hello", "Bob", "2024-02-01T08:30:00Z"},
+		{"json", testJSONFeed, "json", "A", "https://example.org/a", "Carol", "2024-03-01T12:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := makeResp("https://example.com/feed", tt.body)
+			resp.ContentType = ""
+			if !IsFeed(resp) {
+				t.Fatal("expected response to be detected as a feed")
+			}
+
+			items, links, err := NewFeedParser(testLogger).Parse(resp, nil)
+			if err != nil {
+				t.Fatalf("feed parse: %v", err)
+			}
+			if len(items) == 0 {
+				t.Fatal("expected feed items")
+			}
+
+			first := items[0]
+			if got := first.GetString("feed_format"); got != tt.format {
+				t.Errorf("expected format %q, got %q", tt.format, got)
+			}
+			if got := first.GetString("title"); got != tt.title {
+				t.Errorf("expected title %q, got %q", tt.title, got)
+			}
+			if got := first.GetString("link"); got != tt.link {
+				t.Errorf("expected link %q, got %q", tt.link, got)
+			}
+			if got := first.GetString("author"); got != tt.author {
+				t.Errorf("expected author %q, got %q", tt.author, got)
+			}
+			if got := first.GetString("published"); got != tt.published {
+				t.Errorf("expected published %q, got %q", tt.published, got)
+			}
+			if len(links) != len(items) {
+				t.Errorf("expected %d links, got %d", len(items), len(links))
+			}
+		})
+	}
+}
+
+func TestFeedParserOnlyNew(t *testing.T) {
+	state, err := LoadFeedState(t.TempDir() + "/feeds.json")
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	state.Mark("https://example.com/feed", "news-1")
+
+	cp := NewCompositeParser(testLogger)
+	cp.SetFeedState(state)
+
+	resp := makeResp("https://example.com/feed", testRSS)
+	resp.ContentType = "application/rss+xml; charset=utf-8"
+
+	items, links, err := cp.Parse(resp, nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(items) != 1 || items[0].GetString("id") != "news-2" {
+		t.Fatalf("expected only the unseen entry, got %d items", len(items))
+	}
+	if len(links) != 1 || links[0] != "https://example.com/news/2" {
+		t.Errorf("expected only the new entry link, got %v", links)
+	}
+
+	reloaded, err := LoadFeedState(state.path)
+	if err != nil {
+		t.Fatalf("reload state: %v", err)
+	}
+	if !reloaded.Seen("https://example.com/feed", "news-2") {
+		t.Error("expected new entry to be persisted")
+	}
+}
+
+func TestFeedStateInMemory(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	state, err := LoadFeedState("")
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	cp := NewCompositeParser(testLogger)
+	cp.SetFeedState(state)
+	resp := makeResp("https://example.com/feed", testRSS)
+	resp.ContentType = "application/rss+xml; charset=utf-8"
+
+	// The second parse of the same feed has nothing new.
+	for i, want := range []int{2, 0} {
+		items, _, err := cp.Parse(resp, nil)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if len(items) != want {
+			t.Errorf("parse %d: %d items, want %d", i+1, len(items), want)
+		}
+	}
+	if files, _ := os.ReadDir(dir); len(files) > 0 {
+		t.Errorf("in-memory state wrote %v", files)
+	}
+}
+
+func TestFeedStateConcurrentSave(t *testing.T) {
+	state, err := LoadFeedState(t.TempDir() + "/feeds.json")
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			state.Mark("https://example.com/feed", fmt.Sprintf("entry-%d", i))
+			errs <- state.Save()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	// The last save holds every entry marked before it.
+	reloaded, err := LoadFeedState(state.path)
+	if err != nil {
+		t.Fatalf("reload state: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if !reloaded.Seen("https://example.com/feed", fmt.Sprintf("entry-%d", i)) {
+			t.Errorf("entry-%d not persisted", i)
+		}
+	}
+}
+
+func TestXPathParserXML(t *testing.T) {
+	resp := makeResp("https://example.com/feed", testRSS)
+	resp.ContentType = "application/xml"
+
+	items, _, err := NewXPathParser(testLogger).Parse(resp, []config.ParseRule{
+		{Name: "channel", Type: "xpath", Selector: "//channel/title"},
+		{Name: "guids", Type: "xpath", Selector: "//item/guid"},
+	})
+	if err != nil {
+		t.Fatalf("xpath parse: %v", err)
+	}
+	if len(items) == 0 {
+		t.Fatal("expected items from XML xpath")
+	}
+	if got := items[0].GetString("channel"); got != "Example News" {
+		t.Errorf("expected channel title, got %q", got)
+	}
+	if guids, ok := items[0].Get("guids"); !ok || len(guids.([]string)) != 2 {
+		t.Errorf("expected 2 guids, got %v", guids)
+	}
+}
+
+// --- Document Parser Tests ---
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildPDF assembles a single-page PDF with a correct xref table.
+func buildPDF(lines []string) []byte {
+	var content strings.Builder
+	content.WriteString("BT /F1 12 Tf\n")
+	for i, line := range lines {
+		fmt.Fprintf(&content, "1 0 0 1 72 %d Tm (%s) Tj\n", 700-i*20, line)
+	}
+	content.WriteString("ET")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Title (Annual Report) /Author (Jane Doe) >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestDocumentParserPDF(t *testing.T) {
+	resp := makeResp("https://example.com/report.pdf", "")
+	resp.ContentType = "application/pdf"
+	resp.Body = buildPDF([]string{"Revenue grew 12 percent", "Invoice 4711"})
+
+	p := NewCompositeParser(testLogger)
+	items, _, err := p.Parse(resp, []config.ParseRule{
+		{Name: "invoice", Type: "regex", Pattern: `Invoice (\d+)`},
+	})
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	item := items[0]
+	if item.GetString("document_type") != "pdf" {
+		t.Errorf("document_type = %q", item.GetString("document_type"))
+	}
+	if item.GetString("title") != "Annual Report" || item.GetString("author") != "Jane Doe" {
+		t.Errorf("metadata = %q / %q", item.GetString("title"), item.GetString("author"))
+	}
+	if pages, _ := item.Get("page_count"); pages != 1 {
+		t.Errorf("page_count = %v", pages)
+	}
+	if !strings.Contains(item.GetString("text"), "Revenue grew 12 percent") {
+		t.Errorf("text = %q", item.GetString("text"))
+	}
+	if item.GetString("invoice") != "4711" {
+		t.Errorf("invoice = %q", item.GetString("invoice"))
+	}
+}
+
+func TestDocumentParserDOCX(t *testing.T) {
+	body := buildZip(t, map[string]string{
+		"word/document.xml": `<?xml version="1.0"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Hello</w:t></w:r><w:r><w:t xml:space="preserve"> world</w:t></w:r></w:p>
+<w:tbl>
+<w:tr><w:tc><w:p><w:r><w:t>Name</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Price</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>Widget</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>9.99</w:t></w:r></w:p></w:tc></w:tr>
+</w:tbl>
+</w:body></w:document>`,
+		"docProps/core.xml": `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Spec</dc:title><dc:creator>Ann</dc:creator></cp:coreProperties>`,
+		"docProps/app.xml":  `<Properties><Pages>3</Pages></Properties>`,
+	})
+	resp := makeResp("https://example.com/download?id=1", "")
+	resp.ContentType = "application/octet-stream"
+	resp.Body = body
+
+	if DetectDocumentType(resp) != DocumentDOCX {
+		t.Fatalf("detected %q", DetectDocumentType(resp))
+	}
+	doc, err := ParseDocument(resp.Body, DocumentDOCX)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if doc.Title != "Spec" || doc.Author != "Ann" || doc.PageCount != 3 {
+		t.Errorf("metadata = %q %q %d", doc.Title, doc.Author, doc.PageCount)
+	}
+	if !strings.HasPrefix(doc.Text, "Hello world") {
+		t.Errorf("text = %q", doc.Text)
+	}
+	if len(doc.Tables) != 1 || len(doc.Tables[0]) != 2 || doc.Tables[0][1][1] != "9.99" {
+		t.Errorf("tables = %v", doc.Tables)
+	}
+}
+
+func TestDocumentParserXLSX(t *testing.T) {
+	body := buildZip(t, map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Prices" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst><si><t>Item</t></si><si><r><t>Gad</t></r><r><t>get</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="inlineStr"><is><t>Qty</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>1</v></c><c r="C2"><v>42</v></c></row>
+</sheetData></worksheet>`,
+	})
+	resp := makeResp("https://example.com/prices.xlsx", "")
+	resp.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	resp.Body = body
+
+	items, _, err := NewDocumentParser(testLogger).Parse(resp, nil)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	v, _ := items[0].Get("tables")
+	tables, _ := v.([][][]string)
+	want := [][]string{{"Item", "", "Qty"}, {"Gadget", "", "42"}}
+	if len(tables) != 1 || fmt.Sprint(tables[0]) != fmt.Sprint(want) {
+		t.Errorf("tables = %v, want %v", tables, want)
+	}
+	v, _ = items[0].Get("sheets")
+	if sheets, _ := v.([]string); len(sheets) != 1 || sheets[0] != "Prices" {
+		t.Errorf("sheets = %v", v)
+	}
+}
+
+func TestDocumentParserCSV(t *testing.T) {
+	resp := makeResp("https://example.com/data", "name;price\nwidget;9.99\n")
+	resp.ContentType = "text/csv"
+
+	if !IsDocument(resp) {
+		t.Fatal("expected CSV to be detected as a document")
+	}
+	doc, err := ParseDocument(resp.Body, DocumentCSV)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(doc.Tables) != 1 || len(doc.Tables[0]) != 2 || doc.Tables[0][1][1] != "9.99" {
+		t.Errorf("tables = %v", doc.Tables)
+	}
+
+	if IsDocument(makeResp("https://example.com/page", testHTML)) {
+		t.Error("HTML response should not be a document")
+	}
+}
+
+// --- Readability Tests ---
+
+const testArticleHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <title>Why Goats Climb Trees | Farm Weekly</title>
+    <meta name="author" content="By Dana Smith">
+    <meta property="article:published_time" content="2024-03-05T09:30:00Z">
+    <meta property="og:image" content="/img/goats.jpg">
+</head>
+<body>
+    <header><a href="/">Farm Weekly</a></header>
+    <nav><ul><li><a href="/news">News</a></li><li><a href="/tips">Tips</a></li></ul></nav>
+    <div class="cookie-banner">We use cookies to improve your experience, please accept them all.</div>
+    <div id="page">
+        <div class="sidebar"><a href="/a">Popular story one</a> <a href="/b">Popular story two</a></div>
+        <article class="post-content">
+            <h1>Why Goats Climb Trees</h1>
+            <p>Goats in southern Morocco are famous for climbing argan trees, where they graze on leaves and fruit during the dry season.</p>
+            <p>Farmers say the behaviour is learned, passed from older animals to kids, and it helps the herd survive when pasture is scarce.</p>
+            <p>Researchers have also found that goats spit out the seeds, which helps disperse the trees across the region.</p>
+        </article>
+        <div class="related-links"><a href="/c">Related: sheep</a> <a href="/d">Related: cows</a></div>
+    </div>
+    <footer>Copyright Farm Weekly. All rights reserved, including the right to reproduce.</footer>
+</body>
+</html>`
+
+func TestExtractArticle(t *testing.T) {
+	resp := makeResp("https://example.com/goats", testArticleHTML)
+
+	article, err := ExtractArticle(resp)
+	if err != nil {
+		t.Fatalf("extract error: %v", err)
+	}
+	if article.Title != "Why Goats Climb Trees" {
+		t.Errorf("title = %q", article.Title)
+	}
+	if article.Byline != "Dana Smith" {
+		t.Errorf("byline = %q", article.Byline)
+	}
+	if article.Published.IsZero() || article.Published.Year() != 2024 {
+		t.Errorf("published = %v", article.Published)
+	}
+	if article.LeadImage != "https://example.com/img/goats.jpg" {
+		t.Errorf("lead image = %q", article.LeadImage)
+	}
+	if !strings.Contains(article.Text, "argan trees") || !strings.Contains(article.Text, "disperse the trees") {
+		t.Errorf("main content missing from text: %q", article.Text)
+	}
+	for _, boilerplate := range []string{"cookies", "Popular story", "Related:", "Copyright", "News"} {
+		if strings.Contains(article.Text, boilerplate) {
+			t.Errorf("boilerplate %q leaked into text: %q", boilerplate, article.Text)
+		}
+	}
+	if !strings.Contains(article.HTML, "<p>") {
+		t.Errorf("expected article HTML, got %q", article.HTML)
+	}
+
+	// The response document must be left intact.
+	if doc, _ := resp.Document(); doc.Find("nav").Length() != 1 {
+		t.Error("ExtractArticle modified the response document")
+	}
+}
+
+func TestCompositeParserReadabilityRules(t *testing.T) {
+	p := NewCompositeParser(testLogger)
+	resp := makeResp("https://example.com/goats", testArticleHTML)
+
+	items, _, err := p.Parse(resp, []config.ParseRule{
+		{Name: "body", Type: "readability"},
+		{Name: "published", Type: "readability", Attribute: "published"},
+	})
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 merged item, got %d", len(items))
+	}
+	if !strings.HasPrefix(items[0].GetString("body"), "Why Goats Climb Trees") {
+		t.Errorf("body = %q", items[0].GetString("body"))
+	}
+	if items[0].GetString("published") != "2024-03-05T09:30:00Z" {
+		t.Errorf("published = %q", items[0].GetString("published"))
+	}
+}
+
+// --- Markdown Tests ---
+
+const testMarkdownHTML = `<html><body>
+<nav><a href="/">Home</a></nav>
+<div id="doc">
+<h1>Install <em>Guide</em></h1>
+<p>Read the <a href="/docs/faq">FAQ</a> or use <code>go get</code> with <strong>care</strong>.</p>
+<ul>
+  <li>First step</li>
+  <li>Second step
+    <ol><li>Nested one</li><li>Nested two</li></ol>
+  </li>
+</ul>
+<h2>Options</h2>
+<table>
+  <thead><tr><th>Name</th><th>Default</th></tr></thead>
+  <tbody><tr><td>depth</td><td>3</td></tr><tr><td>a|b</td><td></td></tr></tbody>
+</table>
+<pre><code class="language-go">func main() {
+	fmt.Println("hi")
+}</code></pre>
+<blockquote><p>Quoted text</p></blockquote>
+<img src="/logo.png" alt="Logo">
+<script>var x = 1;</script>
+</div>
+</body></html>`
+
+func TestResponseToMarkdown(t *testing.T) {
+	resp := makeResp("https://example.com/guide", testMarkdownHTML)
+
+	md, err := ResponseToMarkdown(resp, "#doc")
+	if err != nil {
+		t.Fatalf("convert error: %v", err)
+	}
+
+	for _, want := range []string{
+		"# Install _Guide_",
+		"Read the [FAQ](https://example.com/docs/faq) or use `go get` with **care**.",
+		"- First step\n- Second step\n  1. Nested one\n  2. Nested two",
+		"## Options",
+		"| Name | Default |\n| --- | --- |\n| depth | 3 |\n| a\\|b |  |",
+		"```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```",
+		"> Quoted text",
+		"![Logo](https://example.com/logo.png)",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "Home") || strings.Contains(md, "var x") {
+		t.Errorf("markdown contains content outside the selection:\n%s", md)
+	}
+}
+
+func TestChunkMarkdown(t *testing.T) {
+	md := "# Guide\n\nIntro paragraph.\n\n## Setup\n\n```sh\n# not a heading\n```\n\n### Linux\n\nUse apt.\n\n## Usage\n\n" +
+		strings.Repeat("word ", 40) + "\n\n" + strings.Repeat("more ", 40)
+
+	chunks := ChunkMarkdown(md, 0)
+	if len(chunks) != 4 {
+		t.Fatalf("expected 4 heading chunks, got %d: %+v", len(chunks), chunks)
+	}
+	if got := strings.Join(chunks[2].Headings, " > "); got != "Guide > Setup > Linux" {
+		t.Errorf("heading path = %q", got)
+	}
+	if !strings.Contains(chunks[1].Text, "# not a heading") {
+		t.Errorf("fenced code split as heading: %q", chunks[1].Text)
+	}
+
+	budgeted := ChunkMarkdown(md, 60)
+	for _, c := range budgeted {
+		if c.Tokens > 60 {
+			t.Errorf("chunk exceeds budget (%d tokens): %q", c.Tokens, c.Text)
+		}
+	}
+	if len(budgeted) <= len(chunks) {
+		t.Errorf("expected token budget to split large sections, got %d chunks", len(budgeted))
+	}
+}
+
+func TestCompositeParserMarkdownRule(t *testing.T) {
+	p := NewCompositeParser(testLogger)
+	resp := makeResp("https://example.com/guide", testMarkdownHTML)
+
+	items, _, err := p.Parse(resp, []config.ParseRule{
+		{Name: "content", Type: "markdown", Selector: "#doc"},
+	})
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(items) != 1 || !strings.HasPrefix(items[0].GetString("content"), "# Install _Guide_") {
+		t.Errorf("unexpected items: %+v", items)
+	}
+}
+
+// --- Record Detection Tests ---
+
+const testListingHTML = `<html><body>
+<nav><ul><li><a href="/">Home</a></li><li><a href="/shop">Shop</a></li><li><a href="/about">About</a></li></ul></nav>
+<div id="results">
+  <div class="product-card"><a class="product-card__title" href="/p/1">Blue Widget</a><span class="price">$9.99</span><img src="/img/1.jpg"><button>Add to cart</button></div>
+  <div class="product-card"><a class="product-card__title" href="/p/2">Red Widget</a><span class="price">$12.50</span><img src="/img/2.jpg"><button>Add to cart</button></div>
+  <div class="product-card featured"><a class="product-card__title" href="/p/3">Green Widget</a><span class="price">$7.25</span><img src="/img/3.jpg"><button>Add to cart</button></div>
+  <div class="product-card"><a class="product-card__title" href="/p/4">Gold Widget</a><span class="price">$99.00</span><button>Add to cart</button></div>
+</div>
+<table class="specs">
+  <tr><th>Model</th><th>Weight</th></tr>
+  <tr><td>W-1</td><td>1kg</td></tr>
+  <tr><td>W-2</td><td>2kg</td></tr>
+  <tr><td>W-3</td><td>3kg</td></tr>
+</table>
+</body></html>`
+
+func TestRecordDetector(t *testing.T) {
+	d := NewRecordDetector(testLogger)
+	resp := makeResp("https://shop.example.com/widgets", testListingHTML)
+
+	sets, err := d.DetectAll(resp, 0)
+	if err != nil {
+		t.Fatalf("detect error: %v", err)
+	}
+	if len(sets) < 2 {
+		t.Fatalf("expected product and table record sets, got %d", len(sets))
+	}
+
+	cards := sets[0]
+	if cards.Count != 4 || !strings.HasSuffix(cards.Selector, "div.product-card") {
+		t.Fatalf("best set = %s (%d records)", cards.Selector, cards.Count)
+	}
+	fields := map[string]RecordField{}
+	for _, f := range cards.Fields {
+		fields[f.Name] = f
+	}
+	for _, name := range []string{"title", "title_url", "price", "image"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("missing field %q in %+v", name, cards.Fields)
+		}
+	}
+	for _, f := range cards.Fields {
+		if strings.Contains(f.Selector, "button") {
+			t.Errorf("constant button text should not be a field: %+v", f)
+		}
+	}
+	if len(cards.Items) != 4 || cards.Items[2].GetString("title") != "Green Widget" || cards.Items[2].GetString("price") != "$7.25" {
+		t.Errorf("unexpected items: %+v", cards.Items)
+	}
+
+	// The generated rules must reproduce the detected values.
+	items, _, err := NewCSSParser(testLogger).Parse(resp, cards.Rules())
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	titles, _ := items[0].Get("title")
+	if got, ok := titles.([]string); !ok || len(got) != 4 || got[3] != "Gold Widget" {
+		t.Errorf("rules extracted titles %v", titles)
+	}
+
+	var table *RecordSet
+	for _, rs := range sets {
+		if strings.Contains(rs.Selector, "table") {
+			table = rs
+		}
+	}
+	if table == nil {
+		t.Fatal("table rows not detected")
+	}
+	if len(table.Items) != 3 || table.Items[1].GetString("model") != "W-2" || table.Items[1].GetString("weight") != "2kg" {
+		t.Errorf("unexpected table items: %+v", table.Items)
+	}
+}
+
+func TestCompositeParserAutoDetect(t *testing.T) {
+	p := NewCompositeParser(testLogger)
+	resp := makeResp("https://shop.example.com/widgets", testListingHTML)
+
+	items, _, _ := p.Parse(resp, nil)
+	if len(items) != 0 {
+		t.Fatalf("auto-detect off: expected no items, got %d", len(items))
+	}
+
+	p.SetAutoDetect(true)
+	items, _, _ = p.Parse(resp, nil)
+	if len(items) != 4 {
+		t.Fatalf("auto-detect on: expected 4 record items, got %d", len(items))
+	}
+}
+
+// --- Self-Healing Selector Tests ---
+
+const testSelfHealBefore = `<html><body>
+<h1 class="name">Blue Widget</h1>
+<span class="price" data-testid="price">$10.00</span>
+<p class="shipping">Free shipping on all orders</p>
+</body></html>`
+
+const testSelfHealAfter = `<html><body>
+<h1 class="name">Blue Widget</h1>
+<div class="buy-box"><span class="amount" data-testid="price">$12.00</span></div>
+<p>Free shipping today</p>
+</body></html>`
+
+func TestSelfHealingSelectors(t *testing.T) {
+	dir := t.TempDir()
+	rules := []config.ParseRule{
+		{Name: "price", Selector: "span.price", Type: "css", SelfHeal: true},
+		{Name: "shipping", Selector: "p.shipping", Type: "css", SelfHeal: true},
+	}
+
+	tracker := NewSmartTracker(testLogger)
+	tracker.SetStore(dir)
+	p := NewCompositeParser(testLogger)
+	p.SetSelfHealing(tracker)
+
+	if _, _, err := p.Parse(makeResp("https://shop.example.com/a", testSelfHealBefore), rules); err != nil {
+		t.Fatalf("parse original layout: %v", err)
+	}
+
+	// A fresh tracker must pick the snapshots up from disk.
+	tracker = NewSmartTracker(testLogger)
+	tracker.SetStore(dir)
+	p = NewCompositeParser(testLogger)
+	p.SetSelfHealing(tracker)
+
+	items, _, err := p.Parse(makeResp("https://shop.example.com/b", testSelfHealAfter), rules)
+	if err != nil {
+		t.Fatalf("parse changed layout: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	if v, _ := items[0].Get("price"); v != "$12.00" {
+		t.Errorf("expected repaired price $12.00, got %v", v)
+	}
+	if _, ok := items[0].Get("shipping"); ok {
+		t.Error("low-similarity relocation should not set a value")
+	}
+	flags, _ := items[0].Get("_selector_flags")
+	if f, ok := flags.([]string); !ok || len(f) != 1 || !strings.HasPrefix(f[0], "shipping:") {
+		t.Errorf("expected one shipping flag, got %v", flags)
+	}
+
+	repairs := tracker.Repairs()
+	if len(repairs) != 2 {
+		t.Fatalf("expected 2 repairs, got %+v", repairs)
+	}
+	for _, r := range repairs {
+		switch r.Rule {
+		case "price":
+			if r.Flagged || r.Strategy != "attribute" || r.NewSelector != `span[data-testid="price"]` {
+				t.Errorf("unexpected price repair: %+v", r)
+			}
+		case "shipping":
+			if !r.Flagged {
+				t.Errorf("shipping repair should be flagged: %+v", r)
+			}
+		}
+	}
+
+	data, err := os.ReadFile(dir + "/repairs.jsonl")
+	if err != nil || bytes.Count(data, []byte("\n")) != 2 {
+		t.Errorf("expected 2 recorded repairs, got %q (%v)", data, err)
+	}
+
+	// The same change on another page is not reported again.
+	p.Parse(makeResp("https://shop.example.com/c", testSelfHealAfter), rules)
+	if n := len(tracker.Repairs()); n != 2 {
+		t.Errorf("repairs should be reported once, got %d", n)
+	}
+}
+
+// --- Script Data Tests ---
+
+const testScriptDataHTML = `<html><head>
+<script id="__NEXT_DATA__" type="application/json">
+{"props":{"pageProps":{"product":{"name":"Blue Widget","price":12.5}}},"page":"/p/[id]"}
+</script>
+<script>
+  // Apollo cache
+  window.__APOLLO_STATE__ = JSON.parse("{\"Product:1\":{\"sku\":\"BW-1\"}}");
+  var config = {
+    apiBase: '/api/v2',   // single quotes, unquoted keys
+    flags: {beta: !0, legacy: !1, debug: void 0,},
+    sizes: [1, 2, 0x10, ],
+    note: ` + "`multi\\nline`" + `,
+  };
+  window.__NUXT__=(function(a,b,c){return {layout:"default",data:[{title:a,tags:[b,c]}],state:{count:12}}}("Hello Nuxt","x","y"));
+  if (config.apiBase == "/api") { console.log("not data") }
+</script>
+<script src="/bundle.js"></script>
+</head><body></body></html>`
+
+func TestScriptDataExtract(t *testing.T) {
+	data, err := ExtractScriptData(makeResp("https://shop.example.com/p/1", testScriptDataHTML))
+	if err != nil {
+		t.Fatalf("ExtractScriptData error: %v", err)
+	}
+	for _, key := range []string{"__NEXT_DATA__", "__APOLLO_STATE__", "config", "__NUXT__"} {
+		if _, ok := data[key]; !ok {
+			t.Errorf("missing %s in %v", key, data)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$.__NEXT_DATA__.props.pageProps.product.name", "[Blue Widget]"},
+		{"__NEXT_DATA__.props.pageProps.product.price", "[12.5]"},
+		{"$.__APOLLO_STATE__['Product:1'].sku", "[BW-1]"},
+		{"$.config.apiBase", "[/api/v2]"},
+		{"$.config.flags", "[map[beta:true debug:<nil> legacy:false]]"},
+		{"$.config.sizes[-1]", "[16]"},
+		{"$.config.note", "[multi\nline]"},
+		{"$.__NUXT__.data[0].title", "[Hello Nuxt]"},
+		{"$.__NUXT__.data[*].tags[*]", "[x y]"},
+		{"$..count", "[12]"},
+		{"$.missing.key", "[]"},
+	}
+	for _, tt := range tests {
+		got, err := QueryJSONPath(data, tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if s := fmt.Sprint(got); s != tt.want {
+			t.Errorf("%s = %s, want %s", tt.path, s, tt.want)
+		}
+	}
+
+	if _, err := QueryJSONPath(data, "$.a[oops]"); err == nil {
+		t.Error("expected error for bad index")
+	}
+}
+
+func TestScriptDataLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"self-application", "var x = (function(a){return a(a)})(function(a){return a(a)});"},
+		{"fan-out", "var x = (function(a){return a(a)})(function(a){return [a(a), a(a)]});"},
+		{"deep nesting", "var x = " + strings.Repeat("[", 3_000_000) + ";"},
+	}
+	for _, tt := range tests {
+		html := "<script>" + tt.script + "</script><script>var ok = {\"a\": [[1]]};</script>"
+		data, err := ExtractScriptData(makeResp("https://example.com/", html))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if _, ok := data["x"]; ok {
+			t.Errorf("%s: x = %v, want it skipped", tt.name, data["x"])
+		}
+		if got := fmt.Sprint(data["ok"]); got != "map[a:[[1]]]" {
+			t.Errorf("%s: ok = %s, want the next script parsed", tt.name, got)
+		}
+	}
+}
+
+func TestCompositeParserScriptDataRules(t *testing.T) {
+	p := NewCompositeParser(testLogger)
+	items, _, err := p.Parse(makeResp("https://shop.example.com/p/1", testScriptDataHTML), []config.ParseRule{
+		{Name: "name", Selector: "$.__NEXT_DATA__.props.pageProps.product.name", Type: "jsdata"},
+		{Name: "tags", Selector: "$.__NUXT__.data[0].tags[*]", Type: "jsdata"},
+	})
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	if v, _ := items[0].Get("name"); v != "Blue Widget" {
+		t.Errorf("name = %v", v)
+	}
+	if v, _ := items[0].Get("tags"); fmt.Sprint(v) != "[x y]" {
+		t.Errorf("tags = %v", v)
+	}
+}
+
+// --- Schema.org Entity Tests ---
+
+const testSchemaOrgHTML = `<html><head>
+<meta property="og:type" content="product">
+<meta property="og:title" content="Blue Widget | Shop">
+<meta property="og:description" content="A very blue widget.">
+<meta property="product:price:currency" content="EUR">
+<script type="application/ld+json">
+{"@context":"https://schema.org","@graph":[
+  {"@type":"Organization","@id":"https://shop.example.com/#org","name":"Widget Co","url":"https://shop.example.com/",
+   "address":{"@type":"PostalAddress","streetAddress":"1 Main St","addressLocality":"Springfield"}},
+  {"@type":"WebPage","breadcrumb":{"@type":"BreadcrumbList","itemListElement":[
+    {"@type":"ListItem","position":2,"name":"Widgets","item":"https://shop.example.com/widgets"},
+    {"@type":"ListItem","position":1,"name":"Home","item":{"@id":"https://shop.example.com/","name":"Home"}}]}},
+  {"@type":"Product","name":"Blue Widget","sku":"BW-1","brand":{"@id":"https://shop.example.com/#org"},
+   "aggregateRating":{"ratingValue":"4.5","reviewCount":"12"},
+   "offers":{"@type":"AggregateOffer","lowPrice":"9.99","highPrice":"1.299,00","priceCurrency":"EUR",
+     "offers":[{"@type":"Offer","price":"9.99","availability":"https://schema.org/InStock"},
+               {"@type":"Offer","price":"1.299,00","availability":"https://schema.org/OutOfStock"}]}}
+]}
+</script>
+<script type="application/ld+json">
+{"@context":"https://schema.org","@type":"Recipe","name":"Pancakes","totalTime":"PT1H15M",
+ "recipeIngredient":["2 eggs","1 cup flour"],
+ "recipeInstructions":[{"@type":"HowToSection","name":"Batter","itemListElement":[
+   {"@type":"HowToStep","text":"Whisk the eggs."},{"@type":"HowToStep","text":"Fold in flour."}]},
+   "Fry until golden."]}
+</script>
+<script type="application/ld+json">
+{"@context":"https://schema.org","@type":"JobPosting","title":"Go Developer",
+ "description":"<p>Build <b>crawlers</b>.</p><ul><li>Go</li></ul>","jobLocationType":"TELECOMMUTE",
+ "hiringOrganization":{"@type":"Organization","name":"Widget Co"},
+ "baseSalary":{"@type":"MonetaryAmount","currency":"USD","value":{"@type":"QuantitativeValue","minValue":90000,"maxValue":120000,"unitText":"YEAR"}}}
+</script>
+</head><body>
+<div itemscope itemtype="https://schema.org/Product">
+  <h1 itemprop="name">Blue Widget</h1>
+  <meta itemprop="gtin13" content="4006381333931">
+  <img itemprop="image" src="https://shop.example.com/bw.jpg">
+</div>
+<div vocab="https://schema.org/" typeof="MusicEvent">
+  <span property="name">Widget Fest</span>
+  <time property="startDate" datetime="2026-07-01T19:00">July 1</time>
+  <div property="location" typeof="Place"><span property="name">Town Hall</span>
+    <div property="address" typeof="PostalAddress"><span property="addressLocality">Springfield</span></div></div>
+</div>
+</body></html>`
+
+func TestExtractEntities(t *testing.T) {
+	sde := NewStructuredDataExtractor(testLogger)
+	entities, err := sde.ExtractEntities(makeResp("https://shop.example.com/p/bw", testSchemaOrgHTML))
+	if err != nil {
+		t.Fatalf("ExtractEntities error: %v", err)
+	}
+
+	byType := make(map[string]*Entity)
+	for _, e := range entities {
+		if byType[e.Type] != nil {
+			t.Errorf("duplicate %s entity: %+v", e.Type, e.Fields)
+		}
+		byType[e.Type] = e
+	}
+	if len(byType) != 6 {
+		t.Fatalf("expected 6 entity types, got %d: %v", len(byType), byType)
+	}
+
+	product := byType["Product"]
+	checks := map[string]any{
+		"name":         "Blue Widget",
+		"sku":          "BW-1",
+		"brand":        "Widget Co",           // @id reference resolved
+		"gtin":         "4006381333931",       // from microdata
+		"description":  "A very blue widget.", // from OpenGraph
+		"low_price":    9.99,
+		"high_price":   1299.0,
+		"currency":     "EUR",
+		"price":        9.99,
+		"availability": "InStock",
+		"rating":       4.5,
+		"rating_count": 12.0,
+	}
+	for k, want := range checks {
+		if got := product.Fields[k]; got != want {
+			t.Errorf("product %s = %v, want %v", k, got, want)
+		}
+	}
+	if offers, _ := product.Fields["offers"].([]map[string]any); len(offers) != 2 {
+		t.Errorf("expected 2 offers, got %v", product.Fields["offers"])
+	}
+	if fmt.Sprint(product.Sources) != "[json-ld microdata opengraph]" {
+		t.Errorf("product sources = %v", product.Sources)
+	}
+
+	if path := byType["BreadcrumbList"].Fields["path"]; path != "Home > Widgets" {
+		t.Errorf("breadcrumb path = %v", path)
+	}
+	if addr := byType["Organization"].Fields["address"]; addr != "1 Main St, Springfield" {
+		t.Errorf("organization address = %v", addr)
+	}
+
+	recipe := byType["Recipe"]
+	if steps := fmt.Sprint(recipe.Fields["instructions"]); steps != "[Whisk the eggs. Fold in flour. Fry until golden.]" {
+		t.Errorf("recipe instructions = %s", steps)
+	}
+	if m := recipe.Fields["total_time_minutes"]; m != 75.0 {
+		t.Errorf("total_time_minutes = %v", m)
+	}
+
+	job := byType["JobPosting"]
+	if job.Fields["description"] != "Build crawlers.\nGo" || job.Fields["remote"] != true ||
+		job.Fields["salary_max"] != 120000.0 || job.Fields["salary_unit"] != "YEAR" {
+		t.Errorf("unexpected job posting: %v", job.Fields)
+	}
+
+	event := byType["Event"]
+	if event.Subtype != "MusicEvent" || event.Fields["location_name"] != "Town Hall" ||
+		event.Fields["address"] != "Springfield" || event.Fields["start_date"] != "2026-07-01T19:00" {
+		t.Errorf("unexpected RDFa event: %+v", event)
+	}
+}
+
+func entityFields(entities []*Entity) []map[string]any {
+	var out []map[string]any
+	for _, e := range entities {
+		out = append(out, e.Fields)
+	}
+	return out
+}
+
+func TestExtractEntitiesCyclicReferences(t *testing.T) {
+	tests := []struct {
+		name  string
+		jsonl string
+		want  string
+	}{
+		{"self reference", `{"@id":"x","@type":"Product","name":{"@id":"x"},"sku":"S1"}`, "map[name:x sku:S1]"},
+		{
+			"reference cycle",
+			`[{"@id":"a","@type":"Product","name":"A","brand":{"@id":"b"}},{"@id":"b","@type":"Brand","name":{"@id":"a"}}]`,
+			"map[brand:a name:A]", // the back reference stays an id
+		},
+		{
+			"recipe section",
+			`{"@id":"r","@type":"Recipe","name":"R","recipeInstructions":[{"@id":"s","@type":"HowToSection","itemListElement":[{"@id":"s"},"Stir."]}]}`,
+			"map[instructions:[Stir.] name:R]",
+		},
+	}
+	sde := NewStructuredDataExtractor(testLogger)
+	for _, tt := range tests {
+		html := `<script type="application/ld+json">` + tt.jsonl + `</script>`
+		entities, err := sde.ExtractEntities(makeResp("https://example.com/", html))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(entities) != 1 || fmt.Sprint(entities[0].Fields) != tt.want {
+			t.Errorf("%s: entities = %v, want fields %s", tt.name, entityFields(entities), tt.want)
+		}
+	}
+}
+
+func TestCompositeParserSchemaOrg(t *testing.T) {
+	p := NewCompositeParser(testLogger)
+	p.SetSchemaOrg(true)
+	items, _, err := p.Parse(makeResp("https://shop.example.com/p/bw", testSchemaOrgHTML), nil)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	// One merged page item plus one item per entity
+	if len(items) != 7 {
+		t.Fatalf("expected 7 items, got %d", len(items))
+	}
+	if items[1].GetString("schema_type") == "" {
+		t.Errorf("entity item missing schema_type: %v", items[1].Fields)
+	}
+}
+
+// --- Conformance Tests ---
+
+func TestRunConformance(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(dir+"/"+name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("product.html", `<html><body><h1>Blue Widget</h1><span class="price">$10</span><a href="/next">n</a></body></html>`)
+	write("product.url", "https://shop.example.com/p/1\n")
+	write("listing.response.json", `{"url":"https://shop.example.com/list","content_type":"text/html","body":"<h1>All widgets</h1>"}`)
+
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		t.Fatalf("LoadFixtures error: %v", err)
+	}
+	if len(fixtures) != 2 || fixtures[0].Name != "listing" || fixtures[1].Response.Request.URLString() != "https://shop.example.com/p/1" {
+		t.Fatalf("unexpected fixtures: %+v", fixtures)
+	}
+
+	rules := []config.ParseRule{
+		{Name: "title", Selector: "h1", Type: "css"},
+		{Name: "price", Selector: ".price", Type: "css"},
+		{Name: "sku", Selector: ".sku", Type: "css"},
+	}
+	p := NewCompositeParser(testLogger)
+
+	report := RunConformance(p, rules, nil, fixtures, false)
+	if report.Failed() != 2 || !report.Results[0].NoGolden {
+		t.Fatalf("expected both fixtures to lack goldens: %+v", report.Results[0])
+	}
+
+	report = RunConformance(p, rules, nil, fixtures, true)
+	if report.Failed() != 0 {
+		t.Fatalf("update run failed: %+v", report.Results)
+	}
+	report = RunConformance(p, rules, nil, fixtures, false)
+	if report.Failed() != 0 {
+		t.Fatalf("expected goldens to match: %+v %+v", report.Results[0], report.Results[1])
+	}
+
+	wantCoverage := "[{ title 2 2} { price 1 2} { sku 0 2}]"
+	if got := fmt.Sprint(report.Coverage); got != wantCoverage {
+		t.Errorf("coverage = %s, want %s", got, wantCoverage)
+	}
+
+	// A site change shows up as a per-field diff
+	write("product.html", `<html><body><h1>Blue Widget</h1><span class="cost">$10</span></body></html>`)
+	fixtures, _ = LoadFixtures(dir)
+	report = RunConformance(p, rules, nil, fixtures, false)
+	res := report.Results[1]
+	if res.Passed() || len(res.Diffs) != 1 || res.Diffs[0].String() != `item[0].price: missing, want "$10"` {
+		t.Errorf("unexpected diffs: %v", res.Diffs)
+	}
+
+	// Fixtures naming a rule set are parsed with it, and coverage is
+	// counted per set.
+	write("offer.html", `<html><body><h1>Offer</h1><span class="sku">W-1</span></body></html>`)
+	write("offer.rule_set", "Offers\n")
+	write("stray.response.json", `{"url":"https://shop.example.com/x","body":"<p>x</p>","rule_set":"gone"}`)
+	fixtures, _ = LoadFixtures(dir)
+	ruleSets := map[string][]config.ParseRule{"offers": {{Name: "sku", Selector: ".sku", Type: "css"}}}
+	report = RunConformance(p, rules, ruleSets, fixtures, true)
+	if len(fixtures) != 4 || fixtures[1].Response.Request.Meta[types.MetaRuleSet] != "Offers" {
+		t.Fatalf("offer fixture = %+v", fixtures[1])
+	}
+	if offer := report.Results[1]; offer.Error != "" || offer.Items != 1 {
+		t.Errorf("offer result = %+v", offer)
+	}
+	if stray := report.Results[3]; stray.Error != `unknown rule set "gone"` {
+		t.Errorf("stray result = %+v", stray)
+	}
+	wantCoverage = "[{ title 2 2} { price 0 2} { sku 0 2} {offers sku 1 1}]"
+	if got := fmt.Sprint(report.Coverage); got != wantCoverage {
+		t.Errorf("coverage = %s, want %s", got, wantCoverage)
+	}
+}
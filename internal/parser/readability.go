@@ -0,0 +1,495 @@
+package parser
+
+import (
+	"log/slog"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// Article is the main content of a page with boilerplate removed.
+type Article struct {
+	Title     string    `json:"title"`
+	Byline    string    `json:"byline,omitempty"`
+	Published time.Time `json:"published,omitempty"`
+	LeadImage string    `json:"lead_image,omitempty"`
+	Excerpt   string    `json:"excerpt,omitempty"`
+	HTML      string    `json:"html"`
+	Text      string    `json:"text"`
+	WordCount int       `json:"word_count"`
+}
+
+// ReadabilityParser handles "readability" rules. The rule's Attribute picks
+// the article field to extract: text (default), html, title, byline,
+// published, image or excerpt.
+type ReadabilityParser struct {
+	logger *slog.Logger
+}
+
+// NewReadabilityParser creates a new main-content parser.
+func NewReadabilityParser(logger *slog.Logger) *ReadabilityParser {
+	return &ReadabilityParser{
+		logger: logger.With("component", "readability_parser"),
+	}
+}
+
+// Parse implements Parser.
+func (p *ReadabilityParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	if len(rules) == 0 {
+		return nil, nil, nil
+	}
+
+	article, err := ExtractArticle(resp)
+	if err != nil {
+		return nil, nil, &types.ParseError{
+			URL: resp.Request.URLString(),
+			Err: err,
+		}
+	}
+
+	item := types.NewItem(resp.Request.URLString())
+	for _, rule := range rules {
+		if rule.Type != "readability" {
+			continue
+		}
+		if val := article.Field(rule.Attribute); val != "" {
+			item.Set(rule.Name, val)
+		}
+	}
+
+	if len(item.Fields) == 0 {
+		return nil, nil, nil
+	}
+	return []*types.Item{item}, nil, nil
+}
+
+// Field returns an article field by name, as used by readability rules.
+func (a *Article) Field(name string) string {
+	switch name {
+	case "", "text":
+		return a.Text
+	case "html":
+		return a.HTML
+	case "title":
+		return a.Title
+	case "byline", "author":
+		return a.Byline
+	case "published", "date":
+		if a.Published.IsZero() {
+			return ""
+		}
+		return a.Published.Format(time.RFC3339)
+	case "image", "lead_image":
+		return a.LeadImage
+	case "excerpt":
+		return a.Excerpt
+	default:
+		return ""
+	}
+}
+
+// ExtractArticle finds the main content of an HTML response. The response's
+// cached document is not modified.
+func ExtractArticle(resp *types.Response) (*Article, error) {
+	doc, err := resp.Document()
+	if err != nil {
+		return nil, err
+	}
+	base := resp.FinalURL
+	if base == "" {
+		base = resp.Request.URLString()
+	}
+	return ExtractArticleFromDocument(doc, base), nil
+}
+
+// ExtractArticleFromDocument finds the main content of a parsed document.
+// baseURL is used to resolve the lead image.
+func ExtractArticleFromDocument(doc *goquery.Document, baseURL string) *Article {
+	base, _ := url.Parse(baseURL)
+
+	article := &Article{
+		Title:     articleTitle(doc),
+		Byline:    articleByline(doc),
+		Published: articlePublished(doc),
+		Excerpt:   strings.TrimSpace(metaContent(doc, "meta[property='og:description']", "meta[name='description']")),
+	}
+
+	content := extractContent(doc)
+	article.HTML, _ = content.Html()
+	article.HTML = strings.TrimSpace(article.HTML)
+	article.Text = blockText(content)
+	article.WordCount = len(strings.Fields(article.Text))
+
+	if article.Excerpt == "" {
+		content.Find("p").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			article.Excerpt = normalizeSpace(s.Text())
+			return article.Excerpt == ""
+		})
+	}
+
+	image := metaContent(doc, "meta[property='og:image']", "meta[name='twitter:image']")
+	if image == "" {
+		image, _ = doc.Find("link[rel='image_src']").Attr("href")
+	}
+	if image == "" {
+		img := content.Find("img").First()
+		image = firstNonEmpty(img.AttrOr("src", ""), img.AttrOr("data-src", ""))
+	}
+	article.LeadImage = resolveFeedLink(base, strings.TrimSpace(image))
+
+	return article
+}
+
+// --- Content scoring ---
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|consent|cookie|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|modal|newsletter|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|yom-remote`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveWeight     = regexp.MustCompile(`(?i)article|blog|body|content|entry|hentry|h-entry|main|page|post|story|text`)
+	negativeWeight     = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|consent|contact|cookie|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// boilerplateSelector matches elements that never hold article content.
+const boilerplateSelector = "script, style, noscript, template, iframe, svg, canvas, form, button, input, select, textarea, " +
+	"nav, header, footer, aside, dialog, [role='navigation'], [role='banner'], [role='contentinfo'], " +
+	"[role='complementary'], [role='dialog'], [aria-hidden='true'], [hidden]"
+
+// blockSelector matches elements that break a div out of "paragraph" mode.
+const blockSelector = "a blockquote dl div img ol p pre table ul section article"
+
+// extractContent scores candidate containers Readability-style and returns
+// a cleaned wrapper around the best one and its related siblings.
+func extractContent(doc *goquery.Document) *goquery.Selection {
+	work := goquery.NewDocumentFromNode(doc.Selection.Clone().Nodes[0])
+	body := work.Find("body")
+	if body.Length() == 0 {
+		body = work.Selection
+	}
+
+	body.Find(boilerplateSelector).Remove()
+	body.Find("*").Each(func(_ int, s *goquery.Selection) {
+		switch goquery.NodeName(s) {
+		case "html", "body", "article", "main":
+			return
+		}
+		match := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match) {
+			s.Remove()
+		}
+	})
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+
+	body.Find("p, pre, td, blockquote, div").Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "div" && s.Children().FilterFunction(func(_ int, c *goquery.Selection) bool {
+			return strings.Contains(" "+blockSelector+" ", " "+goquery.NodeName(c)+" ")
+		}).Length() > 0 {
+			return
+		}
+
+		text := normalizeSpace(s.Text())
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，"))
+		score += math.Min(float64(len(text))/100, 3)
+
+		for level, ancestor := range s.Parents().Slice(0, min(3, s.Parents().Length())).Nodes {
+			if ancestor.Type != html.ElementNode || ancestor.Data == "html" {
+				break
+			}
+			if _, ok := scores[ancestor]; !ok {
+				scores[ancestor] = initialScore(ancestor)
+				candidates = append(candidates, ancestor)
+			}
+			divider := []float64{1, 2, 6}[level]
+			scores[ancestor] += score / divider
+		}
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(goquery.NewDocumentFromNode(n).Selection)
+		if top == nil || scores[n] > topScore {
+			top, topScore = n, scores[n]
+		}
+	}
+
+	wrapper, _ := goquery.NewDocumentFromReader(strings.NewReader("<div></div>"))
+	container := wrapper.Find("div").First()
+	if top == nil {
+		container.AppendSelection(body.Contents())
+		cleanContent(container)
+		return container
+	}
+
+	// Pull in siblings that look like part of the same article.
+	threshold := math.Max(10, topScore*0.2)
+	topClass := attr(top, "class")
+	topSel := goquery.NewDocumentFromNode(top).Selection
+	siblings := topSel.Parent().Children()
+	if top.Parent == nil || top.Parent.Type == html.DocumentNode {
+		siblings = topSel
+	}
+	var keep []*html.Node
+	siblings.Each(func(_ int, s *goquery.Selection) {
+		n := s.Nodes[0]
+		if n == top {
+			keep = append(keep, n)
+			return
+		}
+		bonus := 0.0
+		if topClass != "" && attr(n, "class") == topClass {
+			bonus = topScore * 0.2
+		}
+		if score, ok := scores[n]; ok && score+bonus >= threshold {
+			keep = append(keep, n)
+			return
+		}
+		if n.Data == "p" {
+			text := normalizeSpace(s.Text())
+			density := linkDensity(s)
+			if (len(text) > 80 && density < 0.25) ||
+				(len(text) > 0 && density == 0 && strings.Contains(text, ". ")) {
+				keep = append(keep, n)
+			}
+		}
+	})
+	for _, n := range keep {
+		n.Parent.RemoveChild(n)
+		container.Nodes[0].AppendChild(n)
+	}
+
+	cleanContent(container)
+	return container
+}
+
+// initialScore seeds a candidate's score from its tag and class/id weight.
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.Data {
+	case "article", "main":
+		score += 10
+	case "div", "section":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	return score
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, v := range []string{attr(n, "class"), attr(n, "id")} {
+		if v == "" {
+			continue
+		}
+		if negativeWeight.MatchString(v) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of a selection's text that sits inside links.
+func linkDensity(s *goquery.Selection) float64 {
+	total := len(normalizeSpace(s.Text()))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linked += len(normalizeSpace(a.Text()))
+	})
+	return float64(linked) / float64(total)
+}
+
+// cleanContent drops link farms, negatively weighted and empty containers
+// from the extracted content, innermost first.
+func cleanContent(content *goquery.Selection) {
+	nodes := content.Find("div, section, ul, ol, table").Nodes
+	for i := len(nodes) - 1; i >= 0; i-- {
+		n := nodes[i]
+		if n.Parent == nil {
+			continue
+		}
+		s := goquery.NewDocumentFromNode(n).Selection
+		text := normalizeSpace(s.Text())
+		hasMedia := s.Find("img, picture, video, pre, code").Length() > 0
+		if classWeight(n) < 0 ||
+			(linkDensity(s) > 0.5 && classWeight(n) < 25) ||
+			(text == "" && !hasMedia) {
+			n.Parent.RemoveChild(n)
+		}
+	}
+	content.Find("[style]").RemoveAttr("style")
+}
+
+// --- Metadata ---
+
+var titleSeparators = []string{" | ", " - ", " – ", " — ", " :: ", " » "}
+
+func articleTitle(doc *goquery.Document) string {
+	if og := metaContent(doc, "meta[property='og:title']", "meta[name='twitter:title']"); og != "" {
+		return normalizeSpace(og)
+	}
+
+	title := normalizeSpace(doc.Find("title").First().Text())
+	h1 := normalizeSpace(doc.Find("h1").First().Text())
+	if h1 != "" && strings.Contains(title, h1) {
+		return h1
+	}
+	for _, sep := range titleSeparators {
+		if i := strings.LastIndex(title, sep); i > 0 && len(strings.Fields(title[:i])) >= 2 {
+			title = title[:i]
+			break
+		}
+	}
+	return firstNonEmpty(title, h1)
+}
+
+var bylinePrefix = regexp.MustCompile(`(?i)^(by|written by|posted by|author:)\s+`)
+
+func articleByline(doc *goquery.Document) string {
+	byline := metaContent(doc, "meta[name='author']", "meta[property='article:author']", "meta[name='dc.creator']")
+	if strings.HasPrefix(byline, "http") {
+		byline = ""
+	}
+	if byline == "" {
+		for _, sel := range []string{"[itemprop='author'] [itemprop='name']", "[itemprop='author']", "[rel='author']", ".byline", ".author", ".post-author"} {
+			s := doc.Find(sel).First()
+			if s.Length() == 0 {
+				continue
+			}
+			if v := normalizeSpace(firstNonEmpty(s.AttrOr("content", ""), s.Text())); v != "" && len(v) < 100 {
+				byline = v
+				break
+			}
+		}
+	}
+	return bylinePrefix.ReplaceAllString(strings.TrimSpace(byline), "")
+}
+
+var jsonLDDatePublished = regexp.MustCompile(`"datePublished"\s*:\s*"([^"]+)"`)
+
+func articlePublished(doc *goquery.Document) time.Time {
+	raw := metaContent(doc,
+		"meta[property='article:published_time']",
+		"meta[itemprop='datePublished']",
+		"meta[name='pubdate']",
+		"meta[name='publishdate']",
+		"meta[name='date']",
+		"meta[name='dc.date']",
+		"meta[name='DC.date.issued']",
+	)
+	if raw == "" {
+		s := doc.Find("[itemprop='datePublished']").First()
+		raw = firstNonEmpty(s.AttrOr("datetime", ""), s.AttrOr("content", ""))
+	}
+	if raw == "" {
+		doc.Find("script[type='application/ld+json']").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if m := jsonLDDatePublished.FindStringSubmatch(s.Text()); m != nil {
+				raw = m[1]
+			}
+			return raw == ""
+		})
+	}
+	if raw == "" {
+		raw = doc.Find("time[datetime]").First().AttrOr("datetime", "")
+	}
+	return parseFeedDate(raw)
+}
+
+// metaContent returns the content attribute of the first matching selector.
+func metaContent(doc *goquery.Document, selectors ...string) string {
+	for _, sel := range selectors {
+		if v, ok := doc.Find(sel).First().Attr("content"); ok && strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// --- Text helpers ---
+
+var blockElements = map[string]bool{
+	"address": true, "article": true, "blockquote": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "hr": true, "li": true, "main": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+// blockText renders a selection as plain text with one paragraph per block.
+func blockText(s *goquery.Selection) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if n.Data == "br" {
+				sb.WriteByte('\n')
+				return
+			}
+		}
+		block := n.Type == html.ElementNode && blockElements[n.Data]
+		if block {
+			sb.WriteString("\n\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			sb.WriteString("\n\n")
+		}
+	}
+	for _, n := range s.Nodes {
+		walk(n)
+	}
+
+	var paragraphs []string
+	for _, block := range strings.Split(sb.String(), "\n\n") {
+		var lines []string
+		for _, line := range strings.Split(block, "\n") {
+			if line = normalizeSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
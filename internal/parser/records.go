@@ -0,0 +1,471 @@
+package parser
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// RecordField is a field inferred for a set of repeated records.
+type RecordField struct {
+	Name      string  `json:"name"`
+	Selector  string  `json:"selector"`            // relative to the record element; empty for the record itself
+	Attribute string  `json:"attribute,omitempty"` // empty for text
+	Coverage  float64 `json:"coverage"`            // share of records that have the field
+}
+
+// RecordSet is a group of repeated sibling elements (product cards, search
+// results, table rows) detected on a page.
+type RecordSet struct {
+	Selector string        `json:"selector"` // matches every record element
+	Count    int           `json:"count"`
+	Score    float64       `json:"score"`
+	Fields   []RecordField `json:"fields"`
+	Items    []*types.Item `json:"-"`
+
+	nodes []*html.Node
+}
+
+// Rules returns CSS parse rules that reproduce the detected fields. Like any
+// CSS rule, each rule yields one value per matching record.
+func (rs *RecordSet) Rules() []config.ParseRule {
+	rules := make([]config.ParseRule, 0, len(rs.Fields))
+	for _, f := range rs.Fields {
+		selector := rs.Selector
+		if f.Selector != "" {
+			selector += " > " + f.Selector
+		}
+		rules = append(rules, config.ParseRule{
+			Name:      f.Name,
+			Selector:  selector,
+			Type:      "css",
+			Attribute: f.Attribute,
+		})
+	}
+	return rules
+}
+
+// RecordDetector finds repeated record structures on pages without rules.
+type RecordDetector struct {
+	logger      *slog.Logger
+	minRecords  int
+	minCoverage float64
+}
+
+// NewRecordDetector creates a record detector that needs at least three
+// similar siblings and keeps fields present in at least half of them.
+func NewRecordDetector(logger *slog.Logger) *RecordDetector {
+	return &RecordDetector{
+		logger:      logger.With("component", "record_detector"),
+		minRecords:  3,
+		minCoverage: 0.5,
+	}
+}
+
+// Detect returns the highest scoring record set, or nil if none was found.
+func (d *RecordDetector) Detect(resp *types.Response) (*RecordSet, error) {
+	sets, err := d.DetectAll(resp, 1)
+	if err != nil || len(sets) == 0 {
+		return nil, err
+	}
+	return sets[0], nil
+}
+
+// DetectAll returns up to limit non-overlapping record sets, best first.
+// A limit of zero returns all of them.
+func (d *RecordDetector) DetectAll(resp *types.Response, limit int) ([]*RecordSet, error) {
+	doc, err := resp.Document()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*RecordSet
+	doc.Find("body, body *").Each(func(_ int, parent *goquery.Selection) {
+		if mdSkip[goquery.NodeName(parent)] || parent.Closest("nav, header, footer, aside").Length() > 0 {
+			return
+		}
+
+		groups := make(map[string][]*html.Node)
+		var order []string
+		for c := parent.Nodes[0].FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || mdSkip[c.Data] {
+				continue
+			}
+			sig := nodeSegment(c, false)
+			if _, ok := groups[sig]; !ok {
+				order = append(order, sig)
+			}
+			groups[sig] = append(groups[sig], c)
+		}
+
+		for _, sig := range order {
+			if members := groups[sig]; len(members) >= d.minRecords {
+				if rs := d.analyze(parent, sig, members); rs != nil {
+					candidates = append(candidates, rs)
+				}
+			}
+		}
+	})
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	var sets []*RecordSet
+	for _, rs := range candidates {
+		if limit > 0 && len(sets) >= limit {
+			break
+		}
+		overlaps := false
+		for _, chosen := range sets {
+			if nodesOverlap(rs.nodes, chosen.nodes) {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		d.buildItems(rs, resp.Request.URLString())
+		sets = append(sets, rs)
+		d.logger.Debug("records detected",
+			"url", resp.Request.URLString(), "selector", rs.Selector,
+			"count", rs.Count, "fields", len(rs.Fields), "score", rs.Score)
+	}
+	return sets, nil
+}
+
+// recordFieldStat collects one candidate field across all records.
+type recordFieldStat struct {
+	path    string
+	attr    string
+	node    *html.Node // first occurrence, used for naming
+	values  []string   // per record; empty when missing
+	present int
+}
+
+// analyze infers the stable fields of a sibling group and scores it.
+func (d *RecordDetector) analyze(parent *goquery.Selection, sig string, members []*html.Node) *RecordSet {
+	stats := make(map[string]*recordFieldStat)
+	var order []string
+
+	for i, m := range members {
+		walkRecordFields(m, "", func(path, attr, value string, n *html.Node) {
+			key := path + "|" + attr
+			st, ok := stats[key]
+			if !ok {
+				st = &recordFieldStat{path: path, attr: attr, node: n, values: make([]string, len(members))}
+				stats[key] = st
+				order = append(order, key)
+			}
+			if st.values[i] == "" && value != "" {
+				st.values[i] = value
+				st.present++
+			}
+		})
+	}
+
+	minPresent := max(2, int(math.Ceil(d.minCoverage*float64(len(members)))))
+	var stable []*recordFieldStat
+	paths := make(map[string]bool)
+	textLen := 0
+	for _, key := range order {
+		st := stats[key]
+		if st.present < minPresent || distinctCount(st.values) < 2 {
+			continue // too sparse, or a constant label such as "Add to cart"
+		}
+		stable = append(stable, st)
+		paths[st.path] = true
+		if st.attr == "" {
+			for _, v := range st.values {
+				textLen += len(v)
+			}
+		}
+	}
+	if len(stable) == 0 {
+		return nil
+	}
+
+	avgText := float64(textLen) / float64(len(members))
+	rs := &RecordSet{
+		Selector: recordContainerSelector(parent, sig),
+		Count:    len(members),
+		Score:    float64(len(members)) * float64(len(paths)) * math.Log2(2+avgText),
+		nodes:    members,
+	}
+
+	headers := tableHeaders(members[0])
+	names := make(map[string]int)
+	for _, st := range stable {
+		name := recordFieldName(st, headers)
+		names[name]++
+		if names[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, names[name])
+		}
+		rs.Fields = append(rs.Fields, RecordField{
+			Name:      name,
+			Selector:  st.path,
+			Attribute: st.attr,
+			Coverage:  float64(st.present) / float64(len(members)),
+		})
+	}
+	return rs
+}
+
+// buildItems extracts one item per record using the inferred fields.
+func (d *RecordDetector) buildItems(rs *RecordSet, pageURL string) {
+	for _, n := range rs.nodes {
+		record := goquery.NewDocumentFromNode(n).Selection
+		item := types.NewItem(pageURL)
+		for _, f := range rs.Fields {
+			sel := record
+			if f.Selector != "" {
+				for _, seg := range strings.Split(f.Selector, " > ") {
+					sel = sel.ChildrenFiltered(seg)
+				}
+			}
+			sel = sel.First()
+			if sel.Length() == 0 {
+				continue
+			}
+			var val string
+			switch f.Attribute {
+			case "":
+				val = normalizeSpace(textContent(sel.Nodes[0]))
+			case "src":
+				val = firstNonEmpty(sel.AttrOr("src", ""), sel.AttrOr("data-src", ""))
+			default:
+				val = sel.AttrOr(f.Attribute, "")
+			}
+			if val != "" {
+				item.Set(f.Name, val)
+			}
+		}
+		if len(item.Fields) > 0 {
+			rs.Items = append(rs.Items, item)
+		}
+	}
+}
+
+// walkRecordFields reports the text, link and image values under n with
+// their selector path relative to the record.
+func walkRecordFields(n *html.Node, path string, emit func(path, attr, value string, n *html.Node)) {
+	if hasDirectText(n) {
+		emit(path, "", normalizeSpace(textContent(n)), n)
+	}
+	switch n.Data {
+	case "a":
+		if href := strings.TrimSpace(attr(n, "href")); href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(href, "javascript:") {
+			emit(path, "href", href, n)
+		}
+	case "img":
+		if src := firstNonEmpty(attr(n, "src"), attr(n, "data-src")); src != "" {
+			emit(path, "src", strings.TrimSpace(src), n)
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || mdSkip[c.Data] {
+			continue
+		}
+		seg := nodeSegment(c, true)
+		if path != "" {
+			seg = path + " > " + seg
+		}
+		walkRecordFields(c, seg, emit)
+	}
+}
+
+// nodeSegment renders n as tag plus first class. With position set, it adds
+// :nth-of-type when a sibling shares the same tag and class, so cells in a
+// row or repeated spans get distinct, stable paths.
+func nodeSegment(n *html.Node, position bool) string {
+	seg := n.Data
+	if classes := strings.Fields(attr(n, "class")); len(classes) > 0 {
+		seg += "." + cssEscape(classes[0])
+	}
+	if !position || n.Parent == nil {
+		return seg
+	}
+
+	index, same := 0, 0
+	for s := n.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s.Type != html.ElementNode || s.Data != n.Data {
+			continue
+		}
+		index++
+		if s == n {
+			break
+		}
+	}
+	for s := n.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode && nodeSegment(s, false) == nodeSegment(n, false) {
+			same++
+		}
+	}
+	if same > 1 {
+		seg += fmt.Sprintf(":nth-of-type(%d)", index)
+	}
+	return seg
+}
+
+func hasDirectText(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode && strings.TrimSpace(c.Data) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func recordContainerSelector(parent *goquery.Selection, sig string) string {
+	path := buildElementPath(parent, 3)
+	if path == "" {
+		path = goquery.NodeName(parent)
+	}
+	return path + " > " + sig
+}
+
+// tableHeaders returns the header cell texts of the table a row belongs to.
+func tableHeaders(row *html.Node) []string {
+	if row.Data != "tr" {
+		return nil
+	}
+	table := goquery.NewDocumentFromNode(row).Selection.Closest("table")
+	var headers []string
+	table.Find("tr").First().Children().Each(func(_ int, cell *goquery.Selection) {
+		headers = append(headers, normalizeSpace(cell.Text()))
+	})
+	return headers
+}
+
+var (
+	priceValue     = regexp.MustCompile(`^[^\d]{0,4}\d[\d.,\s]*[^\d]{0,4}$`)
+	currencySymbol = regexp.MustCompile(`[$€£¥₹]|\b(USD|EUR|GBP|INR|JPY)\b`)
+	nonIdentChars  = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// recordFieldName picks a field name from table headers, the element's class
+// or its tag and content.
+func recordFieldName(st *recordFieldStat, headers []string) string {
+	n := st.node
+	suffix := ""
+	switch st.attr {
+	case "href":
+		suffix = "_url"
+	case "src":
+		suffix = "_image"
+	}
+
+	var base string
+	if len(headers) > 0 && (n.Data == "td" || n.Data == "th") && n.Parent != nil && n.Parent.Data == "tr" {
+		col := 0
+		for s := n.Parent.FirstChild; s != nil && s != n; s = s.NextSibling {
+			if s.Type == html.ElementNode {
+				col++
+			}
+		}
+		if col < len(headers) {
+			base = identifier(headers[col])
+		}
+	}
+	if base == "" {
+		if classes := strings.Fields(attr(n, "class")); len(classes) > 0 {
+			class := classes[0]
+			if i := strings.LastIndex(class, "__"); i >= 0 {
+				class = class[i+2:] // BEM element name
+			}
+			base = identifier(class)
+		}
+	}
+	if base != "" {
+		return base + suffix
+	}
+
+	switch {
+	case st.attr == "href":
+		return "url"
+	case st.attr == "src":
+		return "image"
+	case len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6':
+		return "title"
+	case n.Data == "time":
+		return "date"
+	case n.Data == "a":
+		return "link_text"
+	case n.Data == "p":
+		return "description"
+	case looksLikePrice(st.values):
+		return "price"
+	default:
+		return "text"
+	}
+}
+
+func looksLikePrice(values []string) bool {
+	matched, total := 0, 0
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		total++
+		if priceValue.MatchString(v) && currencySymbol.MatchString(v) {
+			matched++
+		}
+	}
+	return total > 0 && matched*2 > total
+}
+
+func identifier(s string) string {
+	return strings.Trim(nonIdentChars.ReplaceAllString(strings.ToLower(s), "_"), "_")
+}
+
+func distinctCount(values []string) int {
+	seen := make(map[string]bool)
+	for _, v := range values {
+		if v != "" {
+			seen[v] = true
+		}
+	}
+	return len(seen)
+}
+
+// nodesOverlap reports whether any node in a contains or is inside any node in b.
+func nodesOverlap(a, b []*html.Node) bool {
+	inside := func(n *html.Node, set map[*html.Node]bool) bool {
+		for p := n; p != nil; p = p.Parent {
+			if set[p] {
+				return true
+			}
+		}
+		return false
+	}
+	setA := make(map[*html.Node]bool, len(a))
+	for _, n := range a {
+		setA[n] = true
+	}
+	setB := make(map[*html.Node]bool, len(b))
+	for _, n := range b {
+		setB[n] = true
+	}
+	for _, n := range a {
+		if inside(n, setB) {
+			return true
+		}
+	}
+	for _, n := range b {
+		if inside(n, setA) {
+			return true
+		}
+	}
+	return false
+}
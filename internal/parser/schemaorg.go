@@ -0,0 +1,929 @@
+package parser
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// Entity is a schema.org entity found on a page, normalised to a fixed set of
+// fields for its type and reconciled across JSON-LD, microdata, RDFa,
+// OpenGraph, Twitter cards and meta tags.
+type Entity struct {
+	Type    string               // Product, Article, Recipe, Event, JobPosting, Organization or BreadcrumbList
+	Subtype string               // the specific schema.org type, e.g. NewsArticle, when it differs
+	ID      string               // @id, itemid or resource, if given
+	Fields  map[string]any       // normalised fields, see the normalise* functions
+	Sources []StructuredDataType // formats that contributed, in order of precedence
+}
+
+// ToItem converts the entity to an item with schema_type, schema_subtype,
+// schema_id and sources alongside its fields.
+func (e *Entity) ToItem(sourceURL string) *types.Item {
+	item := types.NewItem(sourceURL)
+	item.Set("schema_type", e.Type)
+	if e.Subtype != "" {
+		item.Set("schema_subtype", e.Subtype)
+	}
+	if e.ID != "" {
+		item.Set("schema_id", e.ID)
+	}
+	for k, v := range e.Fields {
+		item.Set(k, v)
+	}
+	sources := make([]string, len(e.Sources))
+	for i, s := range e.Sources {
+		sources[i] = string(s)
+	}
+	item.Set("sources", sources)
+	return item
+}
+
+// ExtractEntities returns the schema.org entities described on a page, one
+// per real-world thing. JSON-LD @graph members and @id references are
+// resolved; the same entity marked up in several formats is merged, with
+// JSON-LD taking precedence over microdata, RDFa and then OpenGraph, Twitter
+// and meta tags, which only fill in missing fields.
+func (sde *StructuredDataExtractor) ExtractEntities(resp *types.Response) ([]*Entity, error) {
+	doc, err := resp.Document()
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []sourcedNode
+	for _, sd := range sde.extractJSONLD(doc) {
+		nodes = append(nodes, sourcedNode{JSONLD, sd.Data})
+	}
+	for _, sd := range sde.extractMicrodataItems(doc) {
+		nodes = append(nodes, sourcedNode{Microdata, sd.Data})
+	}
+	for _, sd := range sde.extractRDFa(doc) {
+		nodes = append(nodes, sourcedNode{RDFa, sd.Data})
+	}
+
+	// Index @id'd nodes so references like {"@id": "#org"} can be followed.
+	ids := make(map[string]map[string]any)
+	for _, n := range nodes {
+		indexNodeIDs(n.data, ids)
+	}
+
+	var entities []*Entity
+	for _, n := range nodes {
+		for _, node := range collectEntityNodes(n.data, nil) {
+			if e := newEntity(node, n.source, ids); e != nil {
+				entities = mergeEntity(entities, e)
+			}
+		}
+	}
+
+	for _, e := range metaEntities(doc) {
+		entities = fillFromMeta(entities, e)
+	}
+	return entities, nil
+}
+
+// EntitiesToItems converts entities to one item each.
+func EntitiesToItems(entities []*Entity, sourceURL string) []*types.Item {
+	items := make([]*types.Item, 0, len(entities))
+	for _, e := range entities {
+		items = append(items, e.ToItem(sourceURL))
+	}
+	return items
+}
+
+type sourcedNode struct {
+	source StructuredDataType
+	data   map[string]any
+}
+
+// --- Entity types ---
+
+var entityTypes = map[string]string{
+	"Product": "Product", "ProductGroup": "Product", "ProductModel": "Product",
+	"IndividualProduct": "Product", "Vehicle": "Product", "Car": "Product",
+
+	"Article": "Article", "NewsArticle": "Article", "BlogPosting": "Article",
+	"Report": "Article", "ScholarlyArticle": "Article", "TechArticle": "Article",
+	"AnalysisNewsArticle": "Article", "OpinionNewsArticle": "Article",
+	"ReportageNewsArticle": "Article", "LiveBlogPosting": "Article",
+	"SocialMediaPosting": "Article", "DiscussionForumPosting": "Article",
+
+	"Recipe": "Recipe",
+
+	"JobPosting": "JobPosting",
+
+	"Organization": "Organization", "Corporation": "Organization", "NGO": "Organization",
+	"LocalBusiness": "Organization", "OnlineBusiness": "Organization", "OnlineStore": "Organization",
+	"Store": "Organization", "Restaurant": "Organization", "Hotel": "Organization",
+	"NewsMediaOrganization": "Organization", "EducationalOrganization": "Organization",
+	"GovernmentOrganization": "Organization", "MedicalOrganization": "Organization",
+	"SportsOrganization": "Organization", "Airline": "Organization",
+
+	"BreadcrumbList": "BreadcrumbList",
+}
+
+// entityType maps a node's @type to one of the supported entity types.
+func entityType(node map[string]any) (canonical, specific string) {
+	for _, t := range stringValues(node["@type"]) {
+		t = propertyName(t)
+		if c, ok := entityTypes[t]; ok {
+			return c, t
+		}
+		if strings.HasSuffix(t, "Event") {
+			return "Event", t
+		}
+		if strings.HasSuffix(t, "Organization") || strings.HasSuffix(t, "Business") {
+			return "Organization", t
+		}
+	}
+	return "", ""
+}
+
+// collectEntityNodes finds the nodes of supported types: top-level nodes,
+// @graph members and nodes nested in unsupported containers (a WebPage's
+// breadcrumb, an ItemList's items). Nodes nested in a supported entity
+// belong to that entity and are not collected separately.
+func collectEntityNodes(v any, out []map[string]any) []map[string]any {
+	switch v := v.(type) {
+	case map[string]any:
+		if t, _ := entityType(v); t != "" {
+			return append(out, v)
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = collectEntityNodes(v[k], out)
+		}
+	case []any:
+		for _, e := range v {
+			out = collectEntityNodes(e, out)
+		}
+	}
+	return out
+}
+
+func indexNodeIDs(v any, ids map[string]map[string]any) {
+	switch v := v.(type) {
+	case map[string]any:
+		if id, ok := v["@id"].(string); ok && len(v) > 1 {
+			if _, seen := ids[id]; !seen {
+				ids[id] = v
+			}
+		}
+		for _, e := range v {
+			indexNodeIDs(e, ids)
+		}
+	case []any:
+		for _, e := range v {
+			indexNodeIDs(e, ids)
+		}
+	}
+}
+
+func newEntity(node map[string]any, source StructuredDataType, ids map[string]map[string]any) *Entity {
+	typ, specific := entityType(node)
+	n := newNodeView(node, ids)
+
+	var fields map[string]any
+	switch typ {
+	case "Product":
+		fields = normaliseProduct(n)
+	case "Article":
+		fields = normaliseArticle(n)
+	case "Recipe":
+		fields = normaliseRecipe(n)
+	case "Event":
+		fields = normaliseEvent(n)
+	case "JobPosting":
+		fields = normaliseJobPosting(n)
+	case "Organization":
+		fields = normaliseOrganization(n)
+	case "BreadcrumbList":
+		fields = normaliseBreadcrumbs(n)
+	}
+	for k, v := range fields {
+		if isEmptyValue(v) {
+			delete(fields, k)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	e := &Entity{Type: typ, ID: n.str("@id"), Fields: fields, Sources: []StructuredDataType{source}}
+	if specific != typ {
+		e.Subtype = specific
+	}
+	return e
+}
+
+// --- Reconciliation ---
+
+// sameEntity reports whether two entities describe the same real-world
+// thing, so a product marked up in both JSON-LD and microdata is merged
+// rather than emitted twice. It compares ids, then the first identifying
+// field both carry.
+func sameEntity(a, b *Entity) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	if a.ID != "" && a.ID == b.ID {
+		return true
+	}
+	if a.Type == "BreadcrumbList" {
+		return true // one trail per page
+	}
+	if a.Type == "Event" {
+		da, db := dateOnly(a.Fields["start_date"]), dateOnly(b.Fields["start_date"])
+		if da != "" && db != "" && da != db {
+			return false
+		}
+	}
+	for _, f := range []string{"sku", "gtin", "name", "headline", "title"} {
+		va, _ := a.Fields[f].(string)
+		vb, _ := b.Fields[f].(string)
+		if va != "" && vb != "" {
+			return strings.EqualFold(va, vb)
+		}
+	}
+	return false
+}
+
+// mergeEntity adds e to entities, merging it into an existing entity for the
+// same thing. Fields already present win, so earlier sources take precedence.
+func mergeEntity(entities []*Entity, e *Entity) []*Entity {
+	for _, existing := range entities {
+		if sameEntity(existing, e) {
+			fillFields(existing, e)
+			return entities
+		}
+	}
+	return append(entities, e)
+}
+
+func fillFields(dst, src *Entity) {
+	for k, v := range src.Fields {
+		if _, ok := dst.Fields[k]; !ok {
+			dst.Fields[k] = v
+		}
+	}
+	if dst.ID == "" {
+		dst.ID = src.ID
+	}
+	if dst.Subtype == "" {
+		dst.Subtype = src.Subtype
+	}
+	for _, s := range src.Sources {
+		if !slices.Contains(dst.Sources, s) {
+			dst.Sources = append(dst.Sources, s)
+		}
+	}
+}
+
+// fillFromMeta completes the page's entity of the same type with OpenGraph,
+// Twitter card and meta tag values, or adds it when the page declares its
+// type only through og:type. With several entities of the type on the page
+// it cannot tell which one the tags describe and leaves them alone.
+func fillFromMeta(entities []*Entity, meta *Entity) []*Entity {
+	var match *Entity
+	for _, e := range entities {
+		if e.Type != meta.Type {
+			continue
+		}
+		if match != nil {
+			return entities
+		}
+		match = e
+	}
+	if match == nil {
+		return append(entities, meta)
+	}
+	fillFields(match, meta)
+	return entities
+}
+
+// metaEntities builds entities from OpenGraph, Twitter and meta tags for
+// pages whose og:type is a product or article.
+func metaEntities(doc *goquery.Document) []*Entity {
+	tags := make(map[string]string)
+	doc.Find("meta[property], meta[name]").Each(func(_ int, s *goquery.Selection) {
+		key := attrOr(s, "property", attrOr(s, "name", ""))
+		if content := strings.TrimSpace(attrOr(s, "content", "")); key != "" && content != "" {
+			if _, ok := tags[key]; !ok {
+				tags[key] = content
+			}
+		}
+	})
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := tags[k]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	sources := []StructuredDataType{OpenGraph}
+	if first("twitter:title", "twitter:description", "twitter:image") != "" {
+		sources = append(sources, TwitterCard)
+	}
+	if first("description", "author") != "" {
+		sources = append(sources, MetaTags)
+	}
+
+	ogType := strings.ToLower(tags["og:type"])
+	var e *Entity
+	switch {
+	case strings.Contains(ogType, "product"):
+		e = &Entity{Type: "Product", Fields: map[string]any{
+			"name":         first("og:title", "twitter:title"),
+			"description":  first("og:description", "twitter:description", "description"),
+			"image":        first("og:image", "og:image:url", "twitter:image"),
+			"url":          first("og:url"),
+			"price":        parsePriceValue(first("product:price:amount", "og:price:amount")),
+			"currency":     first("product:price:currency", "og:price:currency"),
+			"availability": schemaEnum(first("product:availability", "og:availability")),
+			"brand":        first("product:brand", "og:brand"),
+			"condition":    schemaEnum(first("product:condition")),
+		}}
+	case ogType == "article":
+		var authors []string
+		if a := first("article:author", "author"); a != "" {
+			authors = []string{a}
+		}
+		e = &Entity{Type: "Article", Fields: map[string]any{
+			"headline":       first("og:title", "twitter:title"),
+			"description":    first("og:description", "twitter:description", "description"),
+			"image":          first("og:image", "og:image:url", "twitter:image"),
+			"url":            first("og:url"),
+			"date_published": first("article:published_time"),
+			"date_modified":  first("article:modified_time", "og:updated_time"),
+			"section":        first("article:section"),
+			"authors":        authors,
+			"publisher":      first("og:site_name"),
+		}}
+	default:
+		return nil
+	}
+	for k, v := range e.Fields {
+		if isEmptyValue(v) {
+			delete(e.Fields, k)
+		}
+	}
+	e.Sources = sources
+	return []*Entity{e}
+}
+
+// --- Normalisation ---
+
+// nodeView reads schema.org properties from a node, following @id
+// references to nodes defined elsewhere on the page. A reference back to a
+// node on the path that led here is left unresolved, so cyclic graphs end.
+type nodeView struct {
+	data map[string]any
+	ids  map[string]map[string]any
+	path []string // @ids of this node and the nodes it was reached from
+}
+
+func newNodeView(data map[string]any, ids map[string]map[string]any) nodeView {
+	return nodeView{ids: ids}.child(data)
+}
+
+// child returns a view of a node reached from n.
+func (n nodeView) child(data map[string]any) nodeView {
+	path := slices.Clip(n.path)
+	if id, ok := data["@id"].(string); ok {
+		path = append(path, id)
+	}
+	return nodeView{data: data, ids: n.ids, path: path}
+}
+
+func (n nodeView) values(prop string) []any {
+	v, ok := n.data[prop]
+	if !ok {
+		return nil
+	}
+	list, isList := v.([]any)
+	if !isList {
+		list = []any{v}
+	}
+	out := make([]any, 0, len(list))
+	for _, e := range list {
+		if m, ok := e.(map[string]any); ok {
+			if id, ok := m["@id"].(string); ok && len(m) == 1 {
+				if ref, ok := n.ids[id]; ok && !slices.Contains(n.path, id) {
+					e = ref
+				}
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// str returns the first value of prop as text. Nested nodes yield their
+// name, @value (JSON-LD value objects) or url.
+func (n nodeView) str(prop string) string {
+	for _, v := range n.values(prop) {
+		if s := n.text(v); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func (n nodeView) strs(prop string) []string {
+	var out []string
+	for _, v := range n.values(prop) {
+		if s := n.text(v); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (n nodeView) node(prop string) nodeView {
+	for _, v := range n.values(prop) {
+		if m, ok := v.(map[string]any); ok {
+			return n.child(m)
+		}
+	}
+	return nodeView{ids: n.ids}
+}
+
+func (n nodeView) nodes(prop string) []nodeView {
+	var out []nodeView
+	for _, v := range n.values(prop) {
+		if m, ok := v.(map[string]any); ok {
+			out = append(out, n.child(m))
+		}
+	}
+	return out
+}
+
+// text returns a value of n as text.
+func (n nodeView) text(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case map[string]any:
+		c := n.child(v)
+		for _, prop := range []string{"name", "@value", "text", "url", "contentUrl", "@id"} {
+			if s := c.str(prop); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func normaliseProduct(n nodeView) map[string]any {
+	f := map[string]any{
+		"name":        n.str("name"),
+		"description": plainText(n.str("description")),
+		"sku":         n.str("sku"),
+		"mpn":         n.str("mpn"),
+		"brand":       n.str("brand"),
+		"image":       n.str("image"),
+		"url":         n.str("url"),
+		"category":    n.str("category"),
+		"color":       n.str("color"),
+	}
+	for _, g := range []string{"gtin", "gtin13", "gtin12", "gtin14", "gtin8"} {
+		if v := n.str(g); v != "" {
+			f["gtin"] = v
+			break
+		}
+	}
+	addRating(f, n)
+
+	var offers []map[string]any
+	for _, o := range n.nodes("offers") {
+		if propertyName(o.str("@type")) == "AggregateOffer" {
+			f["low_price"] = parsePriceValue(o.str("lowPrice"))
+			f["high_price"] = parsePriceValue(o.str("highPrice"))
+			f["offer_count"] = parseNumber(o.str("offerCount"))
+			f["currency"] = o.str("priceCurrency")
+			for _, sub := range o.nodes("offers") {
+				offers = append(offers, normaliseOffer(sub))
+			}
+			continue
+		}
+		offers = append(offers, normaliseOffer(o))
+	}
+	if len(offers) > 0 {
+		// The first offer's terms describe the product; all are kept.
+		for _, k := range []string{"price", "currency", "availability", "condition"} {
+			if v, ok := offers[0][k]; ok && isEmptyValue(f[k]) {
+				f[k] = v
+			}
+		}
+		if len(offers) > 1 {
+			f["offers"] = offers
+		}
+	}
+	return f
+}
+
+func normaliseOffer(o nodeView) map[string]any {
+	price := o.str("price")
+	currency := o.str("priceCurrency")
+	if spec := o.node("priceSpecification"); spec.data != nil {
+		if price == "" {
+			price = spec.str("price")
+		}
+		if currency == "" {
+			currency = spec.str("priceCurrency")
+		}
+	}
+	offer := map[string]any{
+		"price":        parsePriceValue(price),
+		"currency":     currency,
+		"availability": schemaEnum(o.str("availability")),
+		"condition":    schemaEnum(o.str("itemCondition")),
+		"seller":       o.str("seller"),
+		"url":          o.str("url"),
+		"valid_until":  o.str("priceValidUntil"),
+	}
+	for k, v := range offer {
+		if isEmptyValue(v) {
+			delete(offer, k)
+		}
+	}
+	return offer
+}
+
+func normaliseArticle(n nodeView) map[string]any {
+	headline := n.str("headline")
+	if headline == "" {
+		headline = n.str("name")
+	}
+	return map[string]any{
+		"headline":       headline,
+		"description":    plainText(n.str("description")),
+		"authors":        n.strs("author"),
+		"date_published": n.str("datePublished"),
+		"date_modified":  n.str("dateModified"),
+		"publisher":      n.str("publisher"),
+		"image":          n.str("image"),
+		"url":            firstNonEmpty(n.str("url"), n.str("mainEntityOfPage")),
+		"section":        n.str("articleSection"),
+		"keywords":       keywordList(n.values("keywords")),
+		"word_count":     parseNumber(n.str("wordCount")),
+	}
+}
+
+func normaliseRecipe(n nodeView) map[string]any {
+	f := map[string]any{
+		"name":         n.str("name"),
+		"description":  plainText(n.str("description")),
+		"authors":      n.strs("author"),
+		"image":        n.str("image"),
+		"url":          n.str("url"),
+		"prep_time":    n.str("prepTime"),
+		"cook_time":    n.str("cookTime"),
+		"total_time":   n.str("totalTime"),
+		"yield":        n.str("recipeYield"),
+		"category":     n.str("recipeCategory"),
+		"cuisine":      n.str("recipeCuisine"),
+		"ingredients":  n.strs("recipeIngredient"),
+		"instructions": recipeSteps(n, n.values("recipeInstructions")),
+		"calories":     n.node("nutrition").str("calories"),
+	}
+	if len(f["ingredients"].([]string)) == 0 {
+		f["ingredients"] = n.strs("ingredients") // older vocabulary
+	}
+	for _, k := range []string{"prep_time", "cook_time", "total_time"} {
+		if m, ok := isoDurationMinutes(f[k].(string)); ok {
+			f[k+"_minutes"] = m
+		}
+	}
+	addRating(f, n)
+	return f
+}
+
+// recipeSteps flattens recipeInstructions, which may be text, HowToStep
+// nodes or HowToSection nodes holding further steps.
+func recipeSteps(n nodeView, values []any) []string {
+	var steps []string
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			for _, line := range strings.Split(plainText(v), "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					steps = append(steps, line)
+				}
+			}
+		case map[string]any:
+			c := n.child(v)
+			if items := c.values("itemListElement"); len(items) > 0 {
+				steps = append(steps, recipeSteps(c, items)...)
+			} else if text := plainText(firstNonEmpty(c.str("text"), c.str("name"))); text != "" {
+				steps = append(steps, text)
+			}
+		}
+	}
+	return steps
+}
+
+func normaliseEvent(n nodeView) map[string]any {
+	f := map[string]any{
+		"name":            n.str("name"),
+		"description":     plainText(n.str("description")),
+		"start_date":      n.str("startDate"),
+		"end_date":        n.str("endDate"),
+		"status":          schemaEnum(n.str("eventStatus")),
+		"attendance_mode": schemaEnum(n.str("eventAttendanceMode")),
+		"image":           n.str("image"),
+		"url":             n.str("url"),
+		"organizer":       n.str("organizer"),
+		"performers":      n.strs("performer"),
+	}
+	if loc := n.node("location"); loc.data != nil {
+		f["location_name"] = loc.str("name")
+		f["address"] = addressText(loc)
+		if f["location_name"] == "" {
+			f["location_name"] = loc.str("url") // VirtualLocation
+		}
+	} else {
+		f["location_name"] = n.str("location")
+	}
+	if offers := n.nodes("offers"); len(offers) > 0 {
+		o := normaliseOffer(offers[0])
+		f["price"], f["currency"], f["availability"] = o["price"], o["currency"], o["availability"]
+	}
+	return f
+}
+
+func normaliseJobPosting(n nodeView) map[string]any {
+	f := map[string]any{
+		"title":               n.str("title"),
+		"description":         plainText(n.str("description")),
+		"date_posted":         n.str("datePosted"),
+		"valid_through":       n.str("validThrough"),
+		"employment_type":     n.strs("employmentType"),
+		"hiring_organization": n.str("hiringOrganization"),
+		"url":                 n.str("url"),
+		"identifier":          n.str("identifier"),
+		"remote":              strings.EqualFold(n.str("jobLocationType"), "TELECOMMUTE"),
+	}
+	if f["remote"] == false {
+		delete(f, "remote")
+	}
+	var locations []string
+	for _, loc := range n.nodes("jobLocation") {
+		if a := addressText(loc); a != "" {
+			locations = append(locations, a)
+		}
+	}
+	f["locations"] = locations
+
+	salary := n.node("baseSalary")
+	if salary.data == nil {
+		salary = n.node("estimatedSalary")
+	}
+	if salary.data != nil {
+		f["salary_currency"] = salary.str("currency")
+		value := salary.node("value")
+		if value.data == nil {
+			f["salary_min"] = parsePriceValue(salary.str("value"))
+		} else {
+			f["salary_min"] = parsePriceValue(firstNonEmpty(value.str("minValue"), value.str("value")))
+			f["salary_max"] = parsePriceValue(value.str("maxValue"))
+			f["salary_unit"] = value.str("unitText")
+		}
+	}
+	return f
+}
+
+func normaliseOrganization(n nodeView) map[string]any {
+	return map[string]any{
+		"name":        n.str("name"),
+		"legal_name":  n.str("legalName"),
+		"description": plainText(n.str("description")),
+		"url":         n.str("url"),
+		"logo":        n.str("logo"),
+		"telephone":   n.str("telephone"),
+		"email":       strings.TrimPrefix(n.str("email"), "mailto:"),
+		"address":     addressText(n),
+		"same_as":     n.strs("sameAs"),
+	}
+}
+
+func normaliseBreadcrumbs(n nodeView) map[string]any {
+	type crumb struct {
+		pos  float64
+		name string
+		url  string
+	}
+	var crumbs []crumb
+	for i, el := range n.nodes("itemListElement") {
+		c := crumb{pos: float64(i + 1), name: el.str("name"), url: el.str("url")}
+		if p, ok := parseNumber(el.str("position")).(float64); ok {
+			c.pos = p
+		}
+		if item := el.node("item"); item.data != nil {
+			c.name = firstNonEmpty(c.name, item.str("name"))
+			c.url = firstNonEmpty(item.str("@id"), item.str("url"), c.url)
+		} else if s := el.str("item"); s != "" {
+			c.url = s
+		}
+		if c.name != "" || c.url != "" {
+			crumbs = append(crumbs, c)
+		}
+	}
+	sort.SliceStable(crumbs, func(i, j int) bool { return crumbs[i].pos < crumbs[j].pos })
+
+	items := make([]map[string]any, 0, len(crumbs))
+	names := make([]string, 0, len(crumbs))
+	for _, c := range crumbs {
+		entry := map[string]any{"position": c.pos, "name": c.name}
+		if c.url != "" {
+			entry["url"] = c.url
+		}
+		items = append(items, entry)
+		names = append(names, c.name)
+	}
+	return map[string]any{
+		"items": items,
+		"path":  strings.Join(names, " > "),
+	}
+}
+
+func addRating(f map[string]any, n nodeView) {
+	rating := n.node("aggregateRating")
+	if rating.data == nil {
+		return
+	}
+	f["rating"] = parseNumber(rating.str("ratingValue"))
+	f["rating_count"] = parseNumber(firstNonEmpty(rating.str("ratingCount"), rating.str("reviewCount")))
+}
+
+// addressText renders a PostalAddress (or a place holding one) on one line.
+func addressText(n nodeView) string {
+	addr := n.node("address")
+	if addr.data == nil {
+		if s := n.str("address"); s != "" {
+			return s
+		}
+		addr = n
+	}
+	var parts []string
+	for _, p := range []string{"streetAddress", "addressLocality", "addressRegion", "postalCode", "addressCountry"} {
+		if s := addr.str(p); s != "" && !slices.Contains(parts, s) {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// --- Value helpers ---
+
+var priceNumber = regexp.MustCompile(`\d[\d.,\s]*`)
+
+// parsePriceValue parses "1,299.00", "1.299,00" or "$ 12" as a number,
+// returning nil when s holds none.
+func parsePriceValue(s string) any {
+	m := strings.ReplaceAll(priceNumber.FindString(s), " ", "")
+	if m == "" {
+		return nil
+	}
+	lastDot, lastComma := strings.LastIndexByte(m, '.'), strings.LastIndexByte(m, ',')
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			m = strings.ReplaceAll(m, ".", "")
+			m = strings.Replace(m, ",", ".", 1)
+		} else {
+			m = strings.ReplaceAll(m, ",", "")
+		}
+	case lastComma >= 0:
+		if len(m)-lastComma-1 == 2 && strings.Count(m, ",") == 1 {
+			m = strings.Replace(m, ",", ".", 1) // decimal comma
+		} else {
+			m = strings.ReplaceAll(m, ",", "")
+		}
+	}
+	v, err := strconv.ParseFloat(strings.TrimRight(m, ".,"), 64)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+func parseNumber(s string) any {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// schemaEnum shortens enumeration URLs such as https://schema.org/InStock.
+func schemaEnum(s string) string {
+	return propertyName(s)
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:\d+S)?)?$`)
+
+// isoDurationMinutes converts ISO 8601 durations such as PT1H30M to minutes.
+func isoDurationMinutes(s string) (float64, bool) {
+	m := isoDuration.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(s)))
+	if m == nil || s == "P" {
+		return 0, false
+	}
+	var minutes float64
+	for i, mult := range []float64{24 * 60, 60, 1} {
+		if m[i+1] != "" {
+			n, _ := strconv.ParseFloat(m[i+1], 64)
+			minutes += n * mult
+		}
+	}
+	return minutes, true
+}
+
+func keywordList(values []any) []string {
+	var out []string
+	for _, v := range values {
+		s, _ := v.(string)
+		for _, k := range strings.Split(s, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				out = append(out, k)
+			}
+		}
+	}
+	return out
+}
+
+// plainText strips markup from values such as job descriptions that sites
+// embed as HTML.
+func plainText(s string) string {
+	if !strings.Contains(s, "<") {
+		return s
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+	var lines []string
+	doc.Find("p, li, h1, h2, h3, h4, h5, h6, br, div").AppendHtml("\n")
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		if line = normalizeSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func stringValues(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var out []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func dateOnly(v any) string {
+	s, _ := v.(string)
+	if len(s) > 10 {
+		return s[:10]
+	}
+	return s
+}
+
+func isEmptyValue(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	case []map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
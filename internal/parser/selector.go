@@ -0,0 +1,372 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Extended selector syntax. A rule selector is a goquery (CSS) selector that
+// may also use:
+//
+//	p::text                 text directly inside the element, excluding children
+//	a::attr(href)           an attribute, overriding the rule's Attribute
+//	div::html               inner HTML
+//	li:contains("Sale")     elements whose text contains the string (case-sensitive)
+//	dt:has-text("price")    elements whose text contains the string, ignoring
+//	                        case and runs of whitespace
+//	li:nth-match(2)         the 2nd element matched so far (negative counts from
+//	                        the end), unlike :nth-child which is per parent
+//	dt:has-text("SKU") + dd the value element following a label
+//	b:has-text("Tel") + ::text
+//	                        the bare text following a label element
+//
+// Plain selectors are passed to goquery unchanged.
+
+// extractMode says what a compiled selector extracts from each match.
+type extractMode int
+
+const (
+	extractDefault  extractMode = iota // the rule's Attribute
+	extractOwnText                     // ::text
+	extractAttr                        // ::attr(x)
+	extractHTML                        // ::html
+	extractNextText                    // + ::text
+)
+
+// Selector is a compiled extended selector.
+type Selector struct {
+	steps []selectorStep
+	mode  extractMode
+	attr  string
+	plain string // whole selector when it needs no extensions
+}
+
+// selectorStep is one compound selector and the combinator that reaches it.
+type selectorStep struct {
+	combinator byte   // 0 for the first step, ' ', '>', '+' or '~'
+	css        string // standard part of the compound; "" matches any element
+	hasText    []string
+	nthMatch   int // 1-based; 0 when unset
+}
+
+var selectorCache sync.Map // selector string -> *Selector
+
+// CompileSelector parses an extended selector. Results are cached.
+func CompileSelector(s string) (*Selector, error) {
+	if cached, ok := selectorCache.Load(s); ok {
+		return cached.(*Selector), nil
+	}
+	sel, err := compileSelector(s)
+	if err != nil {
+		return nil, err
+	}
+	selectorCache.Store(s, sel)
+	return sel, nil
+}
+
+func compileSelector(s string) (*Selector, error) {
+	sel := &Selector{}
+	body := strings.TrimSpace(s)
+
+	// Trailing pseudo-element.
+	if i := topLevelIndex(body, "::"); i >= 0 {
+		pseudo := strings.TrimSpace(body[i+2:])
+		body = body[:i]
+		switch {
+		case pseudo == "text":
+			sel.mode = extractOwnText
+		case pseudo == "html":
+			sel.mode = extractHTML
+		case strings.HasPrefix(pseudo, "attr(") && strings.HasSuffix(pseudo, ")"):
+			sel.mode = extractAttr
+			sel.attr = unquote(pseudo[len("attr(") : len(pseudo)-1])
+			if sel.attr == "" {
+				return nil, fmt.Errorf("selector %q: ::attr needs an attribute name", s)
+			}
+		default:
+			return nil, fmt.Errorf("selector %q: unknown pseudo-element ::%s", s, pseudo)
+		}
+	}
+
+	// "label + ::text" selects the text after the label.
+	trimmed := strings.TrimRight(body, " \t\n")
+	if sel.mode == extractOwnText && strings.HasSuffix(trimmed, "+") {
+		sel.mode = extractNextText
+		body = strings.TrimSuffix(trimmed, "+")
+	}
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("selector %q: nothing to select", s)
+	}
+
+	if !strings.Contains(body, ":has-text(") && !strings.Contains(body, ":nth-match(") {
+		sel.plain = body
+		return sel, nil
+	}
+
+	steps, err := splitSteps(body)
+	if err != nil {
+		return nil, fmt.Errorf("selector %q: %w", s, err)
+	}
+	sel.steps = steps
+	return sel, nil
+}
+
+// splitSteps splits a selector into compounds at top-level combinators and
+// pulls the custom pseudo-classes out of each compound.
+func splitSteps(body string) ([]selectorStep, error) {
+	var steps []selectorStep
+	var cur strings.Builder
+	var comb byte
+	depth, quote := 0, byte(0)
+
+	flush := func(next byte) error {
+		step, err := parseCompound(cur.String())
+		if err != nil {
+			return err
+		}
+		step.combinator = comb
+		steps = append(steps, step)
+		cur.Reset()
+		comb = next
+		return nil
+	}
+
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(body) {
+				cur.WriteByte(c)
+				i++
+				c = body[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case depth == 0 && (c == ' ' || c == '\t' || c == '\n' || c == '>' || c == '+' || c == '~'):
+			// Collapse whitespace around an explicit combinator.
+			next := byte(' ')
+			j := i
+			for ; j < len(body) && strings.IndexByte(" \t\n>+~", body[j]) >= 0; j++ {
+				if body[j] != ' ' && body[j] != '\t' && body[j] != '\n' {
+					next = body[j]
+				}
+			}
+			if cur.Len() == 0 {
+				return nil, fmt.Errorf("combinator without a selector before it")
+			}
+			if err := flush(next); err != nil {
+				return nil, err
+			}
+			i = j - 1
+			continue
+		}
+		cur.WriteByte(c)
+	}
+	if quote != 0 || depth != 0 {
+		return nil, fmt.Errorf("unbalanced quotes or brackets")
+	}
+	if cur.Len() == 0 {
+		return nil, fmt.Errorf("selector ends with a combinator")
+	}
+	if err := flush(0); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// parseCompound extracts :has-text() and :nth-match() from a compound.
+func parseCompound(compound string) (selectorStep, error) {
+	var step selectorStep
+	for _, name := range []string{":has-text(", ":nth-match("} {
+		for {
+			i := topLevelIndex(compound, name)
+			if i < 0 {
+				break
+			}
+			end := closingParen(compound, i+len(name))
+			if end < 0 {
+				return step, fmt.Errorf("unclosed %s", name)
+			}
+			arg := unquote(compound[i+len(name) : end])
+			compound = compound[:i] + compound[end+1:]
+
+			if name == ":has-text(" {
+				step.hasText = append(step.hasText, normaliseSpace(arg))
+				continue
+			}
+			n, err := strconv.Atoi(arg)
+			if err != nil || n == 0 {
+				return step, fmt.Errorf("bad :nth-match index %q", arg)
+			}
+			step.nthMatch = n
+		}
+	}
+	step.css = strings.TrimSpace(compound)
+	return step, nil
+}
+
+// Select returns the elements the selector matches within root.
+func (s *Selector) Select(root *goquery.Selection) *goquery.Selection {
+	if s.plain != "" {
+		return root.Find(s.plain)
+	}
+
+	sel := root
+	for _, step := range s.steps {
+		css := step.css
+		if css == "" {
+			css = "*"
+		}
+		switch step.combinator {
+		case 0, ' ':
+			sel = sel.Find(css)
+		case '>':
+			sel = sel.ChildrenFiltered(css)
+		case '+':
+			sel = sel.Next().Filter(css)
+		case '~':
+			sel = sel.NextAllFiltered(css)
+		}
+
+		for _, text := range step.hasText {
+			sel = sel.FilterFunction(func(_ int, el *goquery.Selection) bool {
+				return strings.Contains(normaliseSpace(el.Text()), text)
+			})
+		}
+		if n := step.nthMatch; n != 0 {
+			if n > 0 {
+				sel = sel.Eq(n - 1)
+			} else {
+				sel = sel.Eq(sel.Length() + n)
+			}
+		}
+	}
+	return sel
+}
+
+// Values returns the non-empty values extracted from matches. attribute is
+// the rule's Attribute, used unless the selector names a pseudo-element.
+func (s *Selector) Values(matches *goquery.Selection, attribute string) []string {
+	switch s.mode {
+	case extractAttr:
+		attribute = s.attr
+	case extractHTML:
+		attribute = "html"
+	case extractOwnText, extractNextText:
+		var values []string
+		matches.Each(func(_ int, el *goquery.Selection) {
+			var text string
+			if s.mode == extractOwnText {
+				text = ownText(el.Nodes[0])
+			} else {
+				text = followingText(el.Nodes[0])
+			}
+			if text != "" {
+				values = append(values, text)
+			}
+		})
+		return values
+	}
+	return selectionValues(matches, attribute)
+}
+
+// ownText joins the text nodes directly inside n.
+func ownText(n *html.Node) string {
+	var parts []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			if t := strings.TrimSpace(c.Data); t != "" {
+				parts = append(parts, t)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// followingText returns the text between n and its next element sibling,
+// trimmed of separators such as ":" that often follow a label.
+func followingText(n *html.Node) string {
+	var b strings.Builder
+	for c := n.NextSibling; c != nil && c.Type != html.ElementNode; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return strings.TrimLeft(strings.TrimSpace(b.String()), ":-– \t")
+}
+
+// topLevelIndex finds sub outside quotes, brackets and parentheses.
+func topLevelIndex(s, sub string) int {
+	depth, quote := 0, byte(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case depth == 0 && strings.HasPrefix(s[i:], sub):
+			return i
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		}
+	}
+	return -1
+}
+
+// closingParen returns the index of the ")" closing a group opened just
+// before start.
+func closingParen(s string, start int) int {
+	depth, quote := 1, byte(0)
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func normaliseSpace(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,233 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// SelectorRepair records a self-healing rule whose selector stopped matching
+// and the selector found in its place.
+type SelectorRepair struct {
+	Site        string    `json:"site"`
+	Rule        string    `json:"rule"`
+	URL         string    `json:"url"`
+	OldSelector string    `json:"old_selector"`
+	NewSelector string    `json:"new_selector"`
+	Strategy    string    `json:"strategy"`   // id, attribute, classes, path or text
+	Similarity  float64   `json:"similarity"` // confidence of the relocation, 0 to 1
+	Flagged     bool      `json:"flagged"`    // below the threshold; the value was not used
+	At          time.Time `json:"at"`
+}
+
+// Diff renders the repair as a unified-diff style snippet for review.
+func (r SelectorRepair) Diff() string {
+	return fmt.Sprintf("--- %s rule %q\n+++ %s rule %q (%s, similarity %.2f)\n- selector: %s\n+ selector: %s\n",
+		r.Site, r.Rule, r.Site, r.Rule, r.Strategy, r.Similarity, r.OldSelector, r.NewSelector)
+}
+
+// SetStore persists snapshots under dir, one file per site and rule, and
+// appends repairs to dir/repairs.jsonl.
+func (st *SmartTracker) SetStore(dir string) {
+	st.mu.Lock()
+	st.dir = dir
+	st.mu.Unlock()
+}
+
+// SetMinSimilarity sets the relocation confidence below which a repaired
+// value is not used and the item is flagged instead.
+func (st *SmartTracker) SetMinSimilarity(v float64) {
+	st.mu.Lock()
+	st.minSimilarity = v
+	st.mu.Unlock()
+}
+
+// Repairs returns the selector repairs made so far.
+func (st *SmartTracker) Repairs() []SelectorRepair {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return slices.Clone(st.repairs)
+}
+
+// Observe snapshots the element a self-healing rule matched so it can be
+// relocated if the selector later stops matching. The snapshot is only
+// rewritten when the element's structure changes.
+func (st *SmartTracker) Observe(resp *types.Response, rule config.ParseRule, sel *goquery.Selection) {
+	if sel.Length() == 0 {
+		return
+	}
+	site := resp.Request.Domain()
+	snap := newElementSnapshot(sel.First(), rule.Selector)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	key := site + "/" + rule.Name
+	old := st.loadSnapshot(site, rule.Name)
+	if old != nil && old.Selector == snap.Selector && old.Tag == snap.Tag && old.ID == snap.ID &&
+		old.Path == snap.Path && slices.Equal(old.Classes, snap.Classes) {
+		return
+	}
+	st.snapshots[key] = snap
+	if err := st.saveSnapshot(site, rule.Name, snap); err != nil {
+		st.logger.Warn("failed to save selector snapshot", "site", site, "rule", rule.Name, "error", err)
+	}
+}
+
+// Heal relocates the element of a self-healing rule whose selector matched
+// nothing. It returns the relocated selection and the repair, or nils when no
+// snapshot exists or nothing similar was found. Repairs below the similarity
+// threshold are returned flagged; new repairs are logged and recorded once.
+func (st *SmartTracker) Heal(resp *types.Response, rule config.ParseRule) (*goquery.Selection, *SelectorRepair) {
+	doc, err := resp.Document()
+	if err != nil {
+		return nil, nil
+	}
+	site := resp.Request.Domain()
+
+	st.mu.Lock()
+	snap := st.loadSnapshot(site, rule.Name)
+	minSimilarity := st.minSimilarity
+	st.mu.Unlock()
+	if snap == nil {
+		return nil, nil
+	}
+
+	selector, sel, strategy, score := st.relocate(doc, snap, 0.3)
+	if sel == nil {
+		return nil, nil
+	}
+
+	repair := &SelectorRepair{
+		Site:        site,
+		Rule:        rule.Name,
+		URL:         resp.Request.URLString(),
+		OldSelector: rule.Selector,
+		NewSelector: selector,
+		Strategy:    strategy,
+		Similarity:  score,
+		Flagged:     score < minSimilarity,
+		At:          time.Now(),
+	}
+	if strategy == "selector" {
+		// A repair from an earlier page is still in effect.
+		repair.Strategy = "previous"
+		return sel, repair
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	reportKey := site + "/" + rule.Name + "\x00" + selector
+	if st.reported[reportKey] {
+		return sel, repair
+	}
+	st.reported[reportKey] = true
+	st.repairs = append(st.repairs, *repair)
+
+	if repair.Flagged {
+		st.logger.Warn("selector relocation below threshold, item flagged",
+			"site", site, "rule", rule.Name, "selector", rule.Selector,
+			"candidate", selector, "similarity", score)
+	} else {
+		st.logger.Warn("selector repaired",
+			"site", site, "rule", rule.Name, "old", rule.Selector,
+			"new", selector, "strategy", strategy, "similarity", score)
+		// Later pages go straight to the repaired selector.
+		repaired := *snap
+		repaired.Selector = selector
+		st.snapshots[site+"/"+rule.Name] = &repaired
+		if err := st.saveSnapshot(site, rule.Name, &repaired); err != nil {
+			st.logger.Warn("failed to save selector snapshot", "site", site, "rule", rule.Name, "error", err)
+		}
+	}
+	if err := st.appendRepair(repair); err != nil {
+		st.logger.Warn("failed to record selector repair", "error", err)
+	}
+	return sel, repair
+}
+
+// flagItem notes on an item that a field could not be extracted reliably.
+func flagItem(item *types.Item, repair *SelectorRepair) {
+	msg := fmt.Sprintf("%s: selector %q matched nothing; best candidate %q has similarity %.2f",
+		repair.Rule, repair.OldSelector, repair.NewSelector, repair.Similarity)
+	var flags []string
+	if v, ok := item.Get("_selector_flags"); ok {
+		flags, _ = v.([]string)
+	}
+	item.Set("_selector_flags", append(flags, msg))
+}
+
+// --- Persistence (callers hold st.mu) ---
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func (st *SmartTracker) snapshotPath(site, rule string) string {
+	return filepath.Join(st.dir, unsafeFileChars.ReplaceAllString(site, "_"), unsafeFileChars.ReplaceAllString(rule, "_")+".json")
+}
+
+// loadSnapshot returns the cached snapshot for a site and rule, reading it
+// from disk on first use.
+func (st *SmartTracker) loadSnapshot(site, rule string) *elementSnapshot {
+	key := site + "/" + rule
+	if snap, ok := st.snapshots[key]; ok {
+		return snap
+	}
+	if st.dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(st.snapshotPath(site, rule))
+	if err != nil {
+		return nil
+	}
+	var snap elementSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		st.logger.Warn("ignoring corrupt selector snapshot", "site", site, "rule", rule, "error", err)
+		return nil
+	}
+	st.snapshots[key] = &snap
+	return &snap
+}
+
+func (st *SmartTracker) saveSnapshot(site, rule string, snap *elementSnapshot) error {
+	if st.dir == "" {
+		return nil
+	}
+	path := st.snapshotPath(site, rule)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (st *SmartTracker) appendRepair(repair *SelectorRepair) error {
+	if st.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(st.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(st.dir, "repairs.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(repair)
+}
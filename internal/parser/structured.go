@@ -20,6 +20,10 @@ const (
 	TwitterCard StructuredDataType = "twitter_card"
 	RDFa        StructuredDataType = "rdfa"
 	MetaTags    StructuredDataType = "meta"
+
+	// MicrodataItems holds microdata with nested items kept as objects;
+	// Microdata keeps the original flat itemprop map.
+	MicrodataItems StructuredDataType = "microdata_items"
 )
 
 // StructuredData represents extracted structured data from a page.
@@ -69,6 +73,11 @@ func (sde *StructuredDataExtractor) Extract(resp *types.Response) ([]StructuredD
 	// Microdata
 	mdResults := sde.extractMicrodata(doc)
 	results = append(results, mdResults...)
+	results = append(results, sde.extractMicrodataItems(doc)...)
+
+	// RDFa
+	rdfaResults := sde.extractRDFa(doc)
+	results = append(results, rdfaResults...)
 
 	// Standard meta tags
 	metaData := sde.extractMetaTags(doc)
@@ -199,6 +208,152 @@ func (sde *StructuredDataExtractor) extractMicrodata(doc *goquery.Document) []St
 	return results
 }
 
+// extractMicrodataItems parses microdata items the way schema.org reads
+// them: nested items become nested objects and repeated properties become
+// lists.
+func (sde *StructuredDataExtractor) extractMicrodataItems(doc *goquery.Document) []StructuredData {
+	var results []StructuredData
+
+	// Top-level items are those that are not themselves a property
+	doc.Find("[itemscope]:not([itemprop])").Each(func(i int, sel *goquery.Selection) {
+		if data := readItemScope(sel, microdataSyntax); len(data) > 0 {
+			results = append(results, StructuredData{
+				Type: MicrodataItems,
+				Data: data,
+			})
+		}
+	})
+
+	return results
+}
+
+// extractRDFa parses RDFa Lite annotations (vocab/typeof/property).
+func (sde *StructuredDataExtractor) extractRDFa(doc *goquery.Document) []StructuredData {
+	var results []StructuredData
+
+	doc.Find("[typeof]:not([property])").Each(func(i int, sel *goquery.Selection) {
+		if data := readItemScope(sel, rdfaSyntax); len(data) > 1 {
+			results = append(results, StructuredData{
+				Type: RDFa,
+				Data: data,
+			})
+		}
+	})
+
+	return results
+}
+
+// itemSyntax names the attributes an annotation syntax uses.
+type itemSyntax struct {
+	scope string // attribute that starts an item
+	typ   string // attribute holding the item type
+	id    string // attribute holding the item identifier
+	prop  string // attribute naming a property
+}
+
+var (
+	microdataSyntax = itemSyntax{scope: "itemscope", typ: "itemtype", id: "itemid", prop: "itemprop"}
+	rdfaSyntax      = itemSyntax{scope: "typeof", typ: "typeof", id: "resource", prop: "property"}
+)
+
+// readItemScope reads the properties of the item rooted at sel.
+func readItemScope(sel *goquery.Selection, syn itemSyntax) map[string]any {
+	data := make(map[string]any)
+	if t := strings.TrimSpace(attrOr(sel, syn.typ, "")); t != "" {
+		data["@type"] = t
+	}
+	if id := attrOr(sel, syn.id, ""); id != "" {
+		data["@id"] = id
+	}
+	readItemProps(sel, syn, data)
+	return data
+}
+
+func readItemProps(sel *goquery.Selection, syn itemSyntax, data map[string]any) {
+	sel.Children().Each(func(_ int, child *goquery.Selection) {
+		_, isScope := child.Attr(syn.scope)
+		names := strings.Fields(attrOr(child, syn.prop, ""))
+
+		if len(names) > 0 {
+			var value any
+			if isScope {
+				value = readItemScope(child, syn)
+			} else {
+				value = itemPropValue(child, syn)
+			}
+			for _, name := range names {
+				addItemProp(data, propertyName(name), value)
+			}
+		}
+		if !isScope {
+			// Properties of the same item may sit deeper in the tree
+			readItemProps(child, syn, data)
+		}
+	})
+}
+
+// itemPropValue returns a property's value following the microdata rules for
+// which attribute carries it; RDFa prefers content and resource.
+func itemPropValue(sel *goquery.Selection, syn itemSyntax) any {
+	if syn == rdfaSyntax {
+		for _, attr := range []string{"content", "resource", "href", "src", "datetime"} {
+			if v, ok := sel.Attr(attr); ok {
+				return strings.TrimSpace(v)
+			}
+		}
+		return normalizeSpace(sel.Text())
+	}
+
+	attr := ""
+	switch goquery.NodeName(sel) {
+	case "meta":
+		attr = "content"
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		attr = "src"
+	case "a", "area", "link":
+		attr = "href"
+	case "object":
+		attr = "data"
+	case "data", "meter":
+		attr = "value"
+	case "time":
+		attr = "datetime"
+	}
+	if attr == "" {
+		attr = "content"
+	}
+	if v, ok := sel.Attr(attr); ok {
+		return strings.TrimSpace(v)
+	}
+	return normalizeSpace(sel.Text())
+}
+
+// propertyName strips vocabulary prefixes such as "schema:" or a full
+// schema.org URL from a property name.
+func propertyName(name string) string {
+	if i := strings.LastIndexAny(name, "/#"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+func addItemProp(data map[string]any, name string, value any) {
+	if s, ok := value.(string); ok && s == "" {
+		return
+	}
+	switch existing := data[name].(type) {
+	case nil:
+		data[name] = value
+	case []any:
+		data[name] = append(existing, value)
+	default:
+		data[name] = []any{existing, value}
+	}
+}
+
 // extractMetaTags parses standard meta tags (description, keywords, author, etc.).
 func (sde *StructuredDataExtractor) extractMetaTags(doc *goquery.Document) StructuredData {
 	data := make(map[string]any)
@@ -274,6 +429,10 @@ func StructuredDataToItem(results []StructuredData, sourceURL string) *types.Ite
 			item.Set("twitter_card", sd.Data)
 		case Microdata:
 			item.Set("microdata", sd.Data)
+		case MicrodataItems:
+			item.Set("microdata_items", sd.Data)
+		case RDFa:
+			item.Set("rdfa", sd.Data)
 		case MetaTags:
 			for k, v := range sd.Data {
 				item.Set("meta_"+k, v)
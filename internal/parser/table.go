@@ -0,0 +1,357 @@
+package parser
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// TableParser handles "table" rules, which turn HTML tables into one item per
+// body row keyed by the column headers. The rule's Selector narrows the
+// candidate tables (default "table"); Table.Caption and Table.Headers pick
+// among them by caption or header text. Each item carries "_table" (the rule
+// name) and "_row" (the 1-based body row).
+//
+// Header rows come from <thead>, or leading rows made only of <th> cells;
+// several header rows are joined per column as "Group / Column". colspan and
+// rowspan cells are repeated over every position they span. A leading <th>
+// in a body row is the row's label. A table with no header row whose rows are
+// all "<th>label</th><td>value</td>" pairs becomes a single label → value
+// item instead.
+type TableParser struct {
+	logger *slog.Logger
+}
+
+// NewTableParser creates a new table parser.
+func NewTableParser(logger *slog.Logger) *TableParser {
+	return &TableParser{
+		logger: logger.With("component", "table_parser"),
+	}
+}
+
+// Parse implements Parser.
+func (p *TableParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	if len(rules) == 0 {
+		return nil, nil, nil
+	}
+
+	doc, err := resp.Document()
+	if err != nil {
+		return nil, nil, &types.ParseError{
+			URL: resp.Request.URLString(),
+			Err: err,
+		}
+	}
+
+	var items []*types.Item
+	for _, rule := range rules {
+		if rule.Type != "table" {
+			continue
+		}
+		selector := rule.Selector
+		if selector == "" {
+			selector = "table"
+		}
+		query, err := CompileSelector(selector)
+		if err != nil {
+			p.logger.Warn("invalid table selector", "rule", rule.Name, "error", err)
+			continue
+		}
+
+		query.Select(doc.Selection).Each(func(_ int, table *goquery.Selection) {
+			if goquery.NodeName(table) != "table" {
+				return
+			}
+			t, err := readTable(table, rule.Table.HeaderRows)
+			if err != nil {
+				p.logger.Warn("table skipped", "rule", rule.Name, "url", resp.Request.URLString(), "error", err)
+				return
+			}
+			if !t.matches(rule.Table) {
+				return
+			}
+			items = append(items, t.items(resp.Request.URLString(), rule)...)
+		})
+	}
+	return items, nil, nil
+}
+
+// tableCell is one grid position after colspan/rowspan expansion.
+type tableCell struct {
+	text   string
+	header bool // a <th> cell
+}
+
+// htmlTable is a table laid out as a grid.
+type htmlTable struct {
+	caption    string
+	grid       [][]tableCell
+	headerRows int
+	headers    []string
+}
+
+// maxTableCells bounds a table's grid after span expansion. Spans are
+// capped per cell, but a few hundred cells spanning 1000 columns or rows
+// each would still expand to hundreds of millions of positions.
+const maxTableCells = 1_000_000
+
+// readTable lays out a table's rows as a grid and infers its headers.
+// headerRows forces the number of header rows when positive. A table whose
+// grid would exceed maxTableCells is an error.
+func readTable(table *goquery.Selection, headerRows int) (*htmlTable, error) {
+	t := &htmlTable{caption: normaliseText(table.ChildrenFiltered("caption").Text())}
+	if t.caption == "" {
+		t.caption = strings.TrimSpace(table.AttrOr("aria-label", table.AttrOr("summary", "")))
+	}
+
+	// Rows of this table only, not of tables nested in its cells.
+	rows := table.Find("tr").FilterFunction(func(_ int, tr *goquery.Selection) bool {
+		return tr.Closest("table").IsSelection(table)
+	})
+	theadRows := table.ChildrenFiltered("thead").ChildrenFiltered("tr").Length()
+
+	// pending[c] carries a rowspan cell down into later rows.
+	type carry struct {
+		cell tableCell
+		left int
+	}
+	var pending []carry
+	cells := 0
+
+	rows.EachWithBreak(func(_ int, tr *goquery.Selection) bool {
+		var row []tableCell
+		col := 0
+		place := func(cell tableCell, rowspan int) {
+			for len(pending) <= col {
+				pending = append(pending, carry{})
+			}
+			row = append(row, cell)
+			if rowspan > 1 {
+				pending[col] = carry{cell: cell, left: rowspan - 1}
+			}
+			col++
+		}
+		fillPending := func() {
+			for col < len(pending) && pending[col].left > 0 {
+				pending[col].left--
+				row = append(row, pending[col].cell)
+				col++
+			}
+		}
+
+		tr.ChildrenFiltered("th, td").EachWithBreak(func(_ int, td *goquery.Selection) bool {
+			fillPending()
+			cell := tableCell{text: normaliseText(td.Text()), header: goquery.NodeName(td) == "th"}
+			colspan := spanAttr(td, "colspan")
+			rowspan := spanAttr(td, "rowspan")
+			for i := 0; i < colspan; i++ {
+				place(cell, rowspan)
+			}
+			return cells+len(row) <= maxTableCells
+		})
+		// A short row may end before columns still carrying a rowspan; pad
+		// the gap with empty cells so each carry lands in its own column.
+		last := len(pending) - 1
+		for last >= col && pending[last].left == 0 {
+			last--
+		}
+		for ; col <= last; col++ {
+			if pending[col].left > 0 {
+				pending[col].left--
+				row = append(row, pending[col].cell)
+			} else {
+				row = append(row, tableCell{})
+			}
+		}
+		if len(row) > 0 {
+			t.grid = append(t.grid, row)
+		}
+		cells += len(row)
+		return cells <= maxTableCells
+	})
+	if cells > maxTableCells {
+		return nil, fmt.Errorf("table has more than %d cells", maxTableCells)
+	}
+
+	switch {
+	case headerRows > 0:
+		t.headerRows = min(headerRows, len(t.grid))
+	case theadRows > 0:
+		t.headerRows = min(theadRows, len(t.grid))
+	default:
+		for _, row := range t.grid {
+			if !allHeaders(row) {
+				break
+			}
+			t.headerRows++
+		}
+	}
+	t.headers = t.columnNames()
+	return t, nil
+}
+
+// columnNames joins each column's distinct header texts, top to bottom, and
+// makes the names unique.
+func (t *htmlTable) columnNames() []string {
+	width := 0
+	for _, row := range t.grid {
+		width = max(width, len(row))
+	}
+
+	names := make([]string, width)
+	seen := make(map[string]int)
+	for c := range names {
+		var parts []string
+		for _, row := range t.grid[:t.headerRows] {
+			if c < len(row) && row[c].text != "" && (len(parts) == 0 || parts[len(parts)-1] != row[c].text) {
+				parts = append(parts, row[c].text)
+			}
+		}
+		name := strings.Join(parts, " / ")
+		if name == "" {
+			name = fmt.Sprintf("column_%d", c+1)
+		}
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, seen[name])
+		}
+		names[c] = name
+	}
+	return names
+}
+
+// matches reports whether the table fits the rule's caption and header
+// filters. Both compare case-insensitively by substring.
+func (t *htmlTable) matches(opts config.TableRule) bool {
+	if opts.Caption != "" && !strings.Contains(strings.ToLower(t.caption), strings.ToLower(opts.Caption)) {
+		return false
+	}
+	for _, want := range opts.Headers {
+		want = strings.ToLower(want)
+		found := false
+		for _, h := range t.headers {
+			if strings.Contains(strings.ToLower(h), want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(t.grid) > t.headerRows
+}
+
+// items converts the body rows to items.
+func (t *htmlTable) items(url string, rule config.ParseRule) []*types.Item {
+	value := func(s string) any {
+		if rule.Table.Numeric {
+			if n, ok := cleanNumber(s); ok {
+				return n
+			}
+		}
+		return s
+	}
+
+	if t.headerRows == 0 && t.isKeyValue() {
+		item := types.NewItem(url)
+		item.Set("_table", rule.Name)
+		for _, row := range t.grid {
+			item.Set(row[0].text, value(row[1].text))
+		}
+		return []*types.Item{item}
+	}
+
+	var items []*types.Item
+	for i, row := range t.grid[t.headerRows:] {
+		item := types.NewItem(url)
+		item.Set("_table", rule.Name)
+		item.Set("_row", i+1)
+		empty := true
+		for c, cell := range row {
+			if cell.text == "" {
+				continue
+			}
+			empty = false
+			key := t.headers[c]
+			if c == 0 && cell.header && strings.HasPrefix(key, "column_") {
+				key = "label"
+			}
+			item.Set(key, value(cell.text))
+		}
+		if !empty {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isKeyValue reports whether every row is a <th> label followed by a value.
+func (t *htmlTable) isKeyValue() bool {
+	for _, row := range t.grid {
+		if len(row) != 2 || !row[0].header || row[1].header || row[0].text == "" {
+			return false
+		}
+	}
+	return len(t.grid) > 0
+}
+
+// rows returns the cell texts row by row.
+func (t *htmlTable) rows() [][]string {
+	out := make([][]string, len(t.grid))
+	for i, row := range t.grid {
+		out[i] = make([]string, len(row))
+		for j, cell := range row {
+			out[i][j] = cell.text
+		}
+	}
+	return out
+}
+
+func allHeaders(row []tableCell) bool {
+	for _, cell := range row {
+		if !cell.header {
+			return false
+		}
+	}
+	return true
+}
+
+func spanAttr(sel *goquery.Selection, name string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(sel.AttrOr(name, "1")))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return min(n, 1000) // browsers cap spans similarly
+}
+
+func normaliseText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var numericCell = regexp.MustCompile(`^\(?[-+−]?[$€£¥₹]?\s?[-+−]?\d[\d,.\s]*\)?\s?(%|[$€£¥₹]|[A-Z]{3})?$`)
+
+// cleanNumber converts cells such as "1,234", "$9.99", "12.5%", "(300)" and
+// "−4" to numbers. Percentages keep their face value (12.5%, not 0.125).
+func cleanNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || !numericCell.MatchString(s) {
+		return 0, false
+	}
+	negative := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") ||
+		strings.ContainsAny(s, "-−")
+	v, ok := parsePriceValue(s).(float64)
+	if !ok {
+		return 0, false
+	}
+	if negative {
+		v = -v
+	}
+	return v, true
+}
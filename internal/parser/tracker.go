@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"math"
 	"strings"
+	"sync"
 
 	"github.com/PuerkitoBio/goquery"
 
@@ -14,19 +15,26 @@ import (
 // It can relocate content even when a site's HTML structure changes.
 type SmartTracker struct {
 	logger    *slog.Logger
+	mu        sync.Mutex
 	snapshots map[string]*elementSnapshot
+
+	// Self-healing rule state (see selfheal.go)
+	dir           string
+	minSimilarity float64
+	repairs       []SelectorRepair
+	reported      map[string]bool
 }
 
 // elementSnapshot stores attributes of an element for later matching.
 type elementSnapshot struct {
-	Tag        string
-	ID         string
-	Classes    []string
-	Text       string
-	Attributes map[string]string
-	Path       string // CSS path from root
-	Selector   string // Original CSS selector
-	TextHash   string // Hash of text content
+	Tag        string            `json:"tag"`
+	ID         string            `json:"id,omitempty"`
+	Classes    []string          `json:"classes,omitempty"`
+	Text       string            `json:"text,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Path       string            `json:"path,omitempty"`      // CSS path from root
+	Selector   string            `json:"selector"`            // Original CSS selector
+	TextHash   string            `json:"text_hash,omitempty"` // Hash of text content
 }
 
 // SimilarElement represents an element found via similarity matching.
@@ -40,8 +48,10 @@ type SimilarElement struct {
 // NewSmartTracker creates a new smart element tracker.
 func NewSmartTracker(logger *slog.Logger) *SmartTracker {
 	return &SmartTracker{
-		logger:    logger.With("component", "smart_tracker"),
-		snapshots: make(map[string]*elementSnapshot),
+		logger:        logger.With("component", "smart_tracker"),
+		snapshots:     make(map[string]*elementSnapshot),
+		minSimilarity: 0.7,
+		reported:      make(map[string]bool),
 	}
 }
 
@@ -61,7 +71,17 @@ func (st *SmartTracker) TakeSnapshot(resp *types.Response, selector string, name
 		}
 	}
 
-	snap := &elementSnapshot{
+	snap := newElementSnapshot(sel, selector)
+
+	st.mu.Lock()
+	st.snapshots[name] = snap
+	st.mu.Unlock()
+	st.logger.Debug("snapshot taken", "name", name, "selector", selector, "tag", snap.Tag)
+	return nil
+}
+
+func newElementSnapshot(sel *goquery.Selection, selector string) *elementSnapshot {
+	return &elementSnapshot{
 		Tag:        goquery.NodeName(sel),
 		Text:       strings.TrimSpace(sel.Text()),
 		Classes:    strings.Fields(attrOr(sel, "class", "")),
@@ -70,16 +90,14 @@ func (st *SmartTracker) TakeSnapshot(resp *types.Response, selector string, name
 		Path:       buildElementPath(sel, 5),
 		Selector:   selector,
 	}
-
-	st.snapshots[name] = snap
-	st.logger.Debug("snapshot taken", "name", name, "selector", selector, "tag", snap.Tag)
-	return nil
 }
 
 // Relocate tries to find a previously snapshotted element on a new page.
 // It uses multiple strategies: original selector, ID, class, text content, path.
 func (st *SmartTracker) Relocate(resp *types.Response, name string) (string, *goquery.Selection, error) {
+	st.mu.Lock()
 	snap, ok := st.snapshots[name]
+	st.mu.Unlock()
 	if !ok {
 		return "", nil, &types.ParseError{
 			URL: resp.Request.URLString(),
@@ -92,11 +110,29 @@ func (st *SmartTracker) Relocate(resp *types.Response, name string) (string, *go
 		return "", nil, err
 	}
 
+	selector, sel, strategy, score := st.relocate(doc, snap, 0.7)
+	if sel == nil {
+		return "", nil, &types.ParseError{
+			URL:      resp.Request.URLString(),
+			Selector: snap.Selector,
+			Err:      types.ErrEmptyResponse,
+		}
+	}
+	st.logger.Debug("relocated", "name", name, "strategy", strategy, "score", score, "selector", selector)
+	return selector, sel, nil
+}
+
+// relocate runs the relocation strategies in order of reliability and returns
+// the selector found, its match, the strategy name and a confidence score.
+// Text similarity matches below minText are rejected.
+func (st *SmartTracker) relocate(doc *goquery.Document, snap *elementSnapshot, minText float64) (string, *goquery.Selection, string, float64) {
 	// Strategy 1: Try original selector
-	sel := doc.Find(snap.Selector)
+	sel := doc.Selection.Slice(0, 0)
+	if query, err := CompileSelector(snap.Selector); err == nil {
+		sel = query.Select(doc.Selection)
+	}
 	if sel.Length() == 1 {
-		st.logger.Debug("relocated via original selector", "name", name)
-		return snap.Selector, sel, nil
+		return snap.Selector, sel, "selector", 1.0
 	}
 
 	// Strategy 2: Try ID
@@ -104,8 +140,7 @@ func (st *SmartTracker) Relocate(resp *types.Response, name string) (string, *go
 		selector := "#" + cssEscape(snap.ID)
 		sel = doc.Find(selector)
 		if sel.Length() == 1 {
-			st.logger.Debug("relocated via ID", "name", name, "id", snap.ID)
-			return selector, sel, nil
+			return selector, sel, "id", 0.95
 		}
 	}
 
@@ -115,8 +150,7 @@ func (st *SmartTracker) Relocate(resp *types.Response, name string) (string, *go
 			selector := snap.Tag + `[` + attr + `="` + val + `"]`
 			sel = doc.Find(selector)
 			if sel.Length() == 1 {
-				st.logger.Debug("relocated via data attribute", "name", name, "attr", attr)
-				return selector, sel, nil
+				return selector, sel, "attribute", 0.9
 			}
 		}
 	}
@@ -129,8 +163,7 @@ func (st *SmartTracker) Relocate(resp *types.Response, name string) (string, *go
 		}
 		sel = doc.Find(selector)
 		if sel.Length() == 1 {
-			st.logger.Debug("relocated via classes", "name", name)
-			return selector, sel, nil
+			return selector, sel, "classes", 0.85
 		}
 	}
 
@@ -138,26 +171,19 @@ func (st *SmartTracker) Relocate(resp *types.Response, name string) (string, *go
 	if snap.Path != "" {
 		sel = doc.Find(snap.Path)
 		if sel.Length() == 1 {
-			st.logger.Debug("relocated via path", "name", name)
-			return snap.Path, sel, nil
+			return snap.Path, sel, "path", 0.8
 		}
 	}
 
 	// Strategy 6: Text content similarity search
 	if snap.Text != "" {
 		bestMatch, bestSel, bestScore := st.findByTextSimilarity(doc, snap)
-		if bestScore > 0.7 {
-			st.logger.Debug("relocated via text similarity",
-				"name", name, "score", bestScore, "selector", bestMatch)
-			return bestMatch, bestSel, nil
+		if bestSel != nil && bestScore >= minText {
+			return bestMatch, bestSel, "text", bestScore
 		}
 	}
 
-	return "", nil, &types.ParseError{
-		URL:      resp.Request.URLString(),
-		Selector: snap.Selector,
-		Err:      types.ErrEmptyResponse,
-	}
+	return "", nil, "", 0
 }
 
 // FindSimilar finds elements with similar structure/content to a given element.
@@ -4,7 +4,9 @@ import (
 	"log/slog"
 	"strings"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xmlquery"
 	"golang.org/x/net/html"
 
 	"github.com/IshaanNene/ScrapeGoat/internal/config"
@@ -13,7 +15,8 @@ import (
 
 // XPathParser extracts data using XPath expressions.
 type XPathParser struct {
-	logger *slog.Logger
+	logger  *slog.Logger
+	tracker *SmartTracker
 }
 
 // NewXPathParser creates a new XPath parser.
@@ -23,8 +26,20 @@ func NewXPathParser(logger *slog.Logger) *XPathParser {
 	}
 }
 
+// SetTracker enables self-healing for HTML rules with self_heal set. A rule
+// whose expression stops matching is relocated and extracted with the
+// repaired CSS selector.
+func (p *XPathParser) SetTracker(tracker *SmartTracker) {
+	p.tracker = tracker
+}
+
 // Parse implements Parser for XPath rules.
+// XML responses (feeds, sitemaps, APIs) are queried as XML rather than HTML.
 func (p *XPathParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	if IsXML(resp) {
+		return p.parseXML(resp, rules)
+	}
+
 	doc, err := html.Parse(strings.NewReader(string(resp.Body)))
 	if err != nil {
 		return nil, nil, &types.ParseError{
@@ -40,7 +55,12 @@ func (p *XPathParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*
 			continue
 		}
 
-		values := p.extractXPath(doc, rule)
+		var values []string
+		if p.tracker != nil && rule.SelfHeal {
+			values = p.extractHealing(resp, doc, rule, item)
+		} else {
+			values = p.extractXPath(doc, rule)
+		}
 		if len(values) == 1 {
 			item.Set(rule.Name, values[0])
 		} else if len(values) > 1 {
@@ -56,6 +76,30 @@ func (p *XPathParser) Parse(resp *types.Response, rules []config.ParseRule) ([]*
 	return items, nil, nil
 }
 
+// extractHealing applies a self-healing rule: the first match is snapshotted,
+// and when nothing matches the tracker relocates the element instead.
+func (p *XPathParser) extractHealing(resp *types.Response, doc *html.Node, rule config.ParseRule, item *types.Item) []string {
+	node, err := htmlquery.Query(doc, rule.Selector)
+	if err != nil {
+		p.logger.Warn("invalid xpath", "selector", rule.Selector, "error", err)
+		return nil
+	}
+	if node != nil {
+		p.tracker.Observe(resp, rule, goquery.NewDocumentFromNode(node).Selection)
+		return p.extractXPath(doc, rule)
+	}
+
+	sel, repair := p.tracker.Heal(resp, rule)
+	if repair == nil {
+		return nil
+	}
+	if repair.Flagged {
+		flagItem(item, repair)
+		return nil
+	}
+	return selectionValues(sel, rule.Attribute)
+}
+
 // extractXPath applies a single XPath expression and returns matched values.
 func (p *XPathParser) extractXPath(doc *html.Node, rule config.ParseRule) []string {
 	nodes, err := htmlquery.QueryAll(doc, rule.Selector)
@@ -87,3 +131,67 @@ func (p *XPathParser) extractXPath(doc *html.Node, rule config.ParseRule) []stri
 
 	return values
 }
+
+// parseXML applies XPath rules to an XML document.
+func (p *XPathParser) parseXML(resp *types.Response, rules []config.ParseRule) ([]*types.Item, []string, error) {
+	doc, err := xmlquery.Parse(strings.NewReader(string(resp.Body)))
+	if err != nil {
+		return nil, nil, &types.ParseError{
+			URL: resp.Request.URLString(),
+			Err: err,
+		}
+	}
+
+	item := types.NewItem(resp.Request.URLString())
+
+	for _, rule := range rules {
+		if rule.Type != "xpath" {
+			continue
+		}
+
+		values := p.extractXMLXPath(doc, rule)
+		if len(values) == 1 {
+			item.Set(rule.Name, values[0])
+		} else if len(values) > 1 {
+			item.Set(rule.Name, values)
+		}
+	}
+
+	var items []*types.Item
+	if len(item.Fields) > 0 {
+		items = append(items, item)
+	}
+
+	return items, nil, nil
+}
+
+// extractXMLXPath applies a single XPath expression to an XML document.
+func (p *XPathParser) extractXMLXPath(doc *xmlquery.Node, rule config.ParseRule) []string {
+	nodes, err := xmlquery.QueryAll(doc, rule.Selector)
+	if err != nil {
+		p.logger.Warn("invalid xpath", "selector", rule.Selector, "error", err)
+		return nil
+	}
+
+	var values []string
+	for _, node := range nodes {
+		var val string
+
+		switch rule.Attribute {
+		case "", "text":
+			val = strings.TrimSpace(node.InnerText())
+		case "html", "innerHTML":
+			val = node.OutputXML(false)
+		case "outerHTML":
+			val = node.OutputXML(true)
+		default:
+			val = node.SelectAttr(rule.Attribute)
+		}
+
+		if val != "" {
+			values = append(values, val)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// ContextMiddleware is the context-aware middleware interface. The pipeline
+// calls ProcessContext instead of Process, with a context that is cancelled
+// when the crawl stops or the stage's timeout expires, and an Envelope
+// giving access to the request and response the item came from.
+//
+// Process remains for callers outside a pipeline; ContextFunc provides it.
+type ContextMiddleware interface {
+	Middleware
+
+	// ProcessContext transforms env.Item. Return nil to drop the item.
+	ProcessContext(ctx context.Context, env *Envelope) (*types.Item, error)
+}
+
+// Envelope is an item together with where it was scraped from. Request and
+// Response are nil for items that did not come from a crawl.
+type Envelope struct {
+	Item     *types.Item
+	Request  *types.Request
+	Response *types.ResponseMeta
+
+	stats   func() map[string]any
+	emitted []*types.Item
+}
+
+// NewEnvelope wraps item with its origin.
+func NewEnvelope(item *types.Item) *Envelope {
+	env := &Envelope{Item: item}
+	if o := item.Origin; o != nil {
+		env.Request = o.Request
+		env.Response = o.Response
+		env.stats = o.Stats
+	}
+	return env
+}
+
+// Stats returns a snapshot of the crawl statistics, or nil outside a crawl.
+func (e *Envelope) Stats() map[string]any {
+	if e.stats == nil {
+		return nil
+	}
+	return e.stats()
+}
+
+// Emit adds an item to the stream after env.Item. Emitted items pass the
+// remaining stages and share env.Item's origin unless they have their own.
+// Only Stream delivers them: Process returns just the processed item.
+func (e *Envelope) Emit(item *types.Item) {
+	if item.Origin == nil && e.Item != nil {
+		item.Origin = e.Item.Origin
+	}
+	e.emitted = append(e.emitted, item)
+}
+
+// Header returns a response header, or "" when there is no response.
+func (e *Envelope) Header(key string) string {
+	if e.Response == nil {
+		return ""
+	}
+	return e.Response.Headers.Get(key)
+}
+
+// Adapt returns mw as a ContextMiddleware. Middlewares that are not context
+// aware are wrapped so that they are skipped, with the context's error, once
+// the context is done.
+func Adapt(mw Middleware) ContextMiddleware {
+	if cm, ok := mw.(ContextMiddleware); ok {
+		return cm
+	}
+	return adapted{mw}
+}
+
+type adapted struct {
+	Middleware
+}
+
+func (a adapted) ProcessContext(ctx context.Context, env *Envelope) (*types.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.Process(env.Item)
+}
+
+// ContextFunc builds a ContextMiddleware from a function. Its Process runs
+// fn with a background context.
+func ContextFunc(name string, fn func(ctx context.Context, env *Envelope) (*types.Item, error)) ContextMiddleware {
+	return contextFunc{name: name, fn: fn}
+}
+
+type contextFunc struct {
+	name string
+	fn   func(ctx context.Context, env *Envelope) (*types.Item, error)
+}
+
+func (f contextFunc) Name() string { return f.name }
+
+func (f contextFunc) Process(item *types.Item) (*types.Item, error) {
+	return f.fn(context.Background(), NewEnvelope(item))
+}
+
+func (f contextFunc) ProcessContext(ctx context.Context, env *Envelope) (*types.Item, error) {
+	return f.fn(ctx, env)
+}
+
+// call runs one item through the stage, applying its timeout, and returns
+// the result and the items the middleware emitted. A result without an
+// origin inherits the input's, so later stages still see it.
+func (s *stage) call(ctx context.Context, item *types.Item) (*types.Item, []*types.Item, error) {
+	if s.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.Timeout)
+		defer cancel()
+	}
+	env := NewEnvelope(item)
+	result, err := s.cm.ProcessContext(ctx, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	if result != nil && result.Origin == nil {
+		result.Origin = item.Origin
+	}
+	return result, env.emitted, nil
+}
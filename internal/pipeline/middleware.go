@@ -1,13 +1,23 @@
 package pipeline
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/IshaanNene/ScrapeGoat/internal/identity"
+	"github.com/IshaanNene/ScrapeGoat/internal/neardup"
+	"github.com/IshaanNene/ScrapeGoat/internal/parser"
+	"github.com/IshaanNene/ScrapeGoat/internal/schema"
 	"github.com/IshaanNene/ScrapeGoat/internal/types"
 )
 
@@ -289,3 +299,313 @@ func (m *WordCountMiddleware) Process(item *types.Item) (*types.Item, error) {
 	}
 	return item, nil
 }
+
+// MarkdownMiddleware converts HTML string fields to GitHub-flavoured Markdown.
+// When chunkTokens is positive, the Markdown is also split by heading into
+// chunks of at most that many tokens, stored in "<field>_chunks".
+type MarkdownMiddleware struct {
+	fields      []string
+	chunkTokens int
+}
+
+func NewMarkdownMiddleware(fields []string, chunkTokens int) *MarkdownMiddleware {
+	return &MarkdownMiddleware{
+		fields:      fields,
+		chunkTokens: chunkTokens,
+	}
+}
+
+func (m *MarkdownMiddleware) Name() string { return "markdown" }
+
+func (m *MarkdownMiddleware) Process(item *types.Item) (*types.Item, error) {
+	for _, field := range m.fields {
+		s := item.GetString(field)
+		if s == "" {
+			continue
+		}
+		md, err := parser.HTMLStringToMarkdown(s, item.URL)
+		if err != nil {
+			return nil, fmt.Errorf("convert %q to markdown: %w", field, err)
+		}
+		item.Set(field, md)
+		if m.chunkTokens > 0 {
+			item.Set(field+"_chunks", parser.ChunkMarkdown(md, m.chunkTokens))
+		}
+	}
+	return item, nil
+}
+
+// SchemaValidateMiddleware validates items against a JSON Schema. Invalid
+// items are annotated with "_schema_errors" ("path: message" strings),
+// dropped, or written to a dead-letter JSONL file and dropped, depending on
+// onInvalid ("annotate", "drop" or "dead_letter").
+type SchemaValidateMiddleware struct {
+	schema    *schema.Schema
+	onInvalid string
+	coerce    bool
+
+	mu         sync.Mutex
+	deadLetter *os.File
+}
+
+func NewSchemaValidateMiddleware(s *schema.Schema, onInvalid, deadLetterPath string, coerce bool) (*SchemaValidateMiddleware, error) {
+	m := &SchemaValidateMiddleware{schema: s, onInvalid: onInvalid, coerce: coerce}
+	switch onInvalid {
+	case "", "annotate":
+		m.onInvalid = "annotate"
+	case "drop":
+	case "dead_letter":
+		if err := os.MkdirAll(filepath.Dir(deadLetterPath), 0o755); err != nil {
+			return nil, fmt.Errorf("create dead-letter dir: %w", err)
+		}
+		f, err := os.OpenFile(deadLetterPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open dead-letter file: %w", err)
+		}
+		m.deadLetter = f
+	default:
+		return nil, fmt.Errorf("unknown on_invalid mode %q", onInvalid)
+	}
+	return m, nil
+}
+
+func (m *SchemaValidateMiddleware) Name() string { return "schema_validate" }
+
+func (m *SchemaValidateMiddleware) Process(item *types.Item) (*types.Item, error) {
+	if m.coerce {
+		m.schema.Coerce(item.Fields)
+	}
+	fieldErrs := m.schema.ValidateFields(item.Fields)
+	if len(fieldErrs) == 0 {
+		return item, nil
+	}
+
+	switch m.onInvalid {
+	case "drop":
+		return nil, nil
+	case "dead_letter":
+		return nil, m.writeDeadLetter(item, fieldErrs)
+	}
+	msgs := make([]string, len(fieldErrs))
+	for i, fe := range fieldErrs {
+		msgs[i] = fe.Error()
+	}
+	item.Set("_schema_errors", msgs)
+	return item, nil
+}
+
+func (m *SchemaValidateMiddleware) writeDeadLetter(item *types.Item, fieldErrs []schema.FieldError) error {
+	line, err := json.Marshal(map[string]any{
+		"url":       item.URL,
+		"timestamp": item.Timestamp,
+		"fields":    item.Fields,
+		"errors":    fieldErrs,
+	})
+	if err != nil {
+		return fmt.Errorf("encode dead letter: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.deadLetter.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write dead letter: %w", err)
+	}
+	return nil
+}
+
+// Close closes the dead-letter file, if any.
+func (m *SchemaValidateMiddleware) Close() error {
+	if m.deadLetter == nil {
+		return nil
+	}
+	return m.deadLetter.Close()
+}
+
+// IdentityMiddleware tracks items across crawls in a persistent store. Each
+// item gets a Key (its key fields, or its content hash when none are set)
+// and a Checksum of its content, and its status, new, changed or unchanged,
+// is written to statusField. With dropUnchanged, unchanged items are dropped,
+// so recrawls only emit what is new or changed.
+//
+// Items are compared with the store but only recorded in it by Committed,
+// once storage has accepted them, so an item that fails later is not taken
+// as seen by the next crawl.
+type IdentityMiddleware struct {
+	store         *identity.Store
+	keyFields     []string
+	statusField   string
+	dropUnchanged bool
+	logger        *slog.Logger
+
+	mu      sync.Mutex
+	counts  map[identity.Status]int
+	dropped []identity.Entry // unchanged items dropped since the last commit
+}
+
+func NewIdentityMiddleware(store *identity.Store, keyFields []string, statusField string, dropUnchanged bool, logger *slog.Logger) *IdentityMiddleware {
+	return &IdentityMiddleware{
+		store:         store,
+		keyFields:     keyFields,
+		statusField:   statusField,
+		dropUnchanged: dropUnchanged,
+		logger:        logger.With("component", "identity"),
+		counts:        make(map[identity.Status]int),
+	}
+}
+
+func (m *IdentityMiddleware) Name() string { return "identity" }
+
+func (m *IdentityMiddleware) Process(item *types.Item) (*types.Item, error) {
+	hash := identity.Hash(item.Fields)
+	key := m.key(item)
+	if key == "" {
+		key = hash
+	}
+	status, err := m.store.Check(key, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.counts[status]++
+	drop := status == identity.StatusUnchanged && m.dropUnchanged
+	if drop {
+		m.dropped = append(m.dropped, identity.Entry{Key: key, Hash: hash})
+	}
+	m.mu.Unlock()
+
+	if drop {
+		return nil, nil
+	}
+	item.Key = key
+	item.Checksum = hash
+	if m.statusField != "" {
+		item.Set(m.statusField, string(status))
+	}
+	return item, nil
+}
+
+// key joins the key field values; it is empty if any of them is missing.
+func (m *IdentityMiddleware) key(item *types.Item) string {
+	if len(m.keyFields) == 0 {
+		return ""
+	}
+	parts := make([]string, len(m.keyFields))
+	for i, field := range m.keyFields {
+		v, ok := item.Get(field)
+		if !ok || v == nil || v == "" {
+			return ""
+		}
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, "|")
+}
+
+// Committed records stored items, and the unchanged items dropped since
+// the last call, in the store in one transaction.
+func (m *IdentityMiddleware) Committed(items []*types.Item) error {
+	m.mu.Lock()
+	entries := m.dropped
+	m.dropped = nil
+	m.mu.Unlock()
+
+	for _, item := range items {
+		if item.Key != "" && item.Checksum != "" {
+			entries = append(entries, identity.Entry{Key: item.Key, Hash: item.Checksum})
+		}
+	}
+	if err := m.store.Commit(entries); err != nil {
+		return fmt.Errorf("commit identities: %w", err)
+	}
+	return nil
+}
+
+// Close records the remaining dropped items, logs the status counts and
+// closes the store.
+func (m *IdentityMiddleware) Close() error {
+	err := m.Committed(nil)
+	m.mu.Lock()
+	m.logger.Info("item identity",
+		"new", m.counts[identity.StatusNew],
+		"changed", m.counts[identity.StatusChanged],
+		"unchanged", m.counts[identity.StatusUnchanged],
+		"known", m.store.Len())
+	m.mu.Unlock()
+	return errors.Join(err, m.store.Close())
+}
+
+// NearDupMiddleware detects items whose text nearly repeats an earlier
+// item's, such as one article under several URLs, by SimHash. Duplicates
+// are dropped or annotated with "_near_duplicate_of" (the earlier item's
+// URL) and "_near_duplicate_distance". With hints, it also records each
+// site section's duplicate share so the crawl can deprioritise it.
+type NearDupMiddleware struct {
+	fields   []string
+	minWords int
+	drop     bool
+	index    *neardup.Index
+	hints    *neardup.Hints
+}
+
+func NewNearDupMiddleware(fields []string, maxDistance, minWords int, drop bool, hints *neardup.Hints) *NearDupMiddleware {
+	return &NearDupMiddleware{
+		fields:   fields,
+		minWords: minWords,
+		drop:     drop,
+		index:    neardup.NewIndex(maxDistance),
+		hints:    hints,
+	}
+}
+
+func (m *NearDupMiddleware) Name() string { return "near_dup" }
+
+func (m *NearDupMiddleware) Process(item *types.Item) (*types.Item, error) {
+	words := neardup.Words(m.text(item))
+	if len(words) < m.minWords {
+		return item, nil
+	}
+	original, distance, dup := m.index.Check(neardup.SimHash(words), item.URL)
+	if m.hints != nil {
+		m.hints.Record(item.URL, dup)
+	}
+	if !dup {
+		return item, nil
+	}
+	if m.drop {
+		return nil, nil
+	}
+	item.Set("_near_duplicate_of", original)
+	item.Set("_near_duplicate_distance", distance)
+	return item, nil
+}
+
+// text joins the configured fields, or every non-metadata string field in
+// key order.
+func (m *NearDupMiddleware) text(item *types.Item) string {
+	fields := m.fields
+	if len(fields) == 0 {
+		for _, k := range item.Keys() {
+			if !strings.HasPrefix(k, "_") {
+				fields = append(fields, k)
+			}
+		}
+		sort.Strings(fields)
+	}
+	var sb strings.Builder
+	for _, f := range fields {
+		if s := item.GetString(f); s != "" {
+			sb.WriteString(s)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// Prioritize lowers the priority of requests into sections that mostly
+// produced duplicates, when frontier hints are on.
+func (m *NearDupMiddleware) Prioritize(rawURL string, priority int) int {
+	if m.hints == nil {
+		return priority
+	}
+	return m.hints.Prioritize(rawURL, priority)
+}
@@ -1,6 +1,9 @@
 package pipeline
 
 import (
+	"context"
+	"errors"
+	"io"
 	"log/slog"
 	"strings"
 	"sync"
@@ -21,38 +24,56 @@ type Middleware interface {
 // Pipeline chains middleware processors together.
 type Pipeline struct {
 	middlewares []Middleware
+	stages      []*stage
+	ordered     bool
+	buffer      int
 	logger      *slog.Logger
 }
 
 // New creates a new Pipeline.
 func New(logger *slog.Logger) *Pipeline {
 	return &Pipeline{
-		logger: logger.With("component", "pipeline"),
+		ordered: true,
+		buffer:  100,
+		logger:  logger.With("component", "pipeline"),
 	}
 }
 
 // Use adds a middleware to the pipeline chain.
 func (p *Pipeline) Use(mw Middleware) {
+	p.UseStage(mw, StageOptions{})
+}
+
+// UseStage adds a middleware with the options it runs with in Stream.
+func (p *Pipeline) UseStage(mw Middleware, opts StageOptions) {
 	p.middlewares = append(p.middlewares, mw)
-	p.logger.Debug("middleware added", "name", mw.Name(), "position", len(p.middlewares))
+	p.stages = append(p.stages, newStage(mw, opts))
+	p.logger.Debug("middleware added", "name", mw.Name(), "position", len(p.middlewares),
+		"concurrency", opts.Concurrency, "batch_size", opts.BatchSize)
 }
 
 // Process runs the item through all middleware in order.
 func (p *Pipeline) Process(item *types.Item) (*types.Item, error) {
+	return p.ProcessContext(context.Background(), item)
+}
+
+// ProcessContext runs the item through all middleware in order, passing ctx
+// to context-aware middlewares. Items emitted by middlewares are discarded.
+func (p *Pipeline) ProcessContext(ctx context.Context, item *types.Item) (*types.Item, error) {
 	current := item
 
-	for _, mw := range p.middlewares {
-		result, err := mw.Process(current)
+	for _, s := range p.stages {
+		result, _, err := s.call(ctx, current)
 		if err != nil {
 			return nil, &types.PipelineError{
-				Stage: mw.Name(),
+				Stage: s.mw.Name(),
 				Item:  current,
 				Err:   err,
 			}
 		}
 		if result == nil {
 			// Item dropped by middleware
-			p.logger.Debug("item dropped", "stage", mw.Name(), "url", item.URL)
+			p.logger.Debug("item dropped", "stage", s.mw.Name(), "url", item.URL)
 			return nil, nil
 		}
 		current = result
@@ -61,11 +82,46 @@ func (p *Pipeline) Process(item *types.Item) (*types.Item, error) {
 	return current, nil
 }
 
+// Prioritize passes a newly discovered request's priority through the
+// middlewares that adjust it from what they have seen, such as near_dup's
+// frontier hints.
+func (p *Pipeline) Prioritize(rawURL string, priority int) int {
+	for _, mw := range p.middlewares {
+		if h, ok := mw.(interface{ Prioritize(string, int) int }); ok {
+			priority = h.Prioritize(rawURL, priority)
+		}
+	}
+	return priority
+}
+
+// Committed tells the middlewares that record what was stored, such as
+// identity, that storage has accepted items.
+func (p *Pipeline) Committed(items []*types.Item) error {
+	var errs []error
+	for _, mw := range p.middlewares {
+		if c, ok := mw.(interface{ Committed([]*types.Item) error }); ok {
+			errs = append(errs, c.Committed(items))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Len returns the number of middleware in the chain.
 func (p *Pipeline) Len() int {
 	return len(p.middlewares)
 }
 
+// Close closes the middlewares holding resources, such as dead-letter files.
+func (p *Pipeline) Close() error {
+	var errs []error
+	for _, mw := range p.middlewares {
+		if c, ok := mw.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // --- Built-in Middleware ---
 
 // FieldFilterMiddleware keeps only specified fields.
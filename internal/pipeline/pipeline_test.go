@@ -1,11 +1,23 @@
 package pipeline
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/parser"
+	"github.com/IshaanNene/ScrapeGoat/internal/schema"
 	"github.com/IshaanNene/ScrapeGoat/internal/types"
 )
 
@@ -217,6 +229,616 @@ func TestWordCountMiddleware(t *testing.T) {
 	}
 }
 
+func TestMarkdownMiddleware(t *testing.T) {
+	m := NewMarkdownMiddleware([]string{"body"}, 20)
+	item := types.NewItem("https://example.com/docs/")
+	item.Set("body", `<h1>Guide</h1><p>See <a href="intro">the intro</a>.</p><h2>Install</h2><pre><code class="language-sh">go install ./...</code></pre>`)
+
+	result, err := m.Process(item)
+	if err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+
+	md := result.GetString("body")
+	for _, want := range []string{"# Guide", "[the intro](https://example.com/docs/intro)", "## Install", "```sh\ngo install ./...\n```"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+
+	chunks, ok := result.Get("body_chunks")
+	if !ok {
+		t.Fatal("expected body_chunks field")
+	}
+	if n := len(chunks.([]parser.MarkdownChunk)); n != 2 {
+		t.Errorf("expected 2 chunks, got %d", n)
+	}
+}
+
+func TestSchemaValidateMiddleware(t *testing.T) {
+	s, err := schema.Parse([]byte(`
+type: object
+required: [name, price]
+properties:
+  name: {type: string, minLength: 1}
+  price: {type: number, minimum: 0}
+  url: {type: string, format: uri}
+  stock: {enum: [in, out]}
+  tags: {type: array, items: {type: string}, maxItems: 2}
+`))
+	if err != nil {
+		t.Fatalf("parse schema: %v", err)
+	}
+	if got := strings.Join(s.PropertyNames(), ","); got != "name,price,url,stock,tags" {
+		t.Errorf("property order = %s", got)
+	}
+
+	m, err := NewSchemaValidateMiddleware(s, "annotate", "", true)
+	if err != nil {
+		t.Fatalf("create middleware: %v", err)
+	}
+
+	valid := types.NewItem("https://example.com/a")
+	valid.Set("name", "Widget")
+	valid.Set("price", "1,299.50")
+	valid.Set("tags", []string{"a", "b"})
+	result, _ := m.Process(valid)
+	if result.Has("_schema_errors") {
+		t.Errorf("unexpected errors: %v", result.Fields["_schema_errors"])
+	}
+	if price, _ := result.Get("price"); price != 1299.5 {
+		t.Errorf("price not coerced: %#v", price)
+	}
+
+	invalid := types.NewItem("https://example.com/b")
+	invalid.Set("price", "-3")
+	invalid.Set("url", "not a url")
+	invalid.Set("stock", "maybe")
+	invalid.Set("tags", []any{"a", 2, "c"})
+	result, _ = m.Process(invalid)
+	errs, _ := result.Get("_schema_errors")
+	want := []string{
+		"name: is required",
+		"price: must be >= 0",
+		"url: must be a valid uri",
+		`stock: must be one of ["in","out"]`,
+		"tags: must have at most 2 items",
+		"tags[1]: must be string, got integer",
+	}
+	if got := strings.Join(errs.([]string), "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("errors:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+
+	deadLetter := filepath.Join(t.TempDir(), "dead.jsonl")
+	dl, err := NewSchemaValidateMiddleware(s, "dead_letter", deadLetter, false)
+	if err != nil {
+		t.Fatalf("create middleware: %v", err)
+	}
+	if result, _ := dl.Process(types.NewItem("https://example.com/c")); result != nil {
+		t.Error("invalid item should be dropped")
+	}
+	dl.Close()
+	data, _ := os.ReadFile(deadLetter)
+	if !strings.Contains(string(data), `"url":"https://example.com/c"`) || !strings.Contains(string(data), `"path":"name"`) {
+		t.Errorf("dead letter = %s", data)
+	}
+}
+
+func TestRegistryBuild(t *testing.T) {
+	r := NewRegistry()
+	mws, err := r.Build([]config.MiddlewareConfig{
+		{Name: "trim"},
+		{Type: "date_normalize", Options: map[string]any{"fields": []any{"published"}, "format": "2006-01-02"}},
+		{Type: "Currency_Normalize", Options: map[string]any{"fields": []any{"price"}}},
+		{Type: "field_rename", Options: map[string]any{"mapping": map[string]any{"price": "amount"}}},
+	}, Env{Logger: testLogger})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	p := New(testLogger)
+	for _, mw := range mws {
+		p.Use(mw)
+	}
+	item := types.NewItem("https://example.com")
+	item.Set("published", " Jan 2, 2026 ")
+	item.Set("price", "$1,299.00")
+	result, err := p.Process(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.GetString("published"); got != "2026-01-02" {
+		t.Errorf("published = %q", got)
+	}
+	if got := result.GetString("amount"); got != "1299.00" {
+		t.Errorf("amount = %v", got)
+	}
+
+	_, err = r.Build([]config.MiddlewareConfig{
+		{Type: "no_such_thing"},
+		{Type: "date_normalize", Options: map[string]any{"feilds": []any{"x"}}},
+		{Type: "word_count"},
+		{Type: "type_coercion", Options: map[string]any{"fields": map[string]any{"n": "integer"}}},
+		{Type: "field_validate", Options: map[string]any{"drop_invalid": "yes"}},
+	}, Env{Logger: testLogger})
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{
+		`pipeline.middlewares[0]: unknown middleware type "no_such_thing"`,
+		`pipeline.middlewares[1] (date_normalize)`, `feilds`,
+		`pipeline.middlewares[2] (word_count): options: fields is required`,
+		`type must be int, float, bool or string, got "integer"`,
+		`pipeline.middlewares[4] (field_validate)`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q:\n%v", want, err)
+		}
+	}
+
+	if err := r.Register("shout", Typed(func(o fieldsOptions, _ Env) (Middleware, error) {
+		return &FieldFilterMiddleware{Fields: map[string]bool{o.Fields[0]: true}}, nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("SHOUT", nil); err == nil {
+		t.Error("duplicate registration should fail")
+	}
+	if _, err := r.Build([]config.MiddlewareConfig{{Type: "shout", Options: map[string]any{"fields": []any{"a"}}}}, Env{}); err != nil {
+		t.Errorf("custom type: %v", err)
+	}
+
+	// A failed build closes what it built before the bad entry.
+	var closed int
+	if err := r.Register("closer", func(_ map[string]any, _ Env) (Middleware, error) {
+		return &closerStage{&closed}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Build([]config.MiddlewareConfig{{Type: "closer"}, {Type: "no_such_thing"}}, Env{}); err == nil {
+		t.Error("expected unknown type error")
+	}
+	if closed != 1 {
+		t.Errorf("closed %d middlewares after failed build, want 1", closed)
+	}
+}
+
+// closerStage counts its Close calls.
+type closerStage struct{ closed *int }
+
+func (m *closerStage) Name() string { return "closer" }
+
+func (m *closerStage) Process(item *types.Item) (*types.Item, error) { return item, nil }
+
+func (m *closerStage) Close() error {
+	*m.closed++
+	return nil
+}
+
+// testStage is a configurable middleware for Stream tests.
+type testStage struct {
+	name    string
+	process func(item *types.Item) (*types.Item, error)
+}
+
+func (m *testStage) Name() string { return m.name }
+
+func (m *testStage) Process(item *types.Item) (*types.Item, error) { return m.process(item) }
+
+// testBatchStage records the size of each batch it is given.
+type testBatchStage struct {
+	testStage
+	mu    sync.Mutex
+	sizes []int
+}
+
+func (m *testBatchStage) ProcessBatch(items []*types.Item) ([]*types.Item, error) {
+	m.mu.Lock()
+	m.sizes = append(m.sizes, len(items))
+	m.mu.Unlock()
+	for _, item := range items {
+		item.Set("batched", true)
+	}
+	return items, nil
+}
+
+func TestPipelineStream(t *testing.T) {
+	run := func(ordered bool) ([]int, []error, *Pipeline, *testBatchStage) {
+		p := New(testLogger)
+		p.SetOrdered(ordered)
+		p.UseStage(&testStage{name: "slow", process: func(item *types.Item) (*types.Item, error) {
+			n := item.Fields["n"].(int)
+			time.Sleep(time.Duration(10-n%10) * time.Millisecond) // early items finish last
+			switch {
+			case n%7 == 3:
+				return nil, nil
+			case n%7 == 5:
+				return nil, fmt.Errorf("bad item %d", n)
+			}
+			return item, nil
+		}}, StageOptions{Concurrency: 8})
+		batch := &testBatchStage{testStage: testStage{name: "batch"}}
+		p.UseStage(batch, StageOptions{BatchSize: 4, BatchWait: 20 * time.Millisecond})
+
+		in := make(chan *types.Item)
+		go func() {
+			for n := range 30 {
+				item := types.NewItem(fmt.Sprintf("https://example.com/%d", n))
+				item.Set("n", n)
+				in <- item
+			}
+			close(in)
+		}()
+
+		var got []int
+		var errs []error
+		p.Stream(in, func(item *types.Item, err error) {
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			if item.Fields["batched"] != true {
+				t.Errorf("item %v skipped the batch stage", item.Fields["n"])
+			}
+			got = append(got, item.Fields["n"].(int))
+		})
+		return got, errs, p, batch
+	}
+
+	got, errs, p, batch := run(true)
+	var want []int
+	for n := range 30 {
+		if n%7 != 3 && n%7 != 5 {
+			want = append(want, n)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ordered output = %v, want %v", got, want)
+	}
+	if len(errs) != 4 {
+		t.Errorf("expected 4 errors, got %v", errs)
+	}
+	var pe *types.PipelineError
+	if !errors.As(errs[0], &pe) || pe.Stage != "slow" || pe.Item == nil {
+		t.Errorf("error should name the stage and item: %#v", errs[0])
+	}
+
+	stats := p.Stats()
+	if s := stats[0]; s.In != 30 || s.Out != 22 || s.Dropped != 4 || s.Errors != 4 || s.Workers != 8 {
+		t.Errorf("slow stage stats = %+v", s)
+	}
+	if s := stats[1]; s.In != 22 || s.Batches == 0 || s.Queued != 0 {
+		t.Errorf("batch stage stats = %+v", s)
+	}
+	for _, size := range batch.sizes {
+		if size > 4 {
+			t.Errorf("batch of %d exceeds batch_size", size)
+		}
+	}
+
+	got, _, _, _ = run(false)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("unordered output = %v, want %v", got, want)
+	}
+}
+
+// TestPipelineStreamWindow checks that an ordered stream stops reading
+// input while its first item is held up, instead of buffering the rest.
+func TestPipelineStreamWindow(t *testing.T) {
+	release := make(chan struct{})
+	p := New(testLogger)
+	p.SetBuffer(4)
+	p.UseStage(&testStage{name: "first waits", process: func(item *types.Item) (*types.Item, error) {
+		if item.Fields["n"] == 0 {
+			<-release
+		}
+		return item, nil
+	}}, StageOptions{Concurrency: 2})
+
+	var sent atomic.Int64
+	in := make(chan *types.Item)
+	go func() {
+		for n := range 100 {
+			item := types.NewItem(fmt.Sprintf("https://example.com/%d", n))
+			item.Set("n", n)
+			in <- item
+			sent.Add(1)
+		}
+		close(in)
+	}()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+
+	var got []int
+	var maxSent int64
+	p.Stream(in, func(item *types.Item, err error) {
+		if len(got) == 0 {
+			maxSent = sent.Load()
+		}
+		got = append(got, item.Fields["n"].(int))
+	})
+	// 4 queued and 2 in workers, plus the item the source holds.
+	if maxSent > 7 {
+		t.Errorf("read %d items while the first was held up, want at most 7", maxSent)
+	}
+	if len(got) != 100 || !slices.IsSorted(got) {
+		t.Errorf("output = %v, want 0..99 in order", got)
+	}
+}
+
+func TestContextMiddleware(t *testing.T) {
+	req, _ := types.NewRequest("https://example.com/p/1")
+	origin := &types.Origin{
+		Request: req,
+		Response: &types.ResponseMeta{
+			StatusCode: 200,
+			Headers:    http.Header{"Last-Modified": {"Mon, 02 Jan 2006 15:04:05 GMT"}},
+			Page:       map[string]string{"title": "Widget", "lang": "en"},
+		},
+		Stats: func() map[string]any { return map[string]any{"items_scraped": int64(7)} },
+	}
+
+	enrich := ContextFunc("enrich", func(ctx context.Context, env *Envelope) (*types.Item, error) {
+		env.Item.Set("modified", env.Header("Last-Modified"))
+		env.Item.Set("title", env.Response.Page["title"])
+		env.Item.Set("seen", env.Stats()["items_scraped"])
+		// A fresh item inherits the origin of the one it replaces.
+		out := types.NewItem(env.Item.URL)
+		out.Fields = env.Item.Fields
+		return out, nil
+	})
+	wait := ContextFunc("wait", func(ctx context.Context, env *Envelope) (*types.Item, error) {
+		if env.Request == nil || env.Request.URLString() != "https://example.com/p/1" {
+			t.Errorf("origin lost between stages: %+v", env.Request)
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	p := New(testLogger)
+	p.Use(enrich)
+	p.Use(&TrimMiddleware{}) // adapted
+	p.UseStage(wait, StageOptions{Timeout: 20 * time.Millisecond})
+
+	item := types.NewItem("https://example.com/p/1")
+	item.Set("name", "  widget ")
+	item.Origin = origin
+	_, err := p.Process(item)
+	var pe *types.PipelineError
+	if !errors.As(err, &pe) || pe.Stage != "wait" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait stage to time out, got %v", err)
+	}
+	if got := pe.Item.GetString("title"); got != "Widget" {
+		t.Errorf("title = %q", got)
+	}
+	if got := pe.Item.GetString("modified"); got == "" {
+		t.Error("header not copied")
+	}
+	if got := pe.Item.GetString("name"); got != "widget" {
+		t.Errorf("legacy middleware not run: %q", got)
+	}
+	if pe.Item.Fields["seen"] != int64(7) {
+		t.Errorf("stats = %v", pe.Item.Fields["seen"])
+	}
+
+	// Items without an origin get an empty envelope.
+	env := NewEnvelope(types.NewItem("https://example.com"))
+	if env.Response != nil || env.Header("Content-Type") != "" || env.Stats() != nil {
+		t.Errorf("unexpected envelope: %+v", env)
+	}
+
+	// Adapted middlewares are skipped once the context is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Adapt(&TrimMiddleware{}).ProcessContext(ctx, env); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	in := make(chan *types.Item, 3)
+	for range 3 {
+		in <- types.NewItem("https://example.com")
+	}
+	close(in)
+	failed := 0
+	legacy := New(testLogger)
+	legacy.Use(&TrimMiddleware{})
+	legacy.StreamContext(ctx, in, func(item *types.Item, err error) {
+		if errors.Is(err, context.Canceled) {
+			failed++
+		}
+	})
+	if failed != 3 {
+		t.Errorf("expected 3 cancelled items, got %d", failed)
+	}
+}
+
+// TestIdentityMiddleware covers the middleware's wiring: item keys and
+// checksums, the status field, drop_unchanged, and committing only what
+// storage accepted. The store itself is tested in internal/identity.
+func TestIdentityMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identity.db")
+
+	// Each run reopens the store; items are "sku=price" and are committed,
+	// as the engine does after a successful Store, when stored is set.
+	runs := []struct {
+		items         []string
+		stored        bool
+		dropUnchanged bool
+		want          []string
+	}{
+		{[]string{"A1=10", "B2=20"}, true, false, []string{"new", "new"}},
+		{[]string{"A1=10", "B2=25", "C3=5"}, true, false, []string{"unchanged", "changed", "new"}},
+		{[]string{"B2=30", "D4=1"}, false, false, []string{"changed", "new"}},
+		{[]string{"A1=10", "B2=25", "C3=6", "D4=1"}, true, true, []string{"dropped", "dropped", "changed", "new"}},
+	}
+	for i, run := range runs {
+		mws, err := NewRegistry().Build([]config.MiddlewareConfig{{Type: "identity", Options: map[string]any{
+			"path":           path,
+			"key":            []any{"sku"},
+			"drop_unchanged": run.dropUnchanged,
+		}}}, Env{Logger: testLogger})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := New(testLogger)
+		p.Use(mws[0])
+
+		var got []string
+		var out []*types.Item
+		for _, entry := range run.items {
+			sku, price, _ := strings.Cut(entry, "=")
+			item := types.NewItem("https://example.com/" + sku)
+			item.Set("sku", sku)
+			item.Set("price", price)
+			processed, err := p.Process(item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if processed == nil {
+				got = append(got, "dropped")
+				continue
+			}
+			if processed.Key != sku || processed.Checksum == "" {
+				t.Errorf("run %d: key = %q, checksum = %q", i+1, processed.Key, processed.Checksum)
+			}
+			got = append(got, processed.GetString("_item_status"))
+			out = append(out, processed)
+		}
+		if run.stored {
+			if err := p.Committed(out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, run.want) {
+			t.Errorf("run %d = %v, want %v", i+1, got, run.want)
+		}
+	}
+}
+
+// TestNearDupMiddleware covers the middleware's fields and frontier hints;
+// fingerprints and the index are tested in internal/neardup.
+func TestNearDupMiddleware(t *testing.T) {
+	article := "The city council voted on Tuesday to expand the riverside park, adding two new playgrounds, " +
+		"a community garden and a cycling path that will connect the old harbour to the train station. " +
+		"Construction is expected to begin next spring and finish within eighteen months."
+	other := "Researchers at the university have published a study on the migration of songbirds, showing " +
+		"that warmer autumns delay departure by up to two weeks and change the routes the birds follow south."
+
+	mws, err := NewRegistry().Build([]config.MiddlewareConfig{{Type: "near_dup", Options: map[string]any{
+		"fields":         []any{"body"},
+		"frontier_hint":  true,
+		"hint_min_items": 2,
+	}}}, Env{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(testLogger)
+	p.Use(mws[0])
+
+	items := []struct {
+		url, body string
+		dupOf     string
+	}{
+		{"https://news.example.com/2024/park", article, ""},
+		{"https://news.example.com/2024/birds", other, ""},
+		{"https://news.example.com/print/park", strings.ToUpper(article) + " Share this story.", "https://news.example.com/2024/park"},
+		{"https://news.example.com/print/birds", other, "https://news.example.com/2024/birds"},
+	}
+	for _, it := range items {
+		item := types.NewItem(it.url)
+		item.Set("body", it.body)
+		out, err := p.Process(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := out.GetString("_near_duplicate_of"); got != it.dupOf {
+			t.Errorf("%s: _near_duplicate_of = %q, want %q", it.url, got, it.dupOf)
+		}
+		if d, ok := out.Get("_near_duplicate_distance"); ok != (it.dupOf != "") || ok && d.(int) > 3 {
+			t.Errorf("%s: _near_duplicate_distance = %v", it.url, d)
+		}
+	}
+
+	for url, want := range map[string]int{
+		"https://news.example.com/print/other": types.PriorityLowest,
+		"https://news.example.com/2024/other":  types.PriorityNormal,
+	} {
+		if got := p.Prioritize(url, types.PriorityNormal); got != want {
+			t.Errorf("Prioritize(%s) = %d, want %d", url, got, want)
+		}
+	}
+
+	if _, err := NewRegistry().Build([]config.MiddlewareConfig{{Type: "near_dup", Options: map[string]any{"action": "delete"}}}, Env{}); err == nil {
+		t.Error("unknown action should fail")
+	}
+}
+
+// TestEnrichMiddlewares checks that the enrichment middlewares are built
+// from their options and write their output fields; the parsers are
+// tested in internal/enrich.
+func TestEnrichMiddlewares(t *testing.T) {
+	mws, err := NewRegistry().Build([]config.MiddlewareConfig{
+		{Type: "language_detect", Options: map[string]any{"fields": []any{"body"}}},
+		{Type: "reading_time", Options: map[string]any{"fields": []any{"body"}, "words_per_minute": 5}},
+		{Type: "url_normalize", Options: map[string]any{"fields": []any{"links", "canonical"}}},
+		{Type: "phone_parse", Options: map[string]any{"fields": []any{"phone"}, "region": "GB"}},
+		{Type: "address_parse", Options: map[string]any{"fields": []any{"address"}}},
+		{Type: "unit_convert", Options: map[string]any{"fields": []any{"weight"}, "targets": map[string]any{"mass": "g"}}},
+	}, Env{Logger: testLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unshortening makes requests, which stage timeouts must bound.
+	if _, ok := mws[2].(ContextMiddleware); !ok {
+		t.Errorf("url_normalize is %T, not a ContextMiddleware", mws[2])
+	}
+	p := New(testLogger)
+	for _, mw := range mws {
+		p.Use(mw)
+	}
+
+	item := types.NewItem("https://shop.example.com/products/widget")
+	item.Set("body", "The weather was nice so we went for a walk along the river with the children.")
+	item.Set("links", []any{"../reviews/?utm_source=mail", "mailto:sales@example.com"})
+	item.Set("canonical", "HTTPS://Shop.Example.com/products/widget/")
+	item.Set("phone", "020 7946 0958")
+	item.Set("address", "1600 Amphitheatre Pkwy, Mountain View, CA 94043, USA")
+	item.Set("weight", "2.5 lb")
+	out, err := p.Process(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"language", "en"},
+		{"reading_time", "4"}, // 16 words at 5 a minute
+		{"links", "[https://shop.example.com/reviews mailto:sales@example.com]"},
+		{"canonical", "https://shop.example.com/products/widget"},
+		{"phone_e164", "+442079460958"},
+		{"phone_region", "GB"},
+		{"address_parsed", "map[city:Mountain View country:US postal_code:94043 region:CA street:1600 Amphitheatre Pkwy]"},
+		{"weight_value", "1133.981"},
+		{"weight_unit", "g"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(out.Fields[tt.field]); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.field, got, tt.want)
+		}
+	}
+
+	if _, err := NewRegistry().Build([]config.MiddlewareConfig{{Type: "phone_parse", Options: map[string]any{"fields": []any{"p"}, "region": "XX"}}}, Env{}); err == nil {
+		t.Error("unknown region should fail")
+	}
+}
+
 // --- Benchmarks ---
 
 func BenchmarkPipeline(b *testing.B) {
@@ -0,0 +1,632 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/ai"
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/enrich"
+	"github.com/IshaanNene/ScrapeGoat/internal/identity"
+	"github.com/IshaanNene/ScrapeGoat/internal/neardup"
+	"github.com/IshaanNene/ScrapeGoat/internal/schema"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// Env is what middleware factories may use besides their options.
+type Env struct {
+	Logger *slog.Logger
+	AI     config.AIConfig // LLM defaults for the ai_* middlewares
+}
+
+// Factory builds a middleware from the options of a pipeline.middlewares
+// entry.
+type Factory func(options map[string]any, env Env) (Middleware, error)
+
+// Typed adapts a constructor taking an options struct to a Factory. Options
+// are decoded by their mapstructure tags; unknown keys and mistyped values
+// are errors. If *T has a Validate() error method it is called afterwards.
+func Typed[T any](build func(opts T, env Env) (Middleware, error)) Factory {
+	return func(options map[string]any, env Env) (Middleware, error) {
+		var opts T
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			Result:      &opts,
+			ErrorUnused: true,
+			DecodeHook:  mapstructure.StringToTimeDurationHookFunc(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := dec.Decode(options); err != nil {
+			return nil, optionsError(err)
+		}
+		if v, ok := any(&opts).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				return nil, fmt.Errorf("options: %w", err)
+			}
+		}
+		return build(opts, env)
+	}
+}
+
+// optionsError flattens mapstructure's multi-line report into one line,
+// e.g. "options: unknown option(s) feilds".
+func optionsError(err error) error {
+	errs := []error{err}
+	if joined, ok := errors.Unwrap(err).(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msg := strings.Replace(e.Error(), "'' has invalid keys:", "unknown option(s)", 1)
+		msgs = append(msgs, msg)
+	}
+	return fmt.Errorf("options: %s", strings.Join(msgs, "; "))
+}
+
+// Registry maps middleware types to factories.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// DefaultRegistry holds the built-in middlewares and those added with
+// Register. Middleware plugins are added to a Clone of it when the crawl
+// builds its pipeline (see plugin.Registry.BuildPipeline).
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates a registry holding the built-in middlewares.
+func NewRegistry() *Registry {
+	r := &Registry{factories: make(map[string]Factory)}
+	for typ, f := range builtins() {
+		r.factories[typ] = f
+	}
+	return r
+}
+
+// Clone returns a registry holding the same middleware types as r, to which
+// more can be added without changing r.
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := &Registry{factories: make(map[string]Factory, len(r.factories))}
+	for typ, f := range r.factories {
+		c.factories[typ] = f
+	}
+	return c
+}
+
+// Register adds a middleware type to the default registry.
+func Register(typ string, f Factory) error {
+	return DefaultRegistry.Register(typ, f)
+}
+
+// Register adds a middleware type. Types are case-insensitive and may not be
+// registered twice.
+func (r *Registry) Register(typ string, f Factory) error {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if typ == "" {
+		return fmt.Errorf("middleware type is empty")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.factories[typ]; exists {
+		return fmt.Errorf("middleware type %q already registered", typ)
+	}
+	r.factories[typ] = f
+	return nil
+}
+
+// Types returns the registered middleware types, sorted.
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.factories))
+	for typ := range r.factories {
+		types = append(types, typ)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Build constructs the middlewares declared in config, in order. An entry's
+// type defaults to its name. Every invalid entry is reported, each error
+// naming its position, e.g. "pipeline.middlewares[2] (date_normalize): ...",
+// and the middlewares already built are closed.
+func (r *Registry) Build(cfgs []config.MiddlewareConfig, env Env) ([]Middleware, error) {
+	if env.Logger == nil {
+		env.Logger = slog.Default()
+	}
+
+	var (
+		out  []Middleware
+		errs []error
+	)
+	for i, cfg := range cfgs {
+		typ := cfg.Type
+		if typ == "" {
+			typ = cfg.Name
+		}
+		typ = strings.ToLower(strings.TrimSpace(typ))
+
+		r.mu.RLock()
+		f, ok := r.factories[typ]
+		r.mu.RUnlock()
+		if !ok {
+			errs = append(errs, fmt.Errorf("pipeline.middlewares[%d]: unknown middleware type %q (known: %s)",
+				i, typ, strings.Join(r.Types(), ", ")))
+			continue
+		}
+
+		mw, err := f(cfg.Options, env)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pipeline.middlewares[%d] (%s): %w", i, typ, err))
+			continue
+		}
+		out = append(out, mw)
+		if err := validateStage(cfg, mw); err != nil {
+			errs = append(errs, fmt.Errorf("pipeline.middlewares[%d] (%s): %w", i, typ, err))
+		}
+	}
+	if len(errs) > 0 {
+		for _, mw := range out {
+			if c, ok := mw.(io.Closer); ok {
+				errs = append(errs, c.Close())
+			}
+		}
+		return nil, errors.Join(errs...)
+	}
+	return out, nil
+}
+
+func validateStage(cfg config.MiddlewareConfig, mw Middleware) error {
+	if cfg.Concurrency < 0 || cfg.BatchSize < 0 || cfg.BatchWait < 0 || cfg.Timeout < 0 {
+		return fmt.Errorf("concurrency, batch_size, batch_wait and timeout must not be negative")
+	}
+	if _, ok := mw.(BatchMiddleware); !ok && cfg.BatchSize > 1 {
+		return fmt.Errorf("batch_size is set but %s does not process batches", mw.Name())
+	}
+	return nil
+}
+
+// FromConfig builds a pipeline from the pipeline config using the default
+// registry.
+func FromConfig(cfg *config.Config, logger *slog.Logger) (*Pipeline, error) {
+	return DefaultRegistry.FromConfig(cfg, logger)
+}
+
+// FromConfig builds a pipeline from the pipeline config using r.
+func (r *Registry) FromConfig(cfg *config.Config, logger *slog.Logger) (*Pipeline, error) {
+	mws, err := r.Build(cfg.Pipeline.Middlewares, Env{Logger: logger, AI: cfg.AI})
+	if err != nil {
+		return nil, err
+	}
+	p := New(logger)
+	p.SetOrdered(cfg.Pipeline.Ordered)
+	p.SetBuffer(cfg.Pipeline.Buffer)
+	for i, mw := range mws {
+		mc := cfg.Pipeline.Middlewares[i]
+		p.UseStage(mw, StageOptions{
+			Concurrency: mc.Concurrency,
+			BatchSize:   mc.BatchSize,
+			BatchWait:   mc.BatchWait,
+			Timeout:     mc.Timeout,
+		})
+	}
+	return p, nil
+}
+
+// --- Built-in Factories ---
+
+type fieldsOptions struct {
+	Fields []string `mapstructure:"fields"`
+}
+
+func (o *fieldsOptions) Validate() error {
+	if len(o.Fields) == 0 {
+		return fmt.Errorf("fields is required")
+	}
+	return nil
+}
+
+type dateOptions struct {
+	fieldsOptions `mapstructure:",squash"`
+	Format        string `mapstructure:"format"` // Go layout (default RFC 3339)
+}
+
+type coercionOptions struct {
+	Fields map[string]string `mapstructure:"fields"` // field -> int, float, bool, string
+}
+
+func (o *coercionOptions) Validate() error {
+	if len(o.Fields) == 0 {
+		return fmt.Errorf("fields is required")
+	}
+	for field, typ := range o.Fields {
+		if !slices.Contains([]string{"int", "float", "bool", "string"}, typ) {
+			return fmt.Errorf("fields.%s: type must be int, float, bool or string, got %q", field, typ)
+		}
+	}
+	return nil
+}
+
+type validateOptions struct {
+	Patterns    map[string]string `mapstructure:"patterns"` // field -> regex
+	DropInvalid bool              `mapstructure:"drop_invalid"`
+}
+
+type markdownOptions struct {
+	fieldsOptions `mapstructure:",squash"`
+	ChunkTokens   int `mapstructure:"chunk_tokens"`
+}
+
+type renameOptions struct {
+	Mapping map[string]string `mapstructure:"mapping"` // old name -> new name
+}
+
+type dedupOptions struct {
+	Key string `mapstructure:"key"` // field to compare; empty compares URLs
+}
+
+type identityOptions struct {
+	Path          string   `mapstructure:"path"`           // store file (default .scrapegoat_items/identity.db)
+	Key           []string `mapstructure:"key"`            // fields identifying an item; empty uses its content hash
+	StatusField   string   `mapstructure:"status_field"`   // field receiving new/changed/unchanged (default _item_status)
+	DropUnchanged bool     `mapstructure:"drop_unchanged"` // drop items seen before with the same content
+}
+
+type nearDupOptions struct {
+	Fields       []string `mapstructure:"fields"`         // text to fingerprint; empty uses every string field
+	MaxDistance  int      `mapstructure:"max_distance"`   // differing bits still counted as duplicates (default 3)
+	MinWords     int      `mapstructure:"min_words"`      // shorter texts are not checked (default 20)
+	Action       string   `mapstructure:"action"`         // annotate (default) or drop
+	FrontierHint bool     `mapstructure:"frontier_hint"`  // deprioritise site sections producing mostly duplicates
+	HintMinItems int      `mapstructure:"hint_min_items"` // items a section needs before it is judged (default 10)
+	HintRatio    float64  `mapstructure:"hint_ratio"`     // duplicate share that flags a section (default 0.5)
+}
+
+func (o *nearDupOptions) Validate() error {
+	switch o.Action {
+	case "", "annotate", "drop":
+	default:
+		return fmt.Errorf("unknown action %q (valid: annotate, drop)", o.Action)
+	}
+	if o.MaxDistance < 0 || o.MinWords < 0 || o.HintMinItems < 0 || o.HintRatio < 0 || o.HintRatio > 1 {
+		return fmt.Errorf("max_distance, min_words and hint_min_items must not be negative, hint_ratio must be 0–1")
+	}
+	return nil
+}
+
+type scriptOptions struct {
+	File           string        `mapstructure:"file"`            // Starlark script
+	Function       string        `mapstructure:"function"`        // function called per item (default process)
+	Timeout        time.Duration `mapstructure:"timeout"`         // per item (default 1s)
+	MaxSteps       uint64        `mapstructure:"max_steps"`       // execution steps per item (default 10,000,000)
+	MaxOutputMB    int64         `mapstructure:"max_output_mb"`   // field data stored per item (default 16)
+	MaxEmit        int           `mapstructure:"max_emit"`        // extra items per item (default 100)
+	Reload         *bool         `mapstructure:"reload"`          // reload the file when it changes (default true)
+	ReloadInterval time.Duration `mapstructure:"reload_interval"` // how often to check (default 2s)
+}
+
+func (o *scriptOptions) Validate() error {
+	if o.File == "" {
+		return errors.New("file is required")
+	}
+	if o.Timeout < 0 || o.MaxOutputMB < 0 || o.MaxEmit < 0 || o.ReloadInterval < 0 {
+		return errors.New("timeout, max_output_mb, max_emit and reload_interval must not be negative")
+	}
+	return nil
+}
+
+type languageOptions struct {
+	fieldsOptions `mapstructure:",squash"`
+	Output        string `mapstructure:"output"` // field for the ISO 639-1 code (default language)
+}
+
+type readingTimeOptions struct {
+	fieldsOptions  `mapstructure:",squash"`
+	Output         string `mapstructure:"output"`           // field for the minutes (default reading_time)
+	WordsPerMinute int    `mapstructure:"words_per_minute"` // default 230
+}
+
+type urlNormalizeOptions struct {
+	fieldsOptions `mapstructure:",squash"`
+	KeepTracking  bool          `mapstructure:"keep_tracking"` // keep utm_*, fbclid and similar parameters
+	Unshorten     bool          `mapstructure:"unshorten"`     // resolve short links (needs network)
+	Shorteners    []string      `mapstructure:"shorteners"`    // shortener hosts (default: common ones)
+	Timeout       time.Duration `mapstructure:"timeout"`       // per short link (default 5s)
+}
+
+type regionOptions struct {
+	fieldsOptions `mapstructure:",squash"`
+	Region        string `mapstructure:"region"` // ISO 3166 country assumed when a value names none (default US)
+}
+
+func (o *regionOptions) Validate() error {
+	if o.Region == "" {
+		o.Region = "US"
+	}
+	if !enrich.PhoneRegionKnown(o.Region) {
+		return fmt.Errorf("unsupported region %q", o.Region)
+	}
+	return o.fieldsOptions.Validate()
+}
+
+type unitOptions struct {
+	fieldsOptions `mapstructure:",squash"`
+	System        string            `mapstructure:"system"`  // metric (default) or imperial
+	Targets       map[string]string `mapstructure:"targets"` // dimension -> unit overrides, e.g. {length: cm}
+}
+
+type defaultsOptions struct {
+	Values map[string]any `mapstructure:"values"`
+}
+
+type schemaOptions struct {
+	File           string `mapstructure:"file"`
+	OnInvalid      string `mapstructure:"on_invalid"`
+	DeadLetterPath string `mapstructure:"dead_letter_path"`
+	Coerce         bool   `mapstructure:"coerce"`
+}
+
+func (o *schemaOptions) Validate() error {
+	if o.File == "" {
+		return fmt.Errorf("file is required")
+	}
+	return nil
+}
+
+// llmOptions override the ai config for one middleware.
+type llmOptions struct {
+	Provider string `mapstructure:"provider"`
+	Model    string `mapstructure:"model"`
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+type summarizerOptions struct {
+	fieldsOptions `mapstructure:",squash"`
+	llmOptions    `mapstructure:",squash"`
+	MaxLength     int `mapstructure:"max_length"`
+}
+
+type llmFieldsOptions struct {
+	fieldsOptions `mapstructure:",squash"`
+	llmOptions    `mapstructure:",squash"`
+}
+
+type contentFilterOptions struct {
+	fieldsOptions `mapstructure:",squash"`
+	llmOptions    `mapstructure:",squash"`
+	Criteria      string `mapstructure:"criteria"`
+	Drop          bool   `mapstructure:"drop"` // drop items that fail the criteria
+}
+
+func (o *contentFilterOptions) Validate() error {
+	if o.Criteria == "" {
+		return fmt.Errorf("criteria is required")
+	}
+	return o.fieldsOptions.Validate()
+}
+
+func builtins() map[string]Factory {
+	return map[string]Factory{
+		"trim": Typed(func(struct{}, Env) (Middleware, error) {
+			return &TrimMiddleware{}, nil
+		}),
+		"html_sanitize": Typed(func(struct{}, Env) (Middleware, error) {
+			return NewHTMLSanitizeMiddleware(), nil
+		}),
+		"pii_redact": Typed(func(_ struct{}, env Env) (Middleware, error) {
+			return NewPIIRedactMiddleware(env.Logger), nil
+		}),
+		"date_normalize": Typed(func(o dateOptions, _ Env) (Middleware, error) {
+			return NewDateNormalizeMiddleware(o.Fields, o.Format), nil
+		}),
+		"currency_normalize": Typed(func(o fieldsOptions, _ Env) (Middleware, error) {
+			return NewCurrencyNormalizeMiddleware(o.Fields), nil
+		}),
+		"type_coercion": Typed(func(o coercionOptions, _ Env) (Middleware, error) {
+			return NewTypeCoercionMiddleware(o.Fields), nil
+		}),
+		"field_validate": Typed(func(o validateOptions, _ Env) (Middleware, error) {
+			return NewFieldValidateMiddleware(o.Patterns, o.DropInvalid)
+		}),
+		"word_count": Typed(func(o fieldsOptions, _ Env) (Middleware, error) {
+			return NewWordCountMiddleware(o.Fields), nil
+		}),
+		"markdown": Typed(func(o markdownOptions, _ Env) (Middleware, error) {
+			return NewMarkdownMiddleware(o.Fields, o.ChunkTokens), nil
+		}),
+		"field_filter": Typed(func(o fieldsOptions, _ Env) (Middleware, error) {
+			keep := make(map[string]bool, len(o.Fields))
+			for _, f := range o.Fields {
+				keep[f] = true
+			}
+			return &FieldFilterMiddleware{Fields: keep}, nil
+		}),
+		"field_rename": Typed(func(o renameOptions, _ Env) (Middleware, error) {
+			return &FieldRenameMiddleware{Mapping: o.Mapping}, nil
+		}),
+		"required_fields": Typed(func(o fieldsOptions, _ Env) (Middleware, error) {
+			return &RequiredFieldsMiddleware{Fields: o.Fields}, nil
+		}),
+		"dedup": Typed(func(o dedupOptions, _ Env) (Middleware, error) {
+			return NewDedupMiddleware(o.Key), nil
+		}),
+		"identity": Typed(func(o identityOptions, env Env) (Middleware, error) {
+			if o.Path == "" {
+				o.Path = ".scrapegoat_items/identity.db"
+			}
+			if o.StatusField == "" {
+				o.StatusField = "_item_status"
+			}
+			store, err := identity.Open(o.Path)
+			if err != nil {
+				return nil, err
+			}
+			return NewIdentityMiddleware(store, o.Key, o.StatusField, o.DropUnchanged, env.Logger), nil
+		}),
+		"near_dup": Typed(func(o nearDupOptions, _ Env) (Middleware, error) {
+			if o.MaxDistance == 0 {
+				o.MaxDistance = 3
+			}
+			if o.MinWords == 0 {
+				o.MinWords = 20
+			}
+			var hints *neardup.Hints
+			if o.FrontierHint {
+				if o.HintMinItems == 0 {
+					o.HintMinItems = 10
+				}
+				if o.HintRatio == 0 {
+					o.HintRatio = 0.5
+				}
+				hints = neardup.NewHints(o.HintMinItems, o.HintRatio)
+			}
+			return NewNearDupMiddleware(o.Fields, o.MaxDistance, o.MinWords, o.Action == "drop", hints), nil
+		}),
+		"script": Typed(func(o scriptOptions, env Env) (Middleware, error) {
+			if o.Function == "" {
+				o.Function = "process"
+			}
+			if o.Timeout == 0 {
+				o.Timeout = time.Second
+			}
+			if o.MaxSteps == 0 {
+				o.MaxSteps = 10_000_000
+			}
+			if o.MaxOutputMB == 0 {
+				o.MaxOutputMB = 16
+			}
+			if o.MaxEmit == 0 {
+				o.MaxEmit = 100
+			}
+			var reload time.Duration
+			if o.Reload == nil || *o.Reload {
+				reload = o.ReloadInterval
+				if reload == 0 {
+					reload = 2 * time.Second
+				}
+			}
+			limits := ScriptLimits{Timeout: o.Timeout, MaxSteps: o.MaxSteps, MaxOutput: o.MaxOutputMB << 20, MaxEmit: o.MaxEmit}
+			return NewScriptMiddleware(o.File, o.Function, limits, reload, env.Logger)
+		}),
+		"language_detect": Typed(func(o languageOptions, _ Env) (Middleware, error) {
+			if o.Output == "" {
+				o.Output = "language"
+			}
+			return enrich.NewLanguageDetector(o.Fields, o.Output), nil
+		}),
+		"reading_time": Typed(func(o readingTimeOptions, _ Env) (Middleware, error) {
+			if o.Output == "" {
+				o.Output = "reading_time"
+			}
+			if o.WordsPerMinute <= 0 {
+				o.WordsPerMinute = 230
+			}
+			return enrich.NewReadingTime(o.Fields, o.Output, o.WordsPerMinute), nil
+		}),
+		"url_normalize": Typed(func(o urlNormalizeOptions, env Env) (Middleware, error) {
+			if o.Shorteners == nil {
+				o.Shorteners = enrich.DefaultShorteners
+			}
+			if o.Timeout <= 0 {
+				o.Timeout = 5 * time.Second
+			}
+			return urlNormalizer{enrich.NewURLNormalizer(o.Fields, o.KeepTracking, o.Unshorten, o.Shorteners, o.Timeout, env.Logger)}, nil
+		}),
+		"phone_parse": Typed(func(o regionOptions, _ Env) (Middleware, error) {
+			return enrich.NewPhoneParser(o.Fields, o.Region), nil
+		}),
+		"address_parse": Typed(func(o regionOptions, _ Env) (Middleware, error) {
+			return enrich.NewAddressParser(o.Fields, o.Region), nil
+		}),
+		"unit_convert": Typed(func(o unitOptions, _ Env) (Middleware, error) {
+			if o.System == "" {
+				o.System = "metric"
+			}
+			return enrich.NewUnitConverter(o.Fields, o.System, o.Targets)
+		}),
+		"default_values": Typed(func(o defaultsOptions, _ Env) (Middleware, error) {
+			return &DefaultValueMiddleware{Defaults: o.Values}, nil
+		}),
+		"schema_validate": Typed(func(o schemaOptions, _ Env) (Middleware, error) {
+			s, err := schema.Load(o.File)
+			if err != nil {
+				return nil, err
+			}
+			if o.OnInvalid == "" {
+				o.OnInvalid = "annotate"
+			}
+			return NewSchemaValidateMiddleware(s, o.OnInvalid, o.DeadLetterPath, o.Coerce)
+		}),
+		"ai_summarizer": Typed(func(o summarizerOptions, env Env) (Middleware, error) {
+			if o.MaxLength == 0 {
+				o.MaxLength = 200
+			}
+			return ai.NewSummarizer(llmClient(o.llmOptions, env), o.Fields, o.MaxLength, env.Logger), nil
+		}),
+		"ai_ner": Typed(func(o llmFieldsOptions, env Env) (Middleware, error) {
+			return ai.NewNERExtractor(llmClient(o.llmOptions, env), o.Fields, env.Logger), nil
+		}),
+		"ai_sentiment": Typed(func(o llmFieldsOptions, env Env) (Middleware, error) {
+			return ai.NewSentimentAnalyzer(llmClient(o.llmOptions, env), o.Fields, env.Logger), nil
+		}),
+		"ai_content_filter": Typed(func(o contentFilterOptions, env Env) (Middleware, error) {
+			return ai.NewContentFilter(llmClient(o.llmOptions, env), o.Fields, o.Criteria, o.Drop, env.Logger), nil
+		}),
+	}
+}
+
+// llmClient builds the client for an ai_* middleware from its options,
+// falling back to the ai config and then to a local Ollama. The OpenAI key
+// is read from OPENAI_API_KEY.
+func llmClient(o llmOptions, env Env) *ai.LLMClient {
+	provider := ai.LLMProvider(firstNonEmpty(o.Provider, env.AI.Provider, string(ai.ProviderOllama)))
+	endpoint := firstNonEmpty(o.Endpoint, env.AI.Endpoint)
+	if endpoint == "" {
+		switch provider {
+		case ai.ProviderOllama:
+			endpoint = "http://localhost:11434"
+		case ai.ProviderOpenAI:
+			endpoint = "https://api.openai.com"
+		}
+	}
+	return ai.NewLLMClient(ai.LLMConfig{
+		Provider:    provider,
+		Endpoint:    endpoint,
+		Model:       firstNonEmpty(o.Model, env.AI.Model, "llama3.2:3b"),
+		APIKey:      os.Getenv("OPENAI_API_KEY"),
+		MaxTokens:   512,
+		Temperature: 0.3,
+	}, env.Logger)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// urlNormalizer runs enrich.URLNormalizer with the stage's context, so
+// unshortening stops when the crawl does or the stage times out.
+type urlNormalizer struct {
+	*enrich.URLNormalizer
+}
+
+func (m urlNormalizer) ProcessContext(ctx context.Context, env *Envelope) (*types.Item, error) {
+	return m.URLNormalizer.ProcessContext(ctx, env.Item)
+}
@@ -0,0 +1,613 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.starlark.net/lib/json"
+	starmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// ScriptLimits bound a single script call.
+type ScriptLimits struct {
+	Timeout  time.Duration // wall time per item
+	MaxSteps uint64        // Starlark execution steps per item
+	// MaxOutput bounds the field data a call may store with item.set and
+	// emit, in bytes as counted when values are converted. Values the
+	// script builds but does not store are bounded only by the timeout,
+	// the step limit and Starlark's own 1 GB cap on repetition.
+	MaxOutput int64
+	MaxEmit   int // extra items per call
+}
+
+// ScriptMiddleware runs a Starlark script against each item. The script
+// defines a function, process by default, that receives the item and may
+// read and change its fields:
+//
+//	def process(item):
+//	    item.set("title", item.get("title", "").upper())
+//	    if not re.search(r"\d", item.get("price", "")):
+//	        return drop()
+//	    for tag in item.get("tags", []):
+//	        emit({"tag": tag}, url=item.url)
+//
+// Besides the item's get, set, delete, has, keys and fields methods,
+// scripts may call drop(), which marks the item to be dropped once the
+// function returns, emit(fields, url=None) and the re, json and math
+// modules; print goes to the debug log. Returning False also drops the item.
+// Scripts cannot reach the file system, network or clock.
+//
+// The file is checked for changes every reload interval and recompiled; a
+// script that fails to load is logged and the previous one kept.
+type ScriptMiddleware struct {
+	path     string
+	function string
+	limits   ScriptLimits
+	logger   *slog.Logger
+
+	prog    atomic.Pointer[scriptProgram]
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+type scriptProgram struct {
+	fn      starlark.Callable
+	modTime time.Time
+	size    int64
+}
+
+func NewScriptMiddleware(path, function string, limits ScriptLimits, reload time.Duration, logger *slog.Logger) (*ScriptMiddleware, error) {
+	m := &ScriptMiddleware{
+		path:     path,
+		function: function,
+		limits:   limits,
+		logger:   logger.With("component", "script", "file", path),
+		stop:     make(chan struct{}),
+	}
+	prog, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	m.prog.Store(prog)
+	if reload > 0 {
+		m.stopped.Add(1)
+		go m.watch(reload)
+	}
+	return m, nil
+}
+
+func (m *ScriptMiddleware) Name() string { return "script" }
+
+func (m *ScriptMiddleware) Process(item *types.Item) (*types.Item, error) {
+	return m.ProcessContext(context.Background(), NewEnvelope(item))
+}
+
+func (m *ScriptMiddleware) ProcessContext(ctx context.Context, env *Envelope) (*types.Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if m.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.limits.Timeout)
+		defer cancel()
+	}
+
+	call := &scriptCall{item: env.Item.Clone(), limits: m.limits}
+	thread := &starlark.Thread{
+		Name:  "script " + env.Item.URL,
+		Print: func(_ *starlark.Thread, msg string) { m.logger.Debug(msg, "url", env.Item.URL) },
+	}
+	thread.SetLocal(scriptCallKey, call)
+	if m.limits.MaxSteps > 0 {
+		thread.SetMaxExecutionSteps(m.limits.MaxSteps)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go m.guard(ctx, thread, done)
+
+	result, err := starlark.Call(thread, m.prog.Load().fn, starlark.Tuple{&scriptItem{call: call}}, nil)
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return nil, fmt.Errorf("script %s: %s", m.path, evalErr.Backtrace())
+		}
+		return nil, fmt.Errorf("script %s: %w", m.path, err)
+	}
+	for _, extra := range call.emitted {
+		env.Emit(extra)
+	}
+	if call.dropped || result == starlark.False {
+		return nil, nil
+	}
+	return call.item, nil
+}
+
+// guard cancels thread when ctx is done, until done is closed.
+func (m *ScriptMiddleware) guard(ctx context.Context, thread *starlark.Thread, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-ctx.Done():
+		thread.Cancel(ctx.Err().Error())
+	}
+}
+
+// load compiles the script and looks up its function.
+func (m *ScriptMiddleware) load() (*scriptProgram, error) {
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return nil, fmt.Errorf("script: %w", err)
+	}
+	src, err := os.ReadFile(m.path)
+	if err != nil {
+		return nil, fmt.Errorf("script: %w", err)
+	}
+
+	thread := &starlark.Thread{
+		Name:  "load " + m.path,
+		Print: func(_ *starlark.Thread, msg string) { m.logger.Debug(msg) },
+	}
+	if m.limits.MaxSteps > 0 {
+		thread.SetMaxExecutionSteps(m.limits.MaxSteps)
+	}
+	opts := &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true}
+	globals, err := starlark.ExecFileOptions(opts, thread, m.path, src, scriptBuiltins)
+	if err != nil {
+		return nil, fmt.Errorf("script %s: %w", m.path, err)
+	}
+	fn, ok := globals[m.function].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("script %s: no function %q", m.path, m.function)
+	}
+	return &scriptProgram{fn: fn, modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// watch reloads the script when its modification time or size changes.
+func (m *ScriptMiddleware) watch(interval time.Duration) {
+	defer m.stopped.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(m.path)
+		if err != nil {
+			continue
+		}
+		current := m.prog.Load()
+		if info.ModTime().Equal(current.modTime) && info.Size() == current.size {
+			continue
+		}
+		prog, err := m.load()
+		if err != nil {
+			m.logger.Error("script reload failed, keeping previous version", "error", err)
+			// Do not retry until the file changes again.
+			m.prog.Store(&scriptProgram{fn: current.fn, modTime: info.ModTime(), size: info.Size()})
+			continue
+		}
+		m.prog.Store(prog)
+		m.logger.Info("script reloaded")
+	}
+}
+
+// Close stops watching the script file.
+func (m *ScriptMiddleware) Close() error {
+	select {
+	case <-m.stop:
+	default:
+		close(m.stop)
+	}
+	m.stopped.Wait()
+	return nil
+}
+
+// scriptCallKey is the thread-local holding the current *scriptCall.
+const scriptCallKey = "scrapegoat.call"
+
+// scriptCall is the state of one script call: a copy of the item, which
+// replaces the original only if the script succeeds, and what it asked for.
+type scriptCall struct {
+	item    *types.Item
+	limits  ScriptLimits
+	dropped bool
+	emitted []*types.Item
+	output  int64 // bytes stored so far, see ScriptLimits.MaxOutput
+}
+
+var scriptBuiltins = starlark.StringDict{
+	"drop": starlark.NewBuiltin("drop", scriptDrop),
+	"emit": starlark.NewBuiltin("emit", scriptEmit),
+	"re": &starlarkstruct.Module{Name: "re", Members: starlark.StringDict{
+		"search":  starlark.NewBuiltin("re.search", reSearch),
+		"findall": starlark.NewBuiltin("re.findall", reFindAll),
+		"sub":     starlark.NewBuiltin("re.sub", reSub),
+		"split":   starlark.NewBuiltin("re.split", reSplit),
+		"matches": starlark.NewBuiltin("re.matches", reMatches),
+	}},
+	"json": json.Module,
+	"math": starmath.Module,
+}
+
+func callOf(thread *starlark.Thread) *scriptCall {
+	return thread.Local(scriptCallKey).(*scriptCall)
+}
+
+func scriptDrop(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	callOf(thread).dropped = true
+	return starlark.None, nil
+}
+
+func scriptEmit(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var fields *starlark.Dict
+	var url starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "fields", &fields, "url?", &url); err != nil {
+		return nil, err
+	}
+	call := callOf(thread)
+	if call.limits.MaxEmit > 0 && len(call.emitted) >= call.limits.MaxEmit {
+		return nil, fmt.Errorf("%s: more than %d items", b.Name(), call.limits.MaxEmit)
+	}
+	converted, err := call.fromStarlark(fields, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	item := types.NewItem(call.item.URL)
+	if s, ok := url.(starlark.String); ok {
+		item.URL = string(s)
+	}
+	item.SpiderName = call.item.SpiderName
+	item.Depth = call.item.Depth
+	item.Fields = converted.(map[string]any)
+	call.emitted = append(call.emitted, item)
+	return starlark.None, nil
+}
+
+// scriptItem is the item as seen by scripts.
+type scriptItem struct {
+	call *scriptCall
+}
+
+var scriptItemMethods = map[string]*starlark.Builtin{
+	"get":    starlark.NewBuiltin("get", itemGet),
+	"set":    starlark.NewBuiltin("set", itemSet),
+	"delete": starlark.NewBuiltin("delete", itemDelete),
+	"has":    starlark.NewBuiltin("has", itemHas),
+	"keys":   starlark.NewBuiltin("keys", itemKeys),
+	"fields": starlark.NewBuiltin("fields", itemFields),
+}
+
+func (it *scriptItem) String() string        { return fmt.Sprintf("<item %s>", it.call.item.URL) }
+func (it *scriptItem) Type() string          { return "item" }
+func (it *scriptItem) Freeze()               {}
+func (it *scriptItem) Truth() starlark.Bool  { return starlark.True }
+func (it *scriptItem) Hash() (uint32, error) { return 0, errors.New("unhashable type: item") }
+
+func (it *scriptItem) Attr(name string) (starlark.Value, error) {
+	item := it.call.item
+	switch name {
+	case "url":
+		return starlark.String(item.URL), nil
+	case "depth":
+		return starlark.MakeInt(item.Depth), nil
+	case "spider":
+		return starlark.String(item.SpiderName), nil
+	}
+	if b, ok := scriptItemMethods[name]; ok {
+		return b.BindReceiver(it), nil
+	}
+	return nil, nil
+}
+
+func (it *scriptItem) AttrNames() []string {
+	names := []string{"url", "depth", "spider"}
+	for name := range scriptItemMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func receiverItem(b *starlark.Builtin) *types.Item {
+	return b.Receiver().(*scriptItem).call.item
+}
+
+func itemGet(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var def starlark.Value = starlark.None
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &key, &def); err != nil {
+		return nil, err
+	}
+	v, ok := receiverItem(b).Get(key)
+	if !ok {
+		return def, nil
+	}
+	return toStarlark(v), nil
+}
+
+func itemSet(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var value starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &key, &value); err != nil {
+		return nil, err
+	}
+	v, err := callOf(thread).fromStarlark(value, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	receiverItem(b).Set(key, v)
+	return starlark.None, nil
+}
+
+func itemDelete(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &key); err != nil {
+		return nil, err
+	}
+	delete(receiverItem(b).Fields, key)
+	return starlark.None, nil
+}
+
+func itemHas(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &key); err != nil {
+		return nil, err
+	}
+	_, ok := receiverItem(b).Get(key)
+	return starlark.Bool(ok), nil
+}
+
+func itemKeys(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	fields := receiverItem(b).Fields
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]starlark.Value, len(keys))
+	for i, k := range keys {
+		list[i] = starlark.String(k)
+	}
+	return starlark.NewList(list), nil
+}
+
+func itemFields(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	return toStarlark(receiverItem(b).Fields), nil
+}
+
+// regexCache holds compiled script patterns; scripts tend to reuse a few.
+var regexCache sync.Map
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+func unpackRegex(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple, extra ...any) (*regexp.Regexp, string, error) {
+	var pattern, text string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, append([]any{"pattern", &pattern, "text", &text}, extra...)...); err != nil {
+		return nil, "", err
+	}
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	return re, text, nil
+}
+
+// reSearch returns the first match's groups (the whole match first), or
+// None.
+func reSearch(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, text, err := unpackRegex(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	m := re.FindStringSubmatch(text)
+	if m == nil {
+		return starlark.None, nil
+	}
+	return stringList(m), nil
+}
+
+// reFindAll returns every match, or the first group of each when the
+// pattern has groups.
+func reFindAll(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, text, err := unpackRegex(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, m := range re.FindAllStringSubmatch(text, -1) {
+		if len(m) > 1 {
+			out = append(out, m[1])
+		} else {
+			out = append(out, m[0])
+		}
+	}
+	return stringList(out), nil
+}
+
+func reSub(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var repl string
+	re, text, err := unpackRegex(b, args, kwargs, "repl", &repl)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.String(re.ReplaceAllString(text, repl)), nil
+}
+
+func reSplit(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, text, err := unpackRegex(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	return stringList(re.Split(text, -1)), nil
+}
+
+func reMatches(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	re, text, err := unpackRegex(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.Bool(re.MatchString(text)), nil
+}
+
+func stringList(ss []string) *starlark.List {
+	list := make([]starlark.Value, len(ss))
+	for i, s := range ss {
+		list[i] = starlark.String(s)
+	}
+	return starlark.NewList(list)
+}
+
+// toStarlark converts a field value. Values without a Starlark counterpart
+// are passed as their string form.
+func toStarlark(v any) starlark.Value {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None
+	case bool:
+		return starlark.Bool(v)
+	case string:
+		return starlark.String(v)
+	case int:
+		return starlark.MakeInt(v)
+	case int64:
+		return starlark.MakeInt64(v)
+	case float64:
+		return starlark.Float(v)
+	case float32:
+		return starlark.Float(v)
+	case []string:
+		return stringList(v)
+	case []any:
+		list := make([]starlark.Value, len(v))
+		for i, e := range v {
+			list[i] = toStarlark(e)
+		}
+		return starlark.NewList(list)
+	case map[string]any:
+		d := starlark.NewDict(len(v))
+		for k, e := range v {
+			_ = d.SetKey(starlark.String(k), toStarlark(e))
+		}
+		return d
+	case map[string]string:
+		d := starlark.NewDict(len(v))
+		for k, e := range v {
+			_ = d.SetKey(starlark.String(k), starlark.String(e))
+		}
+		return d
+	case time.Time:
+		return starlark.String(v.Format(time.RFC3339))
+	default:
+		return starlark.String(fmt.Sprint(v))
+	}
+}
+
+// maxScriptNesting bounds how deeply the lists and dicts a script stores
+// may nest.
+const maxScriptNesting = 1000
+
+// fromStarlark converts a script value to a field value, charging its size
+// to the call's output limit. inside holds the lists and dicts being
+// converted, so a container that holds itself is an error rather than
+// endless recursion.
+func (c *scriptCall) fromStarlark(v starlark.Value, inside []starlark.Value) (any, error) {
+	size := int64(8)
+	if s, ok := v.(starlark.String); ok {
+		size += int64(len(s))
+	}
+	if c.output += size; c.limits.MaxOutput > 0 && c.output > c.limits.MaxOutput {
+		return nil, fmt.Errorf("stored values exceed %d bytes", c.limits.MaxOutput)
+	}
+	switch v.(type) {
+	case *starlark.List, *starlark.Dict:
+		if slices.Contains(inside, v) {
+			return nil, fmt.Errorf("%s contains itself", v.Type())
+		}
+		if len(inside) >= maxScriptNesting {
+			return nil, fmt.Errorf("values nested deeper than %d", maxScriptNesting)
+		}
+		inside = append(inside, v)
+	}
+
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i, nil
+		}
+		f := v.Float()
+		if math.IsInf(float64(f), 0) {
+			return nil, fmt.Errorf("integer %s out of range", v)
+		}
+		return float64(f), nil
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.Indexable: // list, tuple
+		out := make([]any, v.Len())
+		for i := range out {
+			e, err := c.fromStarlark(v.Index(i), inside)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = e
+		}
+		return out, nil
+	case *starlark.Dict:
+		out := make(map[string]any, v.Len())
+		for _, kv := range v.Items() {
+			k, ok := kv[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("dict key %s is not a string", kv[0])
+			}
+			c.output += int64(len(k))
+			e, err := c.fromStarlark(kv[1], inside)
+			if err != nil {
+				return nil, err
+			}
+			out[string(k)] = e
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("cannot store %s value", v.Type())
+	}
+}
@@ -0,0 +1,202 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+func writeScript(t *testing.T, src string) string {
+	path := filepath.Join(t.TempDir(), "script.star")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// runScript streams items through p and returns the output items as
+// "URL fields" strings.
+func runScript(p *Pipeline, items ...*types.Item) (got []string, errs []error) {
+	in := make(chan *types.Item, len(items))
+	for _, item := range items {
+		in <- item
+	}
+	close(in)
+	p.Stream(in, func(item *types.Item, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		got = append(got, fmt.Sprintf("%s %v", item.URL, item.Fields))
+	})
+	return got, errs
+}
+
+func TestScriptMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		fields map[string]any
+		want   []string
+		err    string
+	}{
+		{
+			name: "transform",
+			script: `def process(item):
+    item.set("title", item.get("title", "").strip().upper())
+    item.delete("junk")
+    item.set("price", float(item.get("price")))`,
+			fields: map[string]any{"title": " widget ", "price": "9.50", "junk": 1},
+			want:   []string{"https://example.com/w map[price:9.5 title:WIDGET]"},
+		},
+		{
+			name: "drop",
+			script: `def process(item):
+    if not re.matches(r"^\d+(\.\d+)?$", item.get("price", "")):
+        return drop()`,
+			fields: map[string]any{"price": "call us"},
+		},
+		{
+			name: "emit",
+			script: `def process(item):
+    for tag in re.split(r"\s*,\s*", item.get("tags", "")):
+        emit({"tag": tag}, url=item.url + "#" + tag)
+    return drop()`,
+			fields: map[string]any{"tags": "red, small"},
+			want:   []string{"https://example.com/w#red map[tag:red]", "https://example.com/w#small map[tag:small]"},
+		},
+		{
+			name: "keys and fields",
+			script: `def process(item):
+    item.set("summary", ",".join(sorted(item.keys())) + "=" + json.encode(item.fields()))`,
+			fields: map[string]any{"b": 1, "a": "x"},
+			want:   []string{`https://example.com/w map[a:x b:1 summary:a,b={"a":"x","b":1}]`},
+		},
+		{
+			name:   "return False",
+			script: "def process(item):\n    return item.has(\"a\") and False\n",
+			fields: map[string]any{"a": 1},
+		},
+		{
+			name:   "self-containing list",
+			script: "def process(item):\n    l = []\n    l.append(l)\n    item.set(\"x\", l)\n",
+			fields: map[string]any{},
+			err:    "list contains itself",
+		},
+		{
+			name:   "self-containing dict",
+			script: "def process(item):\n    d = {}\n    d[\"d\"] = [d]\n    emit(d)\n",
+			fields: map[string]any{},
+			err:    "dict contains itself",
+		},
+		{
+			name:   "script error",
+			script: "def process(item):\n    return 1 // 0\n",
+			fields: map[string]any{},
+			err:    "division by zero",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mws, err := NewRegistry().Build([]config.MiddlewareConfig{{Type: "script", Options: map[string]any{
+				"file": writeScript(t, tt.script),
+			}}}, Env{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := New(testLogger)
+			p.Use(mws[0])
+			p.Use(&TrimMiddleware{})
+			defer p.Close()
+
+			item := types.NewItem("https://example.com/w")
+			item.Fields = tt.fields
+			before := fmt.Sprint(tt.fields)
+			got, errs := runScript(p, item)
+			if tt.err != "" {
+				if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.err) {
+					t.Errorf("errors = %v, want %q", errs, tt.err)
+				}
+				return
+			}
+			if len(errs) != 0 || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, errors %v; want %v", got, errs, tt.want)
+			}
+			if fmt.Sprint(item.Fields) != before {
+				t.Errorf("script changed the input item: %v", item.Fields)
+			}
+		})
+	}
+}
+
+func TestScriptLimits(t *testing.T) {
+	loop := "def process(item):\n    while True:\n        pass\n"
+	tests := []struct {
+		name   string
+		script string
+		limits ScriptLimits
+		err    string
+	}{
+		{"steps", loop, ScriptLimits{Timeout: time.Second, MaxSteps: 10_000}, "too many steps"},
+		{"timeout", loop, ScriptLimits{Timeout: 20 * time.Millisecond}, "deadline"},
+		{
+			"output",
+			"def process(item):\n    item.set(\"a\", \"x\" * 600)\n    item.set(\"b\", [\"x\" * 600])\n",
+			ScriptLimits{MaxOutput: 1 << 10},
+			"stored values exceed 1024 bytes",
+		},
+		{
+			"nesting",
+			"def process(item):\n    v = []\n    for _ in range(2000):\n        v = [v]\n    item.set(\"v\", v)\n",
+			ScriptLimits{},
+			"nested deeper than 1000",
+		},
+	}
+	for _, tt := range tests {
+		m, err := NewScriptMiddleware(writeScript(t, tt.script), "process", tt.limits, 0, testLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Process(types.NewItem("https://example.com")); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+		}
+		m.Close()
+	}
+}
+
+func TestScriptReload(t *testing.T) {
+	path := writeScript(t, "def process(item):\n    item.set(\"v\", 1)\n")
+	m, err := NewScriptMiddleware(path, "process", ScriptLimits{}, 10*time.Millisecond, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// A broken edit keeps the last good version.
+	steps := []struct {
+		src  string
+		want any
+	}{
+		{"def process(item:\n", int64(1)},
+		{"def process(item):\n    item.set(\"v\", 2)\n", int64(2)},
+	}
+	for _, s := range steps {
+		if err := os.WriteFile(path, []byte(s.src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		out, err := m.Process(types.NewItem("https://example.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := out.Get("v"); got != s.want {
+			t.Errorf("after writing %q: v = %v, want %v", s.src, got, s.want)
+		}
+	}
+}
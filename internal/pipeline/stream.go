@@ -0,0 +1,370 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// BatchMiddleware is a Middleware that can also process several items in one
+// call, for stages such as LLM or database lookups where that is cheaper.
+// Stream uses ProcessBatch when the stage's BatchSize is above 1; Process is
+// still used by Pipeline.Process. Batches get no context: a batch is skipped
+// if the context is done before it starts.
+type BatchMiddleware interface {
+	Middleware
+
+	// ProcessBatch returns one result per input item, in order; a nil result
+	// drops that item. An error fails every item in the batch.
+	ProcessBatch(items []*types.Item) ([]*types.Item, error)
+}
+
+// StageOptions controls how a middleware runs in Stream.
+type StageOptions struct {
+	Concurrency int           // workers (default 1)
+	BatchSize   int           // items per ProcessBatch call; BatchMiddlewares only (default 1)
+	BatchWait   time.Duration // longest wait to fill a batch (default 100ms)
+	Timeout     time.Duration // per-item deadline for ContextMiddlewares (0 = none)
+}
+
+// StageStats is a snapshot of one stage's counters. Queued items and Blocked
+// time show backpressure: a stage with a long queue is the bottleneck, and
+// Blocked is how long its workers waited for the next stage to accept items.
+type StageStats struct {
+	Name    string        `json:"name"`
+	Workers int           `json:"workers"`
+	Queued  int64         `json:"queued"` // items waiting for this stage
+	Busy    int64         `json:"busy"`   // workers processing now
+	In      int64         `json:"in"`
+	Out     int64         `json:"out"`
+	Dropped int64         `json:"dropped"`
+	Errors  int64         `json:"errors"`
+	Batches int64         `json:"batches"`
+	Blocked time.Duration `json:"blocked"`
+}
+
+// SetOrdered chooses whether Stream emits items in input order (the
+// default) or as soon as each is done. Ordered, Stream takes no more input
+// while an item waits for earlier ones than the queues and workers hold, so
+// a slow item holds back the input rather than letting finished items pile
+// up behind it.
+func (p *Pipeline) SetOrdered(ordered bool) {
+	p.ordered = ordered
+}
+
+// SetBuffer sets the queue length in front of each stage (default 100).
+func (p *Pipeline) SetBuffer(n int) {
+	if n > 0 {
+		p.buffer = n
+	}
+}
+
+// Stats returns a snapshot of each stage's counters, in pipeline order.
+func (p *Pipeline) Stats() []StageStats {
+	out := make([]StageStats, len(p.stages))
+	for i, s := range p.stages {
+		out[i] = StageStats{
+			Name:    s.mw.Name(),
+			Workers: s.opts.Concurrency,
+			Queued:  s.queued.Load(),
+			Busy:    s.busy.Load(),
+			In:      s.in.Load(),
+			Out:     s.out.Load(),
+			Dropped: s.dropped.Load(),
+			Errors:  s.errors.Load(),
+			Batches: s.batches.Load(),
+			Blocked: time.Duration(s.blocked.Load()),
+		}
+	}
+	return out
+}
+
+// Stream processes the items read from in until it is closed, running each
+// stage on its own worker pool, and calls emit for every item that passes
+// the pipeline and every item that fails, the latter with a
+// *types.PipelineError and the item as the failing stage received it.
+// Dropped items are not emitted; items a ContextMiddleware adds with
+// Envelope.Emit are, right after the item that emitted them. emit is called
+// from a single goroutine, and Stream returns once every item has been
+// emitted.
+func (p *Pipeline) Stream(in <-chan *types.Item, emit func(item *types.Item, err error)) {
+	p.StreamContext(context.Background(), in, emit)
+}
+
+// StreamContext is Stream with a context passed to context-aware
+// middlewares. Cancelling ctx does not stop Stream: it still drains in, and
+// the items still in flight fail with the context's error.
+func (p *Pipeline) StreamContext(ctx context.Context, in <-chan *types.Item, emit func(item *types.Item, err error)) {
+	// window holds a slot for each item between the source and collect.
+	var window chan struct{}
+	if p.ordered {
+		window = make(chan struct{}, p.window())
+	}
+	src := make(chan envelope, p.buffer)
+	go func() {
+		var first *stage
+		if len(p.stages) > 0 {
+			first = p.stages[0]
+		}
+		seq := 0
+		for item := range in {
+			if window != nil {
+				window <- struct{}{}
+			}
+			forward(nil, src, envelope{seq: seq, items: []*types.Item{item}, url: item.URL}, first)
+			seq++
+		}
+		close(src)
+	}()
+
+	var ch <-chan envelope = src
+	for i, s := range p.stages {
+		var next *stage
+		if i+1 < len(p.stages) {
+			next = p.stages[i+1]
+		}
+		ch = p.run(ctx, s, ch, next)
+	}
+	p.collect(ch, window, emit)
+}
+
+// window returns how many items ordered Stream lets in flight: a queue's
+// worth plus what the workers hold.
+func (p *Pipeline) window() int {
+	n := p.buffer
+	for _, s := range p.stages {
+		n += s.opts.Concurrency * s.opts.BatchSize
+	}
+	return n
+}
+
+// envelope carries an item through Stream, along with the items emitted
+// from it. An envelope without items was dropped or failed and passes the
+// remaining stages untouched.
+type envelope struct {
+	seq   int
+	items []*types.Item
+	url   string
+	errs  []error // *types.PipelineErrors of the items that failed
+}
+
+type stage struct {
+	mw   Middleware
+	cm   ContextMiddleware
+	opts StageOptions
+
+	queued, busy                      atomic.Int64
+	in, out, dropped, errors, batches atomic.Int64
+	blocked                           atomic.Int64 // nanoseconds
+}
+
+func newStage(mw Middleware, opts StageOptions) *stage {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if _, ok := mw.(BatchMiddleware); !ok || opts.BatchSize < 1 {
+		opts.BatchSize = 1
+	}
+	if opts.BatchWait <= 0 {
+		opts.BatchWait = 100 * time.Millisecond
+	}
+	return &stage{mw: mw, cm: Adapt(mw), opts: opts}
+}
+
+// run starts the stage's workers and returns its output channel, closed once
+// in is drained.
+func (p *Pipeline) run(ctx context.Context, s *stage, in <-chan envelope, next *stage) <-chan envelope {
+	out := make(chan envelope, p.buffer)
+	var wg sync.WaitGroup
+	for w := 0; w < s.opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.opts.BatchSize > 1 {
+				p.batchWorker(ctx, s, in, out, next)
+			} else {
+				p.worker(ctx, s, in, out, next)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func (p *Pipeline) worker(ctx context.Context, s *stage, in <-chan envelope, out chan<- envelope, next *stage) {
+	for env := range in {
+		s.queued.Add(-1)
+		if len(env.items) > 0 {
+			items := env.items
+			env.items = make([]*types.Item, 0, len(items))
+			for _, item := range items {
+				s.in.Add(1)
+				s.busy.Add(1)
+				result, emitted, err := s.call(ctx, item)
+				s.busy.Add(-1)
+				env = p.settle(s, env, item, result, err)
+				for _, extra := range emitted {
+					s.out.Add(1)
+					env.items = append(env.items, extra)
+				}
+			}
+		}
+		forward(s, out, env, next)
+	}
+}
+
+func (p *Pipeline) batchWorker(ctx context.Context, s *stage, in <-chan envelope, out chan<- envelope, next *stage) {
+	batch := make([]envelope, 0, s.opts.BatchSize)
+	for {
+		env, ok := <-in
+		if !ok {
+			return
+		}
+		s.queued.Add(-1)
+		if len(env.items) == 0 {
+			forward(s, out, env, next)
+			continue
+		}
+
+		batch = append(batch[:0], env)
+		size := len(env.items)
+		timer := time.NewTimer(s.opts.BatchWait)
+	fill:
+		for size < s.opts.BatchSize {
+			select {
+			case env, ok = <-in:
+				if !ok {
+					break fill
+				}
+				s.queued.Add(-1)
+				if len(env.items) == 0 {
+					forward(s, out, env, next)
+					continue
+				}
+				batch = append(batch, env)
+				size += len(env.items)
+			case <-timer.C:
+				break fill
+			}
+		}
+		timer.Stop()
+
+		p.processBatch(ctx, s, batch, out, next)
+		if !ok {
+			return
+		}
+	}
+}
+
+func (p *Pipeline) processBatch(ctx context.Context, s *stage, batch []envelope, out chan<- envelope, next *stage) {
+	var items []*types.Item
+	for _, env := range batch {
+		items = append(items, env.items...)
+	}
+
+	s.in.Add(int64(len(items)))
+	s.batches.Add(1)
+	var results []*types.Item
+	err := ctx.Err()
+	if err == nil {
+		s.busy.Add(1)
+		results, err = s.mw.(BatchMiddleware).ProcessBatch(items)
+		s.busy.Add(-1)
+	}
+	if err == nil && len(results) != len(items) {
+		err = fmt.Errorf("ProcessBatch returned %d results for %d items", len(results), len(items))
+	}
+
+	i := 0
+	for _, env := range batch {
+		in := env.items
+		env.items = make([]*types.Item, 0, len(in))
+		for _, item := range in {
+			var result *types.Item
+			if err == nil {
+				if result = results[i]; result != nil && result.Origin == nil {
+					result.Origin = item.Origin
+				}
+			}
+			env = p.settle(s, env, item, result, err)
+			i++
+		}
+		forward(s, out, env, next)
+	}
+}
+
+// settle records a middleware's result for item, one of env's items.
+func (p *Pipeline) settle(s *stage, env envelope, item, result *types.Item, err error) envelope {
+	switch {
+	case err != nil:
+		s.errors.Add(1)
+		env.errs = append(env.errs, &types.PipelineError{Stage: s.mw.Name(), Item: item, Err: err})
+	case result == nil:
+		s.dropped.Add(1)
+		p.logger.Debug("item dropped", "stage", s.mw.Name(), "url", item.URL)
+	default:
+		s.out.Add(1)
+		env.items = append(env.items, result)
+	}
+	return env
+}
+
+// forward sends env to the next stage, charging time spent waiting for room
+// to the sending stage (nil for the source).
+func forward(from *stage, out chan<- envelope, env envelope, next *stage) {
+	if next != nil {
+		next.queued.Add(1)
+	}
+	select {
+	case out <- env:
+		return
+	default:
+	}
+	start := time.Now()
+	out <- env
+	if from != nil {
+		from.blocked.Add(int64(time.Since(start)))
+	}
+}
+
+// collect emits the results, restoring input order when the pipeline is
+// ordered and freeing each delivered item's slot in window.
+func (p *Pipeline) collect(in <-chan envelope, window <-chan struct{}, emit func(*types.Item, error)) {
+	deliver := func(env envelope) {
+		for _, err := range env.errs {
+			emit(err.(*types.PipelineError).Item, err)
+		}
+		for _, item := range env.items {
+			emit(item, nil)
+		}
+	}
+	if !p.ordered {
+		for env := range in {
+			deliver(env)
+		}
+		return
+	}
+
+	pending := make(map[int]envelope)
+	next := 0
+	for env := range in {
+		pending[env.seq] = env
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			deliver(ready)
+			<-window
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/pipeline"
 	"github.com/IshaanNene/ScrapeGoat/internal/types"
 )
 
@@ -75,6 +76,16 @@ type Registry struct {
 	mu      sync.RWMutex
 }
 
+// DefaultRegistry holds the plugins compiled into the binary, usually
+// registered from an init function. The crawl command builds its pipeline
+// with them.
+var DefaultRegistry = NewRegistry(slog.Default())
+
+// Register adds a plugin to the default registry.
+func Register(p Plugin) error {
+	return DefaultRegistry.Register(p)
+}
+
 // NewRegistry creates a new plugin registry.
 func NewRegistry(logger *slog.Logger) *Registry {
 	return &Registry{
@@ -198,6 +209,47 @@ func (r *Registry) CloseAll() {
 	}
 }
 
+// RegisterMiddlewares makes every middleware plugin available to
+// pipeline.middlewares config under the plugin's name. A config entry
+// initialises the plugin with its options; as plugins are single instances,
+// one configured twice keeps the options of the last entry.
+func (r *Registry) RegisterMiddlewares(into *pipeline.Registry) error {
+	for _, p := range r.GetByType(PluginTypeMiddleware) {
+		mw, ok := p.(MiddlewarePlugin)
+		if !ok {
+			continue
+		}
+		err := into.Register(mw.Name(), func(options map[string]any, _ pipeline.Env) (pipeline.Middleware, error) {
+			if err := mw.Init(options); err != nil {
+				return nil, fmt.Errorf("init plugin %q: %w", mw.Name(), err)
+			}
+			return pluginMiddleware{mw}, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildPipeline builds the pipeline declared in cfg from the middleware
+// types of pipeline.DefaultRegistry plus r's middleware plugins.
+func (r *Registry) BuildPipeline(cfg *config.Config, logger *slog.Logger) (*pipeline.Pipeline, error) {
+	reg := pipeline.DefaultRegistry.Clone()
+	if err := r.RegisterMiddlewares(reg); err != nil {
+		return nil, err
+	}
+	return reg.FromConfig(cfg, logger)
+}
+
+// pluginMiddleware hides the plugin's Close from the pipeline; plugins are
+// closed by the registry.
+type pluginMiddleware struct{ p MiddlewarePlugin }
+
+func (m pluginMiddleware) Name() string { return m.p.Name() }
+
+func (m pluginMiddleware) Process(item *types.Item) (*types.Item, error) { return m.p.Process(item) }
+
 // RunHooks executes a hook function on all HookPlugins.
 func (r *Registry) RunHooks(fn func(HookPlugin) error) {
 	hooks := r.GetByType(PluginTypeHook)
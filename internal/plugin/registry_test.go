@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/config"
+	"github.com/IshaanNene/ScrapeGoat/internal/pipeline"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+// suffixPlugin appends its "suffix" option to the title field.
+type suffixPlugin struct {
+	suffix string
+	closed bool
+}
+
+func (p *suffixPlugin) Name() string     { return "suffix" }
+func (p *suffixPlugin) Type() PluginType { return PluginTypeMiddleware }
+func (p *suffixPlugin) Version() string  { return "1.0" }
+func (p *suffixPlugin) Close() error     { p.closed = true; return nil }
+func (p *suffixPlugin) Init(cfg map[string]any) error {
+	p.suffix, _ = cfg["suffix"].(string)
+	return nil
+}
+
+func (p *suffixPlugin) Process(item *types.Item) (*types.Item, error) {
+	item.Set("title", item.GetString("title")+p.suffix)
+	return item, nil
+}
+
+func TestBuildPipelineWithMiddlewarePlugin(t *testing.T) {
+	r := NewRegistry(testLogger)
+	p := &suffixPlugin{}
+	if err := r.Register(p); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Pipeline.Middlewares = []config.MiddlewareConfig{
+		{Type: "trim"},
+		{Name: "suffix", Options: map[string]any{"suffix": "!"}},
+	}
+	pipe, err := r.BuildPipeline(cfg, testLogger)
+	if err != nil {
+		t.Fatalf("build pipeline: %v", err)
+	}
+	item := types.NewItem("https://example.com")
+	item.Set("title", "  Hello ")
+	out, err := pipe.Process(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.GetString("title"); got != "Hello!" {
+		t.Errorf("title = %q, want %q", got, "Hello!")
+	}
+
+	// Plugins are closed by the registry, not the pipeline.
+	if err := pipe.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if p.closed {
+		t.Error("pipeline closed the plugin")
+	}
+	r.CloseAll()
+	if !p.closed {
+		t.Error("CloseAll did not close the plugin")
+	}
+
+	// The plugin's type is local to this build; the default registry is
+	// unchanged.
+	if _, err := pipeline.FromConfig(cfg, testLogger); err == nil || !strings.Contains(err.Error(), `unknown middleware type "suffix"`) {
+		t.Errorf("default registry error = %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+// Package schema declares the expected shape of scraped items with a subset
+// of JSON Schema, validates items against it, and describes the columns that
+// storage backends derive from it (CSV headers, database DDL).
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Schema is a JSON Schema document. Supported keywords: type, properties,
+// required, additionalProperties (boolean), items, enum, const, format,
+// pattern, minLength, maxLength, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, minItems and maxItems. Other keywords are ignored.
+type Schema struct {
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 TypeList           `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+
+	order   []string       // property declaration order
+	pattern *regexp.Regexp // compiled Pattern
+}
+
+// TypeList holds the "type" keyword, which may be one type or several.
+type TypeList []string
+
+// UnmarshalJSON accepts "string" as well as ["string", "null"].
+func (t *TypeList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = TypeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// Has reports whether the list allows the given type. An empty list allows
+// everything, and "number" allows integers.
+func (t TypeList) Has(typ string) bool {
+	if len(t) == 0 {
+		return true
+	}
+	return slices.Contains(t, typ) || (typ == "integer" && slices.Contains(t, "number"))
+}
+
+// Primary returns the first type other than null, or "" when unconstrained.
+func (t TypeList) Primary() string {
+	for _, typ := range t {
+		if typ != "null" {
+			return typ
+		}
+	}
+	return ""
+}
+
+var validTypes = []string{"string", "number", "integer", "boolean", "object", "array", "null"}
+
+// UnmarshalJSON decodes a schema, remembering the order properties are
+// declared in so columns come out in that order.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	var aux struct {
+		*plain
+		Properties           json.RawMessage `json:"properties"`
+		AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	}
+	aux.plain = (*plain)(s)
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if len(aux.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(aux.AdditionalProperties, &allowed); err == nil {
+			s.AdditionalProperties = &allowed
+		}
+		// A schema for additional properties is accepted but not enforced.
+	}
+
+	if len(aux.Properties) == 0 || string(aux.Properties) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(aux.Properties))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("properties must be an object")
+	}
+	s.Properties = make(map[string]*Schema)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name := tok.(string)
+		var prop Schema
+		if err := dec.Decode(&prop); err != nil {
+			return fmt.Errorf("property %q: %w", name, err)
+		}
+		if _, dup := s.Properties[name]; !dup {
+			s.order = append(s.order, name)
+		}
+		s.Properties[name] = &prop
+	}
+	return nil
+}
+
+// Parse parses a JSON or YAML schema document and checks it.
+func Parse(data []byte) (*Schema, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] != '{' {
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, fmt.Errorf("parse schema: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := yamlToJSON(&node, &buf); err != nil {
+			return nil, fmt.Errorf("parse schema: %w", err)
+		}
+		data = buf.Bytes()
+	}
+
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	if err := s.compile(""); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &s, nil
+}
+
+// Load reads a schema from a .json, .yaml or .yml file.
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read schema: %w", err)
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return s, nil
+}
+
+// compile checks keywords and compiles patterns.
+func (s *Schema) compile(path string) error {
+	for _, t := range s.Type {
+		if !slices.Contains(validTypes, t) {
+			return fmt.Errorf("%s: unknown type %q", displayPath(path), t)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: bad pattern: %w", displayPath(path), err)
+		}
+		s.pattern = re
+	}
+	if s.order == nil && len(s.Properties) > 0 {
+		// Built in code rather than decoded: fall back to sorted order.
+		for name := range s.Properties {
+			s.order = append(s.order, name)
+		}
+		slices.Sort(s.order)
+	}
+	for _, name := range s.order {
+		if err := s.Properties[name].compile(joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// PropertyNames returns the object's property names in declaration order.
+func (s *Schema) PropertyNames() []string {
+	return slices.Clone(s.order)
+}
+
+// Column describes a top-level item field as storage backends see it.
+type Column struct {
+	Name     string
+	Type     string // JSON Schema type; object and array values are stored as JSON
+	Format   string
+	Required bool
+}
+
+// Columns returns the top-level properties as columns, in declaration order.
+func (s *Schema) Columns() []Column {
+	cols := make([]Column, 0, len(s.order))
+	for _, name := range s.order {
+		prop := s.Properties[name]
+		cols = append(cols, Column{
+			Name:     name,
+			Type:     prop.Type.Primary(),
+			Format:   prop.Format,
+			Required: slices.Contains(s.Required, name),
+		})
+	}
+	return cols
+}
+
+// yamlToJSON re-encodes a YAML node as JSON, keeping mapping key order.
+func yamlToJSON(n *yaml.Node, buf *bytes.Buffer) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return yamlToJSON(n.Content[0], buf)
+	case yaml.AliasNode:
+		return yamlToJSON(n.Alias, buf)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(n.Content[i].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := yamlToJSON(n.Content[i+1], buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, c := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := yamlToJSON(c, buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.ScalarNode:
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return strings.TrimPrefix(path, ".")
+}
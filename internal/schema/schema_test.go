@@ -0,0 +1,89 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func errorStrings(errs []FieldError) []string {
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Error())
+	}
+	return out
+}
+
+func TestFromStruct(t *testing.T) {
+	type product struct {
+		Name    string    `json:"name" schema:"required,minLength=1"`
+		Price   float64   `json:"price" schema:"minimum=0"`
+		Qty     int       `json:"qty"`
+		Rating  *float64  `json:"rating,omitempty" schema:"maximum=5"`
+		Tags    []string  `json:"tags" schema:"maxItems=3"`
+		Stock   string    `json:"stock" schema:"enum=in|out"`
+		Updated time.Time `json:"updated"`
+		Skipped string    `json:"-"`
+		secret  string
+	}
+	s, err := FromStruct(&product{})
+	if err != nil {
+		t.Fatalf("from struct: %v", err)
+	}
+	wantCols := []Column{
+		{Name: "name", Type: "string", Required: true},
+		{Name: "price", Type: "number"},
+		{Name: "qty", Type: "integer"},
+		{Name: "rating", Type: "number"},
+		{Name: "tags", Type: "array"},
+		{Name: "stock", Type: "string"},
+		{Name: "updated", Type: "string", Format: "date-time"},
+	}
+	if got := s.Columns(); !reflect.DeepEqual(got, wantCols) {
+		t.Errorf("columns =\n%+v\nwant\n%+v", got, wantCols)
+	}
+
+	tests := []struct {
+		name   string
+		fields map[string]any
+		want   []string
+	}{
+		{"valid", map[string]any{"name": "A", "price": 9.5, "qty": 2, "tags": []any{"x"}}, nil},
+		{"nil pointer", map[string]any{"name": "A", "rating": nil}, nil},
+		{"required and integer", map[string]any{"price": 9.5, "qty": 1.5}, []string{"name: is required", "qty: must be integer, got number"}},
+		{"constraints", map[string]any{"name": "", "price": -1, "rating": 6, "tags": []any{"a", "b", "c", "d"}, "stock": "maybe"}, []string{
+			"name: must be at least 1 characters",
+			"price: must be >= 0",
+			"rating: must be <= 5",
+			"tags: must have at most 3 items",
+			`stock: must be one of ["in","out"]`,
+		}},
+	}
+	for _, tt := range tests {
+		if got := errorStrings(s.ValidateFields(tt.fields)); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: errors =\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+
+	for _, v := range []any{nil, "product", []product{}} {
+		if _, err := FromStruct(v); err == nil {
+			t.Errorf("FromStruct(%T): expected error", v)
+		}
+	}
+}
+
+func TestValidateFieldsMetadata(t *testing.T) {
+	s, err := Parse([]byte(`{"type": "object", "additionalProperties": false, "required": ["name"],
+		"properties": {"name": {"type": "string"}, "_status": {"type": "integer"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]any{"name": "A", "_status": "new", "_source": []string{"feed"}}
+	if errs := s.ValidateFields(fields); len(errs) > 0 {
+		t.Errorf("metadata fields validated: %v", errorStrings(errs))
+	}
+	if errs := errorStrings(s.ValidateFields(map[string]any{"_name": "A"})); len(errs) != 1 || errs[0] != "name: is required" {
+		t.Errorf("errors = %v, want name: is required", errs)
+	}
+}
@@ -0,0 +1,176 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FromStruct derives an object schema from a Go struct. Field names follow
+// their json tags and types map to JSON types; a "schema" tag adds
+// constraints as comma-separated options:
+//
+//	type Product struct {
+//	    Name  string   `json:"name" schema:"required,minLength=1"`
+//	    Price float64  `json:"price" schema:"required,minimum=0"`
+//	    URL   string   `json:"url" schema:"format=uri"`
+//	    Stock string   `json:"stock" schema:"enum=in|out|preorder"`
+//	    Tags  []string `json:"tags,omitempty" schema:"maxItems=10"`
+//	}
+//
+// Options: required, format, pattern, enum (values separated by |),
+// minLength, maxLength, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, minItems and maxItems.
+func FromStruct(v any) (*Schema, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema from struct: %T is not a struct", v)
+	}
+	s, err := typeSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.compile(""); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return s, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func typeSchema(t reflect.Type) (*Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: TypeList{"string"}, Format: "date-time"}, nil
+	case t.Kind() == reflect.String:
+		return &Schema{Type: TypeList{"string"}}, nil
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: TypeList{"boolean"}}, nil
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: TypeList{"integer"}}, nil
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: TypeList{"number"}}, nil
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		items, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: TypeList{"array"}, Items: items}, nil
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: TypeList{"object"}}, nil
+	case t.Kind() == reflect.Interface:
+		return &Schema{}, nil
+	case t.Kind() != reflect.Struct:
+		return nil, fmt.Errorf("schema from struct: unsupported type %s", t)
+	}
+
+	s := &Schema{Type: TypeList{"object"}, Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop, err := typeSchema(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if f.Type.Kind() == reflect.Pointer {
+			prop.Type = append(prop.Type, "null")
+		}
+		required, err := applyTag(prop, f.Tag.Get("schema"))
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if required {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+		s.order = append(s.order, name)
+	}
+	return s, nil
+}
+
+// applyTag applies "schema" tag options and reports whether the field is
+// required.
+func applyTag(s *Schema, tag string) (bool, error) {
+	required := false
+	for _, opt := range strings.Split(tag, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		var err error
+		switch key {
+		case "":
+		case "required":
+			required = true
+		case "format":
+			s.Format = val
+		case "pattern":
+			s.Pattern = val
+		case "enum":
+			for _, e := range strings.Split(val, "|") {
+				s.Enum = append(s.Enum, enumValue(s.Type.Primary(), e))
+			}
+		case "minLength":
+			s.MinLength, err = intOpt(val)
+		case "maxLength":
+			s.MaxLength, err = intOpt(val)
+		case "minItems":
+			s.MinItems, err = intOpt(val)
+		case "maxItems":
+			s.MaxItems, err = intOpt(val)
+		case "minimum":
+			s.Minimum, err = floatOpt(val)
+		case "maximum":
+			s.Maximum, err = floatOpt(val)
+		case "exclusiveMinimum":
+			s.ExclusiveMinimum, err = floatOpt(val)
+		case "exclusiveMaximum":
+			s.ExclusiveMaximum, err = floatOpt(val)
+		default:
+			return false, fmt.Errorf("unknown schema option %q", key)
+		}
+		if err != nil {
+			return false, fmt.Errorf("schema option %s: %w", key, err)
+		}
+	}
+	return required, nil
+}
+
+func enumValue(typ, s string) any {
+	switch typ {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
+
+func intOpt(s string) (*int, error) {
+	n, err := strconv.Atoi(s)
+	return &n, err
+}
+
+func floatOpt(s string) (*float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	return &f, err
+}
@@ -0,0 +1,250 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError is a validation failure at a path such as "offers[0].price".
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return displayPath(e.Path) + ": " + e.Message
+}
+
+// ValidateFields validates item fields against an object schema. Values are
+// compared in their JSON form, so []string and int fields validate like the
+// arrays and numbers they are written as. Fields whose names start with an
+// underscore are item metadata and are not validated.
+func (s *Schema) ValidateFields(fields map[string]any) []FieldError {
+	content := make(map[string]any, len(fields))
+	for k, v := range fields {
+		if !strings.HasPrefix(k, "_") {
+			content[k] = v
+		}
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return []FieldError{{Message: fmt.Sprintf("not representable as JSON: %v", err)}}
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []FieldError{{Message: err.Error()}}
+	}
+	return s.Validate(v)
+}
+
+// Validate validates a decoded JSON value and returns every failure found.
+func (s *Schema) Validate(v any) []FieldError {
+	var errs []FieldError
+	s.validate(v, "", &errs)
+	return errs
+}
+
+func (s *Schema) validate(v any, path string, errs *[]FieldError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	typ := jsonType(v)
+	if !s.Type.Has(typ) {
+		fail("must be %s, got %s", strings.Join(s.Type, " or "), typ)
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return jsonEqual(e, v) }) {
+		fail("must be one of %s", compact(s.Enum))
+	}
+	if s.Const != nil && !jsonEqual(s.Const, v) {
+		fail("must be %s", compact(s.Const))
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match %s", s.Pattern)
+		}
+		if s.Format != "" && !validFormat(s.Format, v) {
+			fail("must be a valid %s", s.Format)
+		}
+
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("must be <= %v", *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+			fail("must be > %v", *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && v >= *s.ExclusiveMaximum {
+			fail("must be < %v", *s.ExclusiveMaximum)
+		}
+
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, e := range v {
+				s.Items.validate(e, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, FieldError{Path: joinPath(path, name), Message: "is required"})
+			}
+		}
+		for _, name := range s.order {
+			if val, ok := v[name]; ok {
+				s.Properties[name].validate(val, joinPath(path, name), errs)
+			}
+		}
+		if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			var extra []string
+			for name := range v {
+				if _, ok := s.Properties[name]; !ok {
+					extra = append(extra, name)
+				}
+			}
+			slices.Sort(extra)
+			for _, name := range extra {
+				*errs = append(*errs, FieldError{Path: joinPath(path, name), Message: "is not allowed"})
+			}
+		}
+	}
+}
+
+// Coerce converts top-level string fields to the number, integer or boolean
+// their property declares, for values scraped as text ("12.99", "true").
+// Fields that do not parse are left for validation to report.
+func (s *Schema) Coerce(fields map[string]any) {
+	for name, prop := range s.Properties {
+		str, ok := fields[name].(string)
+		if !ok || prop.Type.Has("string") {
+			continue
+		}
+		str = strings.TrimSpace(str)
+		switch prop.Type.Primary() {
+		case "number":
+			if f, err := strconv.ParseFloat(strings.ReplaceAll(str, ",", ""), 64); err == nil {
+				fields[name] = f
+			}
+		case "integer":
+			if n, err := strconv.ParseInt(strings.ReplaceAll(str, ",", ""), 10, 64); err == nil {
+				fields[name] = n
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(str); err == nil {
+				fields[name] = b
+			}
+		}
+	}
+}
+
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func jsonEqual(a, b any) bool {
+	// Normalise Go values from struct-derived schemas to their JSON form.
+	norm := func(v any) any {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var out any
+		json.Unmarshal(data, &out)
+		return out
+	}
+	return reflect.DeepEqual(norm(a), norm(b))
+}
+
+func compact(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+var (
+	hostnameRe = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+	uuidRe     = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	timeRe     = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+)
+
+// validFormat checks the common "format" values. Unknown formats pass, as
+// JSON Schema treats format as an annotation unless the validator knows it.
+func validFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "time":
+		return timeRe.MatchString(s)
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uri", "url", "iri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "")
+	case "uri-reference":
+		_, err := url.Parse(s)
+		return err == nil
+	case "hostname":
+		return len(s) <= 253 && hostnameRe.MatchString(s)
+	case "ipv4":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && strings.Contains(s, ".")
+	case "ipv6":
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	case "uuid":
+		return uuidRe.MatchString(s)
+	}
+	return true
+}
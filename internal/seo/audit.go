@@ -1,6 +1,8 @@
 package seo
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -25,14 +27,30 @@ type SitemapURL struct {
 	Priority   float64 `xml:"priority,omitempty" json:"priority,omitempty"`
 }
 
+// Modified parses LastMod, which sitemaps write in W3C datetime format. It
+// returns the zero time when LastMod is missing or malformed.
+func (u SitemapURL) Modified() time.Time {
+	return parseW3CDate(u.LastMod)
+}
+
 // Sitemap represents a parsed sitemap.
 type Sitemap struct {
 	URLs     []SitemapURL `xml:"url" json:"urls"`
 	Sitemaps []struct {
-		Loc string `xml:"loc"`
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
 	} `xml:"sitemap" json:"sitemaps"`
 }
 
+// maxSitemapSize is the protocol's limit on an uncompressed sitemap.
+const maxSitemapSize = 50 << 20
+
+// maxSitemapDepth bounds how deeply sitemap indexes may nest.
+const maxSitemapDepth = 5
+
+// wellKnownSitemaps are probed when robots.txt lists no sitemaps.
+var wellKnownSitemaps = []string{"/sitemap.xml", "/sitemap_index.xml", "/sitemap.xml.gz"}
+
 // SitemapCrawler fetches and parses sitemaps.
 type SitemapCrawler struct {
 	client *http.Client
@@ -49,56 +67,151 @@ func NewSitemapCrawler(logger *slog.Logger) *SitemapCrawler {
 
 // Crawl fetches and parses a sitemap, recursively following sitemap indexes.
 func (sc *SitemapCrawler) Crawl(sitemapURL string) ([]SitemapURL, error) {
+	return sc.CrawlSince(sitemapURL, time.Time{})
+}
+
+// CrawlSince is Crawl for sitemaps changed after since. Child sitemaps whose
+// index entry has a lastmod at or before since are not fetched; URL entries
+// are returned unfiltered. Gzipped sitemaps and plain-text sitemaps (one URL
+// per line) are accepted, and each sitemap is read at most once.
+func (sc *SitemapCrawler) CrawlSince(sitemapURL string, since time.Time) ([]SitemapURL, error) {
 	sc.logger.Info("crawling sitemap", "url", sitemapURL)
+	visited := make(map[string]bool)
+	urls, err := sc.expand(sitemapURL, since, 0, visited)
+	if err != nil {
+		return nil, err
+	}
+	sc.logger.Info("sitemap crawled", "url", sitemapURL, "sitemaps", len(visited), "urls", len(urls))
+	return urls, nil
+}
+
+func (sc *SitemapCrawler) expand(sitemapURL string, since time.Time, depth int, visited map[string]bool) ([]SitemapURL, error) {
+	visited[sitemapURL] = true
+
+	sitemap, err := sc.fetch(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	allURLs := sitemap.URLs
+
+	// Recursively fetch sub-sitemaps
+	for _, sub := range sitemap.Sitemaps {
+		loc := strings.TrimSpace(sub.Loc)
+		if loc == "" || visited[loc] {
+			continue
+		}
+		if depth+1 > maxSitemapDepth {
+			sc.logger.Warn("sitemap index nested too deeply", "url", loc)
+			continue
+		}
+		if mod := parseW3CDate(sub.LastMod); !since.IsZero() && !mod.IsZero() && !mod.After(since) {
+			sc.logger.Debug("sub-sitemap unchanged", "url", loc, "lastmod", sub.LastMod)
+			continue
+		}
+		subURLs, err := sc.expand(loc, since, depth+1, visited)
+		if err != nil {
+			sc.logger.Warn("sub-sitemap error", "url", loc, "error", err)
+			continue
+		}
+		allURLs = append(allURLs, subURLs...)
+	}
+	return allURLs, nil
+}
 
+// fetch downloads and decodes one sitemap.
+func (sc *SitemapCrawler) fetch(sitemapURL string) (*Sitemap, error) {
 	resp, err := sc.client.Get(sitemapURL)
 	if err != nil {
 		return nil, fmt.Errorf("fetch sitemap: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch sitemap: status %d", resp.StatusCode)
+	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSitemapSize))
 	if err != nil {
 		return nil, fmt.Errorf("read sitemap: %w", err)
 	}
 
+	// Servers send .xml.gz as application/x-gzip or application/octet-stream,
+	// so sniff the gzip magic rather than trusting headers.
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("decompress sitemap: %w", err)
+		}
+		body, err = io.ReadAll(io.LimitReader(zr, maxSitemapSize))
+		zr.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decompress sitemap: %w", err)
+		}
+	}
+
 	var sitemap Sitemap
+	trimmed := bytes.TrimSpace(body)
+	if !bytes.HasPrefix(trimmed, []byte("<")) {
+		for _, line := range strings.Split(string(trimmed), "\n") {
+			if line = strings.TrimSpace(line); strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+				sitemap.URLs = append(sitemap.URLs, SitemapURL{Loc: line})
+			}
+		}
+		return &sitemap, nil
+	}
 	if err := xml.Unmarshal(body, &sitemap); err != nil {
 		return nil, fmt.Errorf("parse sitemap: %w", err)
 	}
+	for i := range sitemap.URLs {
+		sitemap.URLs[i].Loc = strings.TrimSpace(sitemap.URLs[i].Loc)
+	}
+	return &sitemap, nil
+}
 
-	var allURLs []SitemapURL
-	allURLs = append(allURLs, sitemap.URLs...)
+// DiscoverSitemap finds the sitemap URL for a domain.
+func (sc *SitemapCrawler) DiscoverSitemap(domain string) string {
+	if found := sc.DiscoverSitemaps("https://" + domain); len(found) > 0 {
+		return found[0]
+	}
+	return ""
+}
 
-	// Recursively fetch sub-sitemaps
-	for _, sub := range sitemap.Sitemaps {
-		subURLs, err := sc.Crawl(sub.Loc)
+// DiscoverSitemaps probes the well-known sitemap locations of a site, given
+// as scheme://host, and returns those that exist.
+func (sc *SitemapCrawler) DiscoverSitemaps(site string) []string {
+	var found []string
+	for _, path := range wellKnownSitemaps {
+		u := strings.TrimSuffix(site, "/") + path
+		resp, err := sc.client.Head(u)
 		if err != nil {
-			sc.logger.Warn("sub-sitemap error", "url", sub.Loc, "error", err)
 			continue
 		}
-		allURLs = append(allURLs, subURLs...)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			found = append(found, u)
+		}
 	}
-
-	sc.logger.Info("sitemap crawled", "url", sitemapURL, "urls", len(allURLs))
-	return allURLs, nil
+	return found
 }
 
-// DiscoverSitemap finds the sitemap URL for a domain.
-func (sc *SitemapCrawler) DiscoverSitemap(domain string) string {
-	candidates := []string{
-		"https://" + domain + "/sitemap.xml",
-		"https://" + domain + "/sitemap_index.xml",
-		"https://" + domain + "/sitemap.xml.gz",
-	}
+// w3cDateFormats are the datetime profiles sitemaps use for lastmod.
+var w3cDateFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
 
-	for _, u := range candidates {
-		resp, err := sc.client.Head(u)
-		if err == nil && resp.StatusCode == 200 {
-			return u
+func parseW3CDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range w3cDateFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
 		}
 	}
-	return ""
+	return time.Time{}
 }
 
 // --- Meta Tag Auditor ---
@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/columnar"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// ColumnarStorage writes items as Parquet or Arrow IPC files named
+// results-00000.parquet (or .arrow), results-00001... in the output
+// directory. Numbering continues past files left by earlier runs, as
+// PartitionedStorage's {seq} does, so earlier output is kept.
+//
+// Items are buffered into row groups; the first row group fixes a file's
+// schema. When a later row group brings new fields or wider values the
+// file is finished and the next one starts with the evolved schema, whose
+// columns extend the previous file's. Readers that combine files by column
+// name (DuckDB's union_by_name, Spark's mergeSchema) see one table. Files
+// are also rotated by item count or size, and each is written under a .tmp
+// name and renamed when complete, then listed in the manifest.
+type ColumnarStorage struct {
+	format      string // parquet or arrow
+	dir         string
+	codec       string
+	rowGroup    int
+	rotateItems int
+	rotateBytes int64
+
+	pending   []map[string]any
+	schema    *columnar.Schema
+	file      *os.File
+	out       *hashWriter
+	writer    columnar.Writer
+	part      int
+	fileItems int
+	started   time.Time
+	files     []string
+	manifest  *manifest
+	mu        sync.Mutex
+	count     int
+	logger    *slog.Logger
+}
+
+// NewColumnarStorage creates a Parquet ("parquet") or Arrow IPC ("arrow")
+// storage writing to outputDir. WithCompression, WithRowGroupSize and
+// WithRotation configure it.
+func NewColumnarStorage(format, outputDir string, logger *slog.Logger, opts ...Option) (*ColumnarStorage, error) {
+	o := applyOptions(opts)
+	s := &ColumnarStorage{
+		format:      format,
+		dir:         outputDir,
+		codec:       o.compression,
+		rowGroup:    o.rowGroupSize,
+		rotateItems: o.rotateItems,
+		rotateBytes: o.rotateBytes,
+		logger:      logger.With("component", format+"_storage"),
+	}
+	if s.rowGroup <= 0 {
+		s.rowGroup = 10000
+	}
+	var codecs []string
+	switch format {
+	case "parquet":
+		codecs = columnar.ParquetCodecs
+		if s.codec == "" {
+			s.codec = "snappy"
+		}
+	case "arrow":
+		codecs = columnar.ArrowCodecs
+		if s.codec == "" {
+			s.codec = "none"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported columnar format: %s", format)
+	}
+	if !slices.Contains(codecs, s.codec) {
+		return nil, fmt.Errorf("unsupported %s compression %q (valid: %s)", format, s.codec, strings.Join(codecs, ", "))
+	}
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create output dir: %w", err)
+	}
+	m, err := loadManifest(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	s.manifest = m
+	return s, nil
+}
+
+func (s *ColumnarStorage) Name() string { return s.format }
+
+// Buffered reports true: pending rows and the open file are complete only
+// after Close.
+func (s *ColumnarStorage) Buffered() bool { return true }
+
+func (s *ColumnarStorage) Store(items []*types.Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		s.pending = append(s.pending, entryOf(item, false))
+	}
+	for len(s.pending) >= s.rowGroup {
+		if err := s.flush(s.rowGroup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush writes the first n pending rows.
+func (s *ColumnarStorage) flush(n int) error {
+	for n > 0 {
+		written, err := s.write(s.pending[:n])
+		if err != nil {
+			return err
+		}
+		s.pending = s.pending[written:]
+		n -= written
+	}
+	return nil
+}
+
+// write writes rows as one row group, first starting a new file if there
+// is none or the rows do not fit its schema. It stops at the file's item
+// limit and returns how many rows it wrote.
+func (s *ColumnarStorage) write(rows []map[string]any) (int, error) {
+	schema := columnar.Infer(s.schema, rows, "_url", "_timestamp", "_spider")
+	if s.writer != nil && !schema.Equal(s.schema) {
+		s.logger.Info("schema changed, starting a new file", "columns", len(schema.Columns))
+		if err := s.finish(); err != nil {
+			return 0, err
+		}
+	}
+	if s.writer == nil {
+		if err := s.open(schema); err != nil {
+			return 0, err
+		}
+	}
+	if s.rotateItems > 0 && len(rows) > s.rotateItems-s.fileItems {
+		rows = rows[:s.rotateItems-s.fileItems]
+	}
+	if err := s.writer.WriteRows(rows); err != nil {
+		return 0, fmt.Errorf("write %s: %w", s.format, err)
+	}
+	s.fileItems += len(rows)
+	s.count += len(rows)
+
+	if s.rotateItems > 0 && s.fileItems >= s.rotateItems || s.rotateBytes > 0 && s.writer.Size() >= s.rotateBytes {
+		return len(rows), s.finish()
+	}
+	return len(rows), nil
+}
+
+func (s *ColumnarStorage) open(schema *columnar.Schema) error {
+	var path string
+	for {
+		path = filepath.Join(s.dir, fmt.Sprintf("results-%05d.%s", s.part, s.format))
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		s.part++
+	}
+	path += ".tmp"
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	out := newHashWriter(f)
+	var w columnar.Writer
+	if s.format == "parquet" {
+		w, err = columnar.NewParquetWriter(out, schema, s.codec)
+	} else {
+		w, err = columnar.NewArrowWriter(out, schema, s.codec)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("start %s file: %w", s.format, err)
+	}
+	s.file, s.out, s.writer, s.schema = f, out, w, schema
+	s.part++
+	s.fileItems = 0
+	s.started = time.Now()
+	return nil
+}
+
+// finish completes the current file and moves it to its final name.
+func (s *ColumnarStorage) finish() error {
+	if s.writer == nil {
+		return nil
+	}
+	tmp := s.file.Name()
+	err := s.writer.Close()
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	s.file, s.writer = nil, nil
+	if err != nil {
+		return fmt.Errorf("finish %s file: %w", s.format, err)
+	}
+	path := tmp[:len(tmp)-len(".tmp")]
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("finish %s file: %w", s.format, err)
+	}
+	s.files = append(s.files, path)
+	s.logger.Debug("file written", "path", path, "items", s.fileItems)
+	return s.manifest.add(path, s.fileItems, s.out, s.started)
+}
+
+// Files returns the completed files, in order.
+func (s *ColumnarStorage) Files() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.files...)
+}
+
+func (s *ColumnarStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.flush(len(s.pending)); err != nil {
+		return err
+	}
+	if err := s.finish(); err != nil {
+		return err
+	}
+	s.logger.Info(s.format+" written", "dir", s.dir, "files", len(s.files), "items", s.count)
+	return nil
+}
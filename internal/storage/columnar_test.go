@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// readManifest returns the manifest entries in dir after checking each
+// against its file's size and hash.
+func readManifest(t *testing.T, dir string) []ManifestEntry {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m struct{ Files []ManifestEntry }
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range m.Files {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(e.Path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum := sha256.Sum256(data); e.SHA256 != hex.EncodeToString(sum[:]) || e.Bytes != int64(len(data)) {
+			t.Errorf("manifest entry %+v does not match the file", e)
+		}
+	}
+	return m.Files
+}
+
+func TestColumnarStorage(t *testing.T) {
+	items := make([]*types.Item, 7)
+	for i := range items {
+		items[i] = types.NewItem(fmt.Sprintf("https://example.com/%d", i))
+		items[i].Set("title", fmt.Sprintf("item %d", i))
+		items[i].Set("price", i)
+		if i >= 4 {
+			items[i].Set("rating", 4.5) // new field: starts a new file
+		}
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		opts   []Option
+		want   []string // file: items
+		magic  [2]string
+	}{
+		{
+			name:   "rotation and schema change",
+			format: "parquet",
+			opts:   []Option{WithRowGroupSize(2), WithRotation(3, 0)},
+			// 3 (rotated), 1 (schema change at item 4), 3.
+			want:  []string{"results-00000.parquet: 3", "results-00001.parquet: 1", "results-00002.parquet: 3"},
+			magic: [2]string{"PAR1", "PAR1"},
+		},
+		{
+			name:   "one row group",
+			format: "parquet",
+			opts:   []Option{WithCompression("gzip")},
+			// Row groups are inferred as a whole, so the new field does
+			// not split the file.
+			want:  []string{"results-00000.parquet: 7"},
+			magic: [2]string{"PAR1", "PAR1"},
+		},
+		{
+			name:   "arrow",
+			format: "arrow",
+			opts:   []Option{WithCompression("zstd"), WithRowGroupSize(4)},
+			want:   []string{"results-00000.arrow: 4", "results-00001.arrow: 3"},
+			magic:  [2]string{"ARROW1\x00\x00", "ARROW1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := NewFileStorage(tt.format, dir, testLogger, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Store(items); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, e := range readManifest(t, dir) {
+				got = append(got, fmt.Sprintf("%s: %d", e.Path, e.Items))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+			for _, path := range store.(*ColumnarStorage).Files() {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.HasPrefix(string(data), tt.magic[0]) || !strings.HasSuffix(string(data), tt.magic[1]) {
+					t.Errorf("%s is not a %s file", path, tt.format)
+				}
+			}
+			if tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmp) > 0 {
+				t.Errorf("leftover temp files: %v", tmp)
+			}
+		})
+	}
+}
+
+func TestColumnarStorageKeepsEarlierRuns(t *testing.T) {
+	dir := t.TempDir()
+	for run := 0; run < 2; run++ {
+		store, err := NewFileStorage("arrow", dir, testLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items := []*types.Item{types.NewItem(fmt.Sprintf("https://example.com/%d", run))}
+		if err := store.Store(items); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	for _, e := range readManifest(t, dir) {
+		got = append(got, fmt.Sprintf("%s: %d", e.Path, e.Items))
+	}
+	if want := []string{"results-00000.arrow: 1", "results-00001.arrow: 1"}; !slices.Equal(got, want) {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
+
+func TestColumnarStorageOptions(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   []Option
+		err    string
+	}{
+		{"arrow", []Option{WithCompression("snappy")}, `unsupported arrow compression "snappy"`},
+		{"parquet", []Option{WithCompression("lzo")}, `unsupported parquet compression "lzo"`},
+		{"orc", nil, "unsupported columnar format"},
+	}
+	for _, tt := range tests {
+		_, err := NewColumnarStorage(tt.format, t.TempDir(), testLogger, tt.opts...)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.format, err, tt.err)
+		}
+	}
+}
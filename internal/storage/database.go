@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
@@ -17,13 +18,14 @@ import (
 type MongoStorage struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	upsert     bool // replace documents by _key instead of inserting
 	mu         sync.Mutex
 	count      int
 	logger     *slog.Logger
 }
 
 // NewMongoStorage creates a new MongoDB storage backend.
-func NewMongoStorage(uri, database, collection string, logger *slog.Logger) (*MongoStorage, error) {
+func NewMongoStorage(uri, database, collection string, logger *slog.Logger, opts ...Option) (*MongoStorage, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -36,11 +38,19 @@ func NewMongoStorage(uri, database, collection string, logger *slog.Logger) (*Mo
 		return nil, fmt.Errorf("mongodb ping: %w", err)
 	}
 
-	return &MongoStorage{
+	s := &MongoStorage{
 		client:     client,
 		collection: client.Database(database).Collection(collection),
+		upsert:     applyOptions(opts).upsert,
 		logger:     logger.With("component", "mongo_storage"),
-	}, nil
+	}
+	if s.upsert {
+		index := mongo.IndexModel{Keys: bson.D{{Key: "_key", Value: 1}}}
+		if _, err := s.collection.Indexes().CreateOne(ctx, index); err != nil {
+			return nil, fmt.Errorf("mongodb create _key index: %w", err)
+		}
+	}
+	return s, nil
 }
 
 func (s *MongoStorage) Name() string { return "mongodb" }
@@ -55,6 +65,9 @@ func (s *MongoStorage) Store(items []*types.Item) error {
 		doc["_source_url"] = item.URL
 		doc["_timestamp"] = item.Timestamp
 		doc["_spider"] = item.SpiderName
+		if s.upsert && item.Key != "" {
+			doc["_key"] = item.Key
+		}
 		for k, v := range item.Fields {
 			doc[k] = v
 		}
@@ -64,8 +77,22 @@ func (s *MongoStorage) Store(items []*types.Item) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	_, err := s.collection.InsertMany(ctx, docs)
-	if err != nil {
+	if s.upsert {
+		models := make([]mongo.WriteModel, len(items))
+		for i, item := range items {
+			if item.Key == "" {
+				models[i] = mongo.NewInsertOneModel().SetDocument(docs[i])
+				continue
+			}
+			models[i] = mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_key": item.Key}).
+				SetReplacement(docs[i]).
+				SetUpsert(true)
+		}
+		if _, err := s.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("mongodb upsert: %w", err)
+		}
+	} else if _, err := s.collection.InsertMany(ctx, docs); err != nil {
 		return fmt.Errorf("mongodb insert: %w", err)
 	}
 
@@ -99,6 +126,16 @@ func NewMultiStorage(backends []Storage, logger *slog.Logger) *MultiStorage {
 
 func (s *MultiStorage) Name() string { return "multi" }
 
+// Buffered reports whether any backend is buffered.
+func (s *MultiStorage) Buffered() bool {
+	for _, backend := range s.backends {
+		if Buffered(backend) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *MultiStorage) Store(items []*types.Item) error {
 	var firstErr error
 	for _, backend := range s.backends {
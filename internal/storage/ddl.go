@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/schema"
+)
+
+// SchemaDDL returns a CREATE TABLE statement for items matching s. Columns
+// follow the schema's property order after the _url, _timestamp and _spider
+// metadata columns, and object and array properties are stored as JSON.
+// dialect is "postgres" or "sqlite".
+//
+// Required properties are not NOT NULL: schema validation may pass invalid
+// items on (on_invalid: annotate), and one such item must not fail a whole
+// batch.
+func SchemaDDL(s *schema.Schema, table, dialect string) (string, error) {
+	var colType func(schema.Column) string
+	var tsType string
+	switch dialect {
+	case "postgres", "postgresql":
+		colType, tsType = postgresType, "TIMESTAMPTZ"
+	case "sqlite", "sqlite3":
+		colType, tsType = sqliteType, "TEXT"
+	default:
+		return "", fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	defs := []string{
+		quoteIdent("_url") + " TEXT NOT NULL",
+		quoteIdent("_timestamp") + " " + tsType + " NOT NULL",
+		quoteIdent("_spider") + " TEXT",
+	}
+	for _, col := range s.Columns() {
+		defs = append(defs, quoteIdent(col.Name)+" "+colType(col))
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n  %s\n)", quoteIdent(table), strings.Join(defs, ",\n  ")), nil
+}
+
+func postgresType(col schema.Column) string {
+	switch col.Type {
+	case "integer":
+		return "BIGINT"
+	case "number":
+		return "DOUBLE PRECISION"
+	case "boolean":
+		return "BOOLEAN"
+	case "object", "array", "":
+		return "JSONB"
+	}
+	switch col.Format {
+	case "date-time":
+		return "TIMESTAMPTZ"
+	case "date":
+		return "DATE"
+	}
+	return "TEXT"
+}
+
+func sqliteType(col schema.Column) string {
+	switch col.Type {
+	case "integer", "boolean":
+		return "INTEGER"
+	case "number":
+		return "REAL"
+	}
+	return "TEXT" // strings, dates and JSON
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/schema"
+)
+
+func TestSchemaDDL(t *testing.T) {
+	s, err := schema.Parse([]byte(`
+type: object
+required: [name] # nullable all the same: annotated invalid items are stored
+properties:
+  name: {type: string}
+  price: {type: number}
+  qty: {type: integer}
+  active: {type: boolean}
+  seen: {type: string, format: date-time}
+  tags: {type: array, items: {type: string}}
+  'odd "name"': {type: string}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dialect string
+		want    string
+		err     bool
+	}{
+		{dialect: "postgres", want: `CREATE TABLE IF NOT EXISTS "products" (
+  "_url" TEXT NOT NULL,
+  "_timestamp" TIMESTAMPTZ NOT NULL,
+  "_spider" TEXT,
+  "name" TEXT,
+  "price" DOUBLE PRECISION,
+  "qty" BIGINT,
+  "active" BOOLEAN,
+  "seen" TIMESTAMPTZ,
+  "tags" JSONB,
+  "odd ""name""" TEXT
+)`},
+		{dialect: "sqlite", want: `CREATE TABLE IF NOT EXISTS "products" (
+  "_url" TEXT NOT NULL,
+  "_timestamp" TEXT NOT NULL,
+  "_spider" TEXT,
+  "name" TEXT,
+  "price" REAL,
+  "qty" INTEGER,
+  "active" INTEGER,
+  "seen" TEXT,
+  "tags" TEXT,
+  "odd ""name""" TEXT
+)`},
+		{dialect: "mysql", err: true},
+	}
+	for _, tt := range tests {
+		got, err := SchemaDDL(s, "products", tt.dialect)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: error = %v", tt.dialect, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s DDL =\n%s\nwant\n%s", tt.dialect, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -20,29 +21,46 @@ type JSONStorage struct {
 	path   string
 	file   *os.File
 	items  []*types.Item
+	merged *upsertSet // existing output plus new items, with WithUpsert
 	mu     sync.Mutex
 	logger *slog.Logger
 }
 
 // NewJSONStorage creates a new JSON file storage.
-func NewJSONStorage(outputPath string, logger *slog.Logger) (*JSONStorage, error) {
+func NewJSONStorage(outputPath string, logger *slog.Logger, opts ...Option) (*JSONStorage, error) {
 	dir := filepath.Dir(outputPath)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return nil, fmt.Errorf("create output dir: %w", err)
 	}
 
-	return &JSONStorage{
+	s := &JSONStorage{
 		path:   outputPath,
 		items:  make([]*types.Item, 0),
 		logger: logger.With("component", "json_storage"),
-	}, nil
+	}
+	if applyOptions(opts).upsert {
+		s.merged = newUpsertSet()
+		if err := s.merged.loadJSON(outputPath); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
 }
 
 func (s *JSONStorage) Name() string { return "json" }
 
+// Buffered reports true: the file is written on Close.
+func (s *JSONStorage) Buffered() bool { return true }
+
 func (s *JSONStorage) Store(items []*types.Item) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.merged != nil {
+		for _, item := range items {
+			s.merged.put(entryOf(item, true))
+		}
+		return nil
+	}
 	s.items = append(s.items, items...)
 	s.logger.Debug("items buffered", "count", len(items), "total", len(s.items))
 	return nil
@@ -52,6 +70,19 @@ func (s *JSONStorage) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.merged != nil {
+		err := writeFileAtomic(s.path, func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(s.merged.entries)
+		})
+		if err != nil {
+			return fmt.Errorf("write JSON: %w", err)
+		}
+		s.logger.Info("JSON upserted", "path", s.path, "items", len(s.merged.entries))
+		return nil
+	}
+
 	f, err := os.Create(s.path)
 	if err != nil {
 		return fmt.Errorf("create output file: %w", err)
@@ -61,16 +92,7 @@ func (s *JSONStorage) Close() error {
 	// Build output as array of field maps
 	output := make([]map[string]any, len(s.items))
 	for i, item := range s.items {
-		entry := make(map[string]any, len(item.Fields)+3)
-		entry["_url"] = item.URL
-		entry["_timestamp"] = item.Timestamp
-		if item.SpiderName != "" {
-			entry["_spider"] = item.SpiderName
-		}
-		for k, v := range item.Fields {
-			entry[k] = v
-		}
-		output[i] = entry
+		output[i] = entryOf(item, false)
 	}
 
 	enc := json.NewEncoder(f)
@@ -86,53 +108,62 @@ func (s *JSONStorage) Close() error {
 // --- JSONL Storage ---
 
 // JSONLStorage writes items as newline-delimited JSON (one object per line).
+// With WithUpsert it merges with the existing file instead, and writes it
+// on Close.
 type JSONLStorage struct {
 	path   string
 	file   *os.File
 	enc    *json.Encoder
+	merged *upsertSet
 	mu     sync.Mutex
 	count  int
 	logger *slog.Logger
 }
 
 // NewJSONLStorage creates a new JSONL file storage (streaming writes).
-func NewJSONLStorage(outputPath string, logger *slog.Logger) (*JSONLStorage, error) {
+func NewJSONLStorage(outputPath string, logger *slog.Logger, opts ...Option) (*JSONLStorage, error) {
 	dir := filepath.Dir(outputPath)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return nil, fmt.Errorf("create output dir: %w", err)
 	}
 
+	s := &JSONLStorage{
+		path:   outputPath,
+		logger: logger.With("component", "jsonl_storage"),
+	}
+	if applyOptions(opts).upsert {
+		s.merged = newUpsertSet()
+		if err := s.merged.loadJSONL(outputPath); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
 	f, err := os.Create(outputPath)
 	if err != nil {
 		return nil, fmt.Errorf("create output file: %w", err)
 	}
-
-	return &JSONLStorage{
-		path:   outputPath,
-		file:   f,
-		enc:    json.NewEncoder(f),
-		logger: logger.With("component", "jsonl_storage"),
-	}, nil
+	s.file = f
+	s.enc = json.NewEncoder(f)
+	return s, nil
 }
 
 func (s *JSONLStorage) Name() string { return "jsonl" }
 
+// Buffered reports whether items wait for Close, as they do with upsert.
+func (s *JSONLStorage) Buffered() bool { return s.merged != nil }
+
 func (s *JSONLStorage) Store(items []*types.Item) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for _, item := range items {
-		entry := make(map[string]any, len(item.Fields)+3)
-		entry["_url"] = item.URL
-		entry["_timestamp"] = item.Timestamp
-		if item.SpiderName != "" {
-			entry["_spider"] = item.SpiderName
-		}
-		for k, v := range item.Fields {
-			entry[k] = v
+		if s.merged != nil {
+			s.merged.put(entryOf(item, true))
+			s.count++
+			continue
 		}
-
-		if err := s.enc.Encode(entry); err != nil {
+		if err := s.enc.Encode(entryOf(item, false)); err != nil {
 			return fmt.Errorf("encode JSONL: %w", err)
 		}
 		s.count++
@@ -141,6 +172,25 @@ func (s *JSONLStorage) Store(items []*types.Item) error {
 }
 
 func (s *JSONLStorage) Close() error {
+	if s.merged != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		err := writeFileAtomic(s.path, func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			for _, entry := range s.merged.entries {
+				if err := enc.Encode(entry); err != nil {
+					return fmt.Errorf("encode JSONL: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		s.logger.Info("JSONL upserted", "path", s.path, "items", s.count, "total", len(s.merged.entries))
+		return nil
+	}
+
 	s.logger.Info("JSONL written", "path", s.path, "items", s.count)
 	if s.file != nil {
 		return s.file.Close()
@@ -156,6 +206,7 @@ type CSVStorage struct {
 	file    *os.File
 	writer  *csv.Writer
 	headers []string
+	written bool // header row written
 	mu      sync.Mutex
 	count   int
 	logger  *slog.Logger
@@ -181,6 +232,15 @@ func NewCSVStorage(outputPath string, logger *slog.Logger) (*CSVStorage, error)
 	}, nil
 }
 
+// SetColumns fixes the header row to the item metadata followed by cols, in
+// that order, instead of the first item's sorted keys. Call it before the
+// first Store; fields outside cols are not written.
+func (s *CSVStorage) SetColumns(cols []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headers = append([]string{"_url", "_timestamp", "_spider"}, cols...)
+}
+
 func (s *CSVStorage) Name() string { return "csv" }
 
 func (s *CSVStorage) Store(items []*types.Item) error {
@@ -190,13 +250,16 @@ func (s *CSVStorage) Store(items []*types.Item) error {
 	for _, item := range items {
 		flat := item.ToFlatMap()
 
-		// Detect headers on first item
+		// Detect headers on first item, unless SetColumns fixed them
 		if s.headers == nil {
 			s.headers = make([]string, 0, len(flat))
 			for k := range flat {
 				s.headers = append(s.headers, k)
 			}
 			sort.Strings(s.headers)
+		}
+		if !s.written {
+			s.written = true
 
 			// Write header row
 			if err := s.writer.Write(s.headers); err != nil {
@@ -230,15 +293,49 @@ func (s *CSVStorage) Close() error {
 	return nil
 }
 
-// NewFileStorage creates the appropriate file-based storage by type.
-func NewFileStorage(storageType, outputDir string, logger *slog.Logger) (Storage, error) {
+// New creates the storage backend for storageType: a file under outputDir,
+// or a table in the sqlite or postgres database at dsn. An empty sqlite dsn
+// means outputDir/results.db.
+func New(storageType, outputDir, dsn, table string, logger *slog.Logger, opts ...Option) (Storage, error) {
+	switch storageType {
+	case "sqlite":
+		if dsn == "" {
+			dsn = filepath.Join(outputDir, "results.db")
+		}
+		return NewSQLStorage(storageType, dsn, table, logger, opts...)
+	case "postgres":
+		return NewSQLStorage(storageType, dsn, table, logger, opts...)
+	}
+	return NewFileStorage(storageType, outputDir, logger, opts...)
+}
+
+// NewFileStorage creates the appropriate file-based storage by type. JSONL
+// and CSV output with a path template, rotation or compression is written
+// by a PartitionedStorage.
+func NewFileStorage(storageType, outputDir string, logger *slog.Logger, opts ...Option) (Storage, error) {
+	o := applyOptions(opts)
+	if (storageType == "jsonl" || storageType == "csv") && o.partitioned() {
+		if o.upsert {
+			return nil, fmt.Errorf("%s storage does not support upsert with rotation, compression or path templates", storageType)
+		}
+		return NewPartitionedStorage(storageType, outputDir, logger, opts...)
+	}
+
 	switch storageType {
 	case "json":
-		return NewJSONStorage(filepath.Join(outputDir, "results.json"), logger)
+		return NewJSONStorage(filepath.Join(outputDir, "results.json"), logger, opts...)
 	case "jsonl":
-		return NewJSONLStorage(filepath.Join(outputDir, "results.jsonl"), logger)
+		return NewJSONLStorage(filepath.Join(outputDir, "results.jsonl"), logger, opts...)
 	case "csv":
+		if o.upsert {
+			return nil, fmt.Errorf("csv storage does not support upsert")
+		}
 		return NewCSVStorage(filepath.Join(outputDir, "results.csv"), logger)
+	case "parquet", "arrow":
+		if o.upsert {
+			return nil, fmt.Errorf("%s storage does not support upsert", storageType)
+		}
+		return NewColumnarStorage(storageType, outputDir, logger, opts...)
 	default:
 		return nil, fmt.Errorf("unsupported storage type: %s", storageType)
 	}
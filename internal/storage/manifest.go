@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// ManifestName is the file, in the output directory, that lists the files
+// written by the rotating storages (partitioned JSONL/CSV, Parquet, Arrow).
+const ManifestName = "manifest.json"
+
+// ManifestEntry describes one completed output file.
+type ManifestEntry struct {
+	Path     string    `json:"path"` // relative to the output directory, slash-separated
+	Items    int       `json:"items"`
+	Bytes    int64     `json:"bytes"`
+	SHA256   string    `json:"sha256"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+// manifest keeps ManifestName up to date as files complete. Entries from
+// earlier runs are kept for as long as their files exist.
+type manifest struct {
+	dir   string
+	Files []ManifestEntry `json:"files"`
+}
+
+func loadManifest(dir string) (*manifest, error) {
+	m := &manifest{dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	m.Files = slices.DeleteFunc(m.Files, func(e ManifestEntry) bool {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(e.Path)))
+		return err != nil
+	})
+	return m, nil
+}
+
+// add records the completed file at path, whose bytes went through out,
+// and rewrites the manifest.
+func (m *manifest) add(path string, items int, out *hashWriter, started time.Time) error {
+	rel, err := filepath.Rel(m.dir, path)
+	if err != nil {
+		return fmt.Errorf("update manifest: %w", err)
+	}
+	entry := ManifestEntry{
+		Path:     filepath.ToSlash(rel),
+		Items:    items,
+		Bytes:    out.n,
+		SHA256:   hex.EncodeToString(out.h.Sum(nil)),
+		Started:  started,
+		Finished: time.Now(),
+	}
+	m.Files = slices.DeleteFunc(m.Files, func(e ManifestEntry) bool { return e.Path == entry.Path })
+	m.Files = append(m.Files, entry)
+
+	err = writeFileAtomic(filepath.Join(m.dir, ManifestName), func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	})
+	if err != nil {
+		return fmt.Errorf("update manifest: %w", err)
+	}
+	return nil
+}
+
+// hashWriter counts and checksums the bytes written through it.
+type hashWriter struct {
+	w io.Writer
+	h hash.Hash
+	n int64
+}
+
+func newHashWriter(w io.Writer) *hashWriter {
+	return &hashWriter{w: w, h: sha256.New()}
+}
+
+func (w *hashWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.h.Write(p[:n])
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,392 @@
+package storage
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// pathPlaceholders are the placeholders a path template may use.
+var pathPlaceholders = []string{"{spider}", "{date}", "{hour}", "{seq}"}
+
+var placeholderRe = regexp.MustCompile(`\{[^{}]*\}`)
+
+// PartitionedStorage writes JSONL or CSV items to files named by a path
+// template relative to the output directory, such as
+// "{spider}/{date}/{hour}-{seq}.jsonl.gz". {spider} is the item's spider,
+// {date} and {hour} come from its timestamp (UTC) and {seq} numbers the
+// files of a partition, continuing past files left by earlier runs.
+//
+// Each partition has one open file, its part, written under a .tmp name and
+// renamed when complete: when it reaches the rotation item count or size,
+// when it is older than the rotation interval, or on Close. Completed
+// files are listed in the output directory's manifest with their item
+// counts and SHA-256 checksums. Files are compressed with gzip or zstd when
+// configured, or when the template ends in .gz or .zst.
+type PartitionedStorage struct {
+	format      string // jsonl or csv
+	dir         string
+	template    string
+	codec       string // none, gzip or zstd
+	rotateItems int
+	rotateBytes int64
+	rotateAfter time.Duration
+
+	parts    map[string]*part // open parts by partition (the template without {seq})
+	seqs     map[string]int   // next sequence number by partition
+	headers  []string         // CSV header row, shared by all files
+	files    []string
+	manifest *manifest
+	mu       sync.Mutex
+	count    int
+	stop     chan struct{}
+	done     chan struct{}
+	logger   *slog.Logger
+}
+
+// part is a file being written.
+type part struct {
+	path    string // final path; written as path+".tmp" until complete
+	file    *os.File
+	out     *hashWriter
+	comp    io.WriteCloser // gzip or zstd writer, nil when uncompressed
+	enc     *json.Encoder
+	csv     *csv.Writer
+	items   int
+	started time.Time
+}
+
+// NewPartitionedStorage creates a JSONL ("jsonl") or CSV ("csv") storage
+// writing under outputDir. WithPathTemplate, WithCompression, WithRotation
+// and WithRotateInterval configure it; without a template files are named
+// results.jsonl, or results-00000.jsonl, results-00001... when rotating,
+// plus the compression's extension.
+func NewPartitionedStorage(format, outputDir string, logger *slog.Logger, opts ...Option) (*PartitionedStorage, error) {
+	o := applyOptions(opts)
+	s := &PartitionedStorage{
+		format:      format,
+		dir:         outputDir,
+		template:    o.pathTemplate,
+		codec:       o.compression,
+		rotateItems: o.rotateItems,
+		rotateBytes: o.rotateBytes,
+		rotateAfter: o.rotateInterval,
+		parts:       make(map[string]*part),
+		seqs:        make(map[string]int),
+		logger:      logger.With("component", format+"_storage"),
+	}
+	if format != "jsonl" && format != "csv" {
+		return nil, fmt.Errorf("unsupported partitioned format: %s", format)
+	}
+	if s.codec == "" {
+		s.codec = "none"
+		switch {
+		case strings.HasSuffix(s.template, ".gz"):
+			s.codec = "gzip"
+		case strings.HasSuffix(s.template, ".zst"):
+			s.codec = "zstd"
+		}
+	}
+	if s.codec != "none" && s.codec != "gzip" && s.codec != "zstd" {
+		return nil, fmt.Errorf("unsupported %s compression %q (valid: none, gzip, zstd)", format, s.codec)
+	}
+
+	rotates := s.rotateItems > 0 || s.rotateBytes > 0 || s.rotateAfter > 0
+	if s.template == "" {
+		s.template = "results." + format
+		if rotates {
+			s.template = "results-{seq}." + format
+		}
+		switch s.codec {
+		case "gzip":
+			s.template += ".gz"
+		case "zstd":
+			s.template += ".zst"
+		}
+	}
+	for _, ph := range placeholderRe.FindAllString(s.template, -1) {
+		if !slices.Contains(pathPlaceholders, ph) {
+			return nil, fmt.Errorf("unknown placeholder %s in path template (valid: %s)", ph, strings.Join(pathPlaceholders, ", "))
+		}
+	}
+	if rotates && !strings.Contains(s.template, "{seq}") {
+		return nil, fmt.Errorf("path template %q needs {seq} to rotate files", s.template)
+	}
+	if !filepath.IsLocal(filepath.FromSlash(s.template)) {
+		return nil, fmt.Errorf("path template %q must stay inside the output directory", s.template)
+	}
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create output dir: %w", err)
+	}
+	m, err := loadManifest(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	s.manifest = m
+
+	if s.rotateAfter > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.rotateLoop()
+	}
+	return s, nil
+}
+
+// SetColumns fixes the CSV header row, as CSVStorage.SetColumns does.
+func (s *PartitionedStorage) SetColumns(cols []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headers = append([]string{"_url", "_timestamp", "_spider"}, cols...)
+}
+
+func (s *PartitionedStorage) Name() string { return s.format }
+
+// Buffered reports true: items in a part are durable only once it is
+// renamed, at rotation or on Close.
+func (s *PartitionedStorage) Buffered() bool { return true }
+
+func (s *PartitionedStorage) Store(items []*types.Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		key := s.partition(item)
+		p := s.parts[key]
+		if p == nil {
+			var err error
+			if p, err = s.open(key); err != nil {
+				return err
+			}
+			s.parts[key] = p
+		}
+		if err := s.write(p, item); err != nil {
+			return fmt.Errorf("write %s: %w", s.format, err)
+		}
+		p.items++
+		s.count++
+
+		if s.rotateItems > 0 && p.items >= s.rotateItems || s.rotateBytes > 0 && s.size(p) >= s.rotateBytes {
+			if err := s.finish(key, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range s.parts {
+		if p.csv != nil {
+			p.csv.Flush()
+			if err := p.csv.Error(); err != nil {
+				return fmt.Errorf("write csv: %w", err)
+			}
+		}
+	}
+	if s.rotateAfter > 0 {
+		return s.expire(time.Now())
+	}
+	return nil
+}
+
+// partition renders the path template for item, leaving {seq}.
+func (s *PartitionedStorage) partition(item *types.Item) string {
+	ts := item.Timestamp.UTC()
+	spider := item.SpiderName
+	switch spider {
+	case "":
+		spider = "default"
+	case ".", "..":
+		spider = "_"
+	}
+	spider = strings.NewReplacer("/", "_", `\`, "_").Replace(spider)
+	return strings.NewReplacer(
+		"{spider}", spider,
+		"{date}", ts.Format("2006-01-02"),
+		"{hour}", ts.Format("15"),
+	).Replace(s.template)
+}
+
+// open starts the next file of the partition key.
+func (s *PartitionedStorage) open(key string) (*part, error) {
+	seq := s.seqs[key]
+	var path string
+	for {
+		path = filepath.Join(s.dir, filepath.FromSlash(strings.ReplaceAll(key, "{seq}", fmt.Sprintf("%05d", seq))))
+		seq++
+		if !strings.Contains(key, "{seq}") {
+			break
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+	}
+	s.seqs[key] = seq
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create output dir: %w", err)
+	}
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, fmt.Errorf("create output file: %w", err)
+	}
+	p := &part{path: path, file: f, out: newHashWriter(f), started: time.Now()}
+	var w io.Writer = p.out
+	switch s.codec {
+	case "gzip":
+		p.comp = gzip.NewWriter(p.out)
+	case "zstd":
+		zw, err := zstd.NewWriter(p.out)
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return nil, fmt.Errorf("start zstd: %w", err)
+		}
+		p.comp = zw
+	}
+	if p.comp != nil {
+		w = p.comp
+	}
+	if s.format == "csv" {
+		p.csv = csv.NewWriter(w)
+	} else {
+		p.enc = json.NewEncoder(w)
+	}
+	return p, nil
+}
+
+func (s *PartitionedStorage) write(p *part, item *types.Item) error {
+	if p.enc != nil {
+		return p.enc.Encode(entryOf(item, false))
+	}
+
+	flat := item.ToFlatMap()
+	if s.headers == nil {
+		s.headers = make([]string, 0, len(flat))
+		for k := range flat {
+			s.headers = append(s.headers, k)
+		}
+		sort.Strings(s.headers)
+	}
+	if p.items == 0 {
+		if err := p.csv.Write(s.headers); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(s.headers))
+	for i, h := range s.headers {
+		row[i] = flat[h]
+	}
+	return p.csv.Write(row)
+}
+
+// size returns the bytes written to p's file. Compressors buffer, so it
+// trails the data written so far by up to their block size.
+func (s *PartitionedStorage) size(p *part) int64 {
+	if p.csv != nil {
+		p.csv.Flush()
+	}
+	return p.out.n
+}
+
+// expire finishes the parts opened at least the rotation interval before
+// now.
+func (s *PartitionedStorage) expire(now time.Time) error {
+	for key, p := range s.parts {
+		if now.Sub(p.started) >= s.rotateAfter {
+			if err := s.finish(key, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rotateLoop finishes expired parts while no items arrive.
+func (s *PartitionedStorage) rotateLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(min(s.rotateAfter, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			if err := s.expire(now); err != nil {
+				s.logger.Error("rotate output file", "error", err)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// finish completes p, moves it to its final name and adds it to the
+// manifest.
+func (s *PartitionedStorage) finish(key string, p *part) error {
+	delete(s.parts, key)
+	var err error
+	if p.csv != nil {
+		p.csv.Flush()
+		err = p.csv.Error()
+	}
+	if p.comp != nil {
+		if cerr := p.comp.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := p.file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(p.file.Name(), p.path)
+	}
+	if err != nil {
+		return fmt.Errorf("finish %s file: %w", s.format, err)
+	}
+	s.files = append(s.files, p.path)
+	s.logger.Debug("file written", "path", p.path, "items", p.items)
+	return s.manifest.add(p.path, p.items, p.out, p.started)
+}
+
+// Files returns the completed files, in the order they completed.
+func (s *PartitionedStorage) Files() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.files...)
+}
+
+func (s *PartitionedStorage) Close() error {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.parts))
+	for key := range s.parts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, key := range keys {
+		errs = append(errs, s.finish(key, s.parts[key]))
+	}
+	s.logger.Info(strings.ToUpper(s.format)+" written", "dir", s.dir, "files", len(s.files), "items", s.count)
+	return errors.Join(errs...)
+}
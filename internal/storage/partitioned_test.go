@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// readOutput returns the decompressed contents of an output file, by its
+// extension.
+func readOutput(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	switch filepath.Ext(path) {
+	case ".gz":
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case ".zst":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestPartitionedStorage(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := func(spider, title string) *types.Item {
+		it := types.NewItem("https://example.com/" + title)
+		it.SpiderName = spider
+		it.Timestamp = ts
+		it.Set("title", title)
+		return it
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		opts   []Option
+		runs   [][]*types.Item
+		want   []string // manifest "path: items", sorted
+		check  string   // a file and a substring of its contents
+		substr string
+	}{
+		{
+			name:   "template with rotation",
+			format: "jsonl",
+			opts:   []Option{WithPathTemplate("{spider}/{date}/{hour}-{seq}.jsonl.gz"), WithRotation(2, 0)},
+			runs: [][]*types.Item{
+				{item("books", "a"), item("news", "x"), item("books", "b"), item("books", "c")},
+				{item("books", "d")}, // continues the sequence
+			},
+			want: []string{
+				"books/2026-01-02/03-00000.jsonl.gz: 2",
+				"books/2026-01-02/03-00001.jsonl.gz: 1",
+				"books/2026-01-02/03-00002.jsonl.gz: 1",
+				"news/2026-01-02/03-00000.jsonl.gz: 1",
+			},
+			check:  "books/2026-01-02/03-00000.jsonl.gz",
+			substr: `"title":"b"`,
+		},
+		{
+			name:   "default names",
+			format: "jsonl",
+			opts:   []Option{WithCompression("zstd"), WithRotation(3, 0)},
+			runs:   [][]*types.Item{{item("s", "a"), item("s", "b"), item("s", "c"), item("s", "d")}},
+			want:   []string{"results-00000.jsonl.zst: 3", "results-00001.jsonl.zst: 1"},
+			check:  "results-00001.jsonl.zst",
+			substr: `"title":"d"`,
+		},
+		{
+			name:   "csv per spider",
+			format: "csv",
+			opts:   []Option{WithPathTemplate("{spider}.csv")},
+			runs:   [][]*types.Item{{item("books", "a"), item("news", "x"), item("books", "b")}},
+			want:   []string{"books.csv: 2", "news.csv: 1"},
+			check:  "books.csv",
+			substr: "https://example.com/b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, items := range tt.runs {
+				store, err := NewFileStorage(tt.format, dir, testLogger, tt.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := store.Store(items); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var got []string
+			for _, e := range readManifest(t, dir) {
+				got = append(got, fmt.Sprintf("%s: %d", e.Path, e.Items))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+			if data := readOutput(t, filepath.Join(dir, tt.check)); !strings.Contains(data, tt.substr) {
+				t.Errorf("%s = %q, want it to contain %s", tt.check, data, tt.substr)
+			}
+			tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+			nested, _ := filepath.Glob(filepath.Join(dir, "*", "*", "*.tmp"))
+			if len(tmp)+len(nested) > 0 {
+				t.Errorf("leftover temp files: %v %v", tmp, nested)
+			}
+		})
+	}
+}
+
+func TestPartitionedStorageOptions(t *testing.T) {
+	tests := []struct {
+		opts []Option
+		err  string
+	}{
+		{[]Option{WithPathTemplate("{spider}/{minute}.jsonl")}, "unknown placeholder {minute}"},
+		{[]Option{WithPathTemplate("items.jsonl"), WithRotation(10, 0)}, "needs {seq}"},
+		{[]Option{WithPathTemplate("../up-{seq}.jsonl")}, "inside the output directory"},
+		{[]Option{WithCompression("brotli")}, `unsupported jsonl compression "brotli"`},
+	}
+	for _, tt := range tests {
+		_, err := NewPartitionedStorage("jsonl", t.TempDir(), testLogger, tt.opts...)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("error = %v, want %q", err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" driver
+	_ "github.com/mattn/go-sqlite3"    // registers the "sqlite3" driver
+
+	"github.com/IshaanNene/ScrapeGoat/internal/schema"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// SQLStorage writes items to a SQLite or PostgreSQL table, one column per
+// top-level field after the _url, _timestamp and _spider metadata columns.
+//
+// The table is created if missing: from the schema given with WithSchema,
+// or from the metadata columns alone. Fields without a column get one on
+// first sight, typed from their value; existing columns are never changed
+// or dropped. Objects and arrays are stored as JSONB on PostgreSQL and as
+// JSON text on SQLite. Each Store call is one transaction.
+//
+// On PostgreSQL a value is converted to its column's type where it can be
+// (the string "2.5" for a DOUBLE PRECISION column); one that cannot, such
+// as text in a numeric column, is stored as NULL and logged rather than
+// failing the batch. SQLite matches field names to columns
+// case-insensitively, as it does in SQL.
+type SQLStorage struct {
+	db      *sql.DB
+	dialect string // postgres or sqlite
+	table   string
+	upsert  bool              // replace rows by _key instead of inserting
+	columns map[string]string // columnKey(name) -> kind, see columnKind
+	mu      sync.Mutex
+	count   int
+	logger  *slog.Logger
+}
+
+// NewSQLStorage opens the database and prepares table. dialect is
+// "postgres" (dsn is a connection URL or key=value string) or "sqlite" (dsn
+// is a file path, whose directory is created).
+func NewSQLStorage(dialect, dsn, table string, logger *slog.Logger, opts ...Option) (*SQLStorage, error) {
+	var driver string
+	switch dialect {
+	case "postgres", "postgresql":
+		dialect, driver = "postgres", "pgx"
+	case "sqlite", "sqlite3":
+		dialect, driver = "sqlite", "sqlite3"
+		if dsn != ":memory:" && !strings.HasPrefix(dsn, "file:") {
+			if err := os.MkdirAll(filepath.Dir(dsn), 0o755); err != nil {
+				return nil, fmt.Errorf("create database directory: %w", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s open: %w", dialect, err)
+	}
+	if dialect == "sqlite" {
+		db.SetMaxOpenConns(1) // one writer; also keeps :memory: to one database
+	}
+
+	o := applyOptions(opts)
+	s := &SQLStorage{
+		db:      db,
+		dialect: dialect,
+		table:   table,
+		upsert:  o.upsert,
+		logger:  logger.With("component", "sql_storage", "dialect", dialect, "table", table),
+	}
+	if err := s.prepare(o.schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLStorage) Name() string { return s.dialect }
+
+// prepare creates the table and adds the schema's columns and, for upsert,
+// the _key column and its unique index when they are missing.
+func (s *SQLStorage) prepare(sch *schema.Schema) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s ping: %w", s.dialect, err)
+	}
+	if sch == nil {
+		sch = &schema.Schema{}
+	}
+	ddl, err := SchemaDDL(sch, s.table, s.dialect)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, ddl); err != nil {
+		return fmt.Errorf("create table %s: %w", s.table, err)
+	}
+	if err := s.loadColumns(ctx); err != nil {
+		return err
+	}
+
+	colType := postgresType
+	if s.dialect == "sqlite" {
+		colType = sqliteType
+	}
+	for _, col := range sch.Columns() {
+		if err := s.addColumn(ctx, s.db, col.Name, colType(col)); err != nil {
+			return err
+		}
+	}
+	if s.upsert {
+		if err := s.addColumn(ctx, s.db, "_key", "TEXT"); err != nil {
+			return err
+		}
+		index := fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s)",
+			quoteIdent(s.table+"_key"), quoteIdent(s.table), quoteIdent("_key"))
+		if _, err := s.db.ExecContext(ctx, index); err != nil {
+			return fmt.Errorf("create _key index: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadColumns reads the table's existing columns.
+func (s *SQLStorage) loadColumns(ctx context.Context) error {
+	query := `SELECT name, type FROM pragma_table_info(?)`
+	if s.dialect == "postgres" {
+		query = `SELECT column_name, data_type FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = $1`
+	}
+	rows, err := s.db.QueryContext(ctx, query, s.table)
+	if err != nil {
+		return fmt.Errorf("read columns of %s: %w", s.table, err)
+	}
+	defer rows.Close()
+
+	s.columns = make(map[string]string)
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return fmt.Errorf("read columns of %s: %w", s.table, err)
+		}
+		s.columns[s.columnKey(name)] = columnKind(typ)
+	}
+	return rows.Err()
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// addColumn adds a nullable column unless the table has it.
+func (s *SQLStorage) addColumn(ctx context.Context, db execer, name, typ string) error {
+	if _, ok := s.columns[s.columnKey(name)]; ok {
+		return nil
+	}
+	stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", quoteIdent(s.table), quoteIdent(name), typ)
+	if _, err := db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("add column %s: %w", name, err)
+	}
+	s.logger.Info("column added", "column", name, "type", typ)
+	s.columns[s.columnKey(name)] = columnKind(typ)
+	return nil
+}
+
+// columnKey is the name a column is known by in s.columns. SQLite
+// compares column names ignoring the case of ASCII letters.
+func (s *SQLStorage) columnKey(name string) string {
+	if s.dialect != "sqlite" {
+		return name
+	}
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, name)
+}
+
+// byColumn keys an item's fields by columnKey. Of fields naming the same
+// column, the first in sorted order is stored.
+func (s *SQLStorage) byColumn(fields map[string]any) map[string]any {
+	if s.dialect != "sqlite" {
+		return fields
+	}
+	out := make(map[string]any, len(fields))
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		col := s.columnKey(k)
+		if _, ok := out[col]; !ok {
+			out[col] = fields[k]
+		}
+	}
+	return out
+}
+
+// columnKind classifies a declared column type by how values are encoded
+// for it: json, text, bool, int, float, time or other.
+func columnKind(typ string) string {
+	typ = strings.ToUpper(typ)
+	switch {
+	case strings.Contains(typ, "JSON"):
+		return "json"
+	case strings.Contains(typ, "TEXT"), strings.Contains(typ, "CHAR"), strings.Contains(typ, "CLOB"):
+		return "text"
+	case strings.HasPrefix(typ, "BOOL"):
+		return "bool"
+	case typ == "INTEGER", strings.HasSuffix(typ, "INT"), strings.Contains(typ, "SERIAL"):
+		return "int"
+	case strings.Contains(typ, "DOUBLE"), strings.Contains(typ, "REAL"), strings.Contains(typ, "FLOAT"),
+		strings.Contains(typ, "NUMERIC"), strings.Contains(typ, "DECIMAL"):
+		return "float"
+	case strings.HasPrefix(typ, "TIMESTAMP"), typ == "DATE":
+		return "time"
+	}
+	return "other"
+}
+
+// fit converts v for a typed PostgreSQL column of the given kind,
+// reporting false when v has no value of that type: a string that does not
+// parse, a fraction for an integer column, an object for a number.
+func fit(kind string, v any) (any, bool) {
+	rv := reflect.ValueOf(v)
+	str := rv.Kind() == reflect.String
+	switch kind {
+	case "bool":
+		switch {
+		case rv.Kind() == reflect.Bool:
+			return rv.Bool(), true
+		case str:
+			b, err := strconv.ParseBool(rv.String())
+			return b, err == nil
+		}
+	case "int":
+		switch {
+		case rv.CanInt():
+			return rv.Int(), true
+		case rv.CanUint():
+			return int64(rv.Uint()), rv.Uint() <= math.MaxInt64
+		case rv.CanFloat():
+			f := rv.Float()
+			return int64(f), f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+		case str:
+			n, err := strconv.ParseInt(strings.TrimSpace(rv.String()), 10, 64)
+			return n, err == nil
+		}
+	case "float":
+		switch {
+		case rv.CanInt():
+			return float64(rv.Int()), true
+		case rv.CanUint():
+			return float64(rv.Uint()), true
+		case rv.CanFloat():
+			return rv.Float(), true
+		case str:
+			f, err := strconv.ParseFloat(strings.TrimSpace(rv.String()), 64)
+			return f, err == nil
+		}
+	case "time":
+		if t, ok := v.(time.Time); ok {
+			return t, true
+		}
+		if str {
+			t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(rv.String()))
+			return t, err == nil
+		}
+	default:
+		return v, true
+	}
+	return nil, false
+}
+
+// inferType returns the column type for a field first seen with value v.
+func (s *SQLStorage) inferType(v any) string {
+	pg := s.dialect == "postgres"
+	switch v.(type) {
+	case bool:
+		if pg {
+			return "BOOLEAN"
+		}
+		return "INTEGER"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		if pg {
+			return "BIGINT"
+		}
+		return "INTEGER"
+	case float32, float64:
+		if pg {
+			return "DOUBLE PRECISION"
+		}
+		return "REAL"
+	case time.Time:
+		if pg {
+			return "TIMESTAMPTZ"
+		}
+		return "TEXT"
+	case string:
+		return "TEXT"
+	}
+	if pg {
+		return "JSONB"
+	}
+	return "TEXT" // JSON
+}
+
+func (s *SQLStorage) Store(items []*types.Item) (err error) {
+	if len(items) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s begin: %w", s.dialect, err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			// Columns added in the transaction are gone again.
+			if lerr := s.loadColumns(context.Background()); lerr != nil {
+				s.logger.Error("reload columns", "error", lerr)
+			}
+		}
+	}()
+
+	// One statement covers every field in the batch; items missing a
+	// field store NULL. A field seen only as null waits for a typed value
+	// before it gets a column.
+	rows := make([]map[string]any, len(items))
+	seen := make(map[string]bool)
+	var fields []string
+	for i, item := range items {
+		rows[i] = s.byColumn(item.Fields)
+		for k, v := range item.Fields {
+			col := s.columnKey(k)
+			if _, exists := s.columns[col]; seen[col] || isMetaColumn(col) || v == nil && !exists {
+				continue
+			}
+			if err := s.addColumn(ctx, tx, k, s.inferType(v)); err != nil {
+				return err
+			}
+			seen[col] = true
+			fields = append(fields, col)
+		}
+	}
+	sort.Strings(fields)
+
+	cols := []string{"_url", "_timestamp", "_spider"}
+	if s.upsert {
+		cols = append(cols, "_key")
+	}
+	cols = append(cols, fields...)
+	stmt, err := tx.PrepareContext(ctx, s.insertSQL(cols))
+	if err != nil {
+		return fmt.Errorf("%s prepare insert: %w", s.dialect, err)
+	}
+	defer stmt.Close()
+
+	for i, item := range items {
+		args := make([]any, 0, len(cols))
+		args = append(args, item.URL, s.encode("other", item.Timestamp), nullString(item.SpiderName))
+		if s.upsert {
+			args = append(args, nullString(item.Key))
+		}
+		for _, k := range fields {
+			v, ok := rows[i][k]
+			if !ok {
+				args = append(args, nil)
+				continue
+			}
+			kind := s.columns[k]
+			if s.dialect == "postgres" && v != nil {
+				fitted, ok := fit(kind, v)
+				if !ok {
+					s.logger.Warn("value does not fit column, storing NULL",
+						"column", k, "type", kind, "url", item.URL)
+				}
+				v = fitted
+			}
+			args = append(args, s.encode(kind, v))
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("%s insert: %w", s.dialect, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s commit: %w", s.dialect, err)
+	}
+
+	s.count += len(items)
+	s.logger.Debug("items stored", "count", len(items), "total", s.count)
+	return nil
+}
+
+// insertSQL builds the INSERT for cols, updating the row with the same
+// _key instead when upserting.
+func (s *SQLStorage) insertSQL(cols []string) string {
+	quoted := make([]string, len(cols))
+	params := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = quoteIdent(c)
+		params[i] = "?"
+		if s.dialect == "postgres" {
+			params[i] = fmt.Sprintf("$%d", i+1)
+		}
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdent(s.table), strings.Join(quoted, ", "), strings.Join(params, ", "))
+	if !s.upsert {
+		return query
+	}
+	updates := make([]string, 0, len(cols))
+	for _, c := range quoted {
+		if c != quoteIdent("_key") {
+			updates = append(updates, c+" = excluded."+c)
+		}
+	}
+	return query + fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", quoteIdent("_key"), strings.Join(updates, ", "))
+}
+
+// encode converts a field value for a column of the given kind. Objects and
+// arrays are always written as JSON.
+func (s *SQLStorage) encode(kind string, v any) any {
+	if v == nil {
+		return nil
+	}
+	if t, ok := v.(time.Time); ok {
+		if s.dialect == "sqlite" || kind == "text" {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+		return t
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		kind = "json"
+	}
+	switch kind {
+	case "json":
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	case "text":
+		if str, ok := v.(string); ok {
+			return str
+		}
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+func isMetaColumn(name string) bool {
+	switch name {
+	case "_url", "_timestamp", "_spider", "_key":
+		return true
+	}
+	return false
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func (s *SQLStorage) Close() error {
+	s.logger.Info("sql storage closing", "total_items", s.count)
+	return s.db.Close()
+}
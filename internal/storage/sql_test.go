@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/schema"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// TestSQLiteOpen stores an item in a sqlite database. The sqlite driver
+// needs cgo; the Dockerfile runs this test with the image's build settings
+// so a build without cgo fails there rather than at run time.
+func TestSQLiteOpen(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "items.db")
+	store, err := New("sqlite", "", dsn, "items", testLogger)
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	item := types.NewItem("https://example.com/a")
+	item.Set("name", "A")
+	if err := store.Store([]*types.Item{item}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var name string
+	if err := db.QueryRow(`SELECT name FROM items`).Scan(&name); err != nil || name != "A" {
+		t.Errorf("stored name = %q, %v", name, err)
+	}
+}
+
+// queryRows returns each result row as its columns joined by spaces,
+// NULLs as empty strings.
+func queryRows(t *testing.T, db *sql.DB, query string) []string {
+	t.Helper()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cols, _ := rows.Columns()
+	var out []string
+	for rows.Next() {
+		vals := make([]sql.NullString, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			t.Fatal(err)
+		}
+		parts := make([]string, len(vals))
+		for i, v := range vals {
+			parts[i] = v.String
+		}
+		out = append(out, strings.Join(parts, " "))
+	}
+	return out
+}
+
+func sqlItem(key string, fields map[string]any) *types.Item {
+	item := types.NewItem("https://example.com/" + key)
+	item.Key = key
+	item.Fields = fields
+	return item
+}
+
+func TestSQLStorage(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "db", "items.db")
+	s, err := schema.Parse([]byte(`{"type": "object", "required": ["name"], "properties": {
+		"name": {"type": "string"}, "price": {"type": "number"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each step opens the store, writes one batch and closes it; want is
+	// the table's cols afterwards.
+	const all = "_key, name, price, specs, stock, tags"
+	steps := []struct {
+		name  string
+		opts  []Option
+		items []*types.Item
+		cols  string
+		want  []string
+	}{
+		{
+			name:  "schema columns",
+			opts:  []Option{WithUpsert(), WithSchema(s)},
+			items: []*types.Item{sqlItem("a", map[string]any{"name": "A", "price": 1.5}), sqlItem("b", map[string]any{"name": "B"})},
+			cols:  "_key, name, price",
+			want:  []string{"a A 1.5", "b B "},
+		},
+		{
+			name: "upsert and new columns",
+			opts: []Option{WithUpsert(), WithSchema(s)},
+			items: []*types.Item{
+				sqlItem("b", map[string]any{"name": "B2", "price": 3.0, "specs": map[string]any{"w": 2}, "stock": 4}),
+				sqlItem("c", map[string]any{"name": "C", "tags": []string{"x", "y"}}),
+			},
+			cols: all,
+			want: []string{"a A 1.5   ", `b B2 3 {"w":2} 4 `, `c C    ["x","y"]`},
+		},
+		{
+			// Required properties are left to schema validation: an item
+			// missing one (passed on by on_invalid: annotate) is stored.
+			name:  "missing required field",
+			opts:  []Option{WithSchema(s)},
+			items: []*types.Item{sqlItem("d", map[string]any{"price": 2.0})},
+			cols:  all,
+			want:  []string{"  2   ", "a A 1.5   ", `b B2 3 {"w":2} 4 `, `c C    ["x","y"]`},
+		},
+		{
+			// SQLite column names ignore case: these fields go to the
+			// existing columns rather than failing as duplicates.
+			name:  "field names differ in case",
+			opts:  []Option{WithUpsert()},
+			items: []*types.Item{sqlItem("e", map[string]any{"Name": "E", "PRICE": 5.0, "price": 6.0, "_URL": "x"})},
+			cols:  "_key, name, price",
+			want:  []string{"  2", "a A 1.5", "b B2 3", "c C ", "e E 5"},
+		},
+	}
+	for _, step := range steps {
+		store, err := New("sqlite", "", dsn, "products", testLogger, step.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if err := store.Store(step.items); err != nil {
+			t.Errorf("%s: store: %v", step.name, err)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		db, err := sql.Open("sqlite3", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := queryRows(t, db, "SELECT "+step.cols+" FROM products ORDER BY _key"); !slices.Equal(got, step.want) {
+			t.Errorf("%s: rows =\n%q\nwant\n%q", step.name, got, step.want)
+		}
+		db.Close()
+	}
+}
+
+func TestSQLStorageRollback(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "items.db")
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE checked (_url TEXT NOT NULL, _timestamp TEXT NOT NULL, _spider TEXT, price REAL CHECK (price >= 0))`); err != nil {
+		t.Fatal(err)
+	}
+	store, err := New("sqlite", "", dsn, "checked", testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	tests := []struct {
+		name  string
+		items []*types.Item
+		err   bool
+		rows  int
+	}{
+		// A batch with a row the table rejects leaves nothing, including
+		// the column it added.
+		{"rejected row", []*types.Item{sqlItem("e", map[string]any{"price": 1.0, "note": "x"}), sqlItem("f", map[string]any{"price": -1.0})}, true, 0},
+		{"valid batch", []*types.Item{sqlItem("g", map[string]any{"price": 2.0, "note": "y"})}, false, 1},
+	}
+	for _, tt := range tests {
+		if err := store.Store(tt.items); (err != nil) != tt.err {
+			t.Errorf("%s: error = %v", tt.name, err)
+		}
+		if got := queryRows(t, db, `SELECT COUNT(*) FROM checked`); fmt.Sprint(got) != fmt.Sprintf("[%d]", tt.rows) {
+			t.Errorf("%s: rows = %v, want %d", tt.name, got, tt.rows)
+		}
+	}
+}
+
+// TestSQLFit covers the conversions for typed PostgreSQL columns, which
+// need a server to test end to end.
+func TestSQLFit(t *testing.T) {
+	when := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		typ  string
+		in   any
+		want any
+		ok   bool
+	}{
+		{"double precision", 2, 2.0, true},
+		{"double precision", " 2.5", 2.5, true},
+		{"double precision", "n/a", nil, false},
+		{"double precision", map[string]any{"v": 1}, nil, false},
+		{"bigint", 3.0, int64(3), true},
+		{"bigint", 3.5, nil, false},
+		{"bigint", uint64(math.MaxUint64), nil, false},
+		{"bigint", "42", int64(42), true},
+		{"boolean", "true", true, true},
+		{"boolean", 1, nil, false},
+		{"timestamp with time zone", "2024-05-01T12:00:00Z", when, true},
+		{"timestamp with time zone", "May 1", nil, false},
+		{"timestamp with time zone", when, when, true},
+		{"text", 7, 7, true},
+		{"jsonb", []any{1}, []any{1}, true},
+		{"uuid", "x", "x", true},
+	}
+	for _, tt := range tests {
+		got, ok := fit(columnKind(tt.typ), tt.in)
+		if ok != tt.ok || ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fit(%s, %#v) = %#v, %v; want %#v, %v", tt.typ, tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -15,3 +15,11 @@ type Storage interface {
 	// Name returns the storage backend identifier.
 	Name() string
 }
+
+// Buffered reports whether s may hold stored items in memory or in
+// unfinished files until Close, so that they are not yet durable when Store
+// returns.
+func Buffered(s Storage) bool {
+	b, ok := s.(interface{ Buffered() bool })
+	return ok && b.Buffered()
+}
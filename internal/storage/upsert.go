@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/schema"
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+// Option configures a storage backend.
+type Option func(*storeOptions)
+
+type storeOptions struct {
+	upsert         bool
+	schema         *schema.Schema
+	compression    string
+	rowGroupSize   int
+	rotateItems    int
+	rotateBytes    int64
+	rotateInterval time.Duration
+	pathTemplate   string
+}
+
+// WithUpsert makes the storage replace the stored item with the same Key
+// instead of appending, keeping what earlier runs stored. Items without a
+// Key are appended.
+func WithUpsert() Option {
+	return func(o *storeOptions) { o.upsert = true }
+}
+
+// WithSchema gives SQL storages the item schema to create their table from.
+func WithSchema(s *schema.Schema) Option {
+	return func(o *storeOptions) { o.schema = s }
+}
+
+// WithCompression sets the codec of Parquet (none, snappy, gzip, zstd,
+// brotli; default snappy), Arrow (none, zstd; default none) and JSONL/CSV
+// (none, gzip, zstd; default none) output.
+func WithCompression(codec string) Option {
+	return func(o *storeOptions) { o.compression = codec }
+}
+
+// WithRowGroupSize sets the items per Parquet row group or Arrow record
+// batch (default 10000).
+func WithRowGroupSize(n int) Option {
+	return func(o *storeOptions) { o.rowGroupSize = n }
+}
+
+// WithRotation makes Parquet, Arrow, JSONL and CSV storages start a new
+// file after items items or bytes bytes, whichever comes first. Zero
+// disables a limit. Parquet and Arrow sizes are checked after each row
+// group.
+func WithRotation(items int, bytes int64) Option {
+	return func(o *storeOptions) { o.rotateItems, o.rotateBytes = items, bytes }
+}
+
+// WithRotateInterval makes JSONL and CSV storages finish each file d after
+// starting it.
+func WithRotateInterval(d time.Duration) Option {
+	return func(o *storeOptions) { o.rotateInterval = d }
+}
+
+// WithPathTemplate names JSONL and CSV output files by template; see
+// PartitionedStorage.
+func WithPathTemplate(template string) Option {
+	return func(o *storeOptions) { o.pathTemplate = template }
+}
+
+// partitioned reports whether JSONL/CSV output needs PartitionedStorage.
+func (o storeOptions) partitioned() bool {
+	return o.pathTemplate != "" || o.rotateItems > 0 || o.rotateBytes > 0 || o.rotateInterval > 0 ||
+		o.compression != "" && o.compression != "none"
+}
+
+func applyOptions(opts []Option) storeOptions {
+	var o storeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// entryOf flattens an item into the map written by the JSON-based storages.
+func entryOf(item *types.Item, withKey bool) map[string]any {
+	entry := make(map[string]any, len(item.Fields)+4)
+	entry["_url"] = item.URL
+	entry["_timestamp"] = item.Timestamp
+	if item.SpiderName != "" {
+		entry["_spider"] = item.SpiderName
+	}
+	if withKey && item.Key != "" {
+		entry["_key"] = item.Key
+	}
+	for k, v := range item.Fields {
+		entry[k] = v
+	}
+	return entry
+}
+
+// upsertSet holds entries in first-stored order, replacing entries whose
+// _key matches.
+type upsertSet struct {
+	entries []map[string]any
+	index   map[string]int
+}
+
+func newUpsertSet() *upsertSet {
+	return &upsertSet{index: make(map[string]int)}
+}
+
+func (u *upsertSet) put(entry map[string]any) {
+	key, _ := entry["_key"].(string)
+	if key == "" {
+		u.entries = append(u.entries, entry)
+		return
+	}
+	if i, ok := u.index[key]; ok {
+		u.entries[i] = entry
+		return
+	}
+	u.index[key] = len(u.entries)
+	u.entries = append(u.entries, entry)
+}
+
+// loadJSON reads a JSON array output file into u. A missing file is empty.
+func (u *upsertSet) loadJSON(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read existing output: %w", err)
+	}
+	var entries []map[string]any
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("decode existing output %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		u.put(entry)
+	}
+	return nil
+}
+
+// loadJSONL reads a JSONL output file into u. A missing file is empty.
+func (u *upsertSet) loadJSONL(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read existing output: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var entry map[string]any
+		if err := dec.Decode(&entry); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("decode existing output %s: %w", path, err)
+		}
+		u.put(entry)
+	}
+}
+
+// writeFileAtomic writes path through a temp file and rename, so a failed
+// run leaves the previous output in place.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	bw := bufio.NewWriter(f)
+	if err := write(bw); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("write output file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("close output file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
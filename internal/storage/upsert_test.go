@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/IshaanNene/ScrapeGoat/internal/types"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+func keyed(key, price string) *types.Item {
+	item := types.NewItem("https://example.com/" + key)
+	item.Key = key
+	item.Set("price", price)
+	return item
+}
+
+func TestStorageUpsert(t *testing.T) {
+	// Two runs against the same output; later items replace earlier ones
+	// with the same key, in place.
+	runs := [][]*types.Item{
+		{keyed("a", "1"), keyed("b", "2"), keyed("", "0")},
+		{keyed("b", "3"), keyed("c", "4"), keyed("c", "5")},
+	}
+	want := []string{"a=1", "b=3", "=0", "c=5"}
+
+	tests := []struct {
+		format string
+		read   func(data []byte) ([]map[string]any, error)
+	}{
+		{"json", func(data []byte) ([]map[string]any, error) {
+			var entries []map[string]any
+			return entries, json.Unmarshal(data, &entries)
+		}},
+		{"jsonl", func(data []byte) ([]map[string]any, error) {
+			var entries []map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				var entry map[string]any
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					return nil, err
+				}
+				entries = append(entries, entry)
+			}
+			return entries, nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, items := range runs {
+				s, err := NewFileStorage(tt.format, dir, testLogger, WithUpsert())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := s.Store(items); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			data, err := os.ReadFile(filepath.Join(dir, "results."+tt.format))
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := tt.read(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				key, _ := e["_key"].(string)
+				got = append(got, key+"="+e["price"].(string))
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("output = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStorageUpsertUnsupported(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   []Option
+	}{
+		{"csv", nil},
+		{"parquet", nil},
+		{"arrow", nil},
+		{"jsonl", []Option{WithRotation(10, 0)}},
+		{"jsonl", []Option{WithCompression("gzip")}},
+	}
+	for _, tt := range tests {
+		opts := append([]Option{WithUpsert()}, tt.opts...)
+		if _, err := NewFileStorage(tt.format, t.TempDir(), testLogger, opts...); err == nil || !strings.Contains(err.Error(), "upsert") {
+			t.Errorf("%s %d options: error = %v, want upsert unsupported", tt.format, len(tt.opts), err)
+		}
+	}
+}
+
+func TestStorageBuffered(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   []Option
+		want   bool
+	}{
+		{"json", nil, true},
+		{"jsonl", nil, false},
+		{"jsonl", []Option{WithUpsert()}, true},
+		{"csv", nil, false},
+		{"parquet", nil, true},
+		{"arrow", nil, true},
+		{"jsonl", []Option{WithCompression("gzip")}, true},
+	}
+	for _, tt := range tests {
+		s, err := NewFileStorage(tt.format, t.TempDir(), testLogger, tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Buffered(s); got != tt.want {
+			t.Errorf("%s %d options: Buffered = %v, want %v", tt.format, len(tt.opts), got, tt.want)
+		}
+		if multi := NewMultiStorage([]Storage{s}, testLogger); Buffered(multi) != tt.want {
+			t.Errorf("%s %d options: multi storage Buffered = %v, want %v", tt.format, len(tt.opts), !tt.want, tt.want)
+		}
+		s.Close()
+	}
+}
@@ -24,6 +24,26 @@ type Item struct {
 
 	// Checksum is a hash of the item content for deduplication.
 	Checksum string
+
+	// Key identifies the item across crawls, for storages that upsert.
+	Key string
+
+	// Origin links the item to the request and response it was scraped
+	// from. It is set by the engine and never serialized.
+	Origin *Origin
+}
+
+// Origin describes where an item came from, for middlewares that enrich
+// items from the response rather than the extracted fields.
+type Origin struct {
+	// Request is the request the item was scraped from.
+	Request *Request
+
+	// Response is its response, without the body.
+	Response *ResponseMeta
+
+	// Stats returns a snapshot of the crawl statistics when called.
+	Stats func() map[string]any
 }
 
 // NewItem creates a new empty Item from a source URL.
@@ -126,6 +146,8 @@ func (i *Item) Clone() *Item {
 		Timestamp:  i.Timestamp,
 		Depth:      i.Depth,
 		Checksum:   i.Checksum,
+		Key:        i.Key,
+		Origin:     i.Origin,
 	}
 	for k, v := range i.Fields {
 		clone.Fields[k] = v
@@ -16,6 +16,13 @@ const (
 	PriorityLowest  = 4
 )
 
+// Request meta keys understood by the engine.
+const (
+	// MetaRuleSet names the parser rule set (config parser.rule_sets) used
+	// for the response instead of the default rules.
+	MetaRuleSet = "rule_set"
+)
+
 // Request represents an HTTP request to be fetched by the crawler.
 type Request struct {
 	// URL is the target URL to fetch.
@@ -3,6 +3,7 @@ package types
 import (
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -115,6 +116,66 @@ func (r *Response) IsServerError() bool {
 	return r.StatusCode >= 500 && r.StatusCode < 600
 }
 
+// ResponseMeta is what items keep of the response they were scraped from:
+// everything but the body and the parsed document.
+type ResponseMeta struct {
+	StatusCode    int
+	Headers       http.Header
+	ContentType   string
+	ContentLength int64
+	FinalURL      string
+	FetchDuration time.Duration
+	FetchedAt     time.Time
+	Meta          map[string]any
+
+	// Page holds the page's title, lang, canonical URL and <meta> tags
+	// (keyed by name or property), when the document was parsed.
+	Page map[string]string
+}
+
+// Info returns the response's metadata. Page metadata is read only from an
+// already parsed document, so Info never parses the body itself.
+func (r *Response) Info() *ResponseMeta {
+	info := &ResponseMeta{
+		StatusCode:    r.StatusCode,
+		Headers:       r.Headers,
+		ContentType:   r.ContentType,
+		ContentLength: r.ContentLength,
+		FinalURL:      r.FinalURL,
+		FetchDuration: r.FetchDuration,
+		FetchedAt:     r.FetchedAt,
+		Meta:          r.Meta,
+	}
+	if r.Doc != nil {
+		info.Page = pageMeta(r.Doc)
+	}
+	return info
+}
+
+func pageMeta(doc *goquery.Document) map[string]string {
+	page := make(map[string]string)
+	if title := strings.TrimSpace(doc.Find("title").First().Text()); title != "" {
+		page["title"] = title
+	}
+	if lang, ok := doc.Find("html").Attr("lang"); ok && lang != "" {
+		page["lang"] = lang
+	}
+	if canonical, ok := doc.Find(`link[rel="canonical"]`).Attr("href"); ok && canonical != "" {
+		page["canonical"] = canonical
+	}
+	doc.Find("meta[content]").Each(func(_ int, s *goquery.Selection) {
+		key := s.AttrOr("name", s.AttrOr("property", ""))
+		if key == "" {
+			return
+		}
+		key = strings.ToLower(key)
+		if _, ok := page[key]; !ok {
+			page[key] = s.AttrOr("content", "")
+		}
+	})
+	return page
+}
+
 // bytesReader implements io.Reader for a byte slice.
 type bytesReader struct {
 	data []byte
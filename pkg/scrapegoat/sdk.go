@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -33,6 +34,8 @@ import (
 	"github.com/IshaanNene/ScrapeGoat/internal/fetcher"
 	"github.com/IshaanNene/ScrapeGoat/internal/parser"
 	"github.com/IshaanNene/ScrapeGoat/internal/pipeline"
+	"github.com/IshaanNene/ScrapeGoat/internal/plugin"
+	"github.com/IshaanNene/ScrapeGoat/internal/schema"
 	"github.com/IshaanNene/ScrapeGoat/internal/storage"
 	"github.com/IshaanNene/ScrapeGoat/internal/types"
 )
@@ -41,9 +44,12 @@ import (
 type Crawler struct {
 	cfg       *config.Config
 	engine    *engine.Engine
+	pipeline  *pipeline.Pipeline
 	logger    *slog.Logger
 	htmlRules map[string]HTMLCallback
 	options   []Option
+	schema    *schema.Schema
+	plugins   *plugin.Registry
 }
 
 // HTMLCallback is called for each element matching a CSS selector.
@@ -90,6 +96,54 @@ func (e *Element) Follow(rawURL string) {
 	e.NewRequests = append(e.NewRequests, req)
 }
 
+// SubmitForm queues a submission of the element's form (the element itself,
+// or the form containing it) with fields overriding the values on the page.
+// Hidden inputs such as CSRF tokens and __VIEWSTATE are sent as found.
+func (e *Element) SubmitForm(fields map[string]string) error {
+	form := e.Selection
+	if goquery.NodeName(form) != "form" {
+		form = form.Closest("form")
+	}
+	if form.Length() == 0 {
+		return fmt.Errorf("element is not in a form")
+	}
+
+	// Mark the form so the parser can select this exact element.
+	const marker = "data-scrapegoat-submit"
+	form.SetAttr(marker, "")
+	defer form.RemoveAttr(marker)
+
+	req, err := parser.NewFormRequest(e.Response, parser.FormOptions{
+		Selector: "[" + marker + "]",
+		Fields:   fields,
+	})
+	if err != nil {
+		return err
+	}
+	e.NewRequests = append(e.NewRequests, req)
+	return nil
+}
+
+// Article returns the main content of the element's page with navigation,
+// sidebars and other boilerplate removed.
+func (e *Element) Article() (*Article, error) {
+	return parser.ExtractArticle(e.Response)
+}
+
+// Article is the main content of a page as found by ExtractArticle.
+type Article = parser.Article
+
+// ExtractArticle finds the main content of an HTML page: article HTML, clean
+// text, title, byline, publish date and lead image. pageURL resolves
+// relative image links.
+func ExtractArticle(rawHTML, pageURL string) (*Article, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawHTML))
+	if err != nil {
+		return nil, fmt.Errorf("parse html: %w", err)
+	}
+	return parser.ExtractArticleFromDocument(doc, pageURL), nil
+}
+
 // Option configures a Crawler.
 type Option func(*config.Config)
 
@@ -116,6 +170,45 @@ func WithOutput(format, path string) Option {
 	}
 }
 
+// WithUpsert merges the output with what earlier runs wrote, replacing items
+// with the same key, as set by the "identity" middleware.
+func WithUpsert() Option {
+	return func(c *config.Config) { c.Storage.Upsert = true }
+}
+
+// WithDatabase stores items in table of a "sqlite" or "postgres" database.
+// For sqlite, dsn is the database file.
+func WithDatabase(dialect, dsn, table string) Option {
+	return func(c *config.Config) {
+		c.Storage.Type = dialect
+		c.Storage.DSN = dsn
+		c.Storage.Table = table
+	}
+}
+
+// WithCompression compresses file output with codec: gzip or zstd for
+// jsonl and csv; see config.StorageConfig for parquet and arrow.
+func WithCompression(codec string) Option {
+	return func(c *config.Config) { c.Storage.Compression = codec }
+}
+
+// WithRotation starts a new output file after items items, sizeMB
+// megabytes or (jsonl and csv only) every, whichever comes first. Zero
+// disables a limit.
+func WithRotation(items, sizeMB int, every time.Duration) Option {
+	return func(c *config.Config) {
+		c.Storage.RotateItems = items
+		c.Storage.RotateSizeMB = sizeMB
+		c.Storage.RotateInterval = every
+	}
+}
+
+// WithPathTemplate names jsonl and csv output files, e.g.
+// "{spider}/{date}/{hour}-{seq}.jsonl.gz".
+func WithPathTemplate(template string) Option {
+	return func(c *config.Config) { c.Storage.PathTemplate = template }
+}
+
 // WithUserAgent sets a custom User-Agent.
 func WithUserAgent(ua string) Option {
 	return func(c *config.Config) { c.Engine.UserAgents = []string{ua} }
@@ -144,6 +237,15 @@ func WithMaxRequests(n int) Option {
 	return func(c *config.Config) { c.Engine.MaxRequests = n }
 }
 
+// WithMiddleware appends a pipeline middleware by type, as a
+// pipeline.middlewares config entry would, e.g.
+// WithMiddleware("date_normalize", map[string]any{"fields": []string{"published"}}).
+func WithMiddleware(typ string, options map[string]any) Option {
+	return func(c *config.Config) {
+		c.Pipeline.Middlewares = append(c.Pipeline.Middlewares, config.MiddlewareConfig{Type: typ, Options: options})
+	}
+}
+
 // WithVerbose enables debug-level logging.
 func WithVerbose() Option {
 	return func(c *config.Config) { c.Logging.Level = "debug" }
@@ -166,14 +268,33 @@ func NewCrawler(opts ...Option) *Crawler {
 		cfg:       cfg,
 		logger:    logger,
 		htmlRules: make(map[string]HTMLCallback),
+		plugins:   plugin.NewRegistry(logger),
 	}
 }
 
+// RegisterPlugin adds a plugin to the crawler. A middleware plugin can then
+// be named, with its options, in the pipeline.middlewares config.
+func (c *Crawler) RegisterPlugin(p plugin.Plugin) error {
+	return c.plugins.Register(p)
+}
+
 // OnHTML registers a callback for elements matching the CSS selector.
 func (c *Crawler) OnHTML(selector string, cb HTMLCallback) {
 	c.htmlRules[selector] = cb
 }
 
+// SetItemSchema validates every item against a schema derived from the Go
+// struct v (see schema.FromStruct). Invalid items get a "_schema_errors"
+// field listing what failed.
+func (c *Crawler) SetItemSchema(v any) error {
+	s, err := schema.FromStruct(v)
+	if err != nil {
+		return err
+	}
+	c.schema = s
+	return nil
+}
+
 // Start begins crawling from the given seed URLs.
 func (c *Crawler) Start(urls ...string) error {
 	// Build the engine
@@ -191,14 +312,40 @@ func (c *Crawler) Start(urls ...string) error {
 	eng.SetParser(compositeParser)
 
 	// Setup pipeline
-	pipe := pipeline.New(c.logger)
+	pipe, err := c.plugins.BuildPipeline(c.cfg, c.logger)
+	if err != nil {
+		return fmt.Errorf("invalid pipeline: %w", err)
+	}
+	if c.schema != nil {
+		validate, err := pipeline.NewSchemaValidateMiddleware(c.schema, "annotate", "", true)
+		if err != nil {
+			return fmt.Errorf("create schema validation: %w", err)
+		}
+		pipe.Use(validate)
+	}
 	eng.SetPipeline(pipe)
 
 	// Setup storage
-	store, err := storage.NewFileStorage(c.cfg.Storage.Type, c.cfg.Storage.OutputPath, c.logger)
+	var storeOpts []storage.Option
+	if c.cfg.Storage.Upsert {
+		storeOpts = append(storeOpts, storage.WithUpsert())
+	}
+	storeOpts = append(storeOpts,
+		storage.WithCompression(c.cfg.Storage.Compression),
+		storage.WithRowGroupSize(c.cfg.Storage.RowGroupSize),
+		storage.WithRotation(c.cfg.Storage.RotateItems, int64(c.cfg.Storage.RotateSizeMB)<<20),
+		storage.WithRotateInterval(c.cfg.Storage.RotateInterval),
+		storage.WithPathTemplate(c.cfg.Storage.PathTemplate))
+	if c.schema != nil {
+		storeOpts = append(storeOpts, storage.WithSchema(c.schema))
+	}
+	store, err := storage.New(c.cfg.Storage.Type, c.cfg.Storage.OutputPath, c.cfg.Storage.DSN, c.cfg.Storage.Table, c.logger, storeOpts...)
 	if err != nil {
 		return fmt.Errorf("create storage: %w", err)
 	}
+	if csvStore, ok := store.(interface{ SetColumns([]string) }); ok && c.schema != nil {
+		csvStore.SetColumns(c.schema.PropertyNames())
+	}
 	eng.SetStorage(store)
 
 	// Register HTML callbacks as engine response callbacks
@@ -250,6 +397,7 @@ func (c *Crawler) Start(urls ...string) error {
 	}
 
 	c.engine = eng
+	c.pipeline = pipe
 	return eng.Start()
 }
 
@@ -258,6 +406,12 @@ func (c *Crawler) Wait() {
 	if c.engine != nil {
 		c.engine.Wait()
 	}
+	if c.pipeline != nil {
+		if err := c.pipeline.Close(); err != nil {
+			c.logger.Warn("pipeline close error", "error", err)
+		}
+	}
+	c.plugins.CloseAll()
 }
 
 // Stop gracefully stops the crawler.